@@ -0,0 +1,371 @@
+// Package abi encodes and decodes the Solidity contract ABI: function calls, constructor
+// arguments, return data, revert reasons and event logs. Values use the SealEVM types:
+// addresses are types.Address, bytes32 is types.Hash, integers are *evmInt256.Int (Go
+// integers and *big.Int are accepted for encoding too).
+package abi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/SealSC/SealEVM/crypto/hashes"
+	"github.com/SealSC/SealEVM/types"
+)
+
+var (
+	ErrMethodNotFound = errors.New("method not found")
+	ErrEventNotFound  = errors.New("event not found")
+	ErrErrorNotFound  = errors.New("error not found")
+)
+
+// Method is a function or the constructor. Name is unique in the ABI, overloaded
+// functions get a number appended ("transfer", "transfer0", ...), RawName is the one of
+// the source.
+type Method struct {
+	Name            string
+	RawName         string
+	Inputs          Arguments
+	Outputs         Arguments
+	StateMutability string
+
+	//Sig is the canonical signature, "transfer(address,uint256)"
+	Sig string
+	ID  [4]byte
+}
+
+// IsConstant tells whether the method does not change state, view or pure.
+func (m *Method) IsConstant() bool {
+	return m.StateMutability == "view" || m.StateMutability == "pure"
+}
+
+func (m *Method) IsPayable() bool {
+	return m.StateMutability == "payable"
+}
+
+// Pack is the call data of the method, its selector followed by the arguments.
+func (m *Method) Pack(args ...interface{}) ([]byte, error) {
+	enc, err := m.Inputs.Pack(args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", m.Name, err)
+	}
+
+	return append(append([]byte{}, m.ID[:]...), enc...), nil
+}
+
+// Event is an event, ID is the topic of its signature, not in the logs of anonymous events.
+type Event struct {
+	Name      string
+	RawName   string
+	Inputs    Arguments
+	Anonymous bool
+
+	Sig string
+	ID  types.Hash
+}
+
+// Error is a custom error, its revert data is its selector followed by the arguments.
+type Error struct {
+	Name   string
+	Inputs Arguments
+	Sig    string
+	ID     [4]byte
+}
+
+type ABI struct {
+	Constructor *Method
+	Methods     map[string]*Method
+	Events      map[string]*Event
+	Errors      map[string]*Error
+
+	HasFallback bool
+	HasReceive  bool
+}
+
+type entryJSON struct {
+	Type            string         `json:"type"`
+	Name            string         `json:"name"`
+	Inputs          []ArgumentJSON `json:"inputs"`
+	Outputs         []ArgumentJSON `json:"outputs"`
+	StateMutability string         `json:"stateMutability"`
+	Anonymous       bool           `json:"anonymous"`
+
+	//before solc 0.5
+	Constant bool `json:"constant"`
+	Payable  bool `json:"payable"`
+}
+
+func signature(name string, args Arguments) string {
+	return name + args.Types()
+}
+
+func uniqueName(name string, exist func(string) bool) string {
+	unique := name
+	for i := 0; exist(unique); i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+
+	return unique
+}
+
+// Parse reads a JSON ABI, an array of entries. Artifacts with an "abi" field (solc
+// combined output, hardhat and foundry) are accepted too.
+func Parse(data []byte) (*ABI, error) {
+	var entries []entryJSON
+	if err := json.Unmarshal(data, &entries); err != nil {
+		var artifact struct {
+			ABI json.RawMessage `json:"abi"`
+		}
+
+		if json.Unmarshal(data, &artifact) != nil || len(artifact.ABI) == 0 {
+			return nil, err
+		}
+
+		return Parse(artifact.ABI)
+	}
+
+	abi := &ABI{
+		Methods: map[string]*Method{},
+		Events:  map[string]*Event{},
+		Errors:  map[string]*Error{},
+	}
+
+	for _, e := range entries {
+		inputs, err := newArguments(e.Inputs)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", e.Type, e.Name, err)
+		}
+
+		outputs, err := newArguments(e.Outputs)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", e.Type, e.Name, err)
+		}
+
+		mutability := e.StateMutability
+		if mutability == "" {
+			switch {
+			case e.Constant:
+				mutability = "view"
+			case e.Payable:
+				mutability = "payable"
+			default:
+				mutability = "nonpayable"
+			}
+		}
+
+		switch e.Type {
+		case "function", "":
+			sig := signature(e.Name, inputs)
+			m := &Method{
+				Name: uniqueName(e.Name, func(n string) bool {
+					_, exist := abi.Methods[n]
+					return exist
+				}),
+				RawName:         e.Name,
+				Inputs:          inputs,
+				Outputs:         outputs,
+				StateMutability: mutability,
+				Sig:             sig,
+			}
+			copy(m.ID[:], hashes.Keccak256([]byte(sig)))
+			abi.Methods[m.Name] = m
+
+		case "constructor":
+			abi.Constructor = &Method{
+				Inputs:          inputs,
+				StateMutability: mutability,
+			}
+
+		case "event":
+			sig := signature(e.Name, inputs)
+			ev := &Event{
+				Name: uniqueName(e.Name, func(n string) bool {
+					_, exist := abi.Events[n]
+					return exist
+				}),
+				RawName:   e.Name,
+				Inputs:    inputs,
+				Anonymous: e.Anonymous,
+				Sig:       sig,
+			}
+			ev.ID.SetBytes(hashes.Keccak256([]byte(sig)))
+			abi.Events[ev.Name] = ev
+
+		case "error":
+			sig := signature(e.Name, inputs)
+			er := &Error{
+				Name: uniqueName(e.Name, func(n string) bool {
+					_, exist := abi.Errors[n]
+					return exist
+				}),
+				Inputs: inputs,
+				Sig:    sig,
+			}
+			copy(er.ID[:], hashes.Keccak256([]byte(sig)))
+			abi.Errors[er.Name] = er
+
+		case "fallback":
+			abi.HasFallback = true
+
+		case "receive":
+			abi.HasReceive = true
+
+		default:
+			return nil, fmt.Errorf("unknown abi entry type %q", e.Type)
+		}
+	}
+
+	return abi, nil
+}
+
+// Load reads a JSON ABI from r.
+func Load(r io.Reader) (*ABI, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(data)
+}
+
+// LoadFile reads a JSON ABI file.
+func LoadFile(path string) (*ABI, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(data)
+}
+
+// MustParse is Parse for ABIs known to be valid, it panics otherwise.
+func MustParse(data string) *ABI {
+	abi, err := Parse([]byte(data))
+	if err != nil {
+		panic(err)
+	}
+
+	return abi
+}
+
+// Pack is the call data of the method name.
+func (abi *ABI) Pack(name string, args ...interface{}) ([]byte, error) {
+	m := abi.Methods[name]
+	if m == nil {
+		return nil, fmt.Errorf("%w: %s", ErrMethodNotFound, name)
+	}
+
+	return m.Pack(args...)
+}
+
+// PackConstructor is the creation data, bytecode followed by the constructor arguments.
+func (abi *ABI) PackConstructor(bytecode []byte, args ...interface{}) ([]byte, error) {
+	var inputs Arguments
+	if abi.Constructor != nil {
+		inputs = abi.Constructor.Inputs
+	}
+
+	enc, err := inputs.Pack(args...)
+	if err != nil {
+		return nil, fmt.Errorf("constructor: %w", err)
+	}
+
+	return append(append([]byte{}, bytecode...), enc...), nil
+}
+
+// Unpack decodes the return data of the method name.
+func (abi *ABI) Unpack(name string, data []byte) ([]interface{}, error) {
+	m := abi.Methods[name]
+	if m == nil {
+		return nil, fmt.Errorf("%w: %s", ErrMethodNotFound, name)
+	}
+
+	return m.Outputs.Unpack(data)
+}
+
+// UnpackInto decodes the return data of the method name into dst, see Arguments.UnpackInto.
+func (abi *ABI) UnpackInto(name string, data []byte, dst ...interface{}) error {
+	m := abi.Methods[name]
+	if m == nil {
+		return fmt.Errorf("%w: %s", ErrMethodNotFound, name)
+	}
+
+	return m.Outputs.UnpackInto(data, dst...)
+}
+
+// MethodByID is the method called by call data.
+func (abi *ABI) MethodByID(data []byte) (*Method, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("%w: %d bytes of call data", ErrMethodNotFound, len(data))
+	}
+
+	for _, m := range abi.Methods {
+		if string(m.ID[:]) == string(data[:4]) {
+			return m, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: selector 0x%x", ErrMethodNotFound, data[:4])
+}
+
+// UnpackInput decodes call data into its method and arguments.
+func (abi *ABI) UnpackInput(data []byte) (*Method, []interface{}, error) {
+	m, err := abi.MethodByID(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	args, err := m.Inputs.Unpack(data[4:])
+	return m, args, err
+}
+
+// EventByID is the event of a log topic.
+func (abi *ABI) EventByID(topic types.Hash) (*Event, error) {
+	for _, e := range abi.Events {
+		if !e.Anonymous && e.ID == topic {
+			return e, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: topic %s", ErrEventNotFound, topic)
+}
+
+// ErrorByID is the custom error of revert data.
+func (abi *ABI) ErrorByID(data []byte) (*Error, error) {
+	if len(data) >= 4 {
+		for _, e := range abi.Errors {
+			if string(e.ID[:]) == string(data[:4]) {
+				return e, nil
+			}
+		}
+	}
+
+	return nil, ErrErrorNotFound
+}
+
+// String lists the signatures of the ABI, for debugging.
+func (abi *ABI) String() string {
+	var lines []string
+	for _, m := range abi.Methods {
+		lines = append(lines, fmt.Sprintf("function %s returns %s", m.Sig, m.Outputs.Types()))
+	}
+
+	for _, e := range abi.Events {
+		lines = append(lines, "event "+e.Sig)
+	}
+
+	for _, e := range abi.Errors {
+		lines = append(lines, "error "+e.Sig)
+	}
+
+	sort.Strings(lines)
+	if abi.Constructor != nil {
+		lines = append([]string{"constructor" + abi.Constructor.Inputs.Types()}, lines...)
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,154 @@
+package abi
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ArgumentJSON is an input, output or component in the JSON ABI.
+type ArgumentJSON struct {
+	Name         string         `json:"name"`
+	Type         string         `json:"type"`
+	InternalType string         `json:"internalType,omitempty"`
+	Components   []ArgumentJSON `json:"components,omitempty"`
+	Indexed      bool           `json:"indexed,omitempty"`
+}
+
+type Argument struct {
+	Name    string
+	Type    *Type
+	Indexed bool
+}
+
+type Arguments []Argument
+
+// newArgumentType is the type of arg, with the struct name of its tuples.
+func newArgumentType(arg ArgumentJSON) (*Type, error) {
+	t, err := NewType(arg.Type, arg.Components)
+	if err != nil {
+		return nil, err
+	}
+
+	if name := strings.TrimPrefix(arg.InternalType, "struct "); name != arg.InternalType {
+		if i := strings.Index(name, "["); i >= 0 {
+			name = name[:i]
+		}
+
+		tuple := t
+		for tuple.Elem != nil {
+			tuple = tuple.Elem
+		}
+		tuple.TupleName = name[strings.LastIndex(name, ".")+1:]
+	}
+
+	return t, nil
+}
+
+func newArguments(args []ArgumentJSON) (Arguments, error) {
+	arguments := make(Arguments, 0, len(args))
+	for _, arg := range args {
+		t, err := newArgumentType(arg)
+		if err != nil {
+			return nil, fmt.Errorf("argument %q: %w", arg.Name, err)
+		}
+
+		arguments = append(arguments, Argument{Name: arg.Name, Type: t, Indexed: arg.Indexed})
+	}
+
+	return arguments, nil
+}
+
+// NonIndexed are the arguments of an event carried in the log data.
+func (args Arguments) NonIndexed() Arguments {
+	var nonIndexed Arguments
+	for _, arg := range args {
+		if !arg.Indexed {
+			nonIndexed = append(nonIndexed, arg)
+		}
+	}
+
+	return nonIndexed
+}
+
+// Types is the canonical list of the types, as in a signature.
+func (args Arguments) Types() string {
+	t := &Type{Kind: TupleTy}
+	for _, arg := range args {
+		t.Components = append(t.Components, arg.Type)
+	}
+
+	return t.String()
+}
+
+func (args Arguments) tuple() *Type {
+	t := &Type{Kind: TupleTy}
+	for _, arg := range args {
+		t.Components = append(t.Components, arg.Type)
+		t.ComponentNames = append(t.ComponentNames, arg.Name)
+	}
+
+	return t
+}
+
+// Pack encodes values, one per argument, as the arguments of a call.
+func (args Arguments) Pack(values ...interface{}) ([]byte, error) {
+	if len(values) != len(args) {
+		return nil, fmt.Errorf("%w: %d values for %d arguments", ErrInvalidValue, len(values), len(args))
+	}
+
+	return encode(args.tuple(), reflect.ValueOf(values))
+}
+
+// Unpack decodes the arguments from data, see Type.Decode for the Go types of the values.
+func (args Arguments) Unpack(data []byte) ([]interface{}, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+
+	values, err := decode(args.tuple(), data)
+	if err != nil {
+		return nil, err
+	}
+
+	return values.([]interface{}), nil
+}
+
+// UnpackIntoMap decodes the arguments from data into m by argument name.
+func (args Arguments) UnpackIntoMap(m map[string]interface{}, data []byte) error {
+	values, err := args.Unpack(data)
+	if err != nil {
+		return err
+	}
+
+	for i, arg := range args {
+		m[arg.Name] = values[i]
+	}
+
+	return nil
+}
+
+// UnpackInto decodes the arguments from data and copies them into dst, a pointer to a
+// struct whose fields match the argument names, or one pointer per argument.
+func (args Arguments) UnpackInto(data []byte, dst ...interface{}) error {
+	values, err := args.Unpack(data)
+	if err != nil {
+		return err
+	}
+
+	if len(dst) == 1 && len(args) != 1 {
+		return Copy(dst[0], &tupleValue{t: args.tuple(), values: values})
+	}
+
+	if len(dst) != len(args) {
+		return fmt.Errorf("%w: %d destinations for %d arguments", ErrInvalidValue, len(dst), len(args))
+	}
+
+	for i := range dst {
+		if err = Copy(dst[i], values[i]); err != nil {
+			return fmt.Errorf("argument %q: %w", args[i].Name, err)
+		}
+	}
+
+	return nil
+}
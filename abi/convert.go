@@ -0,0 +1,192 @@
+package abi
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/SealSC/SealEVM/evmInt256"
+)
+
+// tupleValue is a decoded tuple with its type, so it can be copied into a struct by
+// component names.
+type tupleValue struct {
+	t      *Type
+	values []interface{}
+}
+
+// Copy assigns a decoded value to dst, which must be a non nil pointer. Integers go into
+// *evmInt256.Int, *big.Int or Go integers large enough, byte slices into slices or arrays
+// of their length, arrays into slices or arrays, tuples into structs, field by field in
+// order, or into []interface{}.
+func Copy(dst interface{}, src interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("%w: destination must be a non nil pointer", ErrInvalidValue)
+	}
+
+	return copyValue(v.Elem(), src, nil)
+}
+
+func copyError(dst reflect.Value, src interface{}) error {
+	return fmt.Errorf("%w: can not copy %T into %s", ErrInvalidValue, src, dst.Type())
+}
+
+func copyValue(dst reflect.Value, src interface{}, t *Type) error {
+	if tv, ok := src.(*tupleValue); ok {
+		src, t = tv.values, tv.t
+	}
+
+	if dst.Kind() == reflect.Interface && dst.NumMethod() == 0 {
+		dst.Set(reflect.ValueOf(src))
+		return nil
+	}
+
+	if dst.Kind() == reflect.Ptr && dst.Type().Elem() != bigType && dst.Type().Elem() != int256Type {
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+
+		return copyValue(dst.Elem(), src, t)
+	}
+
+	switch s := src.(type) {
+	case *evmInt256.Int:
+		return copyInt(dst, s.Int)
+
+	case []byte:
+		switch {
+		case dst.Kind() == reflect.Slice && dst.Type().Elem().Kind() == reflect.Uint8:
+			dst.Set(reflect.ValueOf(s).Convert(dst.Type()))
+			return nil
+		case dst.Kind() == reflect.Array && dst.Type().Elem().Kind() == reflect.Uint8 && dst.Len() == len(s):
+			reflect.Copy(dst, reflect.ValueOf(s))
+			return nil
+		}
+
+	case []interface{}:
+		return copySequence(dst, s, t)
+
+	default:
+		sv := reflect.ValueOf(src)
+		switch {
+		case sv.Type().AssignableTo(dst.Type()):
+			dst.Set(sv)
+			return nil
+		case sv.Kind() == reflect.Array && sv.Type().ConvertibleTo(dst.Type()) && dst.Kind() == reflect.Array:
+			dst.Set(sv.Convert(dst.Type()))
+			return nil
+		case sv.Kind() == reflect.Array && dst.Kind() == reflect.Slice && dst.Type().Elem().Kind() == reflect.Uint8:
+			b := make([]byte, sv.Len())
+			reflect.Copy(reflect.ValueOf(b), sv)
+			dst.Set(reflect.ValueOf(b).Convert(dst.Type()))
+			return nil
+		}
+	}
+
+	return copyError(dst, src)
+}
+
+func copyInt(dst reflect.Value, n *big.Int) error {
+	switch dst.Kind() {
+	case reflect.Ptr:
+		switch dst.Type().Elem() {
+		case bigType:
+			dst.Set(reflect.ValueOf(new(big.Int).Set(n)))
+			return nil
+		case int256Type:
+			dst.Set(reflect.ValueOf(evmInt256.FromBigInt(n)))
+			return nil
+		}
+
+	case reflect.Struct:
+		switch dst.Type() {
+		case bigType:
+			dst.Set(reflect.ValueOf(*new(big.Int).Set(n)))
+			return nil
+		case int256Type:
+			dst.Set(reflect.ValueOf(*evmInt256.FromBigInt(n)))
+			return nil
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n.IsInt64() && !dst.OverflowInt(n.Int64()) {
+			dst.SetInt(n.Int64())
+			return nil
+		}
+		return fmt.Errorf("%w: %s overflows %s", ErrInvalidValue, n, dst.Type())
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n.IsUint64() && !dst.OverflowUint(n.Uint64()) {
+			dst.SetUint(n.Uint64())
+			return nil
+		}
+		return fmt.Errorf("%w: %s overflows %s", ErrInvalidValue, n, dst.Type())
+	}
+
+	return copyError(dst, n)
+}
+
+func copySequence(dst reflect.Value, values []interface{}, t *Type) error {
+	elemType := func(i int) *Type {
+		switch {
+		case t == nil:
+			return nil
+		case t.Kind == TupleTy:
+			return t.Components[i]
+		default:
+			return t.Elem
+		}
+	}
+
+	switch dst.Kind() {
+	case reflect.Slice:
+		out := reflect.MakeSlice(dst.Type(), len(values), len(values))
+		for i, v := range values {
+			if err := copyValue(out.Index(i), v, elemType(i)); err != nil {
+				return err
+			}
+		}
+
+		dst.Set(out)
+		return nil
+
+	case reflect.Array:
+		if dst.Len() != len(values) {
+			return fmt.Errorf("%w: %d values for %s", ErrInvalidValue, len(values), dst.Type())
+		}
+
+		for i, v := range values {
+			if err := copyValue(dst.Index(i), v, elemType(i)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+
+	case reflect.Struct:
+		for i, v := range values {
+			var field reflect.Value
+			if t != nil && t.Kind == TupleTy && t.ComponentNames[i] != "" {
+				if f, ok := structField(dst.Type(), t.ComponentNames[i]); ok {
+					field = dst.FieldByIndex(f.Index)
+				}
+			}
+
+			if !field.IsValid() {
+				if dst.NumField() != len(values) {
+					return fmt.Errorf("%w: %d values for %s", ErrInvalidValue, len(values), dst.Type())
+				}
+				field = dst.Field(i)
+			}
+
+			if err := copyValue(field, v, elemType(i)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return copyError(dst, values)
+}
@@ -0,0 +1,208 @@
+package abi
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/SealSC/SealEVM/crypto/hashes"
+	"github.com/SealSC/SealEVM/types"
+)
+
+var bytes32Type = &Type{Kind: FixedBytesTy, Size: 32}
+
+// topicType is the type an indexed argument has in a topic: the value for the elementary
+// types, the keccak hash of the encoding (a bytes32) for the others.
+func topicType(t *Type) *Type {
+	switch t.Kind {
+	case StringTy, BytesTy, SliceTy, ArrayTy, TupleTy:
+		return bytes32Type
+	}
+
+	return t
+}
+
+func (e *Event) topics(log *types.Log) ([]types.Hash, error) {
+	topics := log.Topics
+	if !e.Anonymous {
+		if len(topics) == 0 || topics[0] != e.ID {
+			return nil, fmt.Errorf("%w: log is no %s", ErrInvalidData, e.Sig)
+		}
+		topics = topics[1:]
+	}
+
+	indexed := 0
+	for _, arg := range e.Inputs {
+		if arg.Indexed {
+			indexed++
+		}
+	}
+
+	if len(topics) != indexed {
+		return nil, fmt.Errorf("%w: %d topics for the %d indexed arguments of %s", ErrInvalidData, len(topics), indexed, e.Sig)
+	}
+
+	return topics, nil
+}
+
+// ParseLog decodes the arguments of a log of the event, in the order of the inputs. Indexed
+// strings, bytes, arrays and tuples are the types.Hash of their encoding, as in the topic.
+func (e *Event) ParseLog(log *types.Log) ([]interface{}, error) {
+	topics, err := e.topics(log)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := e.Inputs.NonIndexed().Unpack(log.Data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", e.Sig, err)
+	}
+
+	values := make([]interface{}, 0, len(e.Inputs))
+	for _, arg := range e.Inputs {
+		if !arg.Indexed {
+			values = append(values, data[0])
+			data = data[1:]
+			continue
+		}
+
+		v, err := decodeElementary(topicType(arg.Type), topics[0][:])
+		if err != nil {
+			return nil, fmt.Errorf("%s: topic of %q: %w", e.Sig, arg.Name, err)
+		}
+
+		values = append(values, v)
+		topics = topics[1:]
+	}
+
+	return values, nil
+}
+
+// ParseLogIntoMap decodes a log of the event into m by argument name.
+func (e *Event) ParseLogIntoMap(m map[string]interface{}, log *types.Log) error {
+	values, err := e.ParseLog(log)
+	if err != nil {
+		return err
+	}
+
+	for i, arg := range e.Inputs {
+		m[arg.Name] = values[i]
+	}
+
+	return nil
+}
+
+// ParseLogInto decodes a log of the event into the struct dst points to, fields are matched
+// to the argument names.
+func (e *Event) ParseLogInto(dst interface{}, log *types.Log) error {
+	values, err := e.ParseLog(log)
+	if err != nil {
+		return err
+	}
+
+	t := &Type{Kind: TupleTy}
+	for _, arg := range e.Inputs {
+		argType := arg.Type
+		if arg.Indexed {
+			argType = topicType(argType)
+		}
+
+		t.Components = append(t.Components, argType)
+		t.ComponentNames = append(t.ComponentNames, arg.Name)
+	}
+
+	return Copy(dst, &tupleValue{t: t, values: values})
+}
+
+// ParseLog finds the event of a log by its first topic and decodes it.
+func (abi *ABI) ParseLog(log *types.Log) (*Event, []interface{}, error) {
+	if len(log.Topics) == 0 {
+		return nil, nil, fmt.Errorf("%w: log without topics", ErrEventNotFound)
+	}
+
+	e, err := abi.EventByID(log.Topics[0])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values, err := e.ParseLog(log)
+	return e, values, err
+}
+
+// indexedEncoding is the encoding solc hashes into the topic of an indexed string, bytes,
+// array or tuple: the contents of strings and bytes, the elements of arrays and tuples in
+// place, padded to words, without lengths nor offsets.
+func indexedEncoding(t *Type, v reflect.Value, nested bool) ([]byte, error) {
+	v = indirect(v)
+	if !v.IsValid() {
+		return nil, valueError(t, v)
+	}
+
+	var ts []*Type
+	var values []reflect.Value
+
+	switch t.Kind {
+	case StringTy, BytesTy:
+		b, ok := bytesOf(v)
+		if !ok || (t.Kind == StringTy) != (v.Kind() == reflect.String) {
+			return nil, valueError(t, v)
+		}
+
+		if nested {
+			return padRight(b), nil
+		}
+		return b, nil
+
+	case SliceTy, ArrayTy:
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return nil, valueError(t, v)
+		}
+
+		if t.Kind == ArrayTy && v.Len() != t.Size {
+			return nil, fmt.Errorf("%w %s: %d elements", ErrInvalidValue, t, v.Len())
+		}
+
+		for i := 0; i < v.Len(); i++ {
+			ts = append(ts, t.Elem)
+			values = append(values, v.Index(i))
+		}
+
+	case TupleTy:
+		fields, err := tupleFields(t, v)
+		if err != nil {
+			return nil, err
+		}
+
+		ts, values = t.Components, fields
+
+	default:
+		return encodeElementary(t, v)
+	}
+
+	var enc []byte
+	for i := range ts {
+		b, err := indexedEncoding(ts[i], values[i], true)
+		if err != nil {
+			return nil, err
+		}
+
+		enc = append(enc, b...)
+	}
+
+	return enc, nil
+}
+
+// Topic is the topic of an indexed argument of type t, for filtering logs.
+func Topic(t *Type, v interface{}) (types.Hash, error) {
+	var topic types.Hash
+	enc, err := indexedEncoding(t, reflect.ValueOf(v), false)
+	if err != nil {
+		return topic, err
+	}
+
+	if topicType(t) == bytes32Type && t.Kind != FixedBytesTy {
+		enc = hashes.Keccak256(enc)
+	}
+
+	topic.SetBytes(enc)
+	return topic, nil
+}
@@ -0,0 +1,325 @@
+package abi
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/types"
+)
+
+var ErrInvalidValue = errors.New("invalid value for abi type")
+
+var (
+	bigType    = reflect.TypeOf(big.Int{})
+	int256Type = reflect.TypeOf(evmInt256.Int{})
+	word       = new(big.Int).Lsh(big.NewInt(1), 256)
+)
+
+func valueError(t *Type, v reflect.Value) error {
+	if !v.IsValid() {
+		return fmt.Errorf("%w %s: nil", ErrInvalidValue, t)
+	}
+
+	return fmt.Errorf("%w %s: %s", ErrInvalidValue, t, v.Type())
+}
+
+func padLeft(b []byte) []byte {
+	out := make([]byte, 32)
+	copy(out[32-len(b):], b)
+	return out
+}
+
+func padRight(b []byte) []byte {
+	size := (len(b) + 31) / 32 * 32
+	out := make([]byte, size)
+	copy(out, b)
+	return out
+}
+
+func encodeLength(n int) []byte {
+	return padLeft(big.NewInt(int64(n)).Bytes())
+}
+
+// indirect follows pointers, but keeps the integers given by pointer.
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && !v.IsNil() {
+		if v.Kind() == reflect.Ptr && (v.Type().Elem() == bigType || v.Type().Elem() == int256Type) {
+			return v
+		}
+		v = v.Elem()
+	}
+
+	return v
+}
+
+// toBig reads the integers: *big.Int, *evmInt256.Int and the Go integer kinds.
+func toBig(v reflect.Value) (*big.Int, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return new(big.Int).SetUint64(v.Uint()), true
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil, false
+		}
+
+		switch i := v.Interface().(type) {
+		case *big.Int:
+			return i, true
+		case *evmInt256.Int:
+			if i.Int == nil {
+				return nil, false
+			}
+			return i.Int, true
+		}
+	case reflect.Struct:
+		switch i := v.Interface().(type) {
+		case big.Int:
+			return &i, true
+		case evmInt256.Int:
+			return i.Int, i.Int != nil
+		}
+	}
+
+	return nil, false
+}
+
+func encodeInt(t *Type, v reflect.Value) ([]byte, error) {
+	n, ok := toBig(v)
+	if !ok {
+		return nil, valueError(t, v)
+	}
+
+	if t.Kind == UintTy {
+		if n.Sign() < 0 || n.BitLen() > t.Size {
+			return nil, fmt.Errorf("%w %s: %s out of range", ErrInvalidValue, t, n)
+		}
+
+		return padLeft(n.Bytes()), nil
+	}
+
+	//a 256 bits word with the sign bit set, as the EVM holds negative numbers, is taken
+	//as the negative number it stands for
+	if n.Sign() > 0 && n.BitLen() == 256 {
+		n = new(big.Int).Sub(n, word)
+	}
+
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(t.Size-1))
+	if n.Cmp(limit) >= 0 || n.Cmp(new(big.Int).Neg(limit)) < 0 {
+		return nil, fmt.Errorf("%w %s: %s out of range", ErrInvalidValue, t, n)
+	}
+
+	if n.Sign() < 0 {
+		n = new(big.Int).Add(n, word)
+	}
+
+	return padLeft(n.Bytes()), nil
+}
+
+// bytesOf reads byte slices, byte arrays (types.Address and types.Hash too) and strings.
+func bytesOf(v reflect.Value) ([]byte, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return []byte(v.String()), true
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Bytes(), true
+		}
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return b, true
+		}
+	}
+
+	return nil, false
+}
+
+func encodeElementary(t *Type, v reflect.Value) ([]byte, error) {
+	switch t.Kind {
+	case UintTy, IntTy:
+		return encodeInt(t, v)
+
+	case BoolTy:
+		if v.Kind() != reflect.Bool {
+			return nil, valueError(t, v)
+		}
+
+		if v.Bool() {
+			return padLeft([]byte{1}), nil
+		}
+		return padLeft(nil), nil
+
+	case AddressTy:
+		b, ok := bytesOf(v)
+		if !ok || v.Kind() == reflect.String || len(b) != types.AddressBytesLen {
+			return nil, valueError(t, v)
+		}
+
+		return padLeft(b), nil
+
+	case FixedBytesTy, FunctionTy:
+		b, ok := bytesOf(v)
+		if !ok || v.Kind() == reflect.String || len(b) != t.Size {
+			return nil, valueError(t, v)
+		}
+
+		return padRight(b), nil
+
+	case StringTy, BytesTy:
+		b, ok := bytesOf(v)
+		if !ok || (t.Kind == StringTy) != (v.Kind() == reflect.String) {
+			return nil, valueError(t, v)
+		}
+
+		return append(encodeLength(len(b)), padRight(b)...), nil
+	}
+
+	return nil, valueError(t, v)
+}
+
+// tupleFields are the values of the components of a tuple, from a slice or array given in
+// order, a map by component name, or a struct by field name (or an `abi:"name"` tag),
+// matched case insensitively.
+func tupleFields(t *Type, v reflect.Value) ([]reflect.Value, error) {
+	fields := make([]reflect.Value, len(t.Components))
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if v.Len() != len(t.Components) {
+			return nil, fmt.Errorf("%w %s: %d values", ErrInvalidValue, t, v.Len())
+		}
+
+		for i := range fields {
+			fields[i] = v.Index(i)
+		}
+
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, valueError(t, v)
+		}
+
+		for i, name := range t.ComponentNames {
+			fields[i] = v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		}
+
+	case reflect.Struct:
+		for i, name := range t.ComponentNames {
+			if f, ok := structField(v.Type(), name); ok {
+				fields[i] = v.FieldByIndex(f.Index)
+			} else if v.NumField() == len(fields) && name == "" {
+				fields[i] = v.Field(i)
+			}
+		}
+
+	default:
+		return nil, valueError(t, v)
+	}
+
+	for i, f := range fields {
+		if !f.IsValid() {
+			return nil, fmt.Errorf("%w %s: no value for component %d %q", ErrInvalidValue, t, i, t.ComponentNames[i])
+		}
+	}
+
+	return fields, nil
+}
+
+func structField(st reflect.Type, name string) (reflect.StructField, bool) {
+	if name == "" {
+		return reflect.StructField{}, false
+	}
+
+	for i := 0; i < st.NumField(); i++ {
+		f := st.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		tag := f.Tag.Get("abi")
+		if tag == name || (tag == "" && strings.EqualFold(strings.TrimPrefix(f.Name, "_"), strings.TrimPrefix(name, "_"))) {
+			return f, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// encodeSequence encodes values of types as the heads followed by the tails of the
+// dynamic ones, the layout of tuples, fixed arrays and the elements of slices.
+func encodeSequence(ts []*Type, values []reflect.Value) ([]byte, error) {
+	headSize := 0
+	for _, t := range ts {
+		headSize += t.headSize()
+	}
+
+	var heads, tails []byte
+	for i, t := range ts {
+		enc, err := encode(t, values[i])
+		if err != nil {
+			return nil, err
+		}
+
+		if t.Dynamic() {
+			heads = append(heads, encodeLength(headSize+len(tails))...)
+			tails = append(tails, enc...)
+		} else {
+			heads = append(heads, enc...)
+		}
+	}
+
+	return append(heads, tails...), nil
+}
+
+func encode(t *Type, v reflect.Value) ([]byte, error) {
+	v = indirect(v)
+	if !v.IsValid() {
+		return nil, valueError(t, v)
+	}
+
+	switch t.Kind {
+	case SliceTy, ArrayTy:
+		//byte slices given for uint8[] are fine, strings are not
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return nil, valueError(t, v)
+		}
+
+		if t.Kind == ArrayTy && v.Len() != t.Size {
+			return nil, fmt.Errorf("%w %s: %d elements", ErrInvalidValue, t, v.Len())
+		}
+
+		ts := make([]*Type, v.Len())
+		values := make([]reflect.Value, v.Len())
+		for i := range ts {
+			ts[i], values[i] = t.Elem, v.Index(i)
+		}
+
+		enc, err := encodeSequence(ts, values)
+		if err != nil || t.Kind == ArrayTy {
+			return enc, err
+		}
+
+		return append(encodeLength(v.Len()), enc...), nil
+
+	case TupleTy:
+		fields, err := tupleFields(t, v)
+		if err != nil {
+			return nil, err
+		}
+
+		return encodeSequence(t.Components, fields)
+	}
+
+	return encodeElementary(t, v)
+}
+
+// Encode encodes one value of t.
+func (t *Type) Encode(v interface{}) ([]byte, error) {
+	return encode(t, reflect.ValueOf(v))
+}
@@ -0,0 +1,75 @@
+package abi
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/SealSC/SealEVM/evmInt256"
+)
+
+var (
+	// ErrNoRevertReason is returned for revert data which is no Error(string) nor
+	// Panic(uint256), such as the empty data of a bare revert or a custom error.
+	ErrNoRevertReason = errors.New("no revert reason")
+
+	revertSelector = []byte{0x08, 0xc3, 0x79, 0xa0}
+	panicSelector  = []byte{0x4e, 0x48, 0x7b, 0x71}
+)
+
+var panicReasons = map[uint64]string{
+	0x00: "generic panic",
+	0x01: "assert(false)",
+	0x11: "arithmetic underflow or overflow",
+	0x12: "division or modulo by zero",
+	0x21: "enum overflow",
+	0x22: "invalid encoded storage byte array accessed",
+	0x31: "out-of-bounds array access; popping on an empty array",
+	0x32: "out-of-bounds access of an array or bytesN",
+	0x41: "out of memory",
+	0x51: "uninitialized function",
+}
+
+// UnpackRevert decodes the reason of revert data of require and revert with a message,
+// Error(string), or of a Panic(uint256), as "panic: <reason> (0x11)".
+func UnpackRevert(data []byte) (string, error) {
+	if len(data) < 4 {
+		return "", ErrNoRevertReason
+	}
+
+	switch string(data[:4]) {
+	case string(revertSelector):
+		values, err := Arguments{{Type: MustNewType("string")}}.Unpack(data[4:])
+		if err != nil {
+			return "", err
+		}
+
+		return values[0].(string), nil
+
+	case string(panicSelector):
+		code, err := MustNewType("uint256").Decode(data[4:])
+		if err != nil {
+			return "", err
+		}
+
+		n := code.(*evmInt256.Int)
+		reason, ok := panicReasons[n.Uint64()]
+		if !ok || !n.IsUint64() {
+			reason = "unknown panic"
+		}
+
+		return fmt.Sprintf("panic: %s (0x%s)", reason, n.Text(16)), nil
+	}
+
+	return "", ErrNoRevertReason
+}
+
+// UnpackError decodes revert data of a custom error of the ABI.
+func (abi *ABI) UnpackError(data []byte) (*Error, []interface{}, error) {
+	e, err := abi.ErrorByID(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	args, err := e.Inputs.Unpack(data[4:])
+	return e, args, err
+}
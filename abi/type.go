@@ -0,0 +1,200 @@
+package abi
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type Kind byte
+
+const (
+	UintTy Kind = iota
+	IntTy
+	AddressTy
+	BoolTy
+	StringTy
+	BytesTy
+	FixedBytesTy
+	FunctionTy
+	SliceTy
+	ArrayTy
+	TupleTy
+)
+
+var ErrInvalidType = errors.New("invalid abi type")
+
+// Type is a Solidity ABI type. Size is the bits of an integer, the bytes of a fixed bytes
+// type and the length of a fixed array. Elem is the element type of the arrays, Components
+// and ComponentNames describe a tuple.
+type Type struct {
+	Kind Kind
+	Size int
+	Elem *Type
+
+	Components     []*Type
+	ComponentNames []string
+
+	//TupleName is the struct of a tuple given by its internalType, "Key" for "struct Pool.Key"
+	TupleName string
+}
+
+var arrayPattern = regexp.MustCompile(`^(.*)\[([0-9]*)\]$`)
+
+// NewType parses a type as written in the JSON ABI, components are the ones of a tuple,
+// of an array of tuples too.
+func NewType(typ string, components []ArgumentJSON) (*Type, error) {
+	if m := arrayPattern.FindStringSubmatch(typ); m != nil {
+		elem, err := NewType(m[1], components)
+		if err != nil {
+			return nil, err
+		}
+
+		if m[2] == "" {
+			return &Type{Kind: SliceTy, Elem: elem}, nil
+		}
+
+		size, err := strconv.Atoi(m[2])
+		if err != nil || size == 0 {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidType, typ)
+		}
+
+		return &Type{Kind: ArrayTy, Size: size, Elem: elem}, nil
+	}
+
+	switch {
+	case typ == "address":
+		return &Type{Kind: AddressTy, Size: 20}, nil
+	case typ == "bool":
+		return &Type{Kind: BoolTy}, nil
+	case typ == "string":
+		return &Type{Kind: StringTy}, nil
+	case typ == "bytes":
+		return &Type{Kind: BytesTy}, nil
+	case typ == "function":
+		return &Type{Kind: FunctionTy, Size: 24}, nil
+	case typ == "tuple":
+		t := &Type{Kind: TupleTy}
+		for _, c := range components {
+			ct, err := newArgumentType(c)
+			if err != nil {
+				return nil, err
+			}
+
+			t.Components = append(t.Components, ct)
+			t.ComponentNames = append(t.ComponentNames, c.Name)
+		}
+
+		return t, nil
+	case strings.HasPrefix(typ, "uint"), strings.HasPrefix(typ, "int"):
+		kind, bits := UintTy, strings.TrimPrefix(typ, "uint")
+		if strings.HasPrefix(typ, "int") {
+			kind, bits = IntTy, strings.TrimPrefix(typ, "int")
+		}
+
+		size := 256
+		if bits != "" {
+			var err error
+			if size, err = strconv.Atoi(bits); err != nil || size == 0 || size > 256 || size%8 != 0 {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidType, typ)
+			}
+		}
+
+		return &Type{Kind: kind, Size: size}, nil
+	case strings.HasPrefix(typ, "bytes"):
+		size, err := strconv.Atoi(strings.TrimPrefix(typ, "bytes"))
+		if err != nil || size == 0 || size > 32 {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidType, typ)
+		}
+
+		return &Type{Kind: FixedBytesTy, Size: size}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrInvalidType, typ)
+}
+
+// MustNewType is NewType for types known to be valid, it panics otherwise.
+func MustNewType(typ string) *Type {
+	t, err := NewType(typ, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}
+
+// String is the canonical name used in signatures, tuples are written "(t1,t2)".
+func (t *Type) String() string {
+	switch t.Kind {
+	case UintTy:
+		return "uint" + strconv.Itoa(t.Size)
+	case IntTy:
+		return "int" + strconv.Itoa(t.Size)
+	case AddressTy:
+		return "address"
+	case BoolTy:
+		return "bool"
+	case StringTy:
+		return "string"
+	case BytesTy:
+		return "bytes"
+	case FixedBytesTy:
+		return "bytes" + strconv.Itoa(t.Size)
+	case FunctionTy:
+		return "function"
+	case SliceTy:
+		return t.Elem.String() + "[]"
+	case ArrayTy:
+		return t.Elem.String() + "[" + strconv.Itoa(t.Size) + "]"
+	case TupleTy:
+		names := make([]string, len(t.Components))
+		for i, c := range t.Components {
+			names[i] = c.String()
+		}
+
+		return "(" + strings.Join(names, ",") + ")"
+	}
+
+	return ""
+}
+
+// Dynamic tells whether values of the type are encoded after the heads, behind an offset.
+func (t *Type) Dynamic() bool {
+	switch t.Kind {
+	case StringTy, BytesTy, SliceTy:
+		return true
+	case ArrayTy:
+		return t.Elem.Dynamic()
+	case TupleTy:
+		for _, c := range t.Components {
+			if c.Dynamic() {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// headSize is the bytes a value of the type takes in the heads of its enclosing tuple.
+func (t *Type) headSize() int {
+	if t.Dynamic() {
+		return 32
+	}
+
+	switch t.Kind {
+	case ArrayTy:
+		return t.Size * t.Elem.headSize()
+	case TupleTy:
+		size := 0
+		for _, c := range t.Components {
+			size += c.headSize()
+		}
+
+		return size
+	}
+
+	return 32
+}
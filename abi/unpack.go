@@ -0,0 +1,186 @@
+package abi
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/types"
+)
+
+var ErrInvalidData = errors.New("invalid abi data")
+
+func dataError(t *Type, format string, args ...interface{}) error {
+	return fmt.Errorf("%w for %s: %s", ErrInvalidData, t, fmt.Sprintf(format, args...))
+}
+
+// readWord is the 32 bytes at offset of data.
+func readWord(t *Type, data []byte, offset int) ([]byte, error) {
+	if offset < 0 || offset+32 > len(data) || offset+32 < offset {
+		return nil, dataError(t, "%d bytes, want a word at %d", len(data), offset)
+	}
+
+	return data[offset : offset+32], nil
+}
+
+// readSize reads a length or an offset, which must stay within data.
+func readSize(t *Type, data []byte, offset int) (int, error) {
+	w, err := readWord(t, data, offset)
+	if err != nil {
+		return 0, err
+	}
+
+	n := new(big.Int).SetBytes(w)
+	if !n.IsInt64() || n.Int64() > int64(len(data)) {
+		return 0, dataError(t, "size %s beyond the %d bytes of data", n, len(data))
+	}
+
+	return int(n.Int64()), nil
+}
+
+func decodeInt(t *Type, w []byte) (interface{}, error) {
+	n := new(big.Int).SetBytes(w)
+	if t.Kind == UintTy {
+		if n.BitLen() > t.Size {
+			return nil, dataError(t, "0x%x out of range", w)
+		}
+
+		return evmInt256.FromBigInt(n), nil
+	}
+
+	signed := evmInt256.FromBigInt(n).GetSigned()
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(t.Size-1))
+	if signed.Cmp(limit) >= 0 || signed.Cmp(new(big.Int).Neg(limit)) < 0 {
+		return nil, dataError(t, "0x%x not sign extended", w)
+	}
+
+	return signed, nil
+}
+
+func decodeElementary(t *Type, w []byte) (interface{}, error) {
+	switch t.Kind {
+	case UintTy, IntTy:
+		return decodeInt(t, w)
+
+	case BoolTy:
+		n := new(big.Int).SetBytes(w)
+		if n.BitLen() > 1 {
+			return nil, dataError(t, "0x%x is no bool", w)
+		}
+
+		return n.Sign() == 1, nil
+
+	case AddressTy:
+		if new(big.Int).SetBytes(w[:32-types.AddressBytesLen]).Sign() != 0 {
+			return nil, dataError(t, "0x%x has dirty high bytes", w)
+		}
+
+		var addr types.Address
+		addr.SetBytes(w[32-types.AddressBytesLen:])
+		return addr, nil
+
+	case FixedBytesTy, FunctionTy:
+		if new(big.Int).SetBytes(w[t.Size:]).Sign() != 0 {
+			return nil, dataError(t, "0x%x has dirty low bytes", w)
+		}
+
+		if t.Size == types.HashBytesLen {
+			var h types.Hash
+			h.SetBytes(w)
+			return h, nil
+		}
+
+		return append([]byte{}, w[:t.Size]...), nil
+	}
+
+	return nil, dataError(t, "not elementary")
+}
+
+// decodeSequence decodes count values of types ts(i) from the heads at data[0:], the
+// offsets of the dynamic ones are relative to data.
+func decodeSequence(ts func(i int) *Type, count int, data []byte) ([]interface{}, error) {
+	values := make([]interface{}, count)
+	head := 0
+	for i := 0; i < count; i++ {
+		t := ts(i)
+
+		if t.Dynamic() {
+			offset, err := readSize(t, data, head)
+			if err != nil {
+				return nil, err
+			}
+
+			if values[i], err = decode(t, data[offset:]); err != nil {
+				return nil, err
+			}
+		} else {
+			if head+t.headSize() > len(data) {
+				return nil, dataError(t, "%d bytes, want %d at %d", len(data), t.headSize(), head)
+			}
+
+			var err error
+			if values[i], err = decode(t, data[head:]); err != nil {
+				return nil, err
+			}
+		}
+
+		head += t.headSize()
+	}
+
+	return values, nil
+}
+
+// decode reads a value of t at the start of data. Integers are *evmInt256.Int, signed
+// ones hold their negative value as GetSigned returns it. bytes32 gives a types.Hash, the
+// other fixed bytes and bytes a []byte, arrays and tuples a []interface{}.
+func decode(t *Type, data []byte) (interface{}, error) {
+	switch t.Kind {
+	case StringTy, BytesTy:
+		size, err := readSize(t, data, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		if 32+size > len(data) {
+			return nil, dataError(t, "%d bytes of content beyond the data", size)
+		}
+
+		content := append([]byte{}, data[32:32+size]...)
+		if t.Kind == StringTy {
+			return string(content), nil
+		}
+		return content, nil
+
+	case SliceTy:
+		size, err := readSize(t, data, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		//each element takes a word at least, bound the size before allocating
+		if size*32 > len(data)-32 {
+			return nil, dataError(t, "%d elements beyond the data", size)
+		}
+
+		return decodeSequence(func(int) *Type { return t.Elem }, size, data[32:])
+
+	case ArrayTy:
+		return decodeSequence(func(int) *Type { return t.Elem }, t.Size, data)
+
+	case TupleTy:
+		return decodeSequence(func(i int) *Type { return t.Components[i] }, len(t.Components), data)
+	}
+
+	w, err := readWord(t, data, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeElementary(t, w)
+}
+
+// Decode decodes one value of t encoded at the start of data.
+func (t *Type) Decode(data []byte) (interface{}, error) {
+	return decode(t, data)
+}
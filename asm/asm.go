@@ -0,0 +1,371 @@
+// Package asm assembles EVM mnemonics into bytecode, for hand-written test contracts and
+// trampolines.
+//
+// A source has one statement per line, comments start with ';' or "//":
+//
+//	%macro require(dest)    ; macro with a parameter, expanded as require(label)
+//	    push dest
+//	    jumpi
+//	    push0
+//	    dup1
+//	    revert
+//	%end
+//
+//	    push 1
+//	    require(ok)         ; labels defined in a macro are local to each expansion
+//	ok: jumpdest
+//	    push table.end-table
+//	    push table          ; push picks the smallest width, PUSH1 to PUSH32 take it as given
+//	    stop
+//	table:
+//	    data 0x0102030405   ; raw bytes
+//	table.end:
+//
+// Operands are hex (0x) or decimal numbers, labels, or the difference of two labels.
+package asm
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/SealSC/SealEVM/crypto/hashes"
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/opcodes"
+	"github.com/SealSC/SealEVM/types"
+)
+
+var (
+	ErrUnknownMnemonic = errors.New("unknown mnemonic")
+	ErrInvalidOperand  = errors.New("invalid operand")
+	ErrUnknownLabel    = errors.New("unknown label")
+	ErrDuplicateLabel  = errors.New("duplicate label")
+	ErrPushOverflow    = errors.New("value does not fit the push")
+	ErrMacro           = errors.New("invalid macro")
+)
+
+// mnemonics maps the upper case names of opcodes.OpCode.String() to the opcodes, plus the
+// usual aliases.
+var mnemonics = map[string]opcodes.OpCode{
+	"KECCAK256":  opcodes.SHA3,
+	"PREVRANDAO": opcodes.DIFFICULTY,
+	"INVALID":    opcodes.OpCode(0xFE),
+}
+
+func init() {
+	for i := 0; i < 256; i++ {
+		op := opcodes.OpCode(i)
+		if name := op.String(); name != "" {
+			mnemonics[strings.ToUpper(name)] = op
+		}
+	}
+}
+
+var labelPattern = regexp.MustCompile(`^[A-Za-z_.][A-Za-z0-9_.@]*$`)
+
+// item is one statement after the macros are expanded. A push of a label gets its value
+// and, when auto is set, its width at layout.
+type item struct {
+	line int
+
+	label string //a label defined here
+
+	op    opcodes.OpCode
+	isOp  bool
+	auto  bool //push without a width
+	width int
+	value []byte
+
+	ref    string //label pushed
+	refSub string //label subtracted from ref
+
+	data []byte
+}
+
+func (it *item) size() int {
+	if !it.isOp {
+		return len(it.data)
+	}
+
+	return 1 + it.width
+}
+
+func lineError(line int, err error, format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %w: %s", line, err, fmt.Sprintf(format, args...))
+}
+
+// Assemble turns source into bytecode.
+func Assemble(source string) ([]byte, error) {
+	lines, err := expandMacros(source)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []*item
+	for _, l := range lines {
+		parsed, err := parseLine(l)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, parsed...)
+	}
+
+	labels, err := layout(items)
+	if err != nil {
+		return nil, err
+	}
+
+	var code []byte
+	for _, it := range items {
+		if !it.isOp {
+			code = append(code, it.data...)
+			continue
+		}
+
+		code = append(code, byte(it.op))
+		if it.width > 0 {
+			value := it.value
+			if it.ref != "" {
+				value = labelValue(labels, it).Bytes()
+			}
+
+			code = append(code, make([]byte, it.width-len(value))...)
+			code = append(code, value...)
+		}
+	}
+
+	return code, nil
+}
+
+// Contract assembles source into a contract ready for the interpreter.
+func Contract(source string) (*environment.Contract, error) {
+	code, err := Assemble(source)
+	if err != nil {
+		return nil, err
+	}
+
+	var hash types.Hash
+	hash.SetBytes(hashes.Keccak256(code))
+
+	return &environment.Contract{
+		Code:     code,
+		CodeHash: hash,
+		CodeSize: uint64(len(code)),
+	}, nil
+}
+
+func parseLine(l sourceLine) ([]*item, error) {
+	var items []*item
+	text := l.text
+
+	//"name:" defines a label, an instruction may follow on the same line
+	for {
+		colon := strings.Index(text, ":")
+		if colon < 0 {
+			break
+		}
+
+		name := strings.TrimSpace(text[:colon])
+		if !labelPattern.MatchString(name) {
+			return nil, lineError(l.num, ErrInvalidOperand, "bad label %q", name)
+		}
+
+		items = append(items, &item{line: l.num, label: name})
+		text = strings.TrimSpace(text[colon+1:])
+	}
+
+	if text == "" {
+		return items, nil
+	}
+
+	fields := strings.Fields(text)
+	mnemonic := strings.ToUpper(fields[0])
+	operands := fields[1:]
+
+	if mnemonic == "DATA" {
+		if len(operands) == 0 {
+			return nil, lineError(l.num, ErrInvalidOperand, "data without bytes")
+		}
+
+		it := &item{line: l.num}
+		for _, operand := range operands {
+			b, err := parseHexData(operand)
+			if err != nil {
+				return nil, lineError(l.num, ErrInvalidOperand, "%s", operand)
+			}
+
+			it.data = append(it.data, b...)
+		}
+
+		return append(items, it), nil
+	}
+
+	it := &item{line: l.num, isOp: true}
+	if mnemonic == "PUSH" {
+		it.op = opcodes.PUSH1
+		it.auto = true
+	} else {
+		op, ok := mnemonics[mnemonic]
+		if !ok {
+			return nil, lineError(l.num, ErrUnknownMnemonic, "%s", fields[0])
+		}
+
+		it.op = op
+		if op >= opcodes.PUSH1 && op <= opcodes.PUSH32 {
+			it.width = int(op-opcodes.PUSH1) + 1
+		}
+	}
+
+	isPush := it.auto || (it.op >= opcodes.PUSH1 && it.op <= opcodes.PUSH32)
+	if !isPush {
+		if len(operands) > 0 {
+			return nil, lineError(l.num, ErrInvalidOperand, "%s takes no operand", fields[0])
+		}
+
+		return append(items, it), nil
+	}
+
+	if len(operands) != 1 {
+		return nil, lineError(l.num, ErrInvalidOperand, "%s takes one operand", fields[0])
+	}
+
+	if err := parseOperand(it, operands[0]); err != nil {
+		return nil, err
+	}
+
+	return append(items, it), nil
+}
+
+func parseHexData(s string) ([]byte, error) {
+	if !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") {
+		return nil, ErrInvalidOperand
+	}
+
+	v, ok := new(big.Int).SetString("1"+s[2:], 16)
+	if !ok || len(s)%2 != 0 {
+		return nil, ErrInvalidOperand
+	}
+
+	//the leading 1 keeps the zero bytes
+	return v.Bytes()[1:], nil
+}
+
+func parseOperand(it *item, operand string) error {
+	if operand[0] >= '0' && operand[0] <= '9' {
+		v, ok := new(big.Int).SetString(operand, 0)
+		if !ok || v.Sign() < 0 {
+			return lineError(it.line, ErrInvalidOperand, "%s", operand)
+		}
+
+		value := v.Bytes()
+		switch {
+		case len(value) > 32:
+			return lineError(it.line, ErrPushOverflow, "%s", operand)
+		case it.auto && len(value) == 0:
+			it.op, it.width = opcodes.PUSH0, 0
+		case it.auto:
+			it.op, it.width = opcodes.PUSH1+opcodes.OpCode(len(value)-1), len(value)
+		case len(value) > it.width:
+			return lineError(it.line, ErrPushOverflow, "%s in %d bytes", operand, it.width)
+		}
+
+		it.value = value
+		it.auto = false
+		return nil
+	}
+
+	ref, sub := operand, ""
+	if minus := strings.Index(operand, "-"); minus > 0 {
+		ref, sub = operand[:minus], operand[minus+1:]
+		if !labelPattern.MatchString(sub) {
+			return lineError(it.line, ErrInvalidOperand, "%s", operand)
+		}
+	}
+
+	if !labelPattern.MatchString(ref) {
+		return lineError(it.line, ErrInvalidOperand, "%s", operand)
+	}
+
+	it.ref, it.refSub = ref, sub
+	if it.auto {
+		it.width = 1
+	}
+
+	return nil
+}
+
+func labelValue(labels map[string]int, it *item) *big.Int {
+	v := big.NewInt(int64(labels[it.ref]))
+	if it.refSub != "" {
+		v.Sub(v, big.NewInt(int64(labels[it.refSub])))
+	}
+
+	return v
+}
+
+// layout places the items and returns the label offsets. Pushes of labels without a width
+// start at one byte and grow until every value fits, the offsets only grow so this ends.
+func layout(items []*item) (map[string]int, error) {
+	labels := map[string]int{}
+	for _, it := range items {
+		if it.label == "" {
+			continue
+		}
+
+		if _, exist := labels[it.label]; exist {
+			return nil, lineError(it.line, ErrDuplicateLabel, "%s", it.label)
+		}
+		labels[it.label] = 0
+	}
+
+	for _, it := range items {
+		for _, name := range []string{it.ref, it.refSub} {
+			if _, exist := labels[name]; name != "" && !exist {
+				return nil, lineError(it.line, ErrUnknownLabel, "%s", name)
+			}
+		}
+	}
+
+	for changed := true; changed; {
+		offset := 0
+		for _, it := range items {
+			if it.label != "" {
+				labels[it.label] = offset
+			}
+			offset += it.size()
+		}
+
+		changed = false
+		for _, it := range items {
+			if it.ref == "" || !it.auto {
+				continue
+			}
+
+			if n := len(labelValue(labels, it).Bytes()); n > it.width {
+				it.width = n
+				it.op = opcodes.PUSH1 + opcodes.OpCode(n-1)
+				changed = true
+			}
+		}
+	}
+
+	for _, it := range items {
+		if it.ref == "" {
+			continue
+		}
+
+		v := labelValue(labels, it)
+		if v.Sign() < 0 {
+			return nil, lineError(it.line, ErrInvalidOperand, "%s-%s is negative", it.ref, it.refSub)
+		}
+
+		if len(v.Bytes()) > it.width {
+			return nil, lineError(it.line, ErrPushOverflow, "%s in %d bytes", it.ref, it.width)
+		}
+	}
+
+	return labels, nil
+}
@@ -0,0 +1,167 @@
+package asm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const maxMacroDepth = 16
+
+type sourceLine struct {
+	num  int
+	text string
+}
+
+type macro struct {
+	name   string
+	params []string
+	body   []sourceLine
+	labels []string //labels defined in the body, renamed per expansion
+}
+
+var (
+	macroHeadPattern = regexp.MustCompile(`^%macro\s+([A-Za-z_][A-Za-z0-9_]*)\s*\(([^)]*)\)$`)
+	macroCallPattern = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\s*\(([^)]*)\)$`)
+	wordPattern      = regexp.MustCompile(`[A-Za-z0-9_.@]+`)
+)
+
+func stripComment(text string) string {
+	if i := strings.Index(text, ";"); i >= 0 {
+		text = text[:i]
+	}
+
+	if i := strings.Index(text, "//"); i >= 0 {
+		text = text[:i]
+	}
+
+	return strings.TrimSpace(text)
+}
+
+func splitArgs(s string) []string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+
+	args := strings.Split(s, ",")
+	for i := range args {
+		args[i] = strings.TrimSpace(args[i])
+	}
+
+	return args
+}
+
+// expandMacros strips the comments, collects the macro definitions and replaces their
+// invocations by their bodies.
+func expandMacros(source string) ([]sourceLine, error) {
+	macros := map[string]*macro{}
+
+	var lines []sourceLine
+	var current *macro
+	for i, raw := range strings.Split(source, "\n") {
+		l := sourceLine{num: i + 1, text: stripComment(raw)}
+		if l.text == "" {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(l.text, "%macro"):
+			if current != nil {
+				return nil, lineError(l.num, ErrMacro, "nested definition")
+			}
+
+			m := macroHeadPattern.FindStringSubmatch(l.text)
+			if m == nil {
+				return nil, lineError(l.num, ErrMacro, "%s", l.text)
+			}
+
+			if _, exist := macros[m[1]]; exist {
+				return nil, lineError(l.num, ErrMacro, "%s defined twice", m[1])
+			}
+
+			current = &macro{name: m[1], params: splitArgs(m[2])}
+			macros[current.name] = current
+
+		case l.text == "%end":
+			if current == nil {
+				return nil, lineError(l.num, ErrMacro, "%%end without %%macro")
+			}
+			current = nil
+
+		case current != nil:
+			if colon := strings.Index(l.text, ":"); colon > 0 {
+				current.labels = append(current.labels, strings.TrimSpace(l.text[:colon]))
+			}
+			current.body = append(current.body, l)
+
+		default:
+			lines = append(lines, l)
+		}
+	}
+
+	if current != nil {
+		return nil, fmt.Errorf("%w: %s not closed by %%end", ErrMacro, current.name)
+	}
+
+	expansions := 0
+	var expand func(lines []sourceLine, depth int) ([]sourceLine, error)
+	expand = func(lines []sourceLine, depth int) ([]sourceLine, error) {
+		var out []sourceLine
+		for _, l := range lines {
+			call := macroCallPattern.FindStringSubmatch(l.text)
+			if call == nil {
+				out = append(out, l)
+				continue
+			}
+
+			m := macros[call[1]]
+			if m == nil {
+				return nil, lineError(l.num, ErrMacro, "unknown macro %s", call[1])
+			}
+
+			if depth >= maxMacroDepth {
+				return nil, lineError(l.num, ErrMacro, "%s nested too deep", m.name)
+			}
+
+			args := splitArgs(call[2])
+			if len(args) != len(m.params) {
+				return nil, lineError(l.num, ErrMacro, "%s takes %d arguments", m.name, len(m.params))
+			}
+
+			expansions++
+			words := map[string]string{}
+			for _, label := range m.labels {
+				words[label] = fmt.Sprintf("%s@%d", label, expansions)
+			}
+
+			for i, param := range m.params {
+				words[param] = args[i]
+			}
+
+			body := make([]sourceLine, 0, len(m.body))
+			for _, bl := range m.body {
+				text := wordPattern.ReplaceAllStringFunc(bl.text, func(w string) string {
+					if replaced, ok := words[w]; ok {
+						return replaced
+					}
+
+					return w
+				})
+
+				body = append(body, sourceLine{num: bl.num, text: text})
+			}
+
+			expanded, err := expand(body, depth+1)
+			if err != nil {
+				return nil, err
+			}
+
+			out = append(out, expanded...)
+		}
+
+		return out, nil
+	}
+
+	return expand(lines, 0)
+}
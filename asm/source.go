@@ -0,0 +1,58 @@
+package asm
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/SealSC/SealEVM/disasm"
+	"github.com/SealSC/SealEVM/opcodes"
+)
+
+// Source turns a disassembly back into assembler source, Assemble of it gives the original
+// code. Jump destinations get "tag_<offset>" labels and the pushes right before a JUMP or
+// JUMPI refer to them, pushes keep their width. Bytes without an instruction, truncated
+// pushes and the metadata trailer become data.
+func Source(listing *disasm.Listing) string {
+	dests := map[uint64]bool{}
+	for _, offset := range listing.JumpDests() {
+		dests[offset] = true
+	}
+
+	b := &strings.Builder{}
+	for i, ins := range listing.Instructions {
+		if ins.JumpDest {
+			fmt.Fprintf(b, "tag_%x:\n", ins.Offset)
+		}
+
+		name := ins.OpCode.String()
+		switch {
+		case ins.Truncated || (name == "" && ins.OpCode != 0xFE):
+			fmt.Fprintf(b, "    data 0x%02x%x\n", byte(ins.OpCode), []byte(ins.Data))
+
+		case ins.OpCode == 0xFE:
+			fmt.Fprintln(b, "    INVALID")
+
+		case ins.OpCode >= opcodes.PUSH1 && ins.OpCode <= opcodes.PUSH32:
+			operand := fmt.Sprintf("0x%x", []byte(ins.Data))
+			target := new(big.Int).SetBytes(ins.Data)
+			if i+1 < len(listing.Instructions) && target.IsUint64() && dests[target.Uint64()] {
+				if next := listing.Instructions[i+1].OpCode; next == opcodes.JUMP || next == opcodes.JUMPI {
+					operand = fmt.Sprintf("tag_%x", target.Uint64())
+				}
+			}
+
+			fmt.Fprintf(b, "    %s %s\n", name, operand)
+
+		default:
+			fmt.Fprintf(b, "    %s\n", name)
+		}
+	}
+
+	if meta := listing.Metadata; meta != nil {
+		fmt.Fprintf(b, "    ; metadata: %s\n", meta.String())
+		fmt.Fprintf(b, "    data 0x%x%04x\n", []byte(meta.Raw), len(meta.Raw))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,239 @@
+package bind
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/SealSC/SealEVM"
+	"github.com/SealSC/SealEVM/abi"
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmErrors"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/gasSetting"
+	"github.com/SealSC/SealEVM/storage"
+	"github.com/SealSC/SealEVM/storage/cache"
+	"github.com/SealSC/SealEVM/types"
+)
+
+const DefaultGasLimit = 30000000
+
+// Committer is a storage which applies the result of an execution to itself, as
+// statedb/memory.DB does.
+type Committer interface {
+	Commit(result *cache.ResultCache)
+}
+
+// Backend runs the calls and transactions of the bound contracts on a new SealEVM.EVM
+// each, over Storage. Calls are discarded, the results of successful transactions are
+// given to Commit, gas is not paid for from the balance of the sender.
+type Backend struct {
+	Storage          storage.IExternalStorage
+	DataBlockStorage storage.IExternalDataBlockStorage
+	GasSetting       *gasSetting.Setting
+
+	//Block is the block the executions run in, its GasLimit caps the one of each execution
+	Block environment.Block
+
+	//Commit is called with the result of each successful transaction, nil drops them
+	Commit func(result *cache.ResultCache)
+}
+
+// NewBackend is a backend over store in a block of chain id 1, with a gas limit of
+// DefaultGasLimit. The results of transactions are committed to store if it is a Committer.
+func NewBackend(store storage.IExternalStorage) *Backend {
+	SealEVM.Load()
+
+	b := &Backend{
+		Storage: store,
+		Block: environment.Block{
+			ChainID:     evmInt256.New(1),
+			Difficulty:  evmInt256.New(0),
+			GasLimit:    evmInt256.New(DefaultGasLimit),
+			BaseFee:     evmInt256.New(0),
+			BlobBaseFee: evmInt256.New(1),
+		},
+	}
+
+	if dataBlocks, ok := store.(storage.IExternalDataBlockStorage); ok {
+		b.DataBlockStorage = dataBlocks
+	}
+
+	if committer, ok := store.(Committer); ok {
+		b.Commit = committer.Commit
+	}
+
+	return b
+}
+
+// CallOpts are the options of calls, the zero value calls from the zero address with the
+// gas limit of the block.
+type CallOpts struct {
+	From     types.Address
+	Value    *evmInt256.Int
+	GasLimit uint64
+}
+
+// TransactOpts are the options of transactions, Value is sent along for payable methods
+// and constructors.
+type TransactOpts struct {
+	From     types.Address
+	Value    *evmInt256.Int
+	GasLimit uint64
+	GasPrice *evmInt256.Int
+}
+
+// Receipt is the outcome of a transaction. Logs are the ones emitted by the whole execution,
+// ContractAddress is set for deployments.
+type Receipt struct {
+	ContractAddress *types.Address
+	ReturnData      []byte
+	GasUsed         uint64
+	Logs            []*types.Log
+
+	Result SealEVM.ExecuteResult
+}
+
+// ExecutionError is a failed execution. Err is the SealEVM error, evmErrors.RevertErr for
+// a revert, Reason is the decoded revert reason or custom error if there is one.
+type ExecutionError struct {
+	Err    error
+	Data   []byte
+	Reason string
+}
+
+func (e *ExecutionError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("execution reverted: %s", e.Reason)
+	}
+
+	if errors.Is(e.Err, evmErrors.RevertErr) {
+		return "execution reverted"
+	}
+
+	return "execution failed: " + e.Err.Error()
+}
+
+func (e *ExecutionError) Unwrap() error {
+	return e.Err
+}
+
+// revertReason decodes revert data as Error(string), Panic(uint256) or a custom error of
+// contract.
+func revertReason(contract *abi.ABI, data []byte) string {
+	if reason, err := abi.UnpackRevert(data); err == nil {
+		return reason
+	}
+
+	if contract == nil {
+		return ""
+	}
+
+	e, args, err := contract.UnpackError(data)
+	if err != nil {
+		return ""
+	}
+
+	values := make([]string, len(args))
+	for i, arg := range args {
+		values[i] = fmt.Sprint(arg)
+	}
+
+	return e.Name + "(" + strings.Join(values, ", ") + ")"
+}
+
+// execute runs one message, to is nil for a deployment.
+func (b *Backend) execute(from types.Address, to *types.Address, value *evmInt256.Int, data []byte, gasLimit uint64, gasPrice *evmInt256.Int) (SealEVM.ExecuteResult, uint64, error) {
+	if value == nil {
+		value = evmInt256.New(0)
+	}
+
+	if gasPrice == nil {
+		gasPrice = evmInt256.New(0)
+	}
+
+	if gasLimit == 0 {
+		gasLimit = b.Block.GasLimit.Uint64()
+	}
+
+	evm := SealEVM.New(SealEVM.EVMParam{
+		MaxStackDepth:            1024,
+		ExternalStore:            b.Storage,
+		ExternalDataBlockStorage: b.DataBlockStorage,
+		GasSetting:               b.GasSetting,
+		Context: &environment.Context{
+			Block: b.Block,
+			Transaction: environment.Transaction{
+				Origin:   from,
+				To:       to,
+				GasPrice: gasPrice.Clone(),
+				GasLimit: evmInt256.New(gasLimit),
+			},
+			Message: environment.Message{
+				Caller: from,
+				Value:  value.Clone(),
+				Data:   data,
+			},
+		},
+	})
+
+	result, err := evm.Execute()
+
+	//an exceptional halt consumes all gas, only REVERT gives the rest back
+	gasLeft := result.GasLeft
+	if err != nil && err != evmErrors.RevertErr {
+		gasLeft = 0
+	}
+
+	if gasLeft > gasLimit {
+		gasLeft = gasLimit
+	}
+
+	return result, gasLimit - gasLeft, err
+}
+
+// Call runs data against the contract at to without changing the state and returns the
+// return data. contract, which may be nil, decodes the custom errors of a revert.
+func (b *Backend) Call(opts *CallOpts, to types.Address, data []byte, contract *abi.ABI) ([]byte, error) {
+	if opts == nil {
+		opts = &CallOpts{}
+	}
+
+	result, _, err := b.execute(opts.From, &to, opts.Value, data, opts.GasLimit, nil)
+	if err != nil {
+		return nil, &ExecutionError{Err: err, Data: result.ResultData, Reason: revertReason(contract, result.ResultData)}
+	}
+
+	return result.ResultData, nil
+}
+
+// Transact runs a transaction to to, a deployment of the creation data when to is nil,
+// and commits its result if it succeeds. The receipt is returned with the error of a
+// failed execution.
+func (b *Backend) Transact(opts *TransactOpts, to *types.Address, data []byte, contract *abi.ABI) (*Receipt, error) {
+	if opts == nil {
+		opts = &TransactOpts{}
+	}
+
+	result, gasUsed, err := b.execute(opts.From, to, opts.Value, data, opts.GasLimit, opts.GasPrice)
+	receipt := &Receipt{
+		ReturnData: result.ResultData,
+		GasUsed:    gasUsed,
+		Result:     result,
+	}
+
+	if err != nil {
+		return receipt, &ExecutionError{Err: err, Data: result.ResultData, Reason: revertReason(contract, result.ResultData)}
+	}
+
+	receipt.ContractAddress = result.ContractAddress
+	if result.StorageCache.Logs != nil {
+		receipt.Logs = *result.StorageCache.Logs
+	}
+
+	if b.Commit != nil {
+		b.Commit(&result.StorageCache)
+	}
+
+	return receipt, nil
+}
@@ -0,0 +1,165 @@
+// Package bind runs contracts through their ABI on SealEVM, without RPC: the backend
+// executes calls and transactions on a SealEVM.EVM over an IExternalStorage, and
+// Generate writes typed Go wrappers (abigen style) on top of BoundContract.
+package bind
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/SealSC/SealEVM/abi"
+	"github.com/SealSC/SealEVM/types"
+)
+
+var ErrNoCode = errors.New("no contract code")
+
+// BoundContract is a contract deployed at Address, called through its ABI.
+type BoundContract struct {
+	Address types.Address
+	ABI     *abi.ABI
+	Backend *Backend
+}
+
+func NewBoundContract(address types.Address, contract *abi.ABI, backend *Backend) *BoundContract {
+	return &BoundContract{
+		Address: address,
+		ABI:     contract,
+		Backend: backend,
+	}
+}
+
+// DeployContract deploys bytecode with the constructor arguments and binds the created
+// contract.
+func DeployContract(opts *TransactOpts, backend *Backend, contract *abi.ABI, bytecode []byte, args ...interface{}) (*BoundContract, *Receipt, error) {
+	if len(bytecode) == 0 {
+		return nil, nil, ErrNoCode
+	}
+
+	data, err := contract.PackConstructor(bytecode, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	receipt, err := backend.Transact(opts, nil, data, contract)
+	if err != nil {
+		return nil, receipt, err
+	}
+
+	return NewBoundContract(*receipt.ContractAddress, contract, backend), receipt, nil
+}
+
+// Call calls method without changing the state and decodes its outputs.
+func (c *BoundContract) Call(opts *CallOpts, method string, args ...interface{}) ([]interface{}, error) {
+	data, err := c.ABI.Pack(method, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := c.Backend.Call(opts, c.Address, data, c.ABI)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ret) == 0 && len(c.ABI.Methods[method].Outputs) > 0 {
+		return nil, fmt.Errorf("%w at %s", ErrNoCode, c.Address)
+	}
+
+	return c.ABI.Unpack(method, ret)
+}
+
+// CallInto is Call copying the outputs into dst, see abi.Arguments.UnpackInto.
+func (c *BoundContract) CallInto(opts *CallOpts, dst []interface{}, method string, args ...interface{}) error {
+	data, err := c.ABI.Pack(method, args...)
+	if err != nil {
+		return err
+	}
+
+	ret, err := c.Backend.Call(opts, c.Address, data, c.ABI)
+	if err != nil {
+		return err
+	}
+
+	if len(ret) == 0 && len(dst) > 0 {
+		return fmt.Errorf("%w at %s", ErrNoCode, c.Address)
+	}
+
+	return c.ABI.UnpackInto(method, ret, dst...)
+}
+
+// Transact sends a transaction calling method.
+func (c *BoundContract) Transact(opts *TransactOpts, method string, args ...interface{}) (*Receipt, error) {
+	data, err := c.ABI.Pack(method, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Backend.Transact(opts, &c.Address, data, c.ABI)
+}
+
+// RawTransact sends a transaction with the call data as given, an empty one reaches the
+// receive or fallback function.
+func (c *BoundContract) RawTransact(opts *TransactOpts, data []byte) (*Receipt, error) {
+	return c.Backend.Transact(opts, &c.Address, data, c.ABI)
+}
+
+// FilterLogs are the logs of event emitted by the contract, anonymous events can not be
+// told apart and match any log of the contract.
+func (c *BoundContract) FilterLogs(logs []*types.Log, event string) ([]*types.Log, error) {
+	e := c.ABI.Events[event]
+	if e == nil {
+		return nil, fmt.Errorf("%w: %s", abi.ErrEventNotFound, event)
+	}
+
+	var matched []*types.Log
+	for _, log := range logs {
+		if log.Address != c.Address {
+			continue
+		}
+
+		if !e.Anonymous && (len(log.Topics) == 0 || log.Topics[0] != e.ID) {
+			continue
+		}
+
+		matched = append(matched, log)
+	}
+
+	return matched, nil
+}
+
+// UnpackLog decodes a log of event and copies its arguments, in order, into dst, see
+// abi.Copy.
+func (c *BoundContract) UnpackLog(log *types.Log, event string, dst ...interface{}) error {
+	e := c.ABI.Events[event]
+	if e == nil {
+		return fmt.Errorf("%w: %s", abi.ErrEventNotFound, event)
+	}
+
+	if len(dst) != len(e.Inputs) {
+		return fmt.Errorf("%w: %d destinations for the %d arguments of %s", abi.ErrInvalidValue, len(dst), len(e.Inputs), e.Sig)
+	}
+
+	values, err := e.ParseLog(log)
+	if err != nil {
+		return err
+	}
+
+	for i, v := range values {
+		if err = abi.Copy(dst[i], v); err != nil {
+			return fmt.Errorf("%s argument %q: %w", e.Sig, e.Inputs[i].Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Bytecode decodes the hex bytecode of generated bindings, it panics on invalid hex.
+func Bytecode(code string) []byte {
+	b, err := hex.DecodeString(strings.TrimPrefix(code, "0x"))
+	if err != nil {
+		panic(err)
+	}
+
+	return b
+}
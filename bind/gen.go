@@ -0,0 +1,410 @@
+package bind
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/SealSC/SealEVM/abi"
+)
+
+var ErrInvalidBinding = errors.New("invalid binding")
+
+// Contract is a contract to generate the bindings of. Type is the Go type of the binding,
+// ABI the JSON ABI and Bytecode the creation code, without it no Deploy is generated.
+type Contract struct {
+	Type     string
+	ABI      []byte
+	Bytecode []byte
+}
+
+type tmplParam struct {
+	Name string
+	Type string
+	Tag  string
+}
+
+type tmplMethod struct {
+	GoName     string
+	Name       string
+	Sig        string
+	Mutability string
+	Inputs     []tmplParam
+	Outputs    []tmplParam
+}
+
+type tmplEvent struct {
+	GoName string
+	Type   string
+	Name   string
+	Sig    string
+	Fields []tmplParam
+
+	//Hashed tells whether some fields are the topic hashes of indexed values
+	Hashed bool
+}
+
+type tmplStruct struct {
+	Name   string
+	Sig    string
+	Fields []tmplParam
+}
+
+type tmplContract struct {
+	Type        string
+	ParsedABI   string
+	ABI         string
+	Bin         string
+	Constructor []tmplParam
+	Calls       []*tmplMethod
+	Transacts   []*tmplMethod
+	Events      []*tmplEvent
+	Receive     string
+	Fallback    string
+}
+
+type tmplData struct {
+	Package   string
+	Structs   []*tmplStruct
+	Contracts []*tmplContract
+}
+
+// names hands out unique Go identifiers within a scope.
+type names map[string]bool
+
+func (n names) unique(name string) string {
+	unique := name
+	for i := 0; n[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+
+	n[unique] = true
+	return unique
+}
+
+// camel turns a Solidity name into an exported Go one, "_token_id" into "TokenId".
+func camel(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part == "" {
+			continue
+		}
+
+		r := []rune(part)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+
+	return b.String()
+}
+
+// paramName is a Go parameter name for a Solidity one.
+func paramName(name string, i int, used names) string {
+	goName := camel(name)
+	if goName == "" {
+		goName = fmt.Sprintf("arg%d", i)
+	} else {
+		r := []rune(goName)
+		r[0] = unicode.ToLower(r[0])
+		goName = string(r)
+	}
+
+	if token.IsKeyword(goName) {
+		goName += "_"
+	}
+
+	return used.unique(goName)
+}
+
+// generator keeps the tuple structs shared by the contracts of a file.
+type generator struct {
+	types   names
+	structs map[string]*tmplStruct
+	data    *tmplData
+}
+
+func structKey(t *abi.Type) string {
+	return t.String() + "|" + t.TupleName + "|" + strings.Join(t.ComponentNames, ",")
+}
+
+func (g *generator) goType(t *abi.Type) string {
+	switch t.Kind {
+	case abi.UintTy, abi.IntTy:
+		return "*evmInt256.Int"
+	case abi.BoolTy:
+		return "bool"
+	case abi.StringTy:
+		return "string"
+	case abi.BytesTy:
+		return "[]byte"
+	case abi.AddressTy:
+		return "types.Address"
+	case abi.FixedBytesTy:
+		if t.Size == 32 {
+			return "types.Hash"
+		}
+		return fmt.Sprintf("[%d]byte", t.Size)
+	case abi.FunctionTy:
+		return "[24]byte"
+	case abi.SliceTy:
+		return "[]" + g.goType(t.Elem)
+	case abi.ArrayTy:
+		return fmt.Sprintf("[%d]%s", t.Size, g.goType(t.Elem))
+	}
+
+	return g.tuple(t)
+}
+
+// tuple is the struct of a tuple type, declared once per distinct tuple.
+func (g *generator) tuple(t *abi.Type) string {
+	key := structKey(t)
+	if s := g.structs[key]; s != nil {
+		return s.Name
+	}
+
+	name := camel(t.TupleName)
+	if name == "" {
+		name = "Tuple"
+	}
+
+	s := &tmplStruct{Name: g.types.unique(name), Sig: t.String()}
+	g.structs[key] = s
+
+	fields := names{}
+	for i, c := range t.Components {
+		fieldName := camel(t.ComponentNames[i])
+		if fieldName == "" || !token.IsIdentifier(fieldName) {
+			fieldName = fmt.Sprintf("Field%d", i)
+		}
+
+		s.Fields = append(s.Fields, tmplParam{Name: fields.unique(fieldName), Type: g.goType(c), Tag: t.ComponentNames[i]})
+	}
+
+	g.data.Structs = append(g.data.Structs, s)
+	return s.Name
+}
+
+func (g *generator) params(args abi.Arguments, used names) []tmplParam {
+	params := make([]tmplParam, len(args))
+	for i, arg := range args {
+		params[i] = tmplParam{Name: paramName(arg.Name, i, used), Type: g.goType(arg.Type)}
+	}
+
+	return params
+}
+
+// reserved are the names used by the generated code besides the parameters
+var reserved = []string{"opts", "backend", "c", "err", "contract", "receipt", "ev", "log", "logs",
+	"matched", "events", "abi", "bind", "types", "evmInt256"}
+
+func paramScope() names {
+	used := names{}
+	for _, name := range reserved {
+		used[name] = true
+	}
+
+	return used
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func (g *generator) contract(c Contract) (*tmplContract, error) {
+	if !token.IsIdentifier(c.Type) || !token.IsExported(c.Type) {
+		return nil, fmt.Errorf("%w: type name %q", ErrInvalidBinding, c.Type)
+	}
+
+	parsed, err := abi.Parse(c.ABI)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", c.Type, err)
+	}
+
+	//the ABI is embedded compacted, as in artifacts only its "abi" field is kept
+	var entries []json.RawMessage
+	if json.Unmarshal(c.ABI, &entries) != nil {
+		var artifact struct {
+			ABI []json.RawMessage `json:"abi"`
+		}
+
+		if err = json.Unmarshal(c.ABI, &artifact); err != nil {
+			return nil, fmt.Errorf("%s: %w", c.Type, err)
+		}
+		entries = artifact.ABI
+	}
+
+	compact, err := json.Marshal(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	tc := &tmplContract{
+		Type:      c.Type,
+		ParsedABI: "parsed" + c.Type + "ABI",
+		ABI:       strconv.Quote(string(compact)),
+	}
+
+	if len(c.Bytecode) > 0 {
+		tc.Bin = "0x" + hex.EncodeToString(c.Bytecode)
+	}
+
+	if parsed.Constructor != nil {
+		tc.Constructor = g.params(parsed.Constructor.Inputs, paramScope())
+	}
+
+	methods := names{"Contract": true}
+	for _, name := range sortedKeys(parsed.Methods) {
+		m := parsed.Methods[name]
+		tm := &tmplMethod{
+			GoName:     methods.unique(camel(name)),
+			Name:       name,
+			Sig:        m.Sig,
+			Mutability: m.StateMutability,
+		}
+
+		used := paramScope()
+		tm.Inputs = g.params(m.Inputs, used)
+		if m.IsConstant() {
+			for i, out := range m.Outputs {
+				tm.Outputs = append(tm.Outputs, tmplParam{Name: used.unique(fmt.Sprintf("out%d", i)), Type: g.goType(out.Type)})
+			}
+			tc.Calls = append(tc.Calls, tm)
+		} else {
+			tc.Transacts = append(tc.Transacts, tm)
+		}
+	}
+
+	if parsed.HasReceive {
+		tc.Receive = methods.unique("Receive")
+	}
+
+	if parsed.HasFallback {
+		tc.Fallback = methods.unique("Fallback")
+	}
+
+	for _, name := range sortedKeys(parsed.Events) {
+		e := parsed.Events[name]
+		te := &tmplEvent{
+			GoName: camel(name),
+			Name:   name,
+			Sig:    e.Sig,
+		}
+
+		//ParseX and FilterX must not collide with the methods
+		for i := 0; methods["Parse"+te.GoName] || methods["Filter"+te.GoName]; i++ {
+			te.GoName = fmt.Sprintf("%s%d", camel(name), i)
+		}
+		methods["Parse"+te.GoName] = true
+		methods["Filter"+te.GoName] = true
+		te.Type = g.types.unique(c.Type + te.GoName)
+
+		fields := names{"Raw": true}
+		for i, arg := range e.Inputs {
+			fieldName := camel(arg.Name)
+			if fieldName == "" {
+				fieldName = fmt.Sprintf("Arg%d", i)
+			}
+
+			goType := g.goType(arg.Type)
+			if arg.Indexed && (arg.Type.Dynamic() || arg.Type.Kind == abi.TupleTy || arg.Type.Kind == abi.ArrayTy) {
+				goType = "types.Hash"
+				te.Hashed = true
+			}
+
+			te.Fields = append(te.Fields, tmplParam{Name: fields.unique(fieldName), Type: goType})
+		}
+
+		tc.Events = append(tc.Events, te)
+	}
+
+	return tc, nil
+}
+
+// Generate writes the Go source of the bindings of contracts, in package pkg. Each contract
+// gets a type with a constructor binding a deployed instance, a Deploy function when its
+// bytecode is given, a method per function of the ABI, calls for the view and pure ones and
+// transactions for the others, and a parser and a filter per event. Solidity tuples become
+// structs named after their internalType.
+func Generate(pkg string, contracts []Contract) ([]byte, error) {
+	if !token.IsIdentifier(pkg) {
+		return nil, fmt.Errorf("%w: package name %q", ErrInvalidBinding, pkg)
+	}
+
+	g := &generator{
+		types:   names{},
+		structs: map[string]*tmplStruct{},
+		data:    &tmplData{Package: pkg},
+	}
+
+	//the contract types are claimed first, the structs and events get what is left
+	for _, c := range contracts {
+		if g.types[c.Type] {
+			return nil, fmt.Errorf("%w: type %s declared twice", ErrInvalidBinding, c.Type)
+		}
+		g.types[c.Type] = true
+	}
+
+	for _, c := range contracts {
+		tc, err := g.contract(c)
+		if err != nil {
+			return nil, err
+		}
+
+		g.data.Contracts = append(g.data.Contracts, tc)
+	}
+
+	var buf bytes.Buffer
+	if err := bindingTemplate.Execute(&buf, g.data); err != nil {
+		return nil, err
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidBinding, err)
+	}
+
+	return src, nil
+}
+
+var bindingTemplate = template.Must(template.New("binding").Funcs(template.FuncMap{
+	"args": func(params []tmplParam) string {
+		var b strings.Builder
+		for _, p := range params {
+			b.WriteString(", " + p.Name)
+		}
+		return b.String()
+	},
+	"decl": func(params []tmplParam) string {
+		var b strings.Builder
+		for _, p := range params {
+			b.WriteString(", " + p.Name + " " + p.Type)
+		}
+		return b.String()
+	},
+	"refs": func(params []tmplParam, prefix string) string {
+		refs := make([]string, len(params))
+		for i, p := range params {
+			refs[i] = "&" + prefix + p.Name
+		}
+		return strings.Join(refs, ", ")
+	},
+	"quote": strconv.Quote,
+	"tag": func(name string) string {
+		return "`abi:" + strconv.Quote(name) + "`"
+	},
+}).Parse(bindingSource))
@@ -0,0 +1,134 @@
+package bind
+
+const bindingSource = `// Code generated by sealevm abigen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"github.com/SealSC/SealEVM/abi"
+	"github.com/SealSC/SealEVM/bind"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/types"
+)
+
+// the imports are not used by every binding
+var (
+	_ = abi.Copy
+	_ = evmInt256.New
+	_ types.Address
+)
+{{range .Structs}}
+// {{.Name}} is the Solidity tuple {{.Sig}}.
+type {{.Name}} struct {
+{{- range .Fields}}
+	{{.Name}} {{.Type}} {{tag .Tag}}
+{{- end}}
+}
+{{end}}
+{{- range .Contracts}}
+// {{.Type}}ABI is the JSON ABI of {{.Type}}.
+const {{.Type}}ABI = {{.ABI}}
+{{if .Bin}}
+// {{.Type}}Bin is the creation bytecode of {{.Type}}.
+const {{.Type}}Bin = "{{.Bin}}"
+{{end}}
+var {{.ParsedABI}} = abi.MustParse({{.Type}}ABI)
+
+// {{.Type}} is a binding of the {{.Type}} contract.
+type {{.Type}} struct {
+	Contract *bind.BoundContract
+}
+
+// New{{.Type}} binds the {{.Type}} contract deployed at address.
+func New{{.Type}}(address types.Address, backend *bind.Backend) *{{.Type}} {
+	return &{{.Type}}{Contract: bind.NewBoundContract(address, {{.ParsedABI}}, backend)}
+}
+{{if .Bin}}
+// Deploy{{.Type}} deploys {{.Type}} with the arguments of its constructor.
+func Deploy{{.Type}}(opts *bind.TransactOpts, backend *bind.Backend{{decl .Constructor}}) (*{{.Type}}, *bind.Receipt, error) {
+	contract, receipt, err := bind.DeployContract(opts, backend, {{.ParsedABI}}, bind.Bytecode({{.Type}}Bin){{args .Constructor}})
+	if err != nil {
+		return nil, receipt, err
+	}
+
+	return &{{.Type}}{Contract: contract}, receipt, nil
+}
+{{end}}
+{{- $contract := .}}
+{{- range .Calls}}
+// {{.GoName}} calls {{.Sig}}, a {{.Mutability}} function.
+func (c *{{$contract.Type}}) {{.GoName}}(opts *bind.CallOpts{{decl .Inputs}}) ({{range .Outputs}}{{.Type}}, {{end}}error) {
+{{- if .Outputs}}
+{{- range .Outputs}}
+	var {{.Name}} {{.Type}}
+{{- end}}
+	err := c.Contract.CallInto(opts, []interface{}{ {{- refs .Outputs ""}}}, {{quote .Name}}{{args .Inputs}})
+	return {{range .Outputs}}{{.Name}}, {{end}}err
+{{- else}}
+	return c.Contract.CallInto(opts, nil, {{quote .Name}}{{args .Inputs}})
+{{- end}}
+}
+{{end}}
+{{- range .Transacts}}
+// {{.GoName}} sends a transaction calling {{.Sig}}, a {{.Mutability}} function.
+func (c *{{$contract.Type}}) {{.GoName}}(opts *bind.TransactOpts{{decl .Inputs}}) (*bind.Receipt, error) {
+	return c.Contract.Transact(opts, {{quote .Name}}{{args .Inputs}})
+}
+{{end}}
+{{- if .Receive}}
+// {{.Receive}} sends a transaction with empty call data to the receive function.
+func (c *{{.Type}}) {{.Receive}}(opts *bind.TransactOpts) (*bind.Receipt, error) {
+	return c.Contract.RawTransact(opts, nil)
+}
+{{end}}
+{{- if .Fallback}}
+// {{.Fallback}} sends a transaction with the call data as given to the fallback function.
+func (c *{{.Type}}) {{.Fallback}}(opts *bind.TransactOpts, data []byte) (*bind.Receipt, error) {
+	return c.Contract.RawTransact(opts, data)
+}
+{{end}}
+{{- range .Events}}
+// {{.Type}} is a {{.Sig}} event of {{$contract.Type}}.
+{{- if .Hashed}}
+// Indexed strings, bytes, arrays and tuples are the hash of their encoding.
+{{- end}}
+type {{.Type}} struct {
+{{- range .Fields}}
+	{{.Name}} {{.Type}}
+{{- end}}
+
+	Raw *types.Log
+}
+
+// Parse{{.GoName}} decodes a {{.Name}} log.
+func (c *{{$contract.Type}}) Parse{{.GoName}}(log *types.Log) (*{{.Type}}, error) {
+	ev := &{{.Type}}{Raw: log}
+	if err := c.Contract.UnpackLog(log, {{quote .Name}}{{range .Fields}}, &ev.{{.Name}}{{end}}); err != nil {
+		return nil, err
+	}
+
+	return ev, nil
+}
+
+// Filter{{.GoName}} decodes the {{.Name}} logs emitted by the contract among logs, as the
+// ones of a receipt.
+func (c *{{$contract.Type}}) Filter{{.GoName}}(logs []*types.Log) ([]*{{.Type}}, error) {
+	matched, err := c.Contract.FilterLogs(logs, {{quote .Name}})
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*{{.Type}}, 0, len(matched))
+	for _, log := range matched {
+		ev, err := c.Parse{{.GoName}}(log)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, ev)
+	}
+
+	return events, nil
+}
+{{end}}
+{{- end}}`
@@ -0,0 +1,355 @@
+// Package cfg splits contract code into basic blocks and builds its control-flow graph.
+//
+// Jump targets are resolved by tracking the constants the code moves on the stack (PUSH,
+// DUP, SWAP, POP) along the paths from the entry, which covers the PUSH+JUMP patterns and
+// the return addresses compilers push before jumping to internal functions. A jump whose
+// target is computed is left unresolved, Graph.Complete tells whether there was any.
+package cfg
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/SealSC/SealEVM/disasm"
+	"github.com/SealSC/SealEVM/opcodes"
+)
+
+type EdgeKind int
+
+const (
+	//Fallthrough goes to the next block, after a JUMPI not taken or into a JUMPDEST
+	Fallthrough EdgeKind = iota
+	//Jump is a JUMP or a taken JUMPI
+	Jump
+)
+
+func (k EdgeKind) String() string {
+	if k == Jump {
+		return "jump"
+	}
+
+	return "fallthrough"
+}
+
+type Edge struct {
+	From uint64
+	To   uint64
+	Kind EdgeKind
+}
+
+// Block is a basic block, Start and End are the offsets of its first instruction and of
+// the byte after its last one.
+type Block struct {
+	Start        uint64
+	End          uint64
+	Instructions []*disasm.Instruction
+
+	Successors   []uint64
+	Predecessors []uint64
+
+	//Reachable is set when the analysis reached the block from the entry
+	Reachable bool
+	//Unresolved is set when the block ends with a jump of a computed target
+	Unresolved bool
+	//InvalidJumps are the targets the block jumps to which are no valid JUMPDEST
+	InvalidJumps []uint64
+}
+
+// Last is the last instruction of the block.
+func (b *Block) Last() *disasm.Instruction {
+	return b.Instructions[len(b.Instructions)-1]
+}
+
+// Halts tells whether the last instruction ends the execution.
+func (b *Block) Halts() bool {
+	return halts(b.Last().OpCode)
+}
+
+func halts(op opcodes.OpCode) bool {
+	switch op {
+	case opcodes.STOP, opcodes.RETURN, opcodes.REVERT, opcodes.SELFDESTRUCT:
+		return true
+	}
+
+	return op.String() == ""
+}
+
+func endsBlock(op opcodes.OpCode) bool {
+	return op == opcodes.JUMP || op == opcodes.JUMPI || halts(op)
+}
+
+type Graph struct {
+	Listing *disasm.Listing
+	Blocks  []*Block
+	Edges   []*Edge
+
+	//Complete is set when every reachable jump was resolved, Unreachable is then exact
+	Complete bool
+
+	//Functions are the entries of the selector dispatcher, nil when none was found
+	Functions []*Function
+
+	blockAt map[uint64]*Block
+}
+
+// Build disassembles code and builds its graph.
+func Build(code []byte) *Graph {
+	return FromListing(disasm.Disassemble(code))
+}
+
+// FromListing builds the graph of a disassembly.
+func FromListing(listing *disasm.Listing) *Graph {
+	g := &Graph{
+		Listing: listing,
+		blockAt: map[uint64]*Block{},
+	}
+
+	var current *Block
+	for _, ins := range listing.Instructions {
+		if current == nil || ins.JumpDest {
+			current = &Block{Start: ins.Offset}
+			g.Blocks = append(g.Blocks, current)
+			g.blockAt[ins.Offset] = current
+		}
+
+		current.Instructions = append(current.Instructions, ins)
+		current.End = ins.Offset + ins.Size()
+
+		if endsBlock(ins.OpCode) {
+			current = nil
+		}
+	}
+
+	g.analyze()
+	g.Functions = g.findDispatcher()
+	return g
+}
+
+// Block is the block starting at offset, nil when there is none.
+func (g *Graph) Block(offset uint64) *Block {
+	return g.blockAt[offset]
+}
+
+// BlockOf is the block holding the instruction at offset, nil when there is none.
+func (g *Graph) BlockOf(offset uint64) *Block {
+	i := sort.Search(len(g.Blocks), func(i int) bool {
+		return g.Blocks[i].End > offset
+	})
+
+	if i == len(g.Blocks) || g.Blocks[i].Start > offset {
+		return nil
+	}
+
+	return g.Blocks[i]
+}
+
+// Unreachable are the blocks the analysis never reached, only an estimate when the graph is
+// not Complete.
+func (g *Graph) Unreachable() []*Block {
+	var blocks []*Block
+	for _, b := range g.Blocks {
+		if !b.Reachable {
+			blocks = append(blocks, b)
+		}
+	}
+
+	return blocks
+}
+
+// jumpDest is the block of a valid jump destination, nil when target is none.
+func (g *Graph) jumpDest(target *big.Int) *Block {
+	if !target.IsUint64() {
+		return nil
+	}
+
+	if b := g.blockAt[target.Uint64()]; b != nil && b.Instructions[0].JumpDest {
+		return b
+	}
+
+	return nil
+}
+
+func (g *Graph) next(b *Block) *Block {
+	return g.blockAt[b.End]
+}
+
+type context struct {
+	block *Block
+	stack absStack
+}
+
+// walker is what a walk of the abstract stacks reports to.
+type walker struct {
+	skip       func(b *Block) bool
+	visit      func(b *Block)
+	edge       func(from *Block, to *Block, kind EdgeKind)
+	unresolved func(b *Block)
+	invalid    func(b *Block, target uint64)
+}
+
+// walk follows the paths from start, the stack at start is unknown. Blocks skip returns
+// true for are not entered.
+func (g *Graph) walk(start *Block, w *walker) {
+	seen := map[*Block]map[string]bool{}
+	work := []*context{{block: start}}
+
+	enter := func(from *Block, to *Block, kind EdgeKind, stack absStack) {
+		if w.skip != nil && w.skip(to) {
+			return
+		}
+
+		if w.edge != nil {
+			w.edge(from, to, kind)
+		}
+
+		work = append(work, &context{block: to, stack: stack})
+	}
+
+	for len(work) > 0 {
+		ctx := work[len(work)-1]
+		work = work[:len(work)-1]
+
+		b := ctx.block
+		if seen[b] == nil {
+			seen[b] = map[string]bool{}
+		}
+
+		key := ctx.stack.key()
+		if seen[b][key] {
+			continue
+		}
+
+		//too many stacks for one block, go on with an unknown one which covers them all
+		if len(seen[b]) >= maxContexts {
+			ctx.stack, key = nil, ""
+			if seen[b][key] {
+				continue
+			}
+		}
+
+		if len(seen[b]) == 0 && w.visit != nil {
+			w.visit(b)
+		}
+		seen[b][key] = true
+
+		stack := ctx.stack
+		for _, ins := range b.Instructions[:len(b.Instructions)-1] {
+			stack = step(stack, ins)
+		}
+
+		last := b.Last()
+		switch last.OpCode {
+		case opcodes.JUMP, opcodes.JUMPI:
+			target := stack.peek(0)
+			stack = step(stack, last)
+
+			if target == nil {
+				if w.unresolved != nil {
+					w.unresolved(b)
+				}
+			} else if to := g.jumpDest(target); to != nil {
+				enter(b, to, Jump, stack)
+			} else if w.invalid != nil {
+				offset := ^uint64(0)
+				if target.IsUint64() {
+					offset = target.Uint64()
+				}
+
+				w.invalid(b, offset)
+			}
+
+			if last.OpCode == opcodes.JUMP {
+				continue
+			}
+
+		default:
+			if halts(last.OpCode) {
+				continue
+			}
+
+			stack = step(stack, last)
+		}
+
+		if next := g.next(b); next != nil {
+			enter(b, next, Fallthrough, stack)
+		}
+	}
+}
+
+// analyze walks the blocks from the entry and records the edges of the jumps it resolves.
+func (g *Graph) analyze() {
+	g.Complete = true
+	if len(g.Blocks) == 0 {
+		return
+	}
+
+	edges := map[Edge]bool{}
+	invalid := map[*Block]map[uint64]bool{}
+
+	g.walk(g.Blocks[0], &walker{
+		visit: func(b *Block) {
+			b.Reachable = true
+		},
+		edge: func(from *Block, to *Block, kind EdgeKind) {
+			e := Edge{From: from.Start, To: to.Start, Kind: kind}
+			if edges[e] {
+				return
+			}
+
+			edges[e] = true
+			g.Edges = append(g.Edges, &e)
+			from.Successors = append(from.Successors, to.Start)
+			to.Predecessors = append(to.Predecessors, from.Start)
+		},
+		unresolved: func(b *Block) {
+			b.Unresolved = true
+			g.Complete = false
+		},
+		invalid: func(b *Block, target uint64) {
+			if invalid[b] == nil {
+				invalid[b] = map[uint64]bool{}
+			}
+
+			if !invalid[b][target] {
+				invalid[b][target] = true
+				b.InvalidJumps = append(b.InvalidJumps, target)
+			}
+		},
+	})
+
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].From != g.Edges[j].From {
+			return g.Edges[i].From < g.Edges[j].From
+		}
+
+		return g.Edges[i].To < g.Edges[j].To
+	})
+}
+
+// ReachableFrom are the blocks reached from the block at start, which is entered with an
+// unknown stack, without entering the blocks skip returns true for (skip may be nil).
+// Unlike following the Successors, return jumps only go back to the caller the walk came
+// from. complete is false when a jump on the way could not be resolved.
+func (g *Graph) ReachableFrom(start uint64, skip func(b *Block) bool) (blocks []*Block, complete bool) {
+	b := g.blockAt[start]
+	if b == nil {
+		return nil, true
+	}
+
+	complete = true
+	g.walk(b, &walker{
+		skip: skip,
+		visit: func(b *Block) {
+			blocks = append(blocks, b)
+		},
+		unresolved: func(*Block) {
+			complete = false
+		},
+	})
+
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].Start < blocks[j].Start
+	})
+
+	return blocks, complete
+}
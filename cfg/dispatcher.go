@@ -0,0 +1,85 @@
+package cfg
+
+import (
+	"math/big"
+
+	"github.com/SealSC/SealEVM/disasm"
+	"github.com/SealSC/SealEVM/opcodes"
+)
+
+// Function is an entry of the selector dispatcher: the block at Block compares the
+// selector of the call data with Selector and jumps to Entry when they are equal.
+type Function struct {
+	Selector [4]byte
+	Entry    uint64
+	Block    uint64
+}
+
+func isPush(ins *disasm.Instruction) bool {
+	return ins.OpCode >= opcodes.PUSH1 && ins.OpCode <= opcodes.PUSH32 && !ins.Truncated
+}
+
+// loadsSelector tells whether the code extracts the first four bytes of the call data, by
+// "PUSH1 0xe0 SHR", or by the division by 2^224 of older compilers.
+func (g *Graph) loadsSelector() bool {
+	shift := new(big.Int).Lsh(big.NewInt(1), 224)
+	ins := g.Listing.Instructions
+	for i := 0; i+1 < len(ins); i++ {
+		if !isPush(ins[i]) {
+			continue
+		}
+
+		v := new(big.Int).SetBytes(ins[i].Data)
+		switch {
+		case ins[i+1].OpCode == opcodes.SHR && v.Cmp(big.NewInt(0xe0)) == 0:
+			return true
+		case v.Cmp(shift) == 0:
+			//PUSH29 0x01000...00, SWAP1 or not, then DIV
+			for _, next := range ins[i+1 : min(i+3, len(ins))] {
+				if next.OpCode == opcodes.DIV {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// findDispatcher finds the "PUSH4 selector, DUPn (optional), EQ, PUSH dest, JUMPI" ends
+// of the blocks solc emits to dispatch a call. Selectors with leading zero bytes are
+// pushed narrower, so PUSH1 to PUSH4 are taken.
+func (g *Graph) findDispatcher() []*Function {
+	if !g.loadsSelector() {
+		return nil
+	}
+
+	var functions []*Function
+	for _, b := range g.Blocks {
+		ins := b.Instructions
+		n := len(ins)
+		if !b.Reachable || n < 4 || ins[n-1].OpCode != opcodes.JUMPI || !isPush(ins[n-2]) || ins[n-3].OpCode != opcodes.EQ {
+			continue
+		}
+
+		i := n - 4
+		if ins[i].OpCode >= opcodes.DUP1 && ins[i].OpCode <= opcodes.DUP16 && i > 0 {
+			i--
+		}
+
+		if !isPush(ins[i]) || ins[i].OpCode > opcodes.PUSH4 {
+			continue
+		}
+
+		dest := g.jumpDest(new(big.Int).SetBytes(ins[n-2].Data))
+		if dest == nil {
+			continue
+		}
+
+		f := &Function{Entry: dest.Start, Block: b.Start}
+		copy(f.Selector[4-len(ins[i].Data):], ins[i].Data)
+		functions = append(functions, f)
+	}
+
+	return functions
+}
@@ -0,0 +1,67 @@
+package cfg
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/SealSC/SealEVM/opcodes"
+)
+
+// WriteDOT writes the graph in the Graphviz DOT language, a node per block listing its
+// instructions. Unreachable blocks are grey, blocks with unresolved or invalid jumps are
+// red, dispatcher entries are labeled with their selector. Jumps are solid edges, taken
+// JUMPIs green, fallthroughs dashed.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	entries := map[uint64][]string{}
+	for _, f := range g.Functions {
+		entries[f.Entry] = append(entries[f.Entry], fmt.Sprintf("0x%x", f.Selector))
+	}
+
+	b := &strings.Builder{}
+	b.WriteString("digraph cfg {\n")
+	b.WriteString("  node [shape=box fontname=\"monospace\" fontsize=10];\n")
+
+	for _, block := range g.Blocks {
+		label := &strings.Builder{}
+		if selectors := entries[block.Start]; len(selectors) > 0 {
+			fmt.Fprintf(label, "function %s\\l", strings.Join(selectors, ", "))
+		}
+
+		for _, ins := range block.Instructions {
+			fmt.Fprintf(label, "%04x: %s\\l", ins.Offset, ins.String())
+		}
+
+		var attrs []string
+		if !block.Reachable {
+			attrs = append(attrs, "style=filled", "fillcolor=lightgrey")
+		}
+
+		if block.Unresolved || len(block.InvalidJumps) > 0 {
+			attrs = append(attrs, "color=red")
+		}
+
+		attrs = append(attrs, fmt.Sprintf("label=\"%s\"", label.String()))
+		fmt.Fprintf(b, "  b%x [%s];\n", block.Start, strings.Join(attrs, " "))
+	}
+
+	for _, e := range g.Edges {
+		var attrs []string
+		switch {
+		case e.Kind == Fallthrough:
+			attrs = append(attrs, "style=dashed")
+		case g.blockAt[e.From].Last().OpCode == opcodes.JUMPI:
+			attrs = append(attrs, "color=darkgreen")
+		}
+
+		fmt.Fprintf(b, "  b%x -> b%x", e.From, e.To)
+		if len(attrs) > 0 {
+			fmt.Fprintf(b, " [%s]", strings.Join(attrs, " "))
+		}
+		b.WriteString(";\n")
+	}
+
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,154 @@
+package cfg
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/SealSC/SealEVM/disasm"
+	"github.com/SealSC/SealEVM/opcodes"
+)
+
+const (
+	//maxContexts bounds the entry stacks analyzed per block, loops which keep growing the
+	//stack would never settle otherwise
+	maxContexts = 32
+
+	//maxTrackedStack is the number of stack items the analysis keeps, the deeper ones
+	//are unknown
+	maxTrackedStack = 64
+)
+
+// stackEffect is the number of items op pops and pushes.
+func stackEffect(op opcodes.OpCode) (pops int, pushes int) {
+	switch {
+	case op >= opcodes.PUSH0 && op <= opcodes.PUSH32:
+		return 0, 1
+	case op >= opcodes.DUP1 && op <= opcodes.DUP16:
+		n := int(op-opcodes.DUP1) + 1
+		return n, n + 1
+	case op >= opcodes.SWAP1 && op <= opcodes.SWAP16:
+		n := int(op-opcodes.SWAP1) + 2
+		return n, n
+	case op >= opcodes.LOG0 && op <= opcodes.LOG4:
+		return int(op-opcodes.LOG0) + 2, 0
+	}
+
+	switch op {
+	case opcodes.ADDMOD, opcodes.MULMOD:
+		return 3, 1
+	case opcodes.ISZERO, opcodes.NOT, opcodes.BALANCE, opcodes.CALLDATALOAD, opcodes.EXTCODESIZE,
+		opcodes.EXTCODEHASH, opcodes.BLOCKHASH, opcodes.BLOBHASH, opcodes.MLOAD, opcodes.SLOAD, opcodes.TLOAD:
+		return 1, 1
+	case opcodes.ADDRESS, opcodes.ORIGIN, opcodes.CALLER, opcodes.CALLVALUE, opcodes.CALLDATASIZE,
+		opcodes.CODESIZE, opcodes.GASPRICE, opcodes.RETURNDATASIZE, opcodes.COINBASE, opcodes.TIMESTAMP,
+		opcodes.NUMBER, opcodes.DIFFICULTY, opcodes.GASLIMIT, opcodes.CHAINID, opcodes.SELFBALANCE,
+		opcodes.BASEFEE, opcodes.BLOBBASEFEE, opcodes.PC, opcodes.MSIZE, opcodes.GAS:
+		return 0, 1
+	case opcodes.CALLDATACOPY, opcodes.CODECOPY, opcodes.RETURNDATACOPY, opcodes.MCOPY:
+		return 3, 0
+	case opcodes.EXTCODECOPY:
+		return 4, 0
+	case opcodes.POP, opcodes.JUMP, opcodes.SELFDESTRUCT:
+		return 1, 0
+	case opcodes.MSTORE, opcodes.MSTORE8, opcodes.SSTORE, opcodes.TSTORE, opcodes.JUMPI, opcodes.RETURN, opcodes.REVERT:
+		return 2, 0
+	case opcodes.CREATE:
+		return 3, 1
+	case opcodes.CREATE2:
+		return 4, 1
+	case opcodes.CALL, opcodes.CALLCODE:
+		return 7, 1
+	case opcodes.DELEGATECALL, opcodes.STATICCALL:
+		return 6, 1
+	case opcodes.STOP, opcodes.JUMPDEST:
+		return 0, 0
+	}
+
+	if op <= opcodes.SAR || op == opcodes.SHA3 {
+		return 2, 1
+	}
+
+	return 0, 0
+}
+
+// absStack is the abstract stack of the analysis, top last. An item is a constant or nil
+// for a value only known at run time, the items under the slice are unknown.
+type absStack []*big.Int
+
+func (s absStack) pop() (absStack, *big.Int) {
+	if len(s) == 0 {
+		return s, nil
+	}
+
+	return s[:len(s)-1], s[len(s)-1]
+}
+
+// peek is the n-th item from the top, 0 being the top.
+func (s absStack) peek(n int) *big.Int {
+	if n >= len(s) {
+		return nil
+	}
+
+	return s[len(s)-1-n]
+}
+
+func (s absStack) push(v *big.Int) absStack {
+	s = append(s, v)
+	if len(s) > maxTrackedStack {
+		s = s[len(s)-maxTrackedStack:]
+	}
+
+	return s
+}
+
+func (s absStack) key() string {
+	b := &strings.Builder{}
+	for _, v := range s {
+		if v == nil {
+			b.WriteString("?,")
+		} else {
+			b.WriteString(v.Text(16) + ",")
+		}
+	}
+
+	return b.String()
+}
+
+// step applies the instruction to a copy of s. Only the data moves (PUSH, DUP, SWAP, POP)
+// keep constants, every computed value is unknown.
+func step(s absStack, ins *disasm.Instruction) absStack {
+	s = append(absStack{}, s...)
+	op := ins.OpCode
+
+	switch {
+	case op == opcodes.PUSH0:
+		return s.push(new(big.Int))
+
+	case op >= opcodes.PUSH1 && op <= opcodes.PUSH32:
+		return s.push(new(big.Int).SetBytes(ins.Data))
+
+	case op >= opcodes.DUP1 && op <= opcodes.DUP16:
+		return s.push(s.peek(int(op - opcodes.DUP1)))
+
+	case op >= opcodes.SWAP1 && op <= opcodes.SWAP16:
+		n := int(op-opcodes.SWAP1) + 1
+		for len(s) <= n {
+			s = append(absStack{nil}, s...)
+		}
+
+		top := len(s) - 1
+		s[top], s[top-n] = s[top-n], s[top]
+		return s
+	}
+
+	pops, pushes := stackEffect(op)
+	for i := 0; i < pops; i++ {
+		s, _ = s.pop()
+	}
+
+	for i := 0; i < pushes; i++ {
+		s = s.push(nil)
+	}
+
+	return s
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/SealSC/SealEVM/bind"
+)
+
+// artifactBytecode is the creation bytecode of a hardhat, foundry or solc artifact, nil
+// for a plain ABI.
+func artifactBytecode(data []byte) ([]byte, error) {
+	var artifact struct {
+		Bytecode json.RawMessage `json:"bytecode"`
+		Bin      string          `json:"bin"`
+	}
+
+	if json.Unmarshal(data, &artifact) != nil {
+		return nil, nil
+	}
+
+	code := artifact.Bin
+	if len(artifact.Bytecode) > 0 {
+		var foundry struct {
+			Object string `json:"object"`
+		}
+
+		if json.Unmarshal(artifact.Bytecode, &code) != nil {
+			if err := json.Unmarshal(artifact.Bytecode, &foundry); err != nil {
+				return nil, fmt.Errorf("invalid bytecode: %w", err)
+			}
+			code = foundry.Object
+		}
+	}
+
+	if code == "" || code == "0x" {
+		return nil, nil
+	}
+
+	return parseHex(code)
+}
+
+func abigenCommand(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("abigen", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	abiFile := fs.String("abi", "", "JSON ABI file, or an artifact with abi and bytecode (also the first argument)")
+	binFile := fs.String("bin", "", "file of the creation bytecode in hex, for the Deploy function")
+	typeName := fs.String("type", "", "Go type of the binding, from the ABI file name by default")
+	pkg := fs.String("pkg", "", "Go package of the generated file")
+	out := fs.String("out", "", "output file, stdout by default")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 0 && *abiFile == "" {
+		*abiFile = fs.Arg(0)
+	}
+
+	if *abiFile == "" || *pkg == "" {
+		return errors.New("usage: sealevm abigen [flags] --pkg <package> --abi <file> | <file>")
+	}
+
+	abiJSON, err := os.ReadFile(*abiFile)
+	if err != nil {
+		return err
+	}
+
+	var bytecode []byte
+	if *binFile != "" {
+		bytecode, err = readHexArg("", *binFile, os.Stdin)
+	} else {
+		bytecode, err = artifactBytecode(abiJSON)
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid bytecode: %w", err)
+	}
+
+	if *typeName == "" {
+		base := filepath.Base(*abiFile)
+		*typeName = strings.Split(base, ".")[0]
+		if r := []rune(*typeName); len(r) > 0 {
+			*typeName = strings.ToUpper(string(r[0])) + string(r[1:])
+		}
+	}
+
+	src, err := bind.Generate(*pkg, []bind.Contract{{Type: *typeName, ABI: abiJSON, Bytecode: bytecode}})
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		_, err = stdout.Write(src)
+		return err
+	}
+
+	return os.WriteFile(*out, src, 0644)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/SealSC/SealEVM/asm"
+)
+
+func asmCommand(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("asm", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return errors.New("usage: sealevm asm <source file, - for stdin>")
+	}
+
+	var source []byte
+	var err error
+	if file := fs.Arg(0); file == "-" {
+		source, err = io.ReadAll(os.Stdin)
+	} else {
+		source, err = os.ReadFile(file)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	code, err := asm.Assemble(string(source))
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(stdout, "0x%x\n", code)
+	return err
+}
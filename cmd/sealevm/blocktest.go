@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/SealSC/SealEVM/ethTests"
+)
+
+type blockTestLine struct {
+	Name    string   `json:"name"`
+	Network string   `json:"network"`
+	Pass    bool     `json:"pass"`
+	Skipped bool     `json:"skipped,omitempty"`
+	Error   string   `json:"error,omitempty"`
+	Block   *int     `json:"block,omitempty"`
+	Tx      *int     `json:"tx,omitempty"`
+	Txs     []string `json:"txs,omitempty"`
+	Diff    string   `json:"diff,omitempty"`
+}
+
+func blockTestCommand(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("blocktest", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	run := fs.String("run", "", "run only the tests whose name matches the regular expression")
+	asJSON := fs.Bool("json", false, "print a JSON line per test instead of text")
+	verbose := fs.Bool("v", false, "print passed and skipped tests too")
+	allowFailures := fs.Bool("allow-failures", false, "exit with 0 when tests fail, for reporting pass rates")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		return errors.New("usage: sealevm blocktest [flags] <fixture file or directory>...")
+	}
+
+	var filter func(name string) bool
+	if *run != "" {
+		pattern, err := regexp.Compile(*run)
+		if err != nil {
+			return err
+		}
+
+		filter = pattern.MatchString
+	}
+
+	enc := json.NewEncoder(stdout)
+	var writeErr error
+	onResult := func(r *ethTests.BlockchainResult) {
+		if writeErr != nil {
+			return
+		}
+
+		if *asJSON {
+			line := &blockTestLine{
+				Name:    r.Name,
+				Network: r.Network,
+				Pass:    r.Pass,
+				Skipped: r.Skipped,
+				Error:   r.Error,
+				Txs:     r.Txs,
+				Diff:    r.Diff,
+			}
+
+			//-1 means unknown, left out of the line
+			if r.Block >= 0 {
+				block := r.Block
+				line.Block = &block
+			}
+
+			if r.Tx >= 0 {
+				tx := r.Tx
+				line.Tx = &tx
+			}
+
+			writeErr = enc.Encode(line)
+			return
+		}
+
+		if *verbose || (!r.Pass && !r.Skipped) {
+			_, writeErr = fmt.Fprintln(stdout, r.String())
+		}
+	}
+
+	report := ethTests.NewReport()
+	for _, path := range fs.Args() {
+		pathReport, err := ethTests.RunBlockchainTests(path, filter, onResult)
+		if err != nil {
+			return err
+		}
+
+		report.Merge(pathReport)
+	}
+
+	if writeErr != nil {
+		return writeErr
+	}
+
+	if err := report.WriteSummary(stderr); err != nil {
+		return err
+	}
+
+	if failed := report.Failed(); failed > 0 && !*allowFailures {
+		return fmt.Errorf("%d blockchain tests failed", failed)
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/SealSC/SealEVM/cfg"
+)
+
+func cfgCommand(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("cfg", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	code := fs.String("code", "", "EVM bytecode in hex")
+	codeFile := fs.String("codefile", "", "file of the EVM bytecode in hex, - for stdin (also the first argument)")
+	dot := fs.Bool("dot", false, "print the graph in the Graphviz DOT language")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 0 && *code == "" && *codeFile == "" {
+		*codeFile = fs.Arg(0)
+	}
+
+	if *code == "" && *codeFile == "" {
+		return errors.New("usage: sealevm cfg [flags] --code <hex> | <file>")
+	}
+
+	bytecode, err := readHexArg(*code, *codeFile, os.Stdin)
+	if err != nil {
+		return fmt.Errorf("invalid code: %w", err)
+	}
+
+	g := cfg.Build(bytecode)
+	if *dot {
+		return g.WriteDOT(stdout)
+	}
+
+	w := &errWriter{w: stdout}
+	w.printf("blocks: %d, edges: %d, all jumps resolved: %v\n", len(g.Blocks), len(g.Edges), g.Complete)
+	for _, f := range g.Functions {
+		w.printf("function 0x%x at 0x%04x\n", f.Selector, f.Entry)
+	}
+
+	for _, b := range g.Blocks {
+		if b.Unresolved {
+			w.printf("unresolved jump at 0x%04x\n", b.Last().Offset)
+		}
+
+		for _, target := range b.InvalidJumps {
+			w.printf("invalid jump at 0x%04x to 0x%x\n", b.Last().Offset, target)
+		}
+	}
+
+	for _, b := range g.Unreachable() {
+		w.printf("unreachable 0x%04x-0x%04x\n", b.Start, b.End)
+	}
+
+	return w.err
+}
+
+// errWriter keeps the first error of a series of writes.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (e *errWriter) printf(format string, args ...interface{}) {
+	if e.err == nil {
+		_, e.err = fmt.Fprintf(e.w, format, args...)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/SealSC/SealEVM/disasm"
+)
+
+func disasmCommand(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("disasm", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	code := fs.String("code", "", "EVM bytecode in hex")
+	codeFile := fs.String("codefile", "", "file of the EVM bytecode in hex, - for stdin (also the first argument)")
+	asJSON := fs.Bool("json", false, "print the listing as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 0 && *code == "" && *codeFile == "" {
+		*codeFile = fs.Arg(0)
+	}
+
+	if *code == "" && *codeFile == "" {
+		return errors.New("usage: sealevm disasm [flags] --code <hex> | <file>")
+	}
+
+	bytecode, err := readHexArg(*code, *codeFile, os.Stdin)
+	if err != nil {
+		return fmt.Errorf("invalid code: %w", err)
+	}
+
+	listing := disasm.Disassemble(bytecode)
+	if *asJSON {
+		return listing.WriteJSON(stdout)
+	}
+
+	return listing.WriteText(stdout)
+}
@@ -0,0 +1,85 @@
+// Command sealevm runs EVM bytecode with SealEVM, for quick experiments and bug reports.
+//
+//	sealevm run --code 0x6001600055 --trace eip3155
+//	sealevm run --prestate alloc.json --receiver 0x... --input 0xa9059cbb...
+//	sealevm asm trampoline.asm
+//	sealevm disasm --code 0x6080604052...
+//	sealevm cfg --dot --code 0x6080604052... | dot -Tsvg > cfg.svg
+//	sealevm selectors --sigs signatures.txt --code 0x6080604052...
+//	sealevm abigen --pkg token --out token.go artifacts/Token.json
+//	sealevm node --alloc genesis.json --chain-id 1337 --addr 127.0.0.1:8545
+//	sealevm statetest --fork Cancun ./GeneralStateTests
+//	sealevm blocktest --run 'withdrawals' ./BlockchainTests
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+type command struct {
+	usage string
+	run   func(args []string, stdout io.Writer, stderr io.Writer) error
+}
+
+var commands = map[string]*command{
+	"abigen":    {usage: "generate typed Go bindings running contracts on SealEVM", run: abigenCommand},
+	"blocktest": {usage: "run BlockchainTests fixtures and report the first diverging block", run: blockTestCommand},
+	"asm":       {usage: "assemble mnemonics with labels and macros into bytecode", run: asmCommand},
+	"cfg":       {usage: "print the control-flow graph of bytecode, or its Graphviz DOT", run: cfgCommand},
+	"disasm":    {usage: "disassemble bytecode, solc metadata included", run: disasmCommand},
+	"node":      {usage: "serve the Ethereum JSON-RPC API of a development chain", run: nodeCommand},
+	"run":       {usage: "run bytecode or a deployed contract", run: runCommand},
+	"selectors": {usage: "list the function selectors dispatched by runtime bytecode", run: selectorsCommand},
+	"statetest": {usage: "run GeneralStateTests fixtures and report per-fork pass rates", run: stateTestCommand},
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: sealevm <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].usage)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "run \"sealevm <command> --help\" for the flags of a command")
+}
+
+func main() {
+	args := os.Args[1:]
+
+	//flags without a command mean run, as in "sealevm --code 0x00"
+	name := "run"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name = args[0]
+		args = args[1:]
+	}
+
+	cmd := commands[name]
+	if cmd == nil {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+
+	err := cmd.run(args, os.Stdout, os.Stderr)
+	if err == flag.ErrHelp {
+		os.Exit(0)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/rpc"
+	"github.com/SealSC/SealEVM/sim"
+	"github.com/SealSC/SealEVM/types"
+)
+
+func nodeCommand(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("node", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	addr := fs.String("addr", "127.0.0.1:8545", "address to serve JSON-RPC on, over HTTP and WebSocket")
+	allocFile := fs.String("alloc", "", "JSON file of the genesis accounts")
+	chainID := fs.Uint64("chain-id", rpc.DefaultChainID, "chain id")
+	gasLimit := fs.Uint64("gas-limit", rpc.DefaultGasLimit, "gas limit of the blocks")
+	baseFee := fs.Uint64("base-fee", 0, "base fee of the blocks, in wei")
+	coinbase := fs.String("coinbase", "", "address receiving the priority fees")
+	origins := fs.String("allow-origins", strings.Join(rpc.DefaultOrigins, ","), "comma separated origins or hosts allowed for CORS and WebSocket, * for any")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	alloc := sim.Alloc{}
+	if *allocFile != "" {
+		var err error
+		if alloc, err = sim.LoadAlloc(*allocFile); err != nil {
+			return err
+		}
+	}
+
+	config := rpc.Config{
+		ChainID:  *chainID,
+		GasLimit: *gasLimit,
+		BaseFee:  evmInt256.New(*baseFee),
+	}
+
+	if *coinbase != "" {
+		var a types.Address
+		if err := a.UnmarshalText([]byte(*coinbase)); err != nil {
+			return fmt.Errorf("invalid coinbase: %w", err)
+		}
+		config.Coinbase = a
+	}
+
+	node, err := rpc.NewNode(config, rpc.NewArchiveBackend(alloc))
+	if err != nil {
+		return err
+	}
+
+	server := rpc.NewServer()
+	server.AllowOrigins(strings.Split(*origins, ",")...)
+	node.Register(server)
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stderr, "chain %d with %d accounts, serving on http://%s and ws://%s\n", *chainID, len(alloc), listener.Addr(), listener.Addr())
+	return http.Serve(listener, server)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/types"
+)
+
+func parseHex(s string) ([]byte, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(s), "0x"), "0X")
+	if len(s)%2 != 0 {
+		s = "0" + s
+	}
+
+	return hex.DecodeString(s)
+}
+
+func parseAddress(s string) (types.Address, error) {
+	var addr types.Address
+	b, err := parseHex(s)
+	if err != nil || len(b) != types.AddressBytesLen {
+		return addr, fmt.Errorf("invalid address %q", s)
+	}
+
+	addr.SetBytes(b)
+	return addr, nil
+}
+
+// parseUint64 accepts decimal or 0x prefixed hex numbers.
+func parseUint64(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		return strconv.ParseUint(s[2:], 16, 64)
+	}
+
+	return strconv.ParseUint(s, 10, 64)
+}
+
+// parseInt256 accepts decimal or 0x prefixed hex numbers.
+func parseInt256(s string) (*evmInt256.Int, error) {
+	i := evmInt256.New(0)
+	err := i.UnmarshalJSON([]byte(strconv.Quote(strings.TrimSpace(s))))
+	if err != nil {
+		return nil, err
+	}
+
+	if i.Sign() < 0 || i.BitLen() > 256 {
+		return nil, fmt.Errorf("%s is not a 256 bits unsigned integer", s)
+	}
+
+	return i, nil
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/SealSC/SealEVM"
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/instructions"
+	"github.com/SealSC/SealEVM/sim"
+	"github.com/SealSC/SealEVM/statedb/memory"
+	"github.com/SealSC/SealEVM/tracer"
+	"github.com/SealSC/SealEVM/types"
+)
+
+// supportedForks lists the rule sets SealEVM implements, it follows a single fork.
+var supportedForks = []string{"Cancun"}
+
+func checkFork(name string) (string, error) {
+	for _, fork := range supportedForks {
+		if strings.EqualFold(fork, name) {
+			return fork, nil
+		}
+	}
+
+	return "", fmt.Errorf("unsupported fork %q, supported: %s", name, strings.Join(supportedForks, ", "))
+}
+
+const (
+	traceStructLog = "struct-log"
+	traceEIP3155   = "eip3155"
+)
+
+type runFlags struct {
+	code      string
+	codeFile  string
+	input     string
+	inputFile string
+	create    bool
+
+	gas      uint64
+	price    string
+	value    string
+	sender   string
+	receiver string
+	prestate string
+	fork     string
+
+	chainID   string
+	number    uint64
+	timestamp uint64
+	coinbase  string
+	baseFee   string
+
+	trace       string
+	traceConfig tracer.Config
+	json        bool
+}
+
+func (f *runFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.code, "code", "", "EVM bytecode in hex")
+	fs.StringVar(&f.codeFile, "codefile", "", "file of the EVM bytecode in hex, - for stdin (also the first argument)")
+	fs.StringVar(&f.input, "input", "", "call data in hex")
+	fs.StringVar(&f.inputFile, "inputfile", "", "file of the call data in hex, - for stdin")
+	fs.BoolVar(&f.create, "create", false, "run the code as init code of a contract creation, the input is appended")
+
+	fs.Uint64Var(&f.gas, "gas", 10000000, "gas limit of the execution")
+	fs.StringVar(&f.price, "price", "0", "gas price")
+	fs.StringVar(&f.value, "value", "0", "value sent with the call, the sender must hold it in the prestate")
+	fs.StringVar(&f.sender, "sender", "0x000000000000000000000000000073656e646572", "address of the caller")
+	fs.StringVar(&f.receiver, "receiver", "0x0000000000000000000000007265636569766572", "address of the called contract")
+	fs.StringVar(&f.prestate, "prestate", "", "alloc.json with the accounts before the execution")
+	fs.StringVar(&f.fork, "fork", supportedForks[len(supportedForks)-1], "fork rules, supported: "+strings.Join(supportedForks, ", "))
+
+	fs.StringVar(&f.chainID, "chainid", "1", "chain id")
+	fs.Uint64Var(&f.number, "number", 0, "block number")
+	fs.Uint64Var(&f.timestamp, "timestamp", 0, "block timestamp")
+	fs.StringVar(&f.coinbase, "coinbase", "0x0000000000000000000000000000000000000000", "block coinbase")
+	fs.StringVar(&f.baseFee, "basefee", "0", "block base fee")
+
+	fs.StringVar(&f.trace, "trace", "", "print a trace to stderr: "+traceStructLog+" or "+traceEIP3155)
+	fs.BoolVar(&f.traceConfig.EnableMemory, "trace.memory", false, "include memory in the trace")
+	fs.BoolVar(&f.traceConfig.DisableStack, "trace.nostack", false, "leave the stack out of the trace")
+	fs.BoolVar(&f.traceConfig.DisableStorage, "trace.nostorage", false, "leave storage out of the struct-log trace")
+	fs.BoolVar(&f.traceConfig.EnableReturnData, "trace.returndata", false, "include return data in the trace")
+	fs.IntVar(&f.traceConfig.Limit, "trace.limit", 0, "maximum number of traced steps, 0 means no limit")
+	fs.BoolVar(&f.json, "json", false, "print the result as JSON")
+}
+
+func readHexArg(value string, file string, stdin io.Reader) ([]byte, error) {
+	if file != "" {
+		var data []byte
+		var err error
+		if file == "-" {
+			data, err = io.ReadAll(stdin)
+		} else {
+			data, err = os.ReadFile(file)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		value = string(data)
+	}
+
+	return parseHex(strings.TrimSpace(value))
+}
+
+type runResult struct {
+	ReturnData      types.Bytes    `json:"returnData"`
+	GasUsed         uint64         `json:"gasUsed"`
+	Error           string         `json:"error,omitempty"`
+	ContractAddress *types.Address `json:"contractAddress,omitempty"`
+	Logs            []*logJSON     `json:"logs"`
+	PostState       sim.Alloc      `json:"postState"`
+}
+
+type logJSON struct {
+	Address types.Address `json:"address"`
+	Topics  []types.Topic `json:"topics"`
+	Data    types.Bytes   `json:"data"`
+}
+
+func runCommand(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	f := &runFlags{}
+	f.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 0 && f.codeFile == "" && f.code == "" {
+		f.codeFile = fs.Arg(0)
+	}
+
+	if _, err := checkFork(f.fork); err != nil {
+		return err
+	}
+
+	//stdin can only be read once
+	if f.codeFile == "-" && f.inputFile == "-" {
+		return errors.New("--codefile and --inputfile can not both be read from stdin")
+	}
+
+	code, err := readHexArg(f.code, f.codeFile, os.Stdin)
+	if err != nil {
+		return fmt.Errorf("invalid code: %w", err)
+	}
+
+	input, err := readHexArg(f.input, f.inputFile, os.Stdin)
+	if err != nil {
+		return fmt.Errorf("invalid input: %w", err)
+	}
+
+	alloc := sim.Alloc{}
+	if f.prestate != "" {
+		alloc, err = sim.LoadAlloc(f.prestate)
+		if err != nil {
+			return err
+		}
+	}
+
+	state := sim.FakeHashState{DB: sim.NewState(alloc)}
+
+	ctx, err := f.context(state.DB, code, input)
+	if err != nil {
+		return err
+	}
+
+	var hook instructions.IStepHook
+	var structLogger *tracer.StructLogger
+	var jsonLogger *tracer.JSONLogger
+	switch f.trace {
+	case "":
+	case traceStructLog:
+		structLogger = tracer.NewStructLogger(&f.traceConfig)
+		hook = structLogger
+	case traceEIP3155:
+		jsonLogger = tracer.NewJSONLogger(&f.traceConfig, stderr)
+		hook = jsonLogger
+	default:
+		return fmt.Errorf("unknown trace format %q, use %s or %s", f.trace, traceStructLog, traceEIP3155)
+	}
+
+	SealEVM.Load()
+	evm := SealEVM.New(SealEVM.EVMParam{
+		ExternalStore: state,
+		Context:       ctx,
+		StepHook:      hook,
+	})
+
+	result, execErr := evm.Execute()
+
+	gasUsed := f.gas - result.GasLeft
+	if execErr == nil {
+		state.Commit(&result.StorageCache)
+	}
+
+	if structLogger != nil {
+		enc := json.NewEncoder(stderr)
+		enc.SetIndent("", "  ")
+		if err = enc.Encode(structLogger.Result(gasUsed, result.ResultData, execErr)); err != nil {
+			return err
+		}
+	}
+
+	if jsonLogger != nil {
+		if err = jsonLogger.WriteSummary(result.ResultData, gasUsed, execErr); err != nil {
+			return err
+		}
+	}
+
+	out := &runResult{
+		ReturnData:      result.ResultData,
+		GasUsed:         gasUsed,
+		ContractAddress: result.ContractAddress,
+		Logs:            []*logJSON{},
+		PostState:       sim.Dump(state.DB),
+	}
+
+	if execErr != nil {
+		out.Error = execErr.Error()
+	} else if result.StorageCache.Logs != nil {
+		for _, l := range *result.StorageCache.Logs {
+			out.Logs = append(out.Logs, &logJSON{Address: l.Address, Topics: l.Topics, Data: l.Data})
+		}
+	}
+
+	if f.json {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
+
+	return printResult(stdout, out)
+}
+
+func (f *runFlags) context(state *memory.DB, code []byte, input []byte) (*environment.Context, error) {
+	sender, err := parseAddress(f.sender)
+	if err != nil {
+		return nil, err
+	}
+
+	coinbase, err := parseAddress(f.coinbase)
+	if err != nil {
+		return nil, err
+	}
+
+	var numbers [4]*evmInt256.Int
+	for i, s := range []string{f.value, f.price, f.chainID, f.baseFee} {
+		if numbers[i], err = parseInt256(s); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx := &environment.Context{
+		Block: environment.Block{
+			ChainID:     numbers[2],
+			Coinbase:    coinbase,
+			Timestamp:   f.timestamp,
+			Number:      f.number,
+			Difficulty:  evmInt256.New(0),
+			GasLimit:    evmInt256.New(f.gas),
+			BaseFee:     numbers[3],
+			BlobBaseFee: evmInt256.New(1),
+		},
+		Transaction: environment.Transaction{
+			Origin:   sender,
+			GasPrice: numbers[1],
+			GasLimit: evmInt256.New(f.gas),
+		},
+		Message: environment.Message{
+			Caller: sender,
+			Value:  numbers[0],
+			Data:   input,
+		},
+	}
+
+	if f.create {
+		ctx.Message.Data = append(code, input...)
+		return ctx, nil
+	}
+
+	receiver, err := parseAddress(f.receiver)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(code) > 0 {
+		state.SetCode(receiver, code)
+	} else if !state.AccountExist(receiver) {
+		return nil, errors.New("no code given and the receiver is not in the prestate")
+	}
+
+	ctx.Transaction.To = &receiver
+	return ctx, nil
+}
+
+func printResult(w io.Writer, out *runResult) error {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "return data: 0x%x\n", []byte(out.ReturnData))
+	fmt.Fprintf(buf, "gas used:    %d\n", out.GasUsed)
+	if out.Error != "" {
+		fmt.Fprintf(buf, "error:       %s\n", out.Error)
+	}
+
+	if out.ContractAddress != nil {
+		fmt.Fprintf(buf, "contract:    %s\n", out.ContractAddress)
+	}
+
+	fmt.Fprintf(buf, "logs:        %d\n", len(out.Logs))
+	for i, l := range out.Logs {
+		fmt.Fprintf(buf, "  [%d] address: %s\n", i, l.Address)
+		for j, topic := range l.Topics {
+			fmt.Fprintf(buf, "      topic%d:  %s\n", j, topic)
+		}
+		fmt.Fprintf(buf, "      data:    0x%x\n", []byte(l.Data))
+	}
+
+	postState, err := json.MarshalIndent(out.PostState, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(buf, "post-state:\n%s\n", postState)
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/SealSC/SealEVM/selectors"
+)
+
+func selectorsCommand(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("selectors", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	code := fs.String("code", "", "runtime bytecode in hex")
+	codeFile := fs.String("codefile", "", "file of the runtime bytecode in hex, - for stdin (also the first argument)")
+	sigs := fs.String("sigs", "", "signature database file to name the selectors")
+	asJSON := fs.Bool("json", false, "print the result as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 0 && *code == "" && *codeFile == "" {
+		*codeFile = fs.Arg(0)
+	}
+
+	if *code == "" && *codeFile == "" {
+		return errors.New("usage: sealevm selectors [flags] --code <hex> | <file>")
+	}
+
+	bytecode, err := readHexArg(*code, *codeFile, os.Stdin)
+	if err != nil {
+		return fmt.Errorf("invalid code: %w", err)
+	}
+
+	db := selectors.SignatureDB{}
+	if *sigs != "" {
+		if db, err = selectors.LoadSignatureDB(*sigs); err != nil {
+			return err
+		}
+	}
+
+	contract := selectors.Extract(bytecode)
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(contract)
+	}
+
+	w := &errWriter{w: stdout}
+	for _, f := range contract.Functions {
+		w.printf("%s at 0x%04x payable: %s, view: %s", f.Selector, f.Entry, f.Payable, f.View)
+		if names := db.Lookup(f.Selector); len(names) > 0 {
+			w.printf("  %s", strings.Join(names, " | "))
+		}
+		w.printf("\n")
+	}
+
+	if p := contract.Proxy; p != nil {
+		w.printf("proxy fallback: %s, delegatecall at 0x%04x", p.Kind, p.DelegateCall)
+		if p.Implementation != nil {
+			w.printf(", implementation %s", p.Implementation.String())
+		}
+
+		if p.Slot != nil {
+			w.printf(", slot %s", p.Slot.String())
+		}
+		w.printf("\n")
+	}
+
+	if !contract.Complete {
+		w.printf("some jumps could not be resolved, the hints may be incomplete\n")
+	}
+
+	return w.err
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/SealSC/SealEVM/ethTests"
+	"github.com/SealSC/SealEVM/types"
+)
+
+type stateTestLine struct {
+	Name           string      `json:"name"`
+	Fork           string      `json:"fork"`
+	Index          int         `json:"index"`
+	Pass           bool        `json:"pass"`
+	Skipped        bool        `json:"skipped,omitempty"`
+	Error          string      `json:"error,omitempty"`
+	ExecutionError string      `json:"executionError,omitempty"`
+	Root           *types.Hash `json:"stateRoot,omitempty"`
+	Diff           string      `json:"diff,omitempty"`
+}
+
+func stateTestCommand(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("statetest", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	fork := fs.String("fork", "", "run only the cases of this fork")
+	run := fs.String("run", "", "run only the cases whose \"name/fork/index\" matches the regular expression")
+	asJSON := fs.Bool("json", false, "print a JSON line per case instead of text")
+	verbose := fs.Bool("v", false, "print passed and skipped cases too")
+	allowFailures := fs.Bool("allow-failures", false, "exit with 0 when cases fail, for reporting pass rates")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		return errors.New("usage: sealevm statetest [flags] <fixture file or directory>...")
+	}
+
+	var pattern *regexp.Regexp
+	if *run != "" {
+		var err error
+		if pattern, err = regexp.Compile(*run); err != nil {
+			return err
+		}
+	}
+
+	forkPattern := regexp.MustCompile("/" + regexp.QuoteMeta(*fork) + "/[0-9]+$")
+	filter := func(name string) bool {
+		if pattern != nil && !pattern.MatchString(name) {
+			return false
+		}
+
+		return *fork == "" || forkPattern.MatchString(name)
+	}
+
+	enc := json.NewEncoder(stdout)
+	var writeErr error
+	onResult := func(r *ethTests.Result) {
+		if writeErr != nil {
+			return
+		}
+
+		if *asJSON {
+			line := &stateTestLine{
+				Name:           r.Name,
+				Fork:           r.Subtest.Fork,
+				Index:          r.Subtest.Index,
+				Pass:           r.Pass,
+				Skipped:        r.Skipped,
+				Error:          r.Error,
+				ExecutionError: r.ExecutionError,
+				Diff:           r.Diff,
+			}
+
+			if !r.Skipped {
+				line.Root = &r.Root
+			}
+
+			writeErr = enc.Encode(line)
+			return
+		}
+
+		if *verbose || (!r.Pass && !r.Skipped) {
+			_, writeErr = fmt.Fprintln(stdout, r.String())
+		}
+	}
+
+	report := ethTests.NewReport()
+	for _, path := range fs.Args() {
+		pathReport, err := ethTests.RunStateTests(path, filter, onResult)
+		if err != nil {
+			return err
+		}
+
+		report.Merge(pathReport)
+	}
+
+	if writeErr != nil {
+		return writeErr
+	}
+
+	//the summary goes to stderr so JSON lines on stdout stay machine readable
+	if err := report.WriteSummary(stderr); err != nil {
+		return err
+	}
+
+	if failed := report.Failed(); failed > 0 && !*allowFailures {
+		return fmt.Errorf("%d state test cases failed", failed)
+	}
+
+	return nil
+}
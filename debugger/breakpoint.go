@@ -0,0 +1,73 @@
+package debugger
+
+import (
+	"github.com/SealSC/SealEVM/instructions"
+	"github.com/SealSC/SealEVM/opcodes"
+	"github.com/SealSC/SealEVM/types"
+)
+
+type BreakpointType byte
+
+const (
+	PCBreakpoint BreakpointType = iota
+	OpCodeBreakpoint
+	AddressBreakpoint
+	StorageWriteBreakpoint
+	DepthBreakpoint
+)
+
+var breakpointTypeNames = map[BreakpointType]string{
+	PCBreakpoint:           "PC",
+	OpCodeBreakpoint:       "OpCode",
+	AddressBreakpoint:      "Address",
+	StorageWriteBreakpoint: "StorageWrite",
+	DepthBreakpoint:        "Depth",
+}
+
+func (b BreakpointType) String() string {
+	return breakpointTypeNames[b]
+}
+
+// Breakpoint describes where the execution should pause.
+//
+// PCBreakpoint and OpCodeBreakpoint hit on every matching instruction, AddressBreakpoint and
+// DepthBreakpoint hit when a frame of the matching address or depth is entered,
+// StorageWriteBreakpoint hits on SSTORE to Slot (any slot if Slot is nil).
+// A non-nil Address restricts every type of breakpoint to the code running at that address.
+type Breakpoint struct {
+	Type    BreakpointType
+	PC      uint64
+	OpCode  opcodes.OpCode
+	Depth   uint64
+	Address *types.Address
+	Slot    *types.Slot
+}
+
+func (b *Breakpoint) match(step *instructions.ExecutionStep, enterFrame bool) bool {
+	if b.Address != nil && *b.Address != step.Context.Address() {
+		return false
+	}
+
+	switch b.Type {
+	case PCBreakpoint:
+		return step.PC == b.PC
+	case OpCodeBreakpoint:
+		return step.OpCode == b.OpCode
+	case AddressBreakpoint:
+		return enterFrame
+	case DepthBreakpoint:
+		return enterFrame && step.Depth == b.Depth
+	case StorageWriteBreakpoint:
+		if step.OpCode != opcodes.SSTORE {
+			return false
+		}
+
+		if b.Slot == nil {
+			return true
+		}
+
+		return types.Int256ToSlot(step.Stack.Peek()) == *b.Slot
+	}
+
+	return false
+}
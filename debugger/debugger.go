@@ -0,0 +1,205 @@
+package debugger
+
+import (
+	"sync"
+
+	"github.com/SealSC/SealEVM"
+	"github.com/SealSC/SealEVM/evmErrors"
+	"github.com/SealSC/SealEVM/instructions"
+)
+
+type Command byte
+
+const (
+	Continue Command = iota
+	Step
+	StepOver
+	StepOut
+	Abort
+)
+
+// PauseHandler is called on the executing goroutine every time the execution pauses,
+// the returned command decides how the execution goes on.
+type PauseHandler func(state *State) Command
+
+type Result struct {
+	Result SealEVM.ExecuteResult
+	Err    error
+}
+
+// Debugger pauses an execution on its breakpoints and steps, it is driven by a PauseHandler
+// with Run, or through channels with Start.
+//
+// After Start, each State received from Paused must be answered by exactly one Send, the
+// execution waits for it. Paused must be read until it is closed, Done delivers the result
+// only after that: a caller which stops reading Paused blocks the execution for ever. Send
+// Abort to end the execution early, no pause follows it. A Debugger runs once.
+type Debugger struct {
+	param SealEVM.EVMParam
+
+	lock        sync.Mutex
+	breakpoints map[int]*Breakpoint
+	nextID      int
+
+	stopOnEntry bool
+	started     bool
+	lastDepth   uint64
+	mode        Command
+	modeDepth   uint64
+	aborted     bool
+	handler     PauseHandler
+
+	paused chan *State
+	resume chan Command
+	done   chan Result
+}
+
+// New creates a debugger for the execution described by param, the debugger installs itself
+// as the step hook of the EVM, any StepHook already set in param is replaced.
+func New(param SealEVM.EVMParam) *Debugger {
+	d := &Debugger{
+		param:       param,
+		breakpoints: map[int]*Breakpoint{},
+		mode:        Continue,
+	}
+
+	d.param.StepHook = d
+	return d
+}
+
+func (d *Debugger) StopOnEntry() {
+	d.stopOnEntry = true
+}
+
+func (d *Debugger) AddBreakpoint(bp Breakpoint) int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.nextID += 1
+	d.breakpoints[d.nextID] = &bp
+	return d.nextID
+}
+
+func (d *Debugger) RemoveBreakpoint(id int) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	delete(d.breakpoints, id)
+}
+
+func (d *Debugger) Breakpoints() map[int]Breakpoint {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	ret := map[int]Breakpoint{}
+	for id, bp := range d.breakpoints {
+		ret[id] = *bp
+	}
+
+	return ret
+}
+
+// Run executes the EVM on the current goroutine and calls handler on every pause.
+func (d *Debugger) Run(handler PauseHandler) (SealEVM.ExecuteResult, error) {
+	d.handler = handler
+	evm := SealEVM.New(d.param)
+	return evm.Execute()
+}
+
+// Start executes the EVM on a new goroutine. Pauses are delivered through Paused, the
+// execution stays paused until a command is sent by Send, the final result is delivered
+// through Done once Paused is closed.
+func (d *Debugger) Start() {
+	d.paused = make(chan *State)
+	d.resume = make(chan Command)
+	d.done = make(chan Result, 1)
+
+	go func() {
+		ret, err := d.Run(func(state *State) Command {
+			d.paused <- state
+			return <-d.resume
+		})
+
+		close(d.paused)
+		d.done <- Result{Result: ret, Err: err}
+	}()
+}
+
+// Paused delivers the pauses of the execution started by Start, it is closed when the
+// execution ends.
+func (d *Debugger) Paused() <-chan *State {
+	return d.paused
+}
+
+// Send answers the last pause received from Paused, calling it while the execution is not
+// paused blocks.
+func (d *Debugger) Send(cmd Command) {
+	d.resume <- cmd
+}
+
+func (d *Debugger) Done() <-chan Result {
+	return d.done
+}
+
+func (d *Debugger) hitBreakpoint(step *instructions.ExecutionStep, enterFrame bool) int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for id, bp := range d.breakpoints {
+		if bp.match(step, enterFrame) {
+			return id
+		}
+	}
+
+	return 0
+}
+
+func (d *Debugger) stopReason(step *instructions.ExecutionStep) (StopReason, int, bool) {
+	enterFrame := !d.started || step.Depth > d.lastDepth
+	firstStep := !d.started
+
+	d.started = true
+	d.lastDepth = step.Depth
+
+	if bpID := d.hitBreakpoint(step, enterFrame); bpID != 0 {
+		return StopOnBreakpoint, bpID, true
+	}
+
+	if firstStep && d.stopOnEntry {
+		return StopOnEntry, 0, true
+	}
+
+	switch d.mode {
+	case Step:
+		return StopOnStep, 0, true
+	case StepOver:
+		return StopOnStep, 0, step.Depth <= d.modeDepth
+	case StepOut:
+		return StopOnStep, 0, step.Depth < d.modeDepth
+	}
+
+	return StopOnStep, 0, false
+}
+
+func (d *Debugger) BeforeStep(step *instructions.ExecutionStep) error {
+	if d.aborted {
+		return evmErrors.ExecutionAborted
+	}
+
+	reason, bpID, pause := d.stopReason(step)
+	if !pause || d.handler == nil {
+		return nil
+	}
+
+	d.mode = d.handler(newState(step, reason, bpID))
+	d.modeDepth = step.Depth
+
+	if d.mode == Abort {
+		d.aborted = true
+		return evmErrors.ExecutionAborted
+	}
+
+	return nil
+}
+
+func (d *Debugger) AfterStep(_ *instructions.ExecutionStep, _ error) {}
@@ -0,0 +1,271 @@
+package debugger_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/SealSC/SealEVM"
+	"github.com/SealSC/SealEVM/debugger"
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmErrors"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/opcodes"
+	"github.com/SealSC/SealEVM/statedb/memory"
+	"github.com/SealSC/SealEVM/types"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	caller   = types.Address{0xca}
+	contract = types.Address{19: 0xc0}
+	child    = types.Address{19: 0xc1}
+)
+
+// the contract sets slot 0 to 1, calls the child at pc 32 then sets slot 3 to 2 at pc 38,
+// the child sets its slot 5 to 7 at pc 4
+var (
+	contractCode = "6001600055" + "5f5f5f5f5f73" + common.Bytes2Hex(child[:]) + "5af150" + "6002600355" + "00"
+	childCode    = "6007600555" + "00"
+)
+
+func init() {
+	SealEVM.Load()
+}
+
+func newDebugger() *debugger.Debugger {
+	db := memory.NewDB()
+	db.SetCode(contract, common.FromHex(contractCode))
+	db.SetCode(child, common.FromHex(childCode))
+
+	const gasLimit = 1000000
+	return debugger.New(SealEVM.EVMParam{
+		MaxStackDepth: 1024,
+		ExternalStore: db,
+		Context: &environment.Context{
+			Block: environment.Block{
+				ChainID:     evmInt256.New(1),
+				Difficulty:  evmInt256.New(0),
+				GasLimit:    evmInt256.New(gasLimit),
+				BaseFee:     evmInt256.New(0),
+				BlobBaseFee: evmInt256.New(1),
+			},
+			Transaction: environment.Transaction{
+				Origin:   caller,
+				To:       &contract,
+				GasPrice: evmInt256.New(0),
+				GasLimit: evmInt256.New(gasLimit),
+			},
+			Message: environment.Message{Caller: caller, Value: evmInt256.New(0)},
+		},
+	})
+}
+
+// stop is where the execution paused.
+type stop struct {
+	pc    uint64
+	depth uint64
+}
+
+// run runs d to its end, next answers each pause, and returns the pauses.
+func run(t *testing.T, d *debugger.Debugger, next func(state *debugger.State) debugger.Command) []stop {
+	t.Helper()
+
+	var stops []stop
+	_, err := d.Run(func(state *debugger.State) debugger.Command {
+		stops = append(stops, stop{state.PC, state.Depth})
+		return next(state)
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return stops
+}
+
+func continueAll(*debugger.State) debugger.Command {
+	return debugger.Continue
+}
+
+func TestBreakpoints(t *testing.T) {
+	slot3 := types.Slot{31: 3}
+	for _, c := range []struct {
+		name string
+		bp   debugger.Breakpoint
+		want []stop
+	}{
+		{"pc", debugger.Breakpoint{Type: debugger.PCBreakpoint, PC: 4}, []stop{{4, 0}, {4, 1}}},
+		{"pc of the child", debugger.Breakpoint{Type: debugger.PCBreakpoint, PC: 4, Address: &child}, []stop{{4, 1}}},
+		{"opcode", debugger.Breakpoint{Type: debugger.OpCodeBreakpoint, OpCode: opcodes.SSTORE}, []stop{{4, 0}, {4, 1}, {38, 0}}},
+		{"address", debugger.Breakpoint{Type: debugger.AddressBreakpoint, Address: &child}, []stop{{0, 1}}},
+		{"storage write", debugger.Breakpoint{Type: debugger.StorageWriteBreakpoint, Slot: &slot3}, []stop{{38, 0}}},
+		{"any storage write", debugger.Breakpoint{Type: debugger.StorageWriteBreakpoint}, []stop{{4, 0}, {4, 1}, {38, 0}}},
+		{"depth", debugger.Breakpoint{Type: debugger.DepthBreakpoint, Depth: 1}, []stop{{0, 1}}},
+	} {
+		d := newDebugger()
+		id := d.AddBreakpoint(c.bp)
+
+		var ids []int
+		stops := run(t, d, func(state *debugger.State) debugger.Command {
+			if state.Reason != debugger.StopOnBreakpoint {
+				t.Errorf("%s: paused for %s", c.name, state.Reason)
+			}
+
+			ids = append(ids, state.BreakpointID)
+			return debugger.Continue
+		})
+
+		if !reflect.DeepEqual(stops, c.want) {
+			t.Errorf("%s: paused at %v, want %v", c.name, stops, c.want)
+		}
+
+		for _, got := range ids {
+			if got != id {
+				t.Errorf("%s: hit breakpoint %d, want %d", c.name, got, id)
+			}
+		}
+	}
+}
+
+func TestRemoveBreakpoint(t *testing.T) {
+	d := newDebugger()
+	id := d.AddBreakpoint(debugger.Breakpoint{Type: debugger.OpCodeBreakpoint, OpCode: opcodes.SSTORE})
+	d.RemoveBreakpoint(id)
+
+	if stops := run(t, d, continueAll); len(stops) != 0 || len(d.Breakpoints()) != 0 {
+		t.Fatalf("paused at %v after the breakpoint was removed", stops)
+	}
+}
+
+func TestStep(t *testing.T) {
+	d := newDebugger()
+	d.StopOnEntry()
+
+	stops := run(t, d, func(*debugger.State) debugger.Command { return debugger.Step })
+	want := []stop{
+		{0, 0}, {2, 0}, {4, 0}, {5, 0}, {6, 0}, {7, 0}, {8, 0}, {9, 0}, {10, 0}, {31, 0}, {32, 0},
+		{0, 1}, {2, 1}, {4, 1}, {5, 1},
+		{33, 0}, {34, 0}, {36, 0}, {38, 0}, {39, 0},
+	}
+
+	if !reflect.DeepEqual(stops, want) {
+		t.Fatalf("stepped through %v, want %v", stops, want)
+	}
+}
+
+// stepTo steps to at, then answers it with cmd and continues after the next pause.
+func stepTo(at stop, cmd debugger.Command) func(state *debugger.State) debugger.Command {
+	answered := false
+	return func(state *debugger.State) debugger.Command {
+		if answered {
+			return debugger.Continue
+		}
+
+		if (stop{state.PC, state.Depth}) == at {
+			answered = true
+			return cmd
+		}
+
+		return debugger.Step
+	}
+}
+
+func TestStepOverAndOut(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		at   stop
+		cmd  debugger.Command
+		want stop
+	}{
+		{"step over the call", stop{32, 0}, debugger.StepOver, stop{33, 0}},
+		{"step over an instruction", stop{2, 0}, debugger.StepOver, stop{4, 0}},
+		{"step over the end of the child", stop{5, 1}, debugger.StepOver, stop{33, 0}},
+		{"step over in the child", stop{0, 1}, debugger.StepOver, stop{2, 1}},
+		{"step out of the child", stop{0, 1}, debugger.StepOut, stop{33, 0}},
+	} {
+		d := newDebugger()
+		d.StopOnEntry()
+
+		stops := run(t, d, stepTo(c.at, c.cmd))
+		i := 0
+		for i < len(stops) && stops[i] != c.at {
+			i++
+		}
+
+		if i+1 >= len(stops) || stops[i+1] != c.want {
+			t.Errorf("%s: paused at %v, want %v after %v", c.name, stops, c.want, c.at)
+		}
+
+		if len(stops) != i+2 {
+			t.Errorf("%s: paused at %v after the continue", c.name, stops[i+1:])
+		}
+	}
+}
+
+func TestStepOutOfTheTopFrame(t *testing.T) {
+	d := newDebugger()
+	d.StopOnEntry()
+
+	if stops := run(t, d, stepTo(stop{0, 0}, debugger.StepOut)); len(stops) != 1 {
+		t.Fatalf("paused at %v, want the entry only", stops)
+	}
+}
+
+func TestAbort(t *testing.T) {
+	d := newDebugger()
+	d.AddBreakpoint(debugger.Breakpoint{Type: debugger.AddressBreakpoint, Address: &child})
+
+	pauses := 0
+	_, err := d.Run(func(*debugger.State) debugger.Command {
+		pauses++
+		return debugger.Abort
+	})
+
+	if !errors.Is(err, evmErrors.ExecutionAborted) || pauses != 1 {
+		t.Fatalf("got %v after %d pauses, want %v after 1", err, pauses, evmErrors.ExecutionAborted)
+	}
+}
+
+func TestStart(t *testing.T) {
+	d := newDebugger()
+	d.AddBreakpoint(debugger.Breakpoint{Type: debugger.OpCodeBreakpoint, OpCode: opcodes.SSTORE})
+	d.Start()
+
+	var stops []stop
+	for state := range d.Paused() {
+		stops = append(stops, stop{state.PC, state.Depth})
+		d.Send(debugger.Continue)
+	}
+
+	ret := <-d.Done()
+	if ret.Err != nil {
+		t.Fatal(ret.Err)
+	}
+
+	if want := []stop{{4, 0}, {4, 1}, {38, 0}}; !reflect.DeepEqual(stops, want) {
+		t.Fatalf("paused at %v, want %v", stops, want)
+	}
+
+	if v := ret.Result.StorageCache.CachedAccounts.GetSlot(contract, types.Slot{31: 3}); v == nil || v.Uint64() != 2 {
+		t.Fatalf("slot 3 is %v, want 2", v)
+	}
+}
+
+func TestStartAbort(t *testing.T) {
+	d := newDebugger()
+	d.StopOnEntry()
+	d.Start()
+
+	//no pause follows an abort, Paused is closed
+	<-d.Paused()
+	d.Send(debugger.Abort)
+
+	if _, ok := <-d.Paused(); ok {
+		t.Fatalf("paused after the abort")
+	}
+
+	if ret := <-d.Done(); !errors.Is(ret.Err, evmErrors.ExecutionAborted) {
+		t.Fatalf("got %v, want %v", ret.Err, evmErrors.ExecutionAborted)
+	}
+}
@@ -0,0 +1,88 @@
+package debugger
+
+import (
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/instructions"
+	"github.com/SealSC/SealEVM/opcodes"
+	"github.com/SealSC/SealEVM/storage/cache"
+	"github.com/SealSC/SealEVM/types"
+)
+
+type StopReason byte
+
+const (
+	StopOnEntry StopReason = iota
+	StopOnStep
+	StopOnBreakpoint
+)
+
+var stopReasonNames = map[StopReason]string{
+	StopOnEntry:      "Entry",
+	StopOnStep:       "Step",
+	StopOnBreakpoint: "Breakpoint",
+}
+
+func (s StopReason) String() string {
+	return stopReasonNames[s]
+}
+
+// State is a copy of the VM state taken when the execution paused, it is safe to keep
+// after the execution resumed.
+type State struct {
+	Reason       StopReason
+	BreakpointID int
+
+	PC      uint64
+	OpCode  opcodes.OpCode
+	Depth   uint64
+	Gas     uint64
+	GasCost uint64
+
+	Address types.Address
+	Caller  types.Address
+
+	// Stack items from bottom to top
+	Stack        []*evmInt256.Int
+	Memory       types.Bytes
+	ReturnData   types.Bytes
+	StorageCache cache.ResultCache
+}
+
+func newState(step *instructions.ExecutionStep, reason StopReason, bpID int) *State {
+	stackItems := step.Stack.All()
+	stackCopy := make([]*evmInt256.Int, len(stackItems))
+	for i, item := range stackItems {
+		stackCopy[i] = item.Clone()
+	}
+
+	return &State{
+		Reason:       reason,
+		BreakpointID: bpID,
+		PC:           step.PC,
+		OpCode:       step.OpCode,
+		Depth:        step.Depth,
+		Gas:          step.Gas,
+		GasCost:      step.GasCost,
+		Address:      step.Context.Address(),
+		Caller:       step.Context.Message.Caller,
+		Stack:        stackCopy,
+		Memory:       types.Bytes(step.Memory.All()).Clone(),
+		ReturnData:   types.Bytes(step.ReturnData).Clone(),
+		StorageCache: step.Storage.ResultCache.Clone(),
+	}
+}
+
+// StackItem returns the n-th item from the top of the stack, nil if out of range.
+func (s *State) StackItem(n int) *evmInt256.Int {
+	if n < 0 || n >= len(s.Stack) {
+		return nil
+	}
+
+	return s.Stack[len(s.Stack)-1-n]
+}
+
+// StorageAt returns the current value of a storage slot from the cached result, nil if
+// the slot has not been touched in this execution.
+func (s *State) StorageAt(address types.Address, slot types.Slot) *evmInt256.Int {
+	return s.StorageCache.CachedAccounts.GetSlot(address, slot)
+}
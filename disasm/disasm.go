@@ -0,0 +1,144 @@
+// Package disasm turns EVM bytecode into an instruction listing.
+package disasm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/opcodes"
+	"github.com/SealSC/SealEVM/types"
+)
+
+// Instruction is one decoded instruction. Data is the immediate of a PUSH, Truncated is
+// set when the code ends before all of it, Data then holds the bytes that exist.
+type Instruction struct {
+	Offset    uint64         `json:"offset"`
+	OpCode    opcodes.OpCode `json:"opcode"`
+	Name      string         `json:"name"`
+	Data      types.Bytes    `json:"data,omitempty"`
+	Truncated bool           `json:"truncated,omitempty"`
+	JumpDest  bool           `json:"jumpDest,omitempty"`
+}
+
+// Size is the number of code bytes of the instruction.
+func (i *Instruction) Size() uint64 {
+	return 1 + uint64(len(i.Data))
+}
+
+func (i *Instruction) String() string {
+	if i.OpCode < opcodes.PUSH1 || i.OpCode > opcodes.PUSH32 {
+		return i.Name
+	}
+
+	s := fmt.Sprintf("%s 0x%x", i.Name, []byte(i.Data))
+	if i.Truncated {
+		s += fmt.Sprintf(" (truncated, %d of %d bytes)", len(i.Data), pushSize(i.OpCode))
+	}
+
+	return s
+}
+
+func pushSize(op opcodes.OpCode) int {
+	return int(op-opcodes.PUSH1) + 1
+}
+
+// opName is the mnemonic of op, bytes without an opcode are named after their value.
+func opName(op opcodes.OpCode) string {
+	if name := op.String(); name != "" {
+		return name
+	}
+
+	return fmt.Sprintf("INVALID(0x%02x)", byte(op))
+}
+
+// Listing is the disassembly of a piece of code. Instructions cover the code before the
+// metadata trailer, Metadata is nil when the code has none.
+type Listing struct {
+	Code         types.Bytes    `json:"-"`
+	Instructions []*Instruction `json:"instructions"`
+	Metadata     *Metadata      `json:"metadata,omitempty"`
+}
+
+// Disassemble decodes code. The valid jump destinations are found by the analysis the
+// interpreter runs on the whole code, the metadata trailer included.
+func Disassemble(code []byte) *Listing {
+	body, meta := SplitMetadata(code)
+
+	listing := &Listing{
+		Code:     code,
+		Metadata: meta,
+	}
+
+	contract := &environment.Contract{Code: code}
+	for pc := uint64(0); pc < uint64(len(body)); {
+		op := opcodes.OpCode(body[pc])
+		ins := &Instruction{
+			Offset: pc,
+			OpCode: op,
+			Name:   opName(op),
+		}
+
+		if op >= opcodes.PUSH1 && op <= opcodes.PUSH32 {
+			end := pc + 1 + uint64(pushSize(op))
+			if end > uint64(len(body)) {
+				end = uint64(len(body))
+				ins.Truncated = true
+			}
+
+			ins.Data = types.Bytes(body[pc+1 : end]).Clone()
+		}
+
+		if op == opcodes.JUMPDEST {
+			ins.JumpDest, _ = contract.IsValidJump(pc)
+		}
+
+		listing.Instructions = append(listing.Instructions, ins)
+		pc += ins.Size()
+	}
+
+	return listing
+}
+
+// JumpDests are the offsets of the valid jump destinations.
+func (l *Listing) JumpDests() []uint64 {
+	var dests []uint64
+	for _, ins := range l.Instructions {
+		if ins.JumpDest {
+			dests = append(dests, ins.Offset)
+		}
+	}
+
+	return dests
+}
+
+// WriteText writes a line per instruction, "offset: mnemonic [data]", valid jump
+// destinations are marked with a '>', then a line of the metadata when there is one.
+func (l *Listing) WriteText(w io.Writer) error {
+	for _, ins := range l.Instructions {
+		marker := " "
+		if ins.JumpDest {
+			marker = ">"
+		}
+
+		if _, err := fmt.Fprintf(w, "%s%06x: %s\n", marker, ins.Offset, ins.String()); err != nil {
+			return err
+		}
+	}
+
+	if l.Metadata != nil {
+		if _, err := fmt.Fprintf(w, " %06x: metadata (%d bytes) %s\n", l.Metadata.Offset, l.Metadata.Size(), l.Metadata.String()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteJSON writes the listing as one JSON document.
+func (l *Listing) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(l)
+}
@@ -0,0 +1,297 @@
+package disasm
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/SealSC/SealEVM/types"
+)
+
+// Metadata is the CBOR map solc appends to the runtime code, followed by its length in two
+// big endian bytes. Offset is where the CBOR starts, Raw is the CBOR without the length.
+type Metadata struct {
+	Offset       uint64
+	Raw          types.Bytes
+	Solc         string
+	IPFS         types.Bytes
+	Bzzr0        types.Bytes
+	Bzzr1        types.Bytes
+	Experimental bool
+}
+
+// Size is the number of code bytes of the trailer, the length suffix included.
+func (m *Metadata) Size() uint64 {
+	return uint64(len(m.Raw)) + 2
+}
+
+// IPFSCID is the base58 CIDv0 ("Qm...") of the IPFS hash, empty when there is none.
+func (m *Metadata) IPFSCID() string {
+	if len(m.IPFS) == 0 {
+		return ""
+	}
+
+	return base58(m.IPFS)
+}
+
+func (m *Metadata) String() string {
+	var parts []string
+	if m.Solc != "" {
+		parts = append(parts, "solc "+m.Solc)
+	}
+
+	if len(m.IPFS) > 0 {
+		parts = append(parts, "ipfs "+m.IPFSCID())
+	}
+
+	if len(m.Bzzr0) > 0 {
+		parts = append(parts, fmt.Sprintf("bzzr0 %x", []byte(m.Bzzr0)))
+	}
+
+	if len(m.Bzzr1) > 0 {
+		parts = append(parts, fmt.Sprintf("bzzr1 %x", []byte(m.Bzzr1)))
+	}
+
+	if m.Experimental {
+		parts = append(parts, "experimental")
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+type metadataJSON struct {
+	Offset       uint64      `json:"offset"`
+	Size         uint64      `json:"size"`
+	Raw          types.Bytes `json:"raw"`
+	Solc         string      `json:"solc,omitempty"`
+	IPFS         types.Bytes `json:"ipfs,omitempty"`
+	IPFSCID      string      `json:"ipfsCID,omitempty"`
+	Bzzr0        types.Bytes `json:"bzzr0,omitempty"`
+	Bzzr1        types.Bytes `json:"bzzr1,omitempty"`
+	Experimental bool        `json:"experimental,omitempty"`
+}
+
+func (m *Metadata) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&metadataJSON{
+		Offset:       m.Offset,
+		Size:         m.Size(),
+		Raw:          m.Raw,
+		Solc:         m.Solc,
+		IPFS:         m.IPFS,
+		IPFSCID:      m.IPFSCID(),
+		Bzzr0:        m.Bzzr0,
+		Bzzr1:        m.Bzzr1,
+		Experimental: m.Experimental,
+	})
+}
+
+// SplitMetadata splits the solc metadata trailer off code. The trailer is only accepted
+// when its length fits, the CBOR is one map filling it exactly, and the map has a key solc
+// writes, otherwise code is returned as it is with a nil Metadata.
+func SplitMetadata(code []byte) ([]byte, *Metadata) {
+	if len(code) < 2 {
+		return code, nil
+	}
+
+	size := int(binary.BigEndian.Uint16(code[len(code)-2:]))
+	if size == 0 || size+2 > len(code) {
+		return code, nil
+	}
+
+	offset := len(code) - 2 - size
+	raw := code[offset : len(code)-2]
+
+	d := &cborDecoder{data: raw}
+	value, err := d.decode()
+	if err != nil || d.pos != len(raw) {
+		return code, nil
+	}
+
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return code, nil
+	}
+
+	meta := &Metadata{
+		Offset: uint64(offset),
+		Raw:    types.Bytes(raw).Clone(),
+	}
+
+	known := false
+	for key, v := range fields {
+		switch key {
+		case "solc":
+			known = true
+			switch version := v.(type) {
+			case []byte:
+				//releases are encoded as the three bytes major, minor, patch
+				if len(version) == 3 {
+					meta.Solc = fmt.Sprintf("%d.%d.%d", version[0], version[1], version[2])
+				} else {
+					meta.Solc = fmt.Sprintf("%x", version)
+				}
+			case string:
+				meta.Solc = version
+			}
+		case "ipfs":
+			known = true
+			meta.IPFS, _ = v.([]byte)
+		case "bzzr0":
+			known = true
+			meta.Bzzr0, _ = v.([]byte)
+		case "bzzr1":
+			known = true
+			meta.Bzzr1, _ = v.([]byte)
+		case "experimental":
+			known = true
+			meta.Experimental, _ = v.(bool)
+		}
+	}
+
+	if !known {
+		return code, nil
+	}
+
+	return code[:offset], meta
+}
+
+var errCBOR = errors.New("unsupported or malformed CBOR")
+
+// cborDecoder reads the subset of CBOR solc writes: unsigned integers, byte and text
+// strings, maps with text keys and the simple values.
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *cborDecoder) argument(info byte) (uint64, error) {
+	if info < 24 {
+		return uint64(info), nil
+	}
+
+	var n int
+	switch info {
+	case 24:
+		n = 1
+	case 25:
+		n = 2
+	case 26:
+		n = 4
+	case 27:
+		n = 8
+	default:
+		return 0, errCBOR
+	}
+
+	if d.pos+n > len(d.data) {
+		return 0, errCBOR
+	}
+
+	var arg uint64
+	for _, b := range d.data[d.pos : d.pos+n] {
+		arg = arg<<8 | uint64(b)
+	}
+
+	d.pos += n
+	return arg, nil
+}
+
+func (d *cborDecoder) bytes(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, errCBOR
+	}
+
+	b := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return append([]byte{}, b...), nil
+}
+
+func (d *cborDecoder) decode() (interface{}, error) {
+	if d.pos >= len(d.data) {
+		return nil, errCBOR
+	}
+
+	head := d.data[d.pos]
+	d.pos++
+
+	major, info := head>>5, head&0x1f
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22:
+			return nil, nil
+		default:
+			return nil, errCBOR
+		}
+	}
+
+	arg, err := d.argument(info)
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case 0:
+		return arg, nil
+	case 2:
+		return d.bytes(arg)
+	case 3:
+		b, err := d.bytes(arg)
+		return string(b), err
+	case 5:
+		fields := map[string]interface{}{}
+		for i := uint64(0); i < arg; i++ {
+			key, err := d.decode()
+			if err != nil {
+				return nil, err
+			}
+
+			name, ok := key.(string)
+			if !ok {
+				return nil, errCBOR
+			}
+
+			if fields[name], err = d.decode(); err != nil {
+				return nil, err
+			}
+		}
+
+		return fields, nil
+	default:
+		return nil, errCBOR
+	}
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+func base58(data []byte) string {
+	n := new(big.Int).SetBytes(data)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+
+	//leading zero bytes are kept as '1'
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+
+	return string(out)
+}
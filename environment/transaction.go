@@ -21,6 +21,12 @@ import (
 	"github.com/SealSC/SealEVM/types"
 )
 
+// AccessTuple is an entry of an EIP-2930 access list.
+type AccessTuple struct {
+	Address     types.Address
+	StorageKeys []types.Slot
+}
+
 type Transaction struct {
 	TxHash   types.Hash
 	Origin   types.Address
@@ -29,6 +35,9 @@ type Transaction struct {
 	GasLimit *evmInt256.Int
 
 	BlobHashes []types.Hash
+
+	//AccessList accounts and slots are warm from the start of the transaction
+	AccessList []AccessTuple
 }
 
 func (t Transaction) GenInternal(to *types.Address) *Transaction {
@@ -39,6 +48,7 @@ func (t Transaction) GenInternal(to *types.Address) *Transaction {
 		GasPrice:   t.GasPrice,
 		GasLimit:   t.GasLimit,
 		BlobHashes: t.BlobHashes,
+		AccessList: t.AccessList,
 	}
 
 	return tx
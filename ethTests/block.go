@@ -0,0 +1,338 @@
+package ethTests
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/SealSC/SealEVM"
+	"github.com/SealSC/SealEVM/crypto/hashes"
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/sim"
+	"github.com/SealSC/SealEVM/statedb/memory"
+	"github.com/SealSC/SealEVM/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	baseFeeChangeDenominator = 8
+	elasticityMultiplier     = 2
+	gasLimitBoundDivisor     = 1024
+	minGasLimit              = 5000
+	maxExtraDataSize         = 32
+	targetBlobGasPerBlock    = 3 * sim.BlobGasPerBlob
+
+	systemCallGas = 30000000
+)
+
+var (
+	// beaconRootsAddress is the EIP-4788 contract, called with the parent beacon block root
+	// before the transactions of a block.
+	beaconRootsAddress, _ = parseAddress("0x000F3df6D732807Ef1319fB7B8bB8522d0Beac02")
+	systemAddress, _      = parseAddress("0xfffffffffffffffffffffffffffffffffffffffe")
+
+	emptyUncleHash = hashOf(hashes.Keccak256([]byte{0xc0}))
+)
+
+// errors of invalid blocks, besides the ones of their transactions.
+var (
+	ErrInvalidNumber        = errors.New("invalid block number")
+	ErrInvalidTimestamp     = errors.New("timestamp not after the parent")
+	ErrInvalidGasLimit      = errors.New("invalid gas limit")
+	ErrInvalidBaseFee       = errors.New("invalid base fee")
+	ErrInvalidExcessBlobGas = errors.New("invalid excess blob gas")
+	ErrInvalidBlobGasUsed   = errors.New("invalid blob gas used")
+	ErrExtraDataTooLong     = errors.New("extra data too long")
+	ErrPoWFields            = errors.New("difficulty, nonce and uncles must be empty after the merge")
+	ErrBlockGasExhausted    = errors.New("transaction gas limit exceeds the gas left in the block")
+	ErrInvalidGasUsed       = errors.New("gas used exceeds the gas limit")
+)
+
+// Header holds the fields of a block header the processing reads or checks.
+type Header struct {
+	Hash                  types.Hash
+	ParentHash            types.Hash
+	UncleHash             types.Hash
+	Coinbase              types.Address
+	StateRoot             types.Hash
+	ReceiptsRoot          types.Hash
+	Difficulty            *evmInt256.Int
+	Number                uint64
+	GasLimit              uint64
+	GasUsed               uint64
+	Timestamp             uint64
+	ExtraData             []byte
+	MixHash               types.Hash
+	Nonce                 uint64
+	BaseFee               *evmInt256.Int
+	BlobGasUsed           *uint64
+	ExcessBlobGas         *uint64
+	ParentBeaconBlockRoot *types.Hash
+}
+
+func (h *Header) env() *sim.BlockEnv {
+	env := &sim.BlockEnv{
+		Coinbase:   h.Coinbase,
+		GasLimit:   h.GasLimit,
+		Number:     h.Number,
+		Timestamp:  h.Timestamp,
+		ChainID:    evmInt256.New(1),
+		BaseFee:    h.BaseFee,
+		Difficulty: evmInt256.FromBytes(h.MixHash[:]),
+		Hash:       h.Hash,
+	}
+
+	if h.ExcessBlobGas != nil {
+		env.ExcessBlobGas = *h.ExcessBlobGas
+	}
+
+	return env
+}
+
+// Withdrawal is an EIP-4895 withdrawal, Amount is in Gwei.
+type Withdrawal struct {
+	Index          uint64
+	ValidatorIndex uint64
+	Address        types.Address
+	Amount         uint64
+}
+
+// CalcBaseFee is the EIP-1559 base fee of the child of parent.
+func CalcBaseFee(parent *Header) *big.Int {
+	parentBaseFee := bigOf(parent.BaseFee)
+	target := parent.GasLimit / elasticityMultiplier
+	if target == 0 || parent.GasUsed == target {
+		return parentBaseFee
+	}
+
+	if parent.GasUsed > target {
+		delta := new(big.Int).SetUint64(parent.GasUsed - target)
+		delta.Mul(delta, parentBaseFee)
+		delta.Div(delta, new(big.Int).SetUint64(target))
+		delta.Div(delta, big.NewInt(baseFeeChangeDenominator))
+		if delta.Sign() == 0 {
+			delta.SetInt64(1)
+		}
+
+		return delta.Add(delta, parentBaseFee)
+	}
+
+	delta := new(big.Int).SetUint64(target - parent.GasUsed)
+	delta.Mul(delta, parentBaseFee)
+	delta.Div(delta, new(big.Int).SetUint64(target))
+	delta.Div(delta, big.NewInt(baseFeeChangeDenominator))
+
+	fee := new(big.Int).Sub(parentBaseFee, delta)
+	if fee.Sign() < 0 {
+		fee.SetInt64(0)
+	}
+
+	return fee
+}
+
+// CalcExcessBlobGas is the EIP-4844 excess blob gas of the child of parent.
+func CalcExcessBlobGas(parent *Header) uint64 {
+	var excess, used uint64
+	if parent.ExcessBlobGas != nil {
+		excess = *parent.ExcessBlobGas
+	}
+
+	if parent.BlobGasUsed != nil {
+		used = *parent.BlobGasUsed
+	}
+
+	if excess+used < targetBlobGasPerBlock {
+		return 0
+	}
+
+	return excess + used - targetBlobGasPerBlock
+}
+
+// validateHeader checks the header rules which do not need the execution.
+func validateHeader(parent *Header, header *Header) error {
+	if header.Number != parent.Number+1 {
+		return fmt.Errorf("%w: %d after %d", ErrInvalidNumber, header.Number, parent.Number)
+	}
+
+	if header.Timestamp <= parent.Timestamp {
+		return ErrInvalidTimestamp
+	}
+
+	if len(header.ExtraData) > maxExtraDataSize {
+		return ErrExtraDataTooLong
+	}
+
+	if (header.Difficulty != nil && !header.Difficulty.IsZero()) || header.Nonce != 0 || header.UncleHash != emptyUncleHash {
+		return ErrPoWFields
+	}
+
+	limit := parent.GasLimit / gasLimitBoundDivisor
+	diff := header.GasLimit - parent.GasLimit
+	if header.GasLimit < parent.GasLimit {
+		diff = parent.GasLimit - header.GasLimit
+	}
+
+	if diff >= limit || header.GasLimit < minGasLimit {
+		return fmt.Errorf("%w: %d, parent %d", ErrInvalidGasLimit, header.GasLimit, parent.GasLimit)
+	}
+
+	if header.GasUsed > header.GasLimit {
+		return ErrInvalidGasUsed
+	}
+
+	if expected := CalcBaseFee(parent); bigOf(header.BaseFee).Cmp(expected) != 0 {
+		return fmt.Errorf("%w: have %s, want %s", ErrInvalidBaseFee, bigOf(header.BaseFee), expected)
+	}
+
+	if header.ExcessBlobGas == nil || *header.ExcessBlobGas != CalcExcessBlobGas(parent) {
+		return ErrInvalidExcessBlobGas
+	}
+
+	return nil
+}
+
+// TxError tells which transaction made a block invalid.
+type TxError struct {
+	Index int
+	Err   error
+}
+
+func (e *TxError) Error() string {
+	return fmt.Sprintf("transaction %d: %v", e.Index, e.Err)
+}
+
+func (e *TxError) Unwrap() error {
+	return e.Err
+}
+
+// BlockResult is the outcome of a block, the roots are computed from the execution and
+// are compared to the header by the caller.
+type BlockResult struct {
+	Txs          []*sim.TxResult
+	GasUsed      uint64
+	BlobGasUsed  uint64
+	StateRoot    types.Hash
+	ReceiptsRoot types.Hash
+}
+
+// systemCall runs an EIP-4788 style call from the system address, it pays no gas and
+// does not touch the system address.
+func systemCall(state sim.StateDB, env *sim.BlockEnv, to types.Address, data []byte) {
+	if len(state.Code(to)) == 0 {
+		return
+	}
+
+	existed := state.AccountExist(systemAddress)
+	block := env.EVMBlock()
+	block.GasLimit = evmInt256.New(systemCallGas * 2)
+
+	SealEVM.Load()
+	evm := SealEVM.New(SealEVM.EVMParam{
+		MaxStackDepth: 1024,
+		ExternalStore: state,
+		Context: &environment.Context{
+			Block: block,
+			Transaction: environment.Transaction{
+				Origin:   systemAddress,
+				To:       &to,
+				GasPrice: evmInt256.New(0),
+				GasLimit: evmInt256.New(systemCallGas + 21000 + uint64(len(data))*16),
+			},
+			Message: environment.Message{
+				Caller: systemAddress,
+				Value:  evmInt256.New(0),
+				Data:   data,
+			},
+		},
+	})
+
+	result, err := evm.Execute()
+	if err == nil {
+		state.Commit(&result.StorageCache)
+	}
+
+	if !existed {
+		state.DeleteAccount(systemAddress)
+	}
+}
+
+// ApplyBlock executes the transactions and withdrawals of a block on top of parent.
+// An error means the block is invalid, state is then in an undefined state and should be
+// dropped. Transaction errors are wrapped in a TxError and come with the results of the
+// transactions before.
+func ApplyBlock(state *memory.DB, parent *Header, header *Header, txs []*sim.Message, withdrawals []*Withdrawal) (*BlockResult, error) {
+	if err := validateHeader(parent, header); err != nil {
+		return nil, err
+	}
+
+	env := header.env()
+	state.SetCurrentBlock(header.Number)
+
+	if header.ParentBeaconBlockRoot != nil {
+		root := *header.ParentBeaconBlockRoot
+		systemCall(state, env, beaconRootsAddress, root[:])
+	}
+
+	result := &BlockResult{}
+	var receipts [][]byte
+	for i, msg := range txs {
+		if msg.GasLimit > header.GasLimit-result.GasUsed {
+			return result, &TxError{Index: i, Err: ErrBlockGasExhausted}
+		}
+
+		blobGas := uint64(len(msg.BlobHashes)) * sim.BlobGasPerBlob
+		if result.BlobGasUsed+blobGas > sim.MaxBlobGasPerBlock {
+			return result, &TxError{Index: i, Err: sim.ErrInvalidBlobTx}
+		}
+
+		txResult, err := sim.ApplyMessage(state, env, msg)
+		if err != nil {
+			return result, &TxError{Index: i, Err: err}
+		}
+
+		result.GasUsed += txResult.GasUsed
+		result.BlobGasUsed += blobGas
+		result.Txs = append(result.Txs, txResult)
+		receipts = append(receipts, sim.EncodeReceipt(msg.Type, txResult, result.GasUsed))
+	}
+
+	if header.BlobGasUsed == nil || *header.BlobGasUsed != result.BlobGasUsed {
+		return nil, ErrInvalidBlobGasUsed
+	}
+
+	for _, w := range withdrawals {
+		amount := new(big.Int).Mul(new(big.Int).SetUint64(w.Amount), big.NewInt(1000000000))
+		state.AddBalance(w.Address, evmInt256.FromBigInt(amount))
+		if state.AccountEmpty(w.Address) {
+			state.DeleteAccount(w.Address)
+		}
+	}
+
+	result.StateRoot = sim.StateRoot(state)
+	result.ReceiptsRoot = sim.ListRoot(receipts)
+	return result, nil
+}
+
+func bigOf(i *evmInt256.Int) *big.Int {
+	if i == nil {
+		return new(big.Int)
+	}
+
+	return new(big.Int).Set(i.Int)
+}
+
+func hashOf(b []byte) types.Hash {
+	var h types.Hash
+	h.SetBytes(b)
+	return h
+}
+
+func mustRLP(v interface{}) []byte {
+	enc, err := rlp.EncodeToBytes(v)
+	if err != nil {
+		panic(err)
+	}
+
+	return enc
+}
@@ -0,0 +1,636 @@
+package ethTests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/SealSC/SealEVM/crypto/hashes"
+	"github.com/SealSC/SealEVM/sim"
+	"github.com/SealSC/SealEVM/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+type btHeader struct {
+	Hash                  types.Hash  `json:"hash"`
+	ParentHash            types.Hash  `json:"parentHash"`
+	UncleHash             types.Hash  `json:"uncleHash"`
+	Coinbase              string      `json:"coinbase"`
+	StateRoot             types.Hash  `json:"stateRoot"`
+	ReceiptTrie           types.Hash  `json:"receiptTrie"`
+	Difficulty            *bigNumber  `json:"difficulty"`
+	Number                number      `json:"number"`
+	GasLimit              number      `json:"gasLimit"`
+	GasUsed               number      `json:"gasUsed"`
+	Timestamp             number      `json:"timestamp"`
+	ExtraData             types.Bytes `json:"extraData"`
+	MixHash               types.Hash  `json:"mixHash"`
+	Nonce                 types.Bytes `json:"nonce"`
+	BaseFee               *bigNumber  `json:"baseFeePerGas"`
+	BlobGasUsed           *number     `json:"blobGasUsed"`
+	ExcessBlobGas         *number     `json:"excessBlobGas"`
+	ParentBeaconBlockRoot *types.Hash `json:"parentBeaconBlockRoot"`
+}
+
+func (h *btHeader) header() (*Header, error) {
+	coinbase, err := parseAddress(h.Coinbase)
+	if err != nil {
+		return nil, err
+	}
+
+	header := &Header{
+		Hash:                  h.Hash,
+		ParentHash:            h.ParentHash,
+		UncleHash:             h.UncleHash,
+		Coinbase:              coinbase,
+		StateRoot:             h.StateRoot,
+		ReceiptsRoot:          h.ReceiptTrie,
+		Difficulty:            h.Difficulty.value(),
+		Number:                uint64(h.Number),
+		GasLimit:              uint64(h.GasLimit),
+		GasUsed:               uint64(h.GasUsed),
+		Timestamp:             uint64(h.Timestamp),
+		ExtraData:             h.ExtraData,
+		MixHash:               h.MixHash,
+		Nonce:                 new(big.Int).SetBytes(h.Nonce).Uint64(),
+		BaseFee:               h.BaseFee.value(),
+		ParentBeaconBlockRoot: h.ParentBeaconBlockRoot,
+	}
+
+	if h.BlobGasUsed != nil {
+		used := uint64(*h.BlobGasUsed)
+		header.BlobGasUsed = &used
+	}
+
+	if h.ExcessBlobGas != nil {
+		excess := uint64(*h.ExcessBlobGas)
+		header.ExcessBlobGas = &excess
+	}
+
+	return header, nil
+}
+
+type btTransaction struct {
+	Type                 *number         `json:"type"`
+	ChainID              *bigNumber      `json:"chainId"`
+	Nonce                number          `json:"nonce"`
+	GasPrice             *bigNumber      `json:"gasPrice"`
+	MaxPriorityFeePerGas *bigNumber      `json:"maxPriorityFeePerGas"`
+	MaxFeePerGas         *bigNumber      `json:"maxFeePerGas"`
+	GasLimit             number          `json:"gasLimit"`
+	To                   string          `json:"to"`
+	Value                bigNumber       `json:"value"`
+	Data                 types.Bytes     `json:"data"`
+	AccessList           []stAccessTuple `json:"accessList"`
+	MaxFeePerBlobGas     *bigNumber      `json:"maxFeePerBlobGas"`
+	BlobVersionedHashes  []types.Hash    `json:"blobVersionedHashes"`
+	V                    bigNumber       `json:"v"`
+	R                    bigNumber       `json:"r"`
+	S                    bigNumber       `json:"s"`
+	Sender               string          `json:"sender"`
+}
+
+type btWithdrawal struct {
+	Index          number `json:"index"`
+	ValidatorIndex number `json:"validatorIndex"`
+	Address        string `json:"address"`
+	Amount         number `json:"amount"`
+}
+
+type btBlockBody struct {
+	Header       *btHeader        `json:"blockHeader"`
+	Transactions []*btTransaction `json:"transactions"`
+	Withdrawals  []*btWithdrawal  `json:"withdrawals"`
+}
+
+type btBlock struct {
+	btBlockBody
+	Decoded         *btBlockBody `json:"rlp_decoded"`
+	ExpectException string       `json:"expectException"`
+}
+
+type btJSON struct {
+	Network       string      `json:"network"`
+	GenesisHeader btHeader    `json:"genesisBlockHeader"`
+	Pre           sim.Alloc   `json:"pre"`
+	Blocks        []btBlock   `json:"blocks"`
+	PostState     sim.Alloc   `json:"postState"`
+	PostStateHash *types.Hash `json:"postStateHash"`
+	LastBlockHash types.Hash  `json:"lastblockhash"`
+}
+
+// BlockchainTest is one test of a BlockchainTests fixture file.
+type BlockchainTest struct {
+	Name string
+	File string
+	json btJSON
+}
+
+// LoadBlockchainTests reads a fixture file, or every .json file under a directory.
+// Tests are returned sorted by file and name.
+func LoadBlockchainTests(path string) ([]*BlockchainTest, error) {
+	files, err := fixtureFiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tests []*BlockchainTest
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		var fixtures map[string]btJSON
+		if err = json.Unmarshal(data, &fixtures); err != nil {
+			return nil, fmt.Errorf("invalid blockchain test %s: %w", file, err)
+		}
+
+		names := make([]string, 0, len(fixtures))
+		for name := range fixtures {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			tests = append(tests, &BlockchainTest{Name: name, File: file, json: fixtures[name]})
+		}
+	}
+
+	return tests, nil
+}
+
+func (t *BlockchainTest) Network() string {
+	return t.json.Network
+}
+
+func (tx *btTransaction) txType() byte {
+	switch {
+	case tx.Type != nil:
+		return byte(*tx.Type)
+	case tx.BlobVersionedHashes != nil:
+		return 3
+	case tx.MaxFeePerGas != nil:
+		return 2
+	case tx.AccessList != nil:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func rlpAccessList(list []stAccessTuple) ([]interface{}, error) {
+	out := []interface{}{}
+	for _, tuple := range list {
+		addr, err := parseAddress(tuple.Address)
+		if err != nil {
+			return nil, err
+		}
+
+		keys := [][]byte{}
+		for _, key := range tuple.StorageKeys {
+			slot := bigNumber{}
+			if err = slot.UnmarshalJSON([]byte(`"` + key + `"`)); err != nil {
+				return nil, err
+			}
+
+			hash := types.Int256ToHash(slot.Int)
+			keys = append(keys, hash[:])
+		}
+
+		out = append(out, []interface{}{addr[:], keys})
+	}
+
+	return out, nil
+}
+
+// recoverSender derives the sender from the signature, for fixtures without a "sender" field.
+func (tx *btTransaction) recoverSender() (types.Address, error) {
+	var sender types.Address
+
+	var to []byte
+	if tx.To != "" {
+		addr, err := parseAddress(tx.To)
+		if err != nil {
+			return sender, err
+		}
+		to = addr[:]
+	}
+
+	accessList, err := rlpAccessList(tx.AccessList)
+	if err != nil {
+		return sender, err
+	}
+
+	v := bigOf(tx.V.Int)
+	chainID := bigOf(tx.ChainID.value())
+	value := bigOf(tx.Value.Int)
+	var payload []byte
+	var recID uint64
+
+	switch tx.txType() {
+	case 0:
+		fields := []interface{}{uint64(tx.Nonce), bigOf(tx.GasPrice.value()), uint64(tx.GasLimit), to, value, []byte(tx.Data)}
+		if v.Uint64() >= 35 {
+			//EIP-155
+			chainID = new(big.Int).Sub(v, big.NewInt(35))
+			chainID.Rsh(chainID, 1)
+			recID = v.Uint64() - 35 - 2*chainID.Uint64()
+			fields = append(fields, chainID, uint(0), uint(0))
+		} else {
+			recID = v.Uint64() - 27
+		}
+		payload = mustRLP(fields)
+	case 1:
+		payload = append([]byte{1}, mustRLP([]interface{}{chainID, uint64(tx.Nonce), bigOf(tx.GasPrice.value()), uint64(tx.GasLimit), to, value, []byte(tx.Data), accessList})...)
+		recID = v.Uint64()
+	case 2:
+		payload = append([]byte{2}, mustRLP([]interface{}{chainID, uint64(tx.Nonce), bigOf(tx.MaxPriorityFeePerGas.value()), bigOf(tx.MaxFeePerGas.value()),
+			uint64(tx.GasLimit), to, value, []byte(tx.Data), accessList})...)
+		recID = v.Uint64()
+	case 3:
+		blobHashes := [][]byte{}
+		for i := range tx.BlobVersionedHashes {
+			blobHashes = append(blobHashes, tx.BlobVersionedHashes[i][:])
+		}
+
+		payload = append([]byte{3}, mustRLP([]interface{}{chainID, uint64(tx.Nonce), bigOf(tx.MaxPriorityFeePerGas.value()), bigOf(tx.MaxFeePerGas.value()),
+			uint64(tx.GasLimit), to, value, []byte(tx.Data), accessList, bigOf(tx.MaxFeePerBlobGas.value()), blobHashes})...)
+		recID = v.Uint64()
+	default:
+		return sender, fmt.Errorf("unsupported transaction type %d", tx.txType())
+	}
+
+	if recID > 1 {
+		return sender, errors.New("invalid signature v")
+	}
+
+	sig := make([]byte, 65)
+	tx.R.FillBytes(sig[:32])
+	tx.S.FillBytes(sig[32:64])
+	sig[64] = byte(recID)
+
+	pub, err := crypto.SigToPub(hashes.Keccak256(payload), sig)
+	if err != nil {
+		return sender, err
+	}
+
+	return types.Address(crypto.PubkeyToAddress(*pub)), nil
+}
+
+func (tx *btTransaction) message() (*sim.Message, error) {
+	msg := &sim.Message{
+		Type:          tx.txType(),
+		Nonce:         uint64(tx.Nonce),
+		GasLimit:      uint64(tx.GasLimit),
+		Value:         tx.Value.value(),
+		Data:          tx.Data,
+		GasPrice:      tx.GasPrice.value(),
+		GasFeeCap:     tx.MaxFeePerGas.value(),
+		GasTipCap:     tx.MaxPriorityFeePerGas.value(),
+		BlobHashes:    tx.BlobVersionedHashes,
+		BlobGasFeeCap: tx.MaxFeePerBlobGas.value(),
+	}
+
+	var err error
+	if tx.Sender != "" {
+		msg.From, err = parseAddress(tx.Sender)
+	} else {
+		msg.From, err = tx.recoverSender()
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender: %w", err)
+	}
+
+	if tx.To != "" {
+		to, err := parseAddress(tx.To)
+		if err != nil {
+			return nil, err
+		}
+		msg.To = &to
+	}
+
+	for _, tuple := range tx.AccessList {
+		addr, err := parseAddress(tuple.Address)
+		if err != nil {
+			return nil, err
+		}
+
+		entry := sim.AccessTuple{Address: addr}
+		for _, key := range tuple.StorageKeys {
+			slot := bigNumber{}
+			if err = slot.UnmarshalJSON([]byte(`"` + key + `"`)); err != nil {
+				return nil, err
+			}
+			entry.StorageKeys = append(entry.StorageKeys, types.Int256ToSlot(slot.Int))
+		}
+
+		msg.AccessList = append(msg.AccessList, entry)
+	}
+
+	return msg, nil
+}
+
+func (b *btBlock) body() *btBlockBody {
+	if b.Header == nil && b.Decoded != nil {
+		return b.Decoded
+	}
+
+	return &b.btBlockBody
+}
+
+func (b *btBlockBody) decode() (*Header, []*sim.Message, []*Withdrawal, error) {
+	header, err := b.Header.header()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var txs []*sim.Message
+	for i, tx := range b.Transactions {
+		msg, err := tx.message()
+		if err != nil {
+			return nil, nil, nil, &TxError{Index: i, Err: err}
+		}
+		txs = append(txs, msg)
+	}
+
+	var withdrawals []*Withdrawal
+	for _, w := range b.Withdrawals {
+		addr, err := parseAddress(w.Address)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		withdrawals = append(withdrawals, &Withdrawal{
+			Index:          uint64(w.Index),
+			ValidatorIndex: uint64(w.ValidatorIndex),
+			Address:        addr,
+			Amount:         uint64(w.Amount),
+		})
+	}
+
+	return header, txs, withdrawals, nil
+}
+
+// BlockchainResult is the outcome of a BlockchainTest. Block and Tx locate the first
+// divergence, -1 when unknown. The fixtures carry no per-transaction results, so Tx is only
+// known when a transaction was rejected, Txs helps to find it otherwise.
+type BlockchainResult struct {
+	Name    string
+	File    string
+	Network string
+
+	Pass    bool
+	Skipped bool
+	Error   string
+
+	Block int
+	Tx    int
+
+	//Txs summarizes the transactions of the first diverging block
+	Txs []string
+
+	//Diff lists the accounts which differ from the expected post state
+	Diff string
+}
+
+func (r *BlockchainResult) String() string {
+	status := "PASS"
+	switch {
+	case r.Skipped:
+		status = "SKIP"
+	case !r.Pass:
+		status = "FAIL"
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%s %s (%s)", status, r.Name, r.Network)
+	if r.Pass || r.Skipped {
+		return buf.String()
+	}
+
+	fmt.Fprintf(buf, "\n  file: %s\n  %s", r.File, r.Error)
+	if r.Block >= 0 {
+		fmt.Fprintf(buf, "\n  first diverging block: %d", r.Block)
+		if r.Tx >= 0 {
+			fmt.Fprintf(buf, ", transaction %d", r.Tx)
+		}
+
+		for _, tx := range r.Txs {
+			fmt.Fprintf(buf, "\n    %s", tx)
+		}
+	}
+
+	if r.Diff != "" {
+		fmt.Fprintf(buf, "\n%s", strings.TrimRight(r.Diff, "\n"))
+	}
+
+	return buf.String()
+}
+
+func (r *BlockchainResult) diverge(block int, tx int, problem string) {
+	if r.Block < 0 {
+		r.Block = block
+		r.Tx = tx
+	}
+
+	if r.Error != "" {
+		r.Error += "; "
+	}
+	r.Error += problem
+}
+
+func txSummaries(txs []*sim.Message, results []*sim.TxResult) []string {
+	var lines []string
+	for i, res := range results {
+		line := fmt.Sprintf("tx %d from %s: gas used %d", i, txs[i].From, res.GasUsed)
+		if res.Err != nil {
+			line += ", error: " + res.Err.Error()
+		}
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// Run imports the blocks on the pre-state and checks each block header, the last block hash
+// and the post state. A block with an expected exception must be rejected, it is then
+// dropped and the next block builds on the last valid one.
+func (t *BlockchainTest) Run() *BlockchainResult {
+	result := &BlockchainResult{
+		Name:    t.Name,
+		File:    t.File,
+		Network: t.json.Network,
+		Block:   -1,
+		Tx:      -1,
+	}
+
+	if !IsSupportedFork(t.json.Network) {
+		result.Skipped = true
+		return result
+	}
+
+	parent, err := t.json.GenesisHeader.header()
+	if err != nil {
+		result.Error = "invalid genesis header: " + err.Error()
+		return result
+	}
+
+	state := sim.NewState(t.json.Pre)
+	if root := sim.StateRoot(state); root != parent.StateRoot {
+		result.Error = fmt.Sprintf("genesis state root mismatch: got %s, want %s", root, parent.StateRoot)
+		return result
+	}
+	state.SetBlockHash(parent.Number, parent.Hash)
+
+	for i := range t.json.Blocks {
+		blk := &t.json.Blocks[i]
+		expectInvalid := blk.ExpectException != ""
+
+		body := blk.body()
+		if body.Header == nil {
+			if !expectInvalid {
+				result.diverge(i, -1, fmt.Sprintf("block %d has no decoded header", i))
+			}
+			continue
+		}
+
+		header, txs, withdrawals, err := body.decode()
+		if err != nil {
+			if !expectInvalid {
+				result.diverge(i, txIndex(err), fmt.Sprintf("block %d: %v", i, err))
+			}
+			continue
+		}
+
+		snapshot := state.Snapshot()
+		blockResult, err := ApplyBlock(state, parent, header, txs, withdrawals)
+		if err != nil {
+			state.RevertToSnapshot(snapshot)
+			if !expectInvalid {
+				result.diverge(i, txIndex(err), fmt.Sprintf("block %d rejected: %v", i, err))
+				if blockResult != nil {
+					result.Txs = txSummaries(txs, blockResult.Txs)
+				}
+			}
+			continue
+		}
+
+		var mismatches []string
+		if blockResult.GasUsed != header.GasUsed {
+			mismatches = append(mismatches, fmt.Sprintf("gas used %d, header %d", blockResult.GasUsed, header.GasUsed))
+		}
+
+		if blockResult.StateRoot != header.StateRoot {
+			mismatches = append(mismatches, fmt.Sprintf("state root %s, header %s", blockResult.StateRoot, header.StateRoot))
+		}
+
+		if blockResult.ReceiptsRoot != header.ReceiptsRoot {
+			mismatches = append(mismatches, fmt.Sprintf("receipts root %s, header %s", blockResult.ReceiptsRoot, header.ReceiptsRoot))
+		}
+
+		if expectInvalid {
+			//a mismatch with the header rejects the block as well
+			state.RevertToSnapshot(snapshot)
+			if len(mismatches) == 0 {
+				result.diverge(i, -1, fmt.Sprintf("block %d: expected exception %s, the block was accepted", i, blk.ExpectException))
+				result.Txs = txSummaries(txs, blockResult.Txs)
+			}
+			continue
+		}
+
+		if len(mismatches) > 0 {
+			if result.Block < 0 {
+				result.Txs = txSummaries(txs, blockResult.Txs)
+			}
+
+			//keep going on the computed state, the final diff shows every diverging account
+			result.diverge(i, -1, fmt.Sprintf("block %d: %s", i, strings.Join(mismatches, ", ")))
+		}
+
+		state.ClearSnapshots()
+		parent = header
+		state.SetBlockHash(header.Number, header.Hash)
+	}
+
+	if parent.Hash != t.json.LastBlockHash {
+		if result.Error != "" {
+			result.Error += "; "
+		}
+		result.Error += fmt.Sprintf("last block hash %s, want %s", parent.Hash, t.json.LastBlockHash)
+	}
+
+	if t.json.PostState != nil {
+		result.Diff = DiffAlloc(sim.Dump(state), t.json.PostState)
+	} else if t.json.PostStateHash != nil && sim.StateRoot(state) != *t.json.PostStateHash {
+		if result.Error != "" {
+			result.Error += "; "
+		}
+		result.Error += fmt.Sprintf("post state root %s, want %s", sim.StateRoot(state), *t.json.PostStateHash)
+	}
+
+	if result.Diff != "" && result.Error == "" {
+		result.Error = "post state mismatch"
+	}
+
+	result.Pass = result.Error == ""
+	return result
+}
+
+func txIndex(err error) int {
+	var txErr *TxError
+	if errors.As(err, &txErr) {
+		return txErr.Index
+	}
+
+	return -1
+}
+
+// RunBlockchainTests runs every test of the fixtures under path whose name matches filter,
+// a nil filter runs everything. onResult is called after each test.
+func RunBlockchainTests(path string, filter func(name string) bool, onResult func(*BlockchainResult)) (*Report, error) {
+	tests, err := LoadBlockchainTests(path)
+	if err != nil {
+		return nil, err
+	}
+
+	report := NewReport()
+	for _, test := range tests {
+		if filter != nil && !filter(test.Name) {
+			continue
+		}
+
+		result := test.Run()
+		report.count(result.Network, result.Pass, result.Skipped)
+		if onResult != nil {
+			onResult(result)
+		}
+	}
+
+	return report, nil
+}
+
+func fixtureFiles(path string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() && strings.HasSuffix(info.Name(), ".json") {
+			files = append(files, p)
+		}
+
+		return nil
+	})
+
+	sort.Strings(files)
+	return files, err
+}
@@ -0,0 +1,36 @@
+package ethTests_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/SealSC/SealEVM/ethTests"
+)
+
+// The fixtures of ethereum/tests are not part of the repository, these tests run them from
+// the directories named by the environment and are skipped otherwise. go test
+// runs in the package directory, so the paths are better absolute:
+//
+//	SEALEVM_STATE_TESTS=$PWD/tests/GeneralStateTests go test ./ethTests -run TestStateTests
+//	SEALEVM_BLOCKCHAIN_TESTS=$PWD/tests/BlockchainTests go test ./ethTests -run TestBlockchainTests
+const (
+	stateTestsEnv      = "SEALEVM_STATE_TESTS"
+	blockchainTestsEnv = "SEALEVM_BLOCKCHAIN_TESTS"
+)
+
+func fixtureDir(t *testing.T, env string) string {
+	dir := os.Getenv(env)
+	if dir == "" {
+		t.Skipf("%s is not set", env)
+	}
+
+	return dir
+}
+
+func TestStateTests(t *testing.T) {
+	ethTests.RunStateTestsT(t, fixtureDir(t, stateTestsEnv))
+}
+
+func TestBlockchainTests(t *testing.T) {
+	ethTests.RunBlockchainTestsT(t, fixtureDir(t, blockchainTestsEnv))
+}
@@ -0,0 +1,50 @@
+package ethTests
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/SealSC/SealEVM/types"
+)
+
+// number is an uint64 of the fixtures, hex or decimal, quoted or not.
+type number uint64
+
+func (n *number) UnmarshalJSON(data []byte) error {
+	str := strings.Trim(string(data), `"`)
+
+	var v uint64
+	var err error
+	if strings.HasPrefix(str, "0x") || strings.HasPrefix(str, "0X") {
+		v, err = strconv.ParseUint(str[2:], 16, 64)
+	} else {
+		v, err = strconv.ParseUint(str, 10, 64)
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid number %s: %w", data, err)
+	}
+
+	*n = number(v)
+	return nil
+}
+
+func (n number) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fmt.Sprintf("0x%x", uint64(n)))
+}
+
+func parseAddress(s string) (types.Address, error) {
+	var addr types.Address
+
+	str := strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	b, err := hex.DecodeString(str)
+	if err != nil || len(b) != types.AddressBytesLen {
+		return addr, fmt.Errorf("invalid address %q", s)
+	}
+
+	addr.SetBytes(b)
+	return addr, nil
+}
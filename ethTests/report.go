@@ -0,0 +1,263 @@
+package ethTests
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/sim"
+	"github.com/SealSC/SealEVM/types"
+)
+
+// Result is the outcome of a test case.
+type Result struct {
+	Name    string
+	File    string
+	Subtest Subtest
+	Indexes stIndexes
+
+	Pass    bool
+	Skipped bool
+
+	//Error tells why a case failed, ExecutionError is the error of the EVM, if any
+	Error          string
+	ExecutionError string
+
+	Root         types.Hash
+	ExpectedRoot types.Hash
+	LogsHash     types.Hash
+	ExpectedLogs types.Hash
+
+	//Diff lists the accounts which differ from the expected post state
+	Diff string
+}
+
+func (r *Result) String() string {
+	status := "PASS"
+	switch {
+	case r.Skipped:
+		status = "SKIP"
+	case !r.Pass:
+		status = "FAIL"
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%s %s/%s (data %d, gas %d, value %d)", status, r.Name, r.Subtest, r.Indexes.Data, r.Indexes.Gas, r.Indexes.Value)
+	if !r.Pass && !r.Skipped {
+		fmt.Fprintf(buf, "\n  file: %s\n  %s", r.File, r.Error)
+		if r.ExecutionError != "" {
+			fmt.Fprintf(buf, "\n  execution error: %s", r.ExecutionError)
+		}
+
+		if r.Diff != "" {
+			fmt.Fprintf(buf, "\n%s", strings.TrimRight(r.Diff, "\n"))
+		}
+	}
+
+	return buf.String()
+}
+
+func intText(i *evmInt256.Int) string {
+	if i == nil {
+		return "0x0"
+	}
+
+	return "0x" + i.Text(16)
+}
+
+// DiffAlloc describes how got differs from want, one line per field, sorted by address.
+func DiffAlloc(got sim.Alloc, want sim.Alloc) string {
+	addrs := map[types.Address]bool{}
+	for addr := range got {
+		addrs[addr] = true
+	}
+
+	for addr := range want {
+		addrs[addr] = true
+	}
+
+	sorted := make([]types.Address, 0, len(addrs))
+	for addr := range addrs {
+		sorted = append(sorted, addr)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i][:], sorted[j][:]) < 0
+	})
+
+	buf := &bytes.Buffer{}
+	for _, addr := range sorted {
+		g, w := got[addr], want[addr]
+		switch {
+		case g == nil:
+			fmt.Fprintf(buf, "  %s: missing, want balance %s nonce %d\n", addr, intText(w.Balance), w.Nonce)
+			continue
+		case w == nil:
+			fmt.Fprintf(buf, "  %s: unexpected account, balance %s nonce %d\n", addr, intText(g.Balance), g.Nonce)
+			continue
+		}
+
+		if intText(g.Balance) != intText(w.Balance) {
+			fmt.Fprintf(buf, "  %s: balance got %s, want %s\n", addr, intText(g.Balance), intText(w.Balance))
+		}
+
+		if g.Nonce != w.Nonce {
+			fmt.Fprintf(buf, "  %s: nonce got %d, want %d\n", addr, g.Nonce, w.Nonce)
+		}
+
+		if !bytes.Equal(g.Code, w.Code) {
+			fmt.Fprintf(buf, "  %s: code got 0x%x, want 0x%x\n", addr, []byte(g.Code), []byte(w.Code))
+		}
+
+		slots := map[types.Slot]bool{}
+		for slot := range g.Storage {
+			slots[slot] = true
+		}
+
+		for slot := range w.Storage {
+			slots[slot] = true
+		}
+
+		sortedSlots := make([]types.Slot, 0, len(slots))
+		for slot := range slots {
+			sortedSlots = append(sortedSlots, slot)
+		}
+
+		sort.Slice(sortedSlots, func(i, j int) bool {
+			return bytes.Compare(sortedSlots[i][:], sortedSlots[j][:]) < 0
+		})
+
+		for _, slot := range sortedSlots {
+			gv, wv := intText(g.Storage[slot]), intText(w.Storage[slot])
+			if gv != wv {
+				fmt.Fprintf(buf, "  %s: storage[%s] got %s, want %s\n", addr, evmInt256.FromBytes(slot[:]).Text(16), gv, wv)
+			}
+		}
+	}
+
+	return buf.String()
+}
+
+// ForkStats counts the cases of a fork.
+type ForkStats struct {
+	Passed  int
+	Failed  int
+	Skipped int
+}
+
+// PassRate is the share of the executed cases which passed, skipped ones are not counted.
+func (s *ForkStats) PassRate() float64 {
+	if s.Passed+s.Failed == 0 {
+		return 0
+	}
+
+	return float64(s.Passed) / float64(s.Passed+s.Failed)
+}
+
+// Report collects results per fork, of state test cases or blockchain tests.
+type Report struct {
+	Forks map[string]*ForkStats
+}
+
+func NewReport() *Report {
+	return &Report{Forks: map[string]*ForkStats{}}
+}
+
+func (r *Report) Add(result *Result) {
+	r.count(result.Subtest.Fork, result.Pass, result.Skipped)
+}
+
+func (r *Report) count(fork string, pass bool, skipped bool) {
+	stats := r.Forks[fork]
+	if stats == nil {
+		stats = &ForkStats{}
+		r.Forks[fork] = stats
+	}
+
+	switch {
+	case skipped:
+		stats.Skipped++
+	case pass:
+		stats.Passed++
+	default:
+		stats.Failed++
+	}
+}
+
+// Merge adds the counts of another report.
+func (r *Report) Merge(other *Report) {
+	for fork, stats := range other.Forks {
+		merged := r.Forks[fork]
+		if merged == nil {
+			merged = &ForkStats{}
+			r.Forks[fork] = merged
+		}
+
+		merged.Passed += stats.Passed
+		merged.Failed += stats.Failed
+		merged.Skipped += stats.Skipped
+	}
+}
+
+// Failed is the number of failed cases of all forks.
+func (r *Report) Failed() int {
+	failed := 0
+	for _, stats := range r.Forks {
+		failed += stats.Failed
+	}
+
+	return failed
+}
+
+// WriteSummary writes a line per fork, as in "Cancun: 120/130 passed (92.31%), 0 skipped".
+func (r *Report) WriteSummary(w io.Writer) error {
+	forks := make([]string, 0, len(r.Forks))
+	for fork := range r.Forks {
+		forks = append(forks, fork)
+	}
+	sort.Strings(forks)
+
+	buf := &bytes.Buffer{}
+	for _, fork := range forks {
+		stats := r.Forks[fork]
+		if stats.Passed+stats.Failed == 0 {
+			fmt.Fprintf(buf, "%s: %d skipped (unsupported fork)\n", fork, stats.Skipped)
+			continue
+		}
+
+		fmt.Fprintf(buf, "%s: %d/%d passed (%.2f%%), %d skipped\n",
+			fork, stats.Passed, stats.Passed+stats.Failed, stats.PassRate()*100, stats.Skipped)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// RunStateTests runs every case of the fixtures under path whose "name/fork/index" matches
+// filter, a nil filter runs everything. onResult is called after each case.
+func RunStateTests(path string, filter func(name string) bool, onResult func(*Result)) (*Report, error) {
+	tests, err := LoadStateTests(path)
+	if err != nil {
+		return nil, err
+	}
+
+	report := NewReport()
+	for _, test := range tests {
+		for _, subtest := range test.Subtests() {
+			if filter != nil && !filter(test.Name+"/"+subtest.String()) {
+				continue
+			}
+
+			result := test.Run(subtest)
+			report.Add(result)
+			if onResult != nil {
+				onResult(result)
+			}
+		}
+	}
+
+	return report, nil
+}
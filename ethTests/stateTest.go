@@ -0,0 +1,361 @@
+package ethTests
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/sim"
+	"github.com/SealSC/SealEVM/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// SupportedForks lists the forks SealEVM follows, cases of other forks are skipped.
+var SupportedForks = []string{"Cancun"}
+
+func IsSupportedFork(fork string) bool {
+	for _, f := range SupportedForks {
+		if f == fork {
+			return true
+		}
+	}
+
+	return false
+}
+
+// bigNumber is an uint256 of the fixtures, "0x" and the ":bigint " prefix of the
+// filled tests are accepted.
+type bigNumber struct {
+	*evmInt256.Int
+}
+
+func (n *bigNumber) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		str = string(data)
+	}
+
+	str = strings.TrimSpace(strings.TrimPrefix(str, ":bigint "))
+	v := new(big.Int)
+	ok := true
+	switch {
+	case str == "0x" || str == "":
+	case strings.HasPrefix(str, "0x") || strings.HasPrefix(str, "0X"):
+		_, ok = v.SetString(str[2:], 16)
+	default:
+		_, ok = v.SetString(str, 10)
+	}
+
+	if !ok {
+		return fmt.Errorf("invalid number %s", data)
+	}
+
+	n.Int = evmInt256.FromBigInt(v)
+	return nil
+}
+
+func (n *bigNumber) value() *evmInt256.Int {
+	if n == nil || n.Int == nil {
+		return nil
+	}
+
+	return n.Int.Clone()
+}
+
+type stEnv struct {
+	Coinbase      string     `json:"currentCoinbase"`
+	Difficulty    *bigNumber `json:"currentDifficulty"`
+	Random        *bigNumber `json:"currentRandom"`
+	GasLimit      number     `json:"currentGasLimit"`
+	Number        number     `json:"currentNumber"`
+	Timestamp     number     `json:"currentTimestamp"`
+	BaseFee       *bigNumber `json:"currentBaseFee"`
+	ExcessBlobGas *number    `json:"currentExcessBlobGas"`
+}
+
+type stAccessTuple struct {
+	Address     string   `json:"address"`
+	StorageKeys []string `json:"storageKeys"`
+}
+
+type stTransaction struct {
+	Data                 []types.Bytes     `json:"data"`
+	GasLimit             []number          `json:"gasLimit"`
+	Value                []bigNumber       `json:"value"`
+	GasPrice             *bigNumber        `json:"gasPrice"`
+	MaxFeePerGas         *bigNumber        `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *bigNumber        `json:"maxPriorityFeePerGas"`
+	Nonce                number            `json:"nonce"`
+	SecretKey            types.Bytes       `json:"secretKey"`
+	Sender               string            `json:"sender"`
+	To                   string            `json:"to"`
+	AccessLists          [][]stAccessTuple `json:"accessLists"`
+	BlobVersionedHashes  []types.Hash      `json:"blobVersionedHashes"`
+	MaxFeePerBlobGas     *bigNumber        `json:"maxFeePerBlobGas"`
+}
+
+type stIndexes struct {
+	Data  int `json:"data"`
+	Gas   int `json:"gas"`
+	Value int `json:"value"`
+}
+
+type stPost struct {
+	Root            types.Hash `json:"hash"`
+	Logs            types.Hash `json:"logs"`
+	Indexes         stIndexes  `json:"indexes"`
+	ExpectException string     `json:"expectException"`
+	State           sim.Alloc  `json:"state"`
+}
+
+type stJSON struct {
+	Env         stEnv               `json:"env"`
+	Pre         sim.Alloc           `json:"pre"`
+	Transaction stTransaction       `json:"transaction"`
+	Post        map[string][]stPost `json:"post"`
+}
+
+// StateTest is one test of a GeneralStateTests fixture file, it holds a case per fork and
+// post entry.
+type StateTest struct {
+	Name string
+	File string
+	json stJSON
+}
+
+// Subtest selects a case of a StateTest.
+type Subtest struct {
+	Fork  string
+	Index int
+}
+
+func (s Subtest) String() string {
+	return fmt.Sprintf("%s/%d", s.Fork, s.Index)
+}
+
+// LoadStateTests reads a fixture file, or every .json file under a directory.
+// Tests are returned sorted by file and name.
+func LoadStateTests(path string) ([]*StateTest, error) {
+	files, err := fixtureFiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tests []*StateTest
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		var fixtures map[string]stJSON
+		if err = json.Unmarshal(data, &fixtures); err != nil {
+			return nil, fmt.Errorf("invalid state test %s: %w", file, err)
+		}
+
+		names := make([]string, 0, len(fixtures))
+		for name := range fixtures {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			tests = append(tests, &StateTest{Name: name, File: file, json: fixtures[name]})
+		}
+	}
+
+	return tests, nil
+}
+
+// Subtests lists the cases of every fork in the fixture, supported or not.
+func (t *StateTest) Subtests() []Subtest {
+	forks := make([]string, 0, len(t.json.Post))
+	for fork := range t.json.Post {
+		forks = append(forks, fork)
+	}
+	sort.Strings(forks)
+
+	var subtests []Subtest
+	for _, fork := range forks {
+		for i := range t.json.Post[fork] {
+			subtests = append(subtests, Subtest{Fork: fork, Index: i})
+		}
+	}
+
+	return subtests
+}
+
+func (t *StateTest) blockEnv() (*sim.BlockEnv, error) {
+	coinbase, err := parseAddress(t.json.Env.Coinbase)
+	if err != nil {
+		return nil, err
+	}
+
+	env := &sim.BlockEnv{
+		Coinbase:   coinbase,
+		GasLimit:   uint64(t.json.Env.GasLimit),
+		Number:     uint64(t.json.Env.Number),
+		Timestamp:  uint64(t.json.Env.Timestamp),
+		ChainID:    evmInt256.New(1),
+		BaseFee:    t.json.Env.BaseFee.value(),
+		Difficulty: t.json.Env.Random.value(),
+	}
+
+	if env.Difficulty == nil {
+		env.Difficulty = t.json.Env.Difficulty.value()
+	}
+
+	if t.json.Env.ExcessBlobGas != nil {
+		env.ExcessBlobGas = uint64(*t.json.Env.ExcessBlobGas)
+	}
+
+	return env, nil
+}
+
+func (t *StateTest) message(post *stPost) (*sim.Message, error) {
+	tx := &t.json.Transaction
+	idx := post.Indexes
+	if idx.Data >= len(tx.Data) || idx.Gas >= len(tx.GasLimit) || idx.Value >= len(tx.Value) {
+		return nil, fmt.Errorf("indexes %+v out of range", idx)
+	}
+
+	msg := &sim.Message{
+		Nonce:         uint64(tx.Nonce),
+		GasLimit:      uint64(tx.GasLimit[idx.Gas]),
+		Value:         tx.Value[idx.Value].value(),
+		Data:          tx.Data[idx.Data],
+		GasPrice:      tx.GasPrice.value(),
+		GasFeeCap:     tx.MaxFeePerGas.value(),
+		GasTipCap:     tx.MaxPriorityFeePerGas.value(),
+		BlobHashes:    tx.BlobVersionedHashes,
+		BlobGasFeeCap: tx.MaxFeePerBlobGas.value(),
+	}
+
+	if tx.Sender != "" {
+		sender, err := parseAddress(tx.Sender)
+		if err != nil {
+			return nil, err
+		}
+		msg.From = sender
+	} else {
+		key, err := crypto.ToECDSA(tx.SecretKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret key: %w", err)
+		}
+		msg.From = types.Address(crypto.PubkeyToAddress(key.PublicKey))
+	}
+
+	if tx.To != "" {
+		to, err := parseAddress(tx.To)
+		if err != nil {
+			return nil, err
+		}
+		msg.To = &to
+	}
+
+	if idx.Data < len(tx.AccessLists) {
+		for _, tuple := range tx.AccessLists[idx.Data] {
+			addr, err := parseAddress(tuple.Address)
+			if err != nil {
+				return nil, err
+			}
+
+			entry := sim.AccessTuple{Address: addr}
+			for _, key := range tuple.StorageKeys {
+				slot := bigNumber{}
+				if err = slot.UnmarshalJSON([]byte(`"` + key + `"`)); err != nil {
+					return nil, err
+				}
+				entry.StorageKeys = append(entry.StorageKeys, types.Int256ToSlot(slot.Int))
+			}
+
+			msg.AccessList = append(msg.AccessList, entry)
+		}
+	}
+
+	return msg, nil
+}
+
+// Run executes a case and checks the post state root and logs hash. Cases of unsupported
+// forks come back skipped.
+func (t *StateTest) Run(subtest Subtest) *Result {
+	result := &Result{
+		Name:    t.Name,
+		File:    t.File,
+		Subtest: subtest,
+	}
+
+	posts := t.json.Post[subtest.Fork]
+	if subtest.Index >= len(posts) {
+		result.Error = fmt.Sprintf("no post entry %s", subtest)
+		return result
+	}
+
+	post := &posts[subtest.Index]
+	result.Indexes = post.Indexes
+	result.ExpectedRoot = post.Root
+	result.ExpectedLogs = post.Logs
+
+	if !IsSupportedFork(subtest.Fork) {
+		result.Skipped = true
+		return result
+	}
+
+	env, err := t.blockEnv()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	msg, err := t.message(post)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	pre := sim.NewState(t.json.Pre)
+	state := sim.FakeHashState{DB: pre.Copy()}
+	txResult, txErr := sim.ApplyMessage(state, env, msg)
+
+	var logs []*types.Log
+	if txErr == nil {
+		logs = txResult.Logs
+	}
+
+	result.Root = sim.StateRoot(state.DB)
+	result.LogsHash = sim.LogsHash(logs)
+
+	var problems []string
+	if post.ExpectException != "" && txErr == nil {
+		problems = append(problems, fmt.Sprintf("expected exception %s, the transaction was valid", post.ExpectException))
+	}
+
+	if post.ExpectException == "" && txErr != nil {
+		problems = append(problems, fmt.Sprintf("unexpected invalid transaction: %v", txErr))
+	}
+
+	if result.Root != result.ExpectedRoot {
+		problems = append(problems, fmt.Sprintf("post state root mismatch: got %s, want %s", result.Root, result.ExpectedRoot))
+		if post.State != nil {
+			result.Diff = DiffAlloc(sim.Dump(state.DB), post.State)
+		} else {
+			result.Diff = "the fixture has no post state, accounts changed by the transaction:\n" + DiffAlloc(sim.Dump(state.DB), sim.Dump(pre))
+		}
+	}
+
+	if result.LogsHash != result.ExpectedLogs {
+		problems = append(problems, fmt.Sprintf("logs hash mismatch: got %s, want %s", result.LogsHash, result.ExpectedLogs))
+	}
+
+	if txResult != nil && txResult.Err != nil {
+		result.ExecutionError = txResult.Err.Error()
+	}
+
+	result.Pass = len(problems) == 0
+	result.Error = strings.Join(problems, "; ")
+	return result
+}
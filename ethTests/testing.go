@@ -0,0 +1,85 @@
+package ethTests
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// RunStateTestsT runs the fixtures under path as sub tests of t, named "test/fork/index",
+// and logs the per-fork pass rates at the end. t is skipped when path does not exist, so
+// a test can point at an optional checkout of ethereum/tests:
+//
+//	func TestGeneralStateTests(t *testing.T) {
+//		ethTests.RunStateTestsT(t, "testdata/GeneralStateTests")
+//	}
+func RunStateTestsT(t *testing.T, path string) {
+	t.Helper()
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		t.Skipf("no state tests at %s", path)
+	}
+
+	tests, err := LoadStateTests(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := NewReport()
+	for _, test := range tests {
+		test := test
+		for _, subtest := range test.Subtests() {
+			subtest := subtest
+			t.Run(test.Name+"/"+subtest.String(), func(t *testing.T) {
+				result := test.Run(subtest)
+				report.Add(result)
+
+				switch {
+				case result.Skipped:
+					t.Skipf("unsupported fork %s", subtest.Fork)
+				case !result.Pass:
+					t.Error(result.String())
+				}
+			})
+		}
+	}
+
+	summary := &bytes.Buffer{}
+	_ = report.WriteSummary(summary)
+	t.Logf("state tests pass rates:\n%s", summary.String())
+}
+
+// RunBlockchainTestsT runs the BlockchainTests fixtures under path as sub tests of t, as
+// RunStateTestsT does for the state tests.
+func RunBlockchainTestsT(t *testing.T, path string) {
+	t.Helper()
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		t.Skipf("no blockchain tests at %s", path)
+	}
+
+	tests, err := LoadBlockchainTests(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := NewReport()
+	for _, test := range tests {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			result := test.Run()
+			report.count(result.Network, result.Pass, result.Skipped)
+
+			switch {
+			case result.Skipped:
+				t.Skipf("unsupported fork %s", result.Network)
+			case !result.Pass:
+				t.Error(result.String())
+			}
+		})
+	}
+
+	summary := &bytes.Buffer{}
+	_ = report.WriteSummary(summary)
+	t.Logf("blockchain tests pass rates:\n%s", summary.String())
+}
@@ -0,0 +1,128 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/SealSC/SealEVM/abi"
+	"github.com/SealSC/SealEVM/types"
+)
+
+// Arg is a decoded argument of an event. Indexed strings, bytes, arrays and tuples are the
+// types.Hash of their encoding, as in the topic.
+type Arg struct {
+	Name    string      `json:"name"`
+	Type    string      `json:"type"`
+	Indexed bool        `json:"indexed"`
+	Value   interface{} `json:"value"`
+}
+
+// Event is a decoded log. Index is the position of the log in the list decoded, Standard
+// names the token standards of a built-in event, as "ERC-20". Err is set, and the event
+// fields left empty, for a log which could not be decoded.
+type Event struct {
+	Index     int           `json:"index"`
+	Address   types.Address `json:"address"`
+	Name      string        `json:"name,omitempty"`
+	Signature string        `json:"signature,omitempty"`
+	Anonymous bool          `json:"anonymous,omitempty"`
+	Standard  string        `json:"standard,omitempty"`
+	Args      []Arg         `json:"args,omitempty"`
+
+	Log *types.Log `json:"-"`
+	ABI *abi.Event `json:"-"`
+	Err error      `json:"-"`
+}
+
+func newEvent(log *types.Log, e *abi.Event, values []interface{}) *Event {
+	ev := &Event{
+		Address:   log.Address,
+		Name:      e.RawName,
+		Signature: e.Sig,
+		Anonymous: e.Anonymous,
+		Log:       log,
+		ABI:       e,
+	}
+
+	for i, arg := range e.Inputs {
+		t := arg.Type.String()
+		if arg.Indexed && (arg.Type.Dynamic() || arg.Type.Kind == abi.TupleTy || arg.Type.Kind == abi.ArrayTy) {
+			t = "bytes32"
+		}
+
+		ev.Args = append(ev.Args, Arg{Name: arg.Name, Type: t, Indexed: arg.Indexed, Value: values[i]})
+	}
+
+	return ev
+}
+
+// Indexed are the arguments carried in the topics.
+func (e *Event) Indexed() []Arg {
+	var args []Arg
+	for _, arg := range e.Args {
+		if arg.Indexed {
+			args = append(args, arg)
+		}
+	}
+
+	return args
+}
+
+// NonIndexed are the arguments carried in the log data.
+func (e *Event) NonIndexed() []Arg {
+	var args []Arg
+	for _, arg := range e.Args {
+		if !arg.Indexed {
+			args = append(args, arg)
+		}
+	}
+
+	return args
+}
+
+// Arg is the value of the argument name.
+func (e *Event) Arg(name string) (interface{}, bool) {
+	for _, arg := range e.Args {
+		if arg.Name == name {
+			return arg.Value, true
+		}
+	}
+
+	return nil, false
+}
+
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case []byte:
+		return fmt.Sprintf("0x%x", v)
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []interface{}:
+		values := make([]string, len(v))
+		for i, e := range v {
+			values[i] = formatValue(e)
+		}
+		return "[" + strings.Join(values, ", ") + "]"
+	}
+
+	return fmt.Sprint(v)
+}
+
+// String is the event as "Transfer(from: 0x.., to: 0x.., value: 100)".
+func (e *Event) String() string {
+	if e.Err != nil {
+		return fmt.Sprintf("log %d of %s: %s", e.Index, e.Address, e.Err)
+	}
+
+	args := make([]string, len(e.Args))
+	for i, arg := range e.Args {
+		name := arg.Name
+		if name == "" {
+			name = fmt.Sprintf("arg%d", i)
+		}
+
+		args[i] = name + ": " + formatValue(arg.Value)
+	}
+
+	return e.Name + "(" + strings.Join(args, ", ") + ")"
+}
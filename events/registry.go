@@ -0,0 +1,161 @@
+// Package events decodes the raw logs of executions, as in ResultCache.Logs, into named
+// events with their arguments, through a registry of the ABIs of the contracts.
+package events
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/SealSC/SealEVM/abi"
+	"github.com/SealSC/SealEVM/storage/cache"
+	"github.com/SealSC/SealEVM/types"
+)
+
+var ErrUnknownEvent = errors.New("unknown event")
+
+// Registry finds the event of a log. The ABI registered for the address of the log is
+// tried first, then the events registered by signature, in the order they were added,
+// then the built-in ERC-20, ERC-721 and ERC-1155 events. A candidate matches when its
+// indexed arguments fill the topics and its other arguments decode from the data, so
+// events sharing a signature, as the Transfer of ERC-20 and ERC-721, are told apart.
+// Anonymous events have no signature topic, they are only tried from the ABI of the
+// address and after all the others from AddABI.
+type Registry struct {
+	byAddress   map[types.Address]*abi.ABI
+	bySignature map[types.Hash][]*abi.Event
+	anonymous   []*abi.Event
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		byAddress:   map[types.Address]*abi.ABI{},
+		bySignature: map[types.Hash][]*abi.Event{},
+	}
+}
+
+func sortedEvents(contract *abi.ABI) []string {
+	names := make([]string, 0, len(contract.Events))
+	for name := range contract.Events {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Register sets the ABI of the contract at address, its events are added by signature too.
+func (r *Registry) Register(address types.Address, contract *abi.ABI) {
+	r.byAddress[address] = contract
+	r.AddABI(contract)
+}
+
+// AddABI adds the events of contract by signature, for the logs of any address.
+func (r *Registry) AddABI(contract *abi.ABI) {
+	for _, name := range sortedEvents(contract) {
+		r.AddEvent(contract.Events[name])
+	}
+}
+
+// AddEvent adds e by signature, an event already known with the same layout is skipped.
+func (r *Registry) AddEvent(e *abi.Event) {
+	if e.Anonymous {
+		for _, known := range r.anonymous {
+			if sameLayout(known, e) && known.Sig == e.Sig {
+				return
+			}
+		}
+
+		r.anonymous = append(r.anonymous, e)
+		return
+	}
+
+	for _, known := range r.bySignature[e.ID] {
+		if sameLayout(known, e) {
+			return
+		}
+	}
+
+	r.bySignature[e.ID] = append(r.bySignature[e.ID], e)
+}
+
+// match decodes log as the first candidate fitting it.
+func match(log *types.Log, candidates []*abi.Event) (*abi.Event, []interface{}) {
+	for _, e := range candidates {
+		if values, err := e.ParseLog(log); err == nil {
+			return e, values
+		}
+	}
+
+	return nil, nil
+}
+
+// Decode decodes one log, the returned event has Index 0.
+func (r *Registry) Decode(log *types.Log) (*Event, error) {
+	var topic types.Hash
+	if len(log.Topics) > 0 {
+		topic = log.Topics[0]
+	}
+
+	if contract := r.byAddress[log.Address]; contract != nil {
+		var candidates, anonymous []*abi.Event
+		for _, name := range sortedEvents(contract) {
+			e := contract.Events[name]
+			if e.Anonymous {
+				anonymous = append(anonymous, e)
+			} else if e.ID == topic && len(log.Topics) > 0 {
+				candidates = append(candidates, e)
+			}
+		}
+
+		if e, values := match(log, append(candidates, anonymous...)); e != nil {
+			return newEvent(log, e, values), nil
+		}
+	}
+
+	if len(log.Topics) > 0 {
+		if e, values := match(log, r.bySignature[topic]); e != nil {
+			return newEvent(log, e, values), nil
+		}
+
+		for _, std := range standardEvents[topic] {
+			if values, err := std.event.ParseLog(log); err == nil {
+				ev := newEvent(log, std.event, values)
+				ev.Standard = std.standard
+				return ev, nil
+			}
+		}
+	}
+
+	if e, values := match(log, r.anonymous); e != nil {
+		return newEvent(log, e, values), nil
+	}
+
+	return nil, fmt.Errorf("%w: %d topics from %s", ErrUnknownEvent, len(log.Topics), log.Address)
+}
+
+// DecodeLogs decodes logs, one event each with its position in Index. The logs which
+// could not be decoded give an event with Err set.
+func (r *Registry) DecodeLogs(logs []*types.Log) []*Event {
+	decoded := make([]*Event, len(logs))
+	for i, log := range logs {
+		ev, err := r.Decode(log)
+		if err != nil {
+			ev = &Event{Address: log.Address, Log: log, Err: err}
+		}
+
+		ev.Index = i
+		decoded[i] = ev
+	}
+
+	return decoded
+}
+
+// DecodeResult decodes the logs of an execution result.
+func (r *Registry) DecodeResult(result *cache.ResultCache) []*Event {
+	if result == nil || result.Logs == nil {
+		return nil
+	}
+
+	return r.DecodeLogs(*result.Logs)
+}
@@ -0,0 +1,79 @@
+package events
+
+import (
+	"github.com/SealSC/SealEVM/abi"
+	"github.com/SealSC/SealEVM/types"
+)
+
+// The events of the token standards, known to every registry. ERC-20 and ERC-721 share
+// the signatures of Transfer and Approval, their logs differ in the number of topics.
+const (
+	ERC20ABI = `[
+{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256"}]},
+{"type":"event","name":"Approval","inputs":[{"name":"owner","type":"address","indexed":true},{"name":"spender","type":"address","indexed":true},{"name":"value","type":"uint256"}]}
+]`
+
+	ERC721ABI = `[
+{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"tokenId","type":"uint256","indexed":true}]},
+{"type":"event","name":"Approval","inputs":[{"name":"owner","type":"address","indexed":true},{"name":"approved","type":"address","indexed":true},{"name":"tokenId","type":"uint256","indexed":true}]},
+{"type":"event","name":"ApprovalForAll","inputs":[{"name":"owner","type":"address","indexed":true},{"name":"operator","type":"address","indexed":true},{"name":"approved","type":"bool"}]}
+]`
+
+	ERC1155ABI = `[
+{"type":"event","name":"TransferSingle","inputs":[{"name":"operator","type":"address","indexed":true},{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"id","type":"uint256"},{"name":"value","type":"uint256"}]},
+{"type":"event","name":"TransferBatch","inputs":[{"name":"operator","type":"address","indexed":true},{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"ids","type":"uint256[]"},{"name":"values","type":"uint256[]"}]},
+{"type":"event","name":"ApprovalForAll","inputs":[{"name":"account","type":"address","indexed":true},{"name":"operator","type":"address","indexed":true},{"name":"approved","type":"bool"}]},
+{"type":"event","name":"URI","inputs":[{"name":"value","type":"string"},{"name":"id","type":"uint256","indexed":true}]}
+]`
+)
+
+var (
+	ERC20   = abi.MustParse(ERC20ABI)
+	ERC721  = abi.MustParse(ERC721ABI)
+	ERC1155 = abi.MustParse(ERC1155ABI)
+)
+
+type standardEvent struct {
+	event    *abi.Event
+	standard string
+}
+
+// standardEvents are the candidates of the standard events by topic, in the order of the
+// standards. ApprovalForAll is the same event in ERC-721 and ERC-1155, it is kept once.
+var standardEvents = func() map[types.Hash][]standardEvent {
+	events := map[types.Hash][]standardEvent{}
+	for _, std := range []struct {
+		name string
+		abi  *abi.ABI
+	}{{"ERC-20", ERC20}, {"ERC-721", ERC721}, {"ERC-1155", ERC1155}} {
+	next:
+		for _, name := range sortedEvents(std.abi) {
+			e := std.abi.Events[name]
+			for i, known := range events[e.ID] {
+				if sameLayout(known.event, e) {
+					events[e.ID][i].standard += ", " + std.name
+					continue next
+				}
+			}
+
+			events[e.ID] = append(events[e.ID], standardEvent{event: e, standard: std.name})
+		}
+	}
+
+	return events
+}()
+
+// sameLayout tells whether logs of a and b can not be told apart.
+func sameLayout(a *abi.Event, b *abi.Event) bool {
+	if a.ID != b.ID || a.Anonymous != b.Anonymous || len(a.Inputs) != len(b.Inputs) {
+		return false
+	}
+
+	for i := range a.Inputs {
+		if a.Inputs[i].Indexed != b.Inputs[i].Indexed {
+			return false
+		}
+	}
+
+	return true
+}
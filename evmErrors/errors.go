@@ -38,6 +38,7 @@ var RevertErr = errors.New("revert")
 var BN256BadPairingInput = errors.New("bn256 bad pairing input")
 var InvalidExternalStorageResult = errors.New("external storage return invalid values")
 var ExternalStorageIsNil = errors.New("external storage is nil")
+var ExecutionAborted = errors.New("execution aborted")
 
 func Panicked(err error) error {
 	return errors.New("panic error: " + err.Error())
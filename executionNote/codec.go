@@ -0,0 +1,127 @@
+package executionNote
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmErrors"
+	"github.com/SealSC/SealEVM/storage/cache"
+	"github.com/SealSC/SealEVM/types"
+)
+
+// NoteSchemaVersion is the version of the JSON and binary note encodings written by this
+// package. Documents of the legacy schema (a Note marshaled by encoding/json directly, no
+// version) and of any version up to NoteSchemaVersion are readable.
+const NoteSchemaVersion = 1
+
+var ErrUnsupportedNoteVersion = errors.New("unsupported note schema version")
+var ErrCorruptedNote = errors.New("corrupted note")
+
+// ErrLegacyExecutionError stands for the execution error of a legacy note, encoding/json
+// did not keep the error message.
+var ErrLegacyExecutionError = errors.New("execution error (message not kept by legacy note)")
+
+// knownErrors restores the sentinel errors of evmErrors and ErrLegacyExecutionError, so
+// errors.Is still works on decoded notes.
+var knownErrors = map[string]error{}
+
+func init() {
+	for _, err := range []error{
+		evmErrors.StackUnderFlow,
+		evmErrors.StackOverFlow,
+		evmErrors.ClosureDepthOverflow,
+		evmErrors.StorageNotInitialized,
+		evmErrors.InvalidEVMInstance,
+		evmErrors.ReturnDataCopyOutOfBounds,
+		evmErrors.JumpOutOfBounds,
+		evmErrors.InvalidJumpDest,
+		evmErrors.JumpToNoneOpCode,
+		evmErrors.OutOfGas,
+		evmErrors.InsufficientBalance,
+		evmErrors.WriteProtection,
+		evmErrors.RevertErr,
+		evmErrors.BN256BadPairingInput,
+		evmErrors.InvalidExternalStorageResult,
+		evmErrors.ExternalStorageIsNil,
+		evmErrors.ExecutionAborted,
+		evmErrors.OutOfMemory,
+		ErrLegacyExecutionError,
+	} {
+		knownErrors[err.Error()] = err
+	}
+}
+
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	return err.Error()
+}
+
+func restoreError(msg string) error {
+	if msg == "" {
+		return nil
+	}
+
+	if err, ok := knownErrors[msg]; ok {
+		return err
+	}
+
+	return errors.New(msg)
+}
+
+func executionTypeFromName(name string) (ExecutionType, bool) {
+	for t, n := range executionTypeNames {
+		if n == name {
+			return t, true
+		}
+	}
+
+	return 0, false
+}
+
+// setConfig shares cfg with the whole tree, as GenSubNote does.
+func (n *Note) setConfig(cfg *NoteConfig) {
+	n.config = cfg
+	for _, sub := range n.SubNotes {
+		sub.setConfig(cfg)
+	}
+}
+
+// newDecodedCache returns an empty ResultCache with all maps allocated.
+func newDecodedCache() *cache.ResultCache {
+	c := cache.NewResultCache()
+	return &c
+}
+
+func sortedAddresses[V any](m map[types.Address]V) []types.Address {
+	keys := make([]types.Address, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return string(keys[i][:]) < string(keys[j][:])
+	})
+
+	return keys
+}
+
+func sortedSlots[V any](m map[types.Slot]V) []types.Slot {
+	keys := make([]types.Slot, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return string(keys[i][:]) < string(keys[j][:])
+	})
+
+	return keys
+}
+
+func newDecodedAccount(address types.Address) *environment.Account {
+	return environment.NewAccount(address, nil, nil)
+}
@@ -0,0 +1,327 @@
+package executionNote
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmErrors"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/opcodes"
+	"github.com/SealSC/SealEVM/storage/cache"
+	"github.com/SealSC/SealEVM/types"
+)
+
+func address(b byte) types.Address {
+	var a types.Address
+	a[len(a)-1] = b
+	return a
+}
+
+func slot(b byte) types.Slot {
+	var s types.Slot
+	s[len(s)-1] = b
+	return s
+}
+
+// noteTree is an external call with a call below it, which made a create that faulted.
+func noteTree() *Note {
+	caller, callee, created := address(1), address(2), address(3)
+
+	result := cache.NewResultCache()
+	original := environment.NewAccount(callee, evmInt256.New(10), nil)
+	original.Slots[slot(1)] = evmInt256.New(0)
+	result.OriginalAccounts[callee] = original
+
+	code := types.Bytes{byte(opcodes.PUSH1), 0x2a, byte(opcodes.STOP)}
+	account := environment.NewAccount(callee, evmInt256.New(7), &environment.Contract{Code: code, CodeSize: uint64(len(code))})
+	account.Slots[slot(1)] = evmInt256.New(42)
+	result.CachedAccounts[callee] = account
+	result.NewContractAccounts[callee] = account
+	result.Destructs[created] = caller
+	result.DataBlockCache[callee] = types.DataBlock{slot(2): types.Bytes{1, 2, 3}}
+
+	log := &types.Log{Address: callee, Topics: []types.Topic{slot(9)}, Data: types.Bytes{0xff}}
+	*result.Logs = append(*result.Logs, log)
+
+	create := &Note{
+		Type:           Create2,
+		From:           callee,
+		Gas:            30000,
+		Val:            evmInt256.New(0),
+		Input:          types.Bytes{0xfe},
+		ExecutionError: evmErrors.InvalidOpCode(0xfe),
+		Depth:          2,
+		GasUsed:        30000,
+		Fault: &FaultReport{
+			Error:        "invalid op code: 0xFE",
+			PC:           0,
+			OpCode:       0xfe,
+			Address:      created,
+			Depth:        2,
+			GasLeft:      30000,
+			Stack:        []*evmInt256.Int{evmInt256.New(1), evmInt256.New(2)},
+			Memory:       types.Bytes{0, 1},
+			MemoryOffset: 30,
+			MemorySize:   32,
+			Frames: []FaultFrame{
+				{Type: ExternalCall, Depth: 0, From: caller, To: callee, PC: 12, GasLeft: 90000},
+				{Type: Create2, Depth: 2, From: callee, To: created, PC: 0, GasLeft: 30000},
+			},
+		},
+	}
+
+	call := &Note{
+		Type:           Call,
+		From:           caller,
+		To:             &callee,
+		Gas:            60000,
+		Val:            evmInt256.New(3),
+		Input:          types.Bytes{0xa9, 0x05},
+		ExecutionError: evmErrors.RevertErr,
+		ReturnData:     types.Bytes{0x08, 0xc3},
+		Depth:          1,
+		GasUsed:        50000,
+		Storage: []StorageRecord{
+			{Address: callee, Slot: slot(1), Before: evmInt256.New(0), After: evmInt256.New(42), Written: true},
+		},
+		SubNotes: []*Note{create},
+	}
+
+	root := &Note{
+		Type:         ExternalCall,
+		From:         caller,
+		To:           &callee,
+		Gas:          100000,
+		Val:          evmInt256.New(5),
+		Input:        types.Bytes{1},
+		ReturnData:   types.Bytes{2},
+		StorageCache: &result,
+		GasUsed:      80000,
+		Logs:         []*types.Log{log},
+		SubNotes:     []*Note{call},
+	}
+
+	root.setConfig(&NoteConfig{
+		RecordCache:    true,
+		RecordLogs:     true,
+		RecordStorage:  true,
+		RecordGasUsed:  true,
+		MaxDepth:       4,
+		ExecutionTypes: []ExecutionType{Call, Create2},
+		Addresses:      []types.Address{callee},
+	})
+
+	return root
+}
+
+// checkTree checks what the JSON encoding does not tell, the sentinel errors and the
+// config shared by the tree.
+func checkTree(t *testing.T, n *Note) {
+	t.Helper()
+
+	if len(n.SubNotes) != 1 || len(n.SubNotes[0].SubNotes) != 1 {
+		t.Fatalf("tree shape lost")
+	}
+
+	call, create := n.SubNotes[0], n.SubNotes[0].SubNotes[0]
+	if !errors.Is(call.ExecutionError, evmErrors.RevertErr) {
+		t.Fatalf("call error %v, want %v", call.ExecutionError, evmErrors.RevertErr)
+	}
+
+	if create.ExecutionError == nil || create.ExecutionError.Error() != evmErrors.InvalidOpCode(0xfe).Error() {
+		t.Fatalf("create error %v", create.ExecutionError)
+	}
+
+	if n.Config() == nil || create.Config() != n.Config() || n.Config().MaxDepth != 4 {
+		t.Fatalf("config not shared by the tree")
+	}
+
+	if n.StorageCache.NewContractAccounts[address(2)] != n.StorageCache.CachedAccounts[address(2)] {
+		t.Fatalf("new contract does not point to its cached account")
+	}
+}
+
+func TestNoteJSONRoundTrip(t *testing.T) {
+	data, err := json.Marshal(noteTree())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := &Note{}
+	if err = json.Unmarshal(data, decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	checkTree(t, decoded)
+
+	again, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(data, again) {
+		t.Fatalf("round trip changed the note\nhave %s\nwant %s", again, data)
+	}
+}
+
+func TestNoteBinaryRoundTrip(t *testing.T) {
+	note := noteTree()
+	data, err := note.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := &Note{}
+	if err = decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+
+	checkTree(t, decoded)
+
+	again, err := decoded.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(data, again) {
+		t.Fatalf("round trip changed the binary note")
+	}
+
+	want, _ := json.Marshal(note)
+	have, _ := json.Marshal(decoded)
+	if !bytes.Equal(want, have) {
+		t.Fatalf("binary round trip changed the note\nhave %s\nwant %s", have, want)
+	}
+
+	for _, n := range []int{0, 3, len(data) / 2, len(data) - 1} {
+		if err = (&Note{}).UnmarshalBinary(data[:n]); err == nil {
+			t.Fatalf("truncated note of %d bytes decoded", n)
+		}
+	}
+}
+
+// legacyNote is a note tree as encoding/json wrote the Note struct before the versioned
+// schema, errors were written as {}.
+const legacyNote = `{
+	"Type": 0,
+	"From": "0x0000000000000000000000000000000000000001",
+	"To": "0x0000000000000000000000000000000000000002",
+	"Gas": 100000,
+	"Val": "0x5",
+	"Input": "0x01",
+	"ExecutionError": null,
+	"ReturnData": "0x02",
+	"StorageCache": {
+		"OriginalAccounts": {
+			"0x0000000000000000000000000000000000000002": {
+				"Address": "0x0000000000000000000000000000000000000002",
+				"Balance": "0xa",
+				"Contract": null,
+				"Slots": null
+			}
+		},
+		"CachedAccounts": {
+			"0x0000000000000000000000000000000000000002": {
+				"Address": "0x0000000000000000000000000000000000000002",
+				"Balance": "0x7",
+				"Contract": {"Code": "0x602a00", "CodeHash": "0x0000000000000000000000000000000000000000000000000000000000000000", "CodeSize": 3, "InitCode": "0x"},
+				"Slots": {"0x0000000000000000000000000000000000000000000000000000000000000001": "0x2a"}
+			}
+		},
+		"NewContractAccounts": {
+			"0x0000000000000000000000000000000000000002": {
+				"Address": "0x0000000000000000000000000000000000000002",
+				"Balance": "0x7",
+				"Contract": null,
+				"Slots": null
+			}
+		},
+		"Logs": [],
+		"Destructs": {},
+		"DataBlockCache": {}
+	},
+	"Fault": null,
+	"Depth": 0,
+	"GasUsed": 80000,
+	"Logs": null,
+	"Storage": null,
+	"SubNotes": [{
+		"Type": 241,
+		"From": "0x0000000000000000000000000000000000000001",
+		"To": "0x0000000000000000000000000000000000000002",
+		"Gas": 60000,
+		"Val": "0x3",
+		"Input": "0xa905",
+		"ExecutionError": {},
+		"ReturnData": "0x08c3",
+		"StorageCache": null,
+		"Fault": null,
+		"Depth": 1,
+		"GasUsed": 50000,
+		"Logs": null,
+		"Storage": null,
+		"SubNotes": null
+	}]
+}`
+
+func TestNoteDecodesLegacyJSON(t *testing.T) {
+	n := &Note{}
+	if err := json.Unmarshal([]byte(legacyNote), n); err != nil {
+		t.Fatal(err)
+	}
+
+	if n.Gas != 100000 || n.Val.Uint64() != 5 || n.To == nil || *n.To != address(2) || n.ExecutionError != nil {
+		t.Fatalf("legacy root decoded wrong: %+v", n)
+	}
+
+	if len(n.SubNotes) != 1 {
+		t.Fatalf("legacy sub note lost")
+	}
+
+	call := n.SubNotes[0]
+	if call.Type != Call || call.Depth != 1 || !errors.Is(call.ExecutionError, ErrLegacyExecutionError) {
+		t.Fatalf("legacy sub note decoded wrong: %+v", call)
+	}
+
+	acc := n.StorageCache.CachedAccounts[address(2)]
+	if acc == nil || acc.Slots[slot(1)].Uint64() != 42 || acc.Contract == nil || acc.Contract.CodeSize != 3 {
+		t.Fatalf("legacy cache decoded wrong")
+	}
+
+	if n.StorageCache.NewContractAccounts[address(2)] != acc {
+		t.Fatalf("legacy new contract does not point to its cached account")
+	}
+
+	if n.StorageCache.OriginalAccounts[address(2)].Slots == nil {
+		t.Fatalf("legacy account without slots")
+	}
+
+	if !n.Config().RecordCache || call.Config() != n.Config() {
+		t.Fatalf("legacy config not restored")
+	}
+
+	//a decoded legacy note is written in the current schema
+	data, err := json.Marshal(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	current := &Note{}
+	if err = json.Unmarshal(data, current); err != nil {
+		t.Fatal(err)
+	}
+
+	if !errors.Is(current.SubNotes[0].ExecutionError, ErrLegacyExecutionError) {
+		t.Fatalf("legacy error lost in the current schema: %v", current.SubNotes[0].ExecutionError)
+	}
+}
+
+func TestNoteRejectsNewerVersion(t *testing.T) {
+	err := json.Unmarshal([]byte(`{"version":99,"note":{}}`), &Note{})
+	if !errors.Is(err, ErrUnsupportedNoteVersion) {
+		t.Fatalf("got %v, want %v", err, ErrUnsupportedNoteVersion)
+	}
+}
@@ -0,0 +1,44 @@
+package executionNote
+
+import (
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/opcodes"
+	"github.com/SealSC/SealEVM/types"
+)
+
+const MaxFaultMemoryExcerpt = 1024
+
+// FaultFrame is one frame of the call chain leading to a fault, PC is the position the
+// frame was executing when the fault happened, for outer frames that is the call site.
+type FaultFrame struct {
+	Type    ExecutionType
+	Depth   uint64
+	From    types.Address
+	To      types.Address
+	PC      uint64
+	GasLeft uint64
+}
+
+// FaultReport describes an exceptional halt of a frame (any error other than REVERT).
+// Frames starts with the outermost frame and ends with the faulted one. A frame which failed
+// before running any code, on the intrinsic gas, the value transfer or a precompiled
+// contract, is reported at PC 0 with no opcode (STOP), stack or memory.
+type FaultReport struct {
+	Error   string
+	PC      uint64
+	OpCode  opcodes.OpCode
+	Address types.Address
+	Depth   uint64
+	GasLeft uint64
+
+	// Stack items from bottom to top
+	Stack []*evmInt256.Int
+
+	// Memory holds the last MaxFaultMemoryExcerpt bytes of the memory at most, starting
+	// from MemoryOffset, MemorySize is the full memory size
+	Memory       types.Bytes
+	MemoryOffset uint64
+	MemorySize   uint64
+
+	Frames []FaultFrame
+}
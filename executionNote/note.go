@@ -4,6 +4,7 @@ import (
 	"github.com/SealSC/SealEVM/environment"
 	"github.com/SealSC/SealEVM/evmInt256"
 	"github.com/SealSC/SealEVM/opcodes"
+	"github.com/SealSC/SealEVM/storage"
 	"github.com/SealSC/SealEVM/storage/cache"
 	"github.com/SealSC/SealEVM/types"
 )
@@ -36,7 +37,66 @@ func (e ExecutionType) String() string {
 }
 
 type NoteConfig struct {
-	RecordCache bool
+	RecordCache   bool
+	RecordLogs    bool
+	RecordStorage bool
+	RecordGasUsed bool
+
+	// sub frames deeper than MaxDepth are not recorded, 0 means no limit
+	MaxDepth uint64
+
+	// only sub frames of these types are recorded, all types if empty
+	ExecutionTypes []ExecutionType
+
+	// only sub frames from or to these addresses are recorded, all addresses if empty
+	Addresses []types.Address
+}
+
+// ShouldRecord reports whether a sub frame passes the depth limit and filters of the config.
+// The top frame is always recorded, a recorded sub frame is attached to the nearest recorded
+// ancestor.
+func (c *NoteConfig) ShouldRecord(execType ExecutionType, from types.Address, to *types.Address, depth uint64) bool {
+	if c.MaxDepth > 0 && depth > c.MaxDepth {
+		return false
+	}
+
+	if len(c.ExecutionTypes) > 0 {
+		matched := false
+		for _, t := range c.ExecutionTypes {
+			if t == execType {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	if len(c.Addresses) > 0 {
+		for _, addr := range c.Addresses {
+			if addr == from || (to != nil && addr == *to) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
+func (c *NoteConfig) JournalRequired() bool {
+	return c.RecordLogs || c.RecordStorage
+}
+
+type StorageRecord struct {
+	Address types.Address
+	Slot    types.Slot
+	Before  *evmInt256.Int
+	After   *evmInt256.Int
+	Written bool
 }
 
 type Note struct {
@@ -50,6 +110,12 @@ type Note struct {
 	ExecutionError error
 	ReturnData     types.Bytes
 	StorageCache   *cache.ResultCache
+	Fault          *FaultReport
+
+	Depth   uint64
+	GasUsed uint64
+	Logs    []*types.Log
+	Storage []StorageRecord
 
 	SubNotes []*Note
 
@@ -88,6 +154,69 @@ func (n *Note) SetResult(retData []byte, retErr error, storageCache cache.Result
 	}
 }
 
+func (n *Note) Config() *NoteConfig {
+	return n.config
+}
+
+// SetFrameRecord records the gas used, logs and storage accesses of the frame as configured.
+// The After value of a slot is taken from resultCache, it equals Before if the frame failed.
+// The logs of a failed frame are reverted with it, they are dropped with those of its sub
+// notes, SetResult must be called first.
+func (n *Note) SetFrameRecord(journal *storage.FrameJournal, resultCache cache.ResultCache, gasUsed uint64) {
+	if n.config.RecordGasUsed {
+		n.GasUsed = gasUsed
+	}
+
+	if n.ExecutionError != nil {
+		n.dropLogs()
+	}
+
+	if journal == nil {
+		return
+	}
+
+	if n.config.RecordLogs && n.ExecutionError == nil {
+		n.Logs = journal.Logs
+	}
+
+	if n.config.RecordStorage {
+		n.Storage = make([]StorageRecord, len(journal.Accesses))
+		for i, access := range journal.Accesses {
+			after := resultCache.CachedAccounts.GetSlot(access.Address, access.Slot)
+			if after == nil {
+				after = access.Before
+			}
+
+			n.Storage[i] = StorageRecord{
+				Address: access.Address,
+				Slot:    access.Slot,
+				Before:  access.Before,
+				After:   after.Clone(),
+				Written: access.Written,
+			}
+		}
+	}
+}
+
+func (n *Note) dropLogs() {
+	n.Logs = nil
+	for _, subNote := range n.SubNotes {
+		subNote.dropLogs()
+	}
+}
+
+// DropLogsFrom drops the logs of the sub notes from the index from on, with those of their
+// own sub notes. They were attached to n under a frame which is not recorded and failed.
+func (n *Note) DropLogsFrom(from int) {
+	for i := from; i < len(n.SubNotes); i++ {
+		n.SubNotes[i].dropLogs()
+	}
+}
+
+func (n *Note) SetFault(fault *FaultReport) {
+	n.Fault = fault
+}
+
 func (n *Note) GenSubNote(execType ExecutionType, tx *environment.Transaction, msg *environment.Message) *Note {
 	newNote := New(n.config, execType, tx, msg)
 	n.SubNotes = append(n.SubNotes, newNote)
@@ -0,0 +1,613 @@
+package executionNote
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/opcodes"
+	"github.com/SealSC/SealEVM/storage/cache"
+	"github.com/SealSC/SealEVM/types"
+)
+
+// binary notes start with noteBinaryMagic followed by the schema version as an uvarint,
+// integers are uvarints, byte strings and lists are prefixed with their length.
+var noteBinaryMagic = []byte("SEVN")
+
+const (
+	intNil      byte = 0
+	intPositive byte = 1
+	intNegative byte = 2
+)
+
+const (
+	noteHasTo byte = 1 << iota
+	noteHasCache
+	noteHasFault
+)
+
+type noteWriter struct {
+	buf bytes.Buffer
+}
+
+func (w *noteWriter) uvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	w.buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func (w *noteWriter) byte(b byte) {
+	w.buf.WriteByte(b)
+}
+
+func (w *noteWriter) bool(b bool) {
+	if b {
+		w.byte(1)
+	} else {
+		w.byte(0)
+	}
+}
+
+func (w *noteWriter) bytes(b []byte) {
+	w.uvarint(uint64(len(b)))
+	w.buf.Write(b)
+}
+
+func (w *noteWriter) string(s string) {
+	w.bytes([]byte(s))
+}
+
+func (w *noteWriter) address(a types.Address) {
+	w.buf.Write(a[:])
+}
+
+func (w *noteWriter) hash(h types.Hash) {
+	w.buf.Write(h[:])
+}
+
+func (w *noteWriter) int(i *evmInt256.Int) {
+	if i == nil || i.Int == nil {
+		w.byte(intNil)
+		return
+	}
+
+	if i.Sign() < 0 {
+		w.byte(intNegative)
+	} else {
+		w.byte(intPositive)
+	}
+
+	w.bytes(i.Int.Bytes())
+}
+
+type noteReader struct {
+	data []byte
+	err  error
+}
+
+func (r *noteReader) fail() {
+	if r.err == nil {
+		r.err = ErrCorruptedNote
+	}
+}
+
+func (r *noteReader) next(n uint64) []byte {
+	if r.err != nil {
+		return nil
+	}
+
+	if n > uint64(len(r.data)) {
+		r.fail()
+		return nil
+	}
+
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *noteReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.fail()
+		return 0
+	}
+
+	r.data = r.data[n:]
+	return v
+}
+
+// count reads a list length, every element takes one byte at least.
+func (r *noteReader) count() int {
+	n := r.uvarint()
+	if n > uint64(len(r.data)) {
+		r.fail()
+		return 0
+	}
+
+	return int(n)
+}
+
+func (r *noteReader) byte() byte {
+	b := r.next(1)
+	if b == nil {
+		return 0
+	}
+
+	return b[0]
+}
+
+func (r *noteReader) bool() bool {
+	return r.byte() != 0
+}
+
+func (r *noteReader) bytes() types.Bytes {
+	b := r.next(r.uvarint())
+	if b == nil {
+		return nil
+	}
+
+	return types.Bytes(b).Clone()
+}
+
+func (r *noteReader) string() string {
+	return string(r.bytes())
+}
+
+func (r *noteReader) address() types.Address {
+	var a types.Address
+	copy(a[:], r.next(types.AddressBytesLen))
+	return a
+}
+
+func (r *noteReader) hash() types.Hash {
+	var h types.Hash
+	copy(h[:], r.next(types.HashBytesLen))
+	return h
+}
+
+func (r *noteReader) int() *evmInt256.Int {
+	flag := r.byte()
+	if flag == intNil {
+		return nil
+	}
+
+	if flag != intPositive && flag != intNegative {
+		r.fail()
+		return nil
+	}
+
+	v := new(big.Int).SetBytes(r.bytes())
+	if flag == intNegative {
+		v.Neg(v)
+	}
+
+	return &evmInt256.Int{Int: v}
+}
+
+func (r *noteReader) executionType() ExecutionType {
+	t := ExecutionType(r.byte())
+	if _, ok := executionTypeNames[t]; !ok {
+		r.fail()
+	}
+
+	return t
+}
+
+// MarshalBinary encodes the note tree with its config in the compact binary schema.
+func (n *Note) MarshalBinary() ([]byte, error) {
+	w := &noteWriter{}
+	w.buf.Write(noteBinaryMagic)
+	w.uvarint(NoteSchemaVersion)
+
+	cfg := n.config
+	if cfg == nil {
+		cfg = &NoteConfig{}
+	}
+
+	w.config(cfg)
+	w.note(n)
+	return w.buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a note tree written by MarshalBinary of this or an older version.
+func (n *Note) UnmarshalBinary(data []byte) error {
+	if !bytes.HasPrefix(data, noteBinaryMagic) {
+		return ErrCorruptedNote
+	}
+
+	r := &noteReader{data: data[len(noteBinaryMagic):]}
+	version := r.uvarint()
+	if r.err != nil {
+		return r.err
+	}
+
+	if version < 1 || version > NoteSchemaVersion {
+		return fmt.Errorf("%w: %d", ErrUnsupportedNoteVersion, version)
+	}
+
+	cfg := r.config()
+	note := r.note()
+	if r.err != nil {
+		return r.err
+	}
+
+	if len(r.data) != 0 {
+		return ErrCorruptedNote
+	}
+
+	*n = *note
+	n.setConfig(cfg)
+	return nil
+}
+
+func (w *noteWriter) config(cfg *NoteConfig) {
+	w.bool(cfg.RecordCache)
+	w.bool(cfg.RecordLogs)
+	w.bool(cfg.RecordStorage)
+	w.bool(cfg.RecordGasUsed)
+	w.uvarint(cfg.MaxDepth)
+
+	w.uvarint(uint64(len(cfg.ExecutionTypes)))
+	for _, t := range cfg.ExecutionTypes {
+		w.byte(byte(t))
+	}
+
+	w.uvarint(uint64(len(cfg.Addresses)))
+	for _, addr := range cfg.Addresses {
+		w.address(addr)
+	}
+}
+
+func (r *noteReader) config() *NoteConfig {
+	cfg := &NoteConfig{
+		RecordCache:   r.bool(),
+		RecordLogs:    r.bool(),
+		RecordStorage: r.bool(),
+		RecordGasUsed: r.bool(),
+		MaxDepth:      r.uvarint(),
+	}
+
+	for i, c := 0, r.count(); i < c; i++ {
+		cfg.ExecutionTypes = append(cfg.ExecutionTypes, r.executionType())
+	}
+
+	for i, c := 0, r.count(); i < c; i++ {
+		cfg.Addresses = append(cfg.Addresses, r.address())
+	}
+
+	return cfg
+}
+
+func (w *noteWriter) note(n *Note) {
+	var flags byte
+	if n.To != nil {
+		flags |= noteHasTo
+	}
+	if n.StorageCache != nil {
+		flags |= noteHasCache
+	}
+	if n.Fault != nil {
+		flags |= noteHasFault
+	}
+
+	w.byte(byte(n.Type))
+	w.byte(flags)
+	w.address(n.From)
+	if n.To != nil {
+		w.address(*n.To)
+	}
+
+	w.uvarint(n.Gas)
+	w.int(n.Val)
+	w.bytes(n.Input)
+	w.string(errorMessage(n.ExecutionError))
+	w.bytes(n.ReturnData)
+	w.uvarint(n.Depth)
+	w.uvarint(n.GasUsed)
+
+	if n.StorageCache != nil {
+		w.resultCache(n.StorageCache)
+	}
+
+	if n.Fault != nil {
+		w.fault(n.Fault)
+	}
+
+	w.logs(n.Logs)
+
+	w.uvarint(uint64(len(n.Storage)))
+	for _, s := range n.Storage {
+		w.address(s.Address)
+		w.hash(s.Slot)
+		w.int(s.Before)
+		w.int(s.After)
+		w.bool(s.Written)
+	}
+
+	w.uvarint(uint64(len(n.SubNotes)))
+	for _, sub := range n.SubNotes {
+		w.note(sub)
+	}
+}
+
+func (r *noteReader) note() *Note {
+	n := &Note{
+		Type: r.executionType(),
+	}
+
+	flags := r.byte()
+	n.From = r.address()
+	if flags&noteHasTo != 0 {
+		to := r.address()
+		n.To = &to
+	}
+
+	n.Gas = r.uvarint()
+	n.Val = r.int()
+	n.Input = r.bytes()
+	n.ExecutionError = restoreError(r.string())
+	n.ReturnData = r.bytes()
+	n.Depth = r.uvarint()
+	n.GasUsed = r.uvarint()
+
+	if flags&noteHasCache != 0 {
+		n.StorageCache = r.resultCache()
+	}
+
+	if flags&noteHasFault != 0 {
+		n.Fault = r.fault()
+	}
+
+	n.Logs = r.logs()
+
+	for i, c := 0, r.count(); i < c; i++ {
+		n.Storage = append(n.Storage, StorageRecord{
+			Address: r.address(),
+			Slot:    r.hash(),
+			Before:  r.int(),
+			After:   r.int(),
+			Written: r.bool(),
+		})
+	}
+
+	for i, c := 0, r.count(); i < c && r.err == nil; i++ {
+		n.SubNotes = append(n.SubNotes, r.note())
+	}
+
+	return n
+}
+
+func (w *noteWriter) logs(logs []*types.Log) {
+	w.uvarint(uint64(len(logs)))
+	for _, l := range logs {
+		w.address(l.Address)
+		w.uvarint(uint64(len(l.Topics)))
+		for _, topic := range l.Topics {
+			w.hash(topic)
+		}
+		w.bytes(l.Data)
+	}
+}
+
+func (r *noteReader) logs() []*types.Log {
+	var logs []*types.Log
+	for i, c := 0, r.count(); i < c; i++ {
+		l := &types.Log{
+			Address: r.address(),
+		}
+
+		tc := r.count()
+		l.Topics = make([]types.Topic, 0, tc)
+		for j := 0; j < tc; j++ {
+			l.Topics = append(l.Topics, r.hash())
+		}
+
+		l.Data = r.bytes()
+		logs = append(logs, l)
+	}
+
+	return logs
+}
+
+func (w *noteWriter) account(acc *environment.Account) {
+	w.address(acc.Address)
+	w.int(acc.Balance)
+
+	w.bool(acc.Contract != nil)
+	if acc.Contract != nil {
+		w.bytes(acc.Contract.Code)
+		w.hash(acc.Contract.CodeHash)
+		w.bytes(acc.Contract.InitCode)
+	}
+
+	slots := sortedSlots(acc.Slots)
+	w.uvarint(uint64(len(slots)))
+	for _, slot := range slots {
+		w.hash(slot)
+		w.int(acc.Slots[slot])
+	}
+}
+
+func (r *noteReader) account() *environment.Account {
+	acc := newDecodedAccount(r.address())
+	if balance := r.int(); balance != nil {
+		acc.Balance = balance
+	}
+
+	if r.bool() {
+		code := r.bytes()
+		acc.Contract = &environment.Contract{
+			Code:     code,
+			CodeHash: r.hash(),
+			CodeSize: uint64(len(code)),
+			InitCode: r.bytes(),
+		}
+	}
+
+	for i, c := 0, r.count(); i < c; i++ {
+		slot := r.hash()
+		acc.Slots[slot] = r.int()
+	}
+
+	return acc
+}
+
+func (w *noteWriter) accounts(accounts cache.AccountCache) {
+	addresses := make([]types.Address, 0, len(accounts))
+	for _, addr := range sortedAddresses(accounts) {
+		if accounts[addr] != nil {
+			addresses = append(addresses, addr)
+		}
+	}
+
+	w.uvarint(uint64(len(addresses)))
+	for _, addr := range addresses {
+		w.account(accounts[addr])
+	}
+}
+
+func (r *noteReader) accounts(accounts cache.AccountCache) {
+	for i, c := 0, r.count(); i < c && r.err == nil; i++ {
+		accounts.Set(r.account())
+	}
+}
+
+// resultCache encodes the persistent parts of a ResultCache as the JSON schema does.
+func (w *noteWriter) resultCache(c *cache.ResultCache) {
+	w.accounts(c.OriginalAccounts)
+	w.accounts(c.CachedAccounts)
+
+	newContracts := sortedAddresses(c.NewContractAccounts)
+	w.uvarint(uint64(len(newContracts)))
+	for _, addr := range newContracts {
+		w.address(addr)
+	}
+
+	var logs []*types.Log
+	if c.Logs != nil {
+		logs = *c.Logs
+	}
+	w.logs(logs)
+
+	destructs := sortedAddresses(c.Destructs)
+	w.uvarint(uint64(len(destructs)))
+	for _, addr := range destructs {
+		w.address(addr)
+		w.address(c.Destructs[addr])
+	}
+
+	blocks := sortedAddresses(c.DataBlockCache)
+	w.uvarint(uint64(len(blocks)))
+	for _, addr := range blocks {
+		block := c.DataBlockCache[addr]
+		keys := sortedSlots(block)
+
+		w.address(addr)
+		w.uvarint(uint64(len(keys)))
+		for _, key := range keys {
+			w.hash(key)
+			w.bytes(block[key])
+		}
+	}
+}
+
+func (r *noteReader) resultCache() *cache.ResultCache {
+	c := newDecodedCache()
+	r.accounts(c.OriginalAccounts)
+	r.accounts(c.CachedAccounts)
+
+	for i, n := 0, r.count(); i < n; i++ {
+		addr := r.address()
+		if acc := c.CachedAccounts.Get(addr); acc != nil {
+			c.NewContractAccounts[addr] = acc
+		}
+	}
+
+	*c.Logs = r.logs()
+
+	for i, n := 0, r.count(); i < n; i++ {
+		addr := r.address()
+		c.Destructs[addr] = r.address()
+	}
+
+	for i, n := 0, r.count(); i < n; i++ {
+		addr := r.address()
+		block := types.DataBlock{}
+		for j, bn := 0, r.count(); j < bn; j++ {
+			key := r.hash()
+			block[key] = r.bytes()
+		}
+		c.DataBlockCache[addr] = block
+	}
+
+	return c
+}
+
+func (w *noteWriter) fault(f *FaultReport) {
+	w.string(f.Error)
+	w.uvarint(f.PC)
+	w.byte(byte(f.OpCode))
+	w.address(f.Address)
+	w.uvarint(f.Depth)
+	w.uvarint(f.GasLeft)
+
+	w.uvarint(uint64(len(f.Stack)))
+	for _, item := range f.Stack {
+		w.int(item)
+	}
+
+	w.bytes(f.Memory)
+	w.uvarint(f.MemoryOffset)
+	w.uvarint(f.MemorySize)
+
+	w.uvarint(uint64(len(f.Frames)))
+	for _, frame := range f.Frames {
+		w.byte(byte(frame.Type))
+		w.uvarint(frame.Depth)
+		w.address(frame.From)
+		w.address(frame.To)
+		w.uvarint(frame.PC)
+		w.uvarint(frame.GasLeft)
+	}
+}
+
+func (r *noteReader) fault() *FaultReport {
+	f := &FaultReport{
+		Error:   r.string(),
+		PC:      r.uvarint(),
+		OpCode:  opcodes.OpCode(r.byte()),
+		Address: r.address(),
+		Depth:   r.uvarint(),
+		GasLeft: r.uvarint(),
+	}
+
+	c := r.count()
+	f.Stack = make([]*evmInt256.Int, 0, c)
+	for i := 0; i < c; i++ {
+		f.Stack = append(f.Stack, r.int())
+	}
+
+	f.Memory = r.bytes()
+	f.MemoryOffset = r.uvarint()
+	f.MemorySize = r.uvarint()
+
+	for i, c := 0, r.count(); i < c; i++ {
+		f.Frames = append(f.Frames, FaultFrame{
+			Type:    r.executionType(),
+			Depth:   r.uvarint(),
+			From:    r.address(),
+			To:      r.address(),
+			PC:      r.uvarint(),
+			GasLeft: r.uvarint(),
+		})
+	}
+
+	return f
+}
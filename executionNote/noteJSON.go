@@ -0,0 +1,577 @@
+package executionNote
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/opcodes"
+	"github.com/SealSC/SealEVM/storage/cache"
+	"github.com/SealSC/SealEVM/types"
+)
+
+type noteDocumentJSON struct {
+	Version int         `json:"version"`
+	Config  *configJSON `json:"config"`
+	Note    *noteJSON   `json:"note"`
+}
+
+type configJSON struct {
+	RecordCache    bool            `json:"recordCache"`
+	RecordLogs     bool            `json:"recordLogs"`
+	RecordStorage  bool            `json:"recordStorage"`
+	RecordGasUsed  bool            `json:"recordGasUsed"`
+	MaxDepth       uint64          `json:"maxDepth"`
+	ExecutionTypes []string        `json:"executionTypes,omitempty"`
+	Addresses      []types.Address `json:"addresses,omitempty"`
+}
+
+type noteJSON struct {
+	Type         string           `json:"type"`
+	From         types.Address    `json:"from"`
+	To           *types.Address   `json:"to"`
+	Gas          uint64           `json:"gas"`
+	Value        *evmInt256.Int   `json:"value"`
+	Input        types.Bytes      `json:"input"`
+	Error        string           `json:"error,omitempty"`
+	ReturnData   types.Bytes      `json:"returnData"`
+	StorageCache *resultCacheJSON `json:"storageCache,omitempty"`
+	Fault        *faultJSON       `json:"fault,omitempty"`
+	Depth        uint64           `json:"depth"`
+	GasUsed      uint64           `json:"gasUsed"`
+	Logs         []*logJSON       `json:"logs,omitempty"`
+	Storage      []*storageJSON   `json:"storage,omitempty"`
+	SubNotes     []*noteJSON      `json:"subNotes,omitempty"`
+}
+
+type logJSON struct {
+	Address types.Address `json:"address"`
+	Topics  []types.Topic `json:"topics"`
+	Data    types.Bytes   `json:"data"`
+}
+
+type storageJSON struct {
+	Address types.Address  `json:"address"`
+	Slot    types.Slot     `json:"slot"`
+	Before  *evmInt256.Int `json:"before"`
+	After   *evmInt256.Int `json:"after"`
+	Written bool           `json:"written"`
+}
+
+type accountJSON struct {
+	Balance  *evmInt256.Int                `json:"balance"`
+	Code     types.Bytes                   `json:"code,omitempty"`
+	CodeHash *types.Hash                   `json:"codeHash,omitempty"`
+	InitCode types.Bytes                   `json:"initCode,omitempty"`
+	Slots    map[types.Slot]*evmInt256.Int `json:"slots,omitempty"`
+}
+
+// resultCacheJSON keeps the persistent parts of a ResultCache, transient storage is
+// dropped at the end of every transaction and is not encoded.
+type resultCacheJSON struct {
+	OriginalAccounts map[types.Address]*accountJSON    `json:"originalAccounts"`
+	CachedAccounts   map[types.Address]*accountJSON    `json:"cachedAccounts"`
+	NewContracts     []types.Address                   `json:"newContracts,omitempty"`
+	Logs             []*logJSON                        `json:"logs,omitempty"`
+	Destructs        map[types.Address]types.Address   `json:"destructs,omitempty"`
+	DataBlocks       map[types.Address]types.DataBlock `json:"dataBlocks,omitempty"`
+}
+
+type faultFrameJSON struct {
+	Type    string        `json:"type"`
+	Depth   uint64        `json:"depth"`
+	From    types.Address `json:"from"`
+	To      types.Address `json:"to"`
+	PC      uint64        `json:"pc"`
+	GasLeft uint64        `json:"gasLeft"`
+}
+
+type faultJSON struct {
+	Error        string            `json:"error"`
+	PC           uint64            `json:"pc"`
+	OpCode       opcodes.OpCode    `json:"opCode"`
+	Address      types.Address     `json:"address"`
+	Depth        uint64            `json:"depth"`
+	GasLeft      uint64            `json:"gasLeft"`
+	Stack        []*evmInt256.Int  `json:"stack"`
+	Memory       types.Bytes       `json:"memory"`
+	MemoryOffset uint64            `json:"memoryOffset"`
+	MemorySize   uint64            `json:"memorySize"`
+	Frames       []*faultFrameJSON `json:"frames"`
+}
+
+// legacyNoteJSON is a Note as encoding/json wrote it before notes had their own schema.
+type legacyNoteJSON struct {
+	Type           ExecutionType
+	From           types.Address
+	To             *types.Address
+	Gas            uint64
+	Val            *evmInt256.Int
+	Input          types.Bytes
+	ExecutionError json.RawMessage
+	ReturnData     types.Bytes
+	StorageCache   *cache.ResultCache
+	Fault          *FaultReport
+	Depth          uint64
+	GasUsed        uint64
+	Logs           []*types.Log
+	Storage        []StorageRecord
+	SubNotes       []*Note
+}
+
+// MarshalJSON encodes the note tree with its config in the versioned JSON schema.
+func (n *Note) MarshalJSON() ([]byte, error) {
+	doc := noteDocumentJSON{
+		Version: NoteSchemaVersion,
+		Note:    n.toJSON(),
+	}
+
+	if n.config != nil {
+		doc.Config = configToJSON(n.config)
+	}
+
+	return json.Marshal(doc)
+}
+
+// UnmarshalJSON decodes a note tree of the versioned schema or of the legacy schema.
+func (n *Note) UnmarshalJSON(data []byte) error {
+	var header map[string]json.RawMessage
+	if err := json.Unmarshal(data, &header); err != nil {
+		return err
+	}
+
+	if _, versioned := header["version"]; !versioned {
+		legacy := &legacyNoteJSON{}
+		if err := json.Unmarshal(data, legacy); err != nil {
+			return err
+		}
+
+		*n = *legacy.toNote()
+
+		cfg := &NoteConfig{}
+		n.walk(func(note *Note, _ uint64) {
+			cfg.RecordCache = cfg.RecordCache || note.StorageCache != nil
+		}, 0)
+
+		n.setConfig(cfg)
+		return nil
+	}
+
+	doc := noteDocumentJSON{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	if doc.Version < 1 || doc.Version > NoteSchemaVersion {
+		return fmt.Errorf("%w: %d", ErrUnsupportedNoteVersion, doc.Version)
+	}
+
+	if doc.Note == nil {
+		return ErrCorruptedNote
+	}
+
+	note, err := doc.Note.toNote()
+	if err != nil {
+		return err
+	}
+
+	cfg := &NoteConfig{}
+	if doc.Config != nil {
+		cfg, err = doc.Config.toConfig()
+		if err != nil {
+			return err
+		}
+	}
+
+	*n = *note
+	n.setConfig(cfg)
+	return nil
+}
+
+func configToJSON(cfg *NoteConfig) *configJSON {
+	c := &configJSON{
+		RecordCache:   cfg.RecordCache,
+		RecordLogs:    cfg.RecordLogs,
+		RecordStorage: cfg.RecordStorage,
+		RecordGasUsed: cfg.RecordGasUsed,
+		MaxDepth:      cfg.MaxDepth,
+		Addresses:     cfg.Addresses,
+	}
+
+	for _, t := range cfg.ExecutionTypes {
+		c.ExecutionTypes = append(c.ExecutionTypes, t.String())
+	}
+
+	return c
+}
+
+func (c *configJSON) toConfig() (*NoteConfig, error) {
+	cfg := &NoteConfig{
+		RecordCache:   c.RecordCache,
+		RecordLogs:    c.RecordLogs,
+		RecordStorage: c.RecordStorage,
+		RecordGasUsed: c.RecordGasUsed,
+		MaxDepth:      c.MaxDepth,
+		Addresses:     c.Addresses,
+	}
+
+	for _, name := range c.ExecutionTypes {
+		t, ok := executionTypeFromName(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown execution type %q", ErrCorruptedNote, name)
+		}
+
+		cfg.ExecutionTypes = append(cfg.ExecutionTypes, t)
+	}
+
+	return cfg, nil
+}
+
+func (n *Note) toJSON() *noteJSON {
+	j := &noteJSON{
+		Type:       n.Type.String(),
+		From:       n.From,
+		To:         n.To,
+		Gas:        n.Gas,
+		Value:      n.Val,
+		Input:      n.Input,
+		Error:      errorMessage(n.ExecutionError),
+		ReturnData: n.ReturnData,
+		Depth:      n.Depth,
+		GasUsed:    n.GasUsed,
+	}
+
+	if n.StorageCache != nil {
+		j.StorageCache = resultCacheToJSON(n.StorageCache)
+	}
+
+	if n.Fault != nil {
+		j.Fault = faultToJSON(n.Fault)
+	}
+
+	for _, l := range n.Logs {
+		j.Logs = append(j.Logs, logToJSON(l))
+	}
+
+	for _, s := range n.Storage {
+		j.Storage = append(j.Storage, &storageJSON{
+			Address: s.Address,
+			Slot:    s.Slot,
+			Before:  s.Before,
+			After:   s.After,
+			Written: s.Written,
+		})
+	}
+
+	for _, sub := range n.SubNotes {
+		j.SubNotes = append(j.SubNotes, sub.toJSON())
+	}
+
+	return j
+}
+
+func (j *noteJSON) toNote() (*Note, error) {
+	execType, ok := executionTypeFromName(j.Type)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown execution type %q", ErrCorruptedNote, j.Type)
+	}
+
+	n := &Note{
+		Type:           execType,
+		From:           j.From,
+		To:             j.To,
+		Gas:            j.Gas,
+		Val:            j.Value,
+		Input:          j.Input,
+		ExecutionError: restoreError(j.Error),
+		ReturnData:     j.ReturnData,
+		Depth:          j.Depth,
+		GasUsed:        j.GasUsed,
+	}
+
+	if j.StorageCache != nil {
+		n.StorageCache = j.StorageCache.toResultCache()
+	}
+
+	if j.Fault != nil {
+		fault, err := j.Fault.toFault()
+		if err != nil {
+			return nil, err
+		}
+		n.Fault = fault
+	}
+
+	for _, l := range j.Logs {
+		n.Logs = append(n.Logs, l.toLog())
+	}
+
+	for _, s := range j.Storage {
+		n.Storage = append(n.Storage, StorageRecord{
+			Address: s.Address,
+			Slot:    s.Slot,
+			Before:  s.Before,
+			After:   s.After,
+			Written: s.Written,
+		})
+	}
+
+	for _, sub := range j.SubNotes {
+		subNote, err := sub.toNote()
+		if err != nil {
+			return nil, err
+		}
+		n.SubNotes = append(n.SubNotes, subNote)
+	}
+
+	return n, nil
+}
+
+func logToJSON(l *types.Log) *logJSON {
+	return &logJSON{
+		Address: l.Address,
+		Topics:  l.Topics,
+		Data:    l.Data,
+	}
+}
+
+func (j *logJSON) toLog() *types.Log {
+	return &types.Log{
+		Address: j.Address,
+		Topics:  j.Topics,
+		Data:    j.Data,
+	}
+}
+
+func accountToJSON(acc *environment.Account) *accountJSON {
+	j := &accountJSON{
+		Balance: acc.Balance,
+		Slots:   acc.Slots,
+	}
+
+	if acc.Contract != nil {
+		codeHash := acc.Contract.CodeHash
+		j.Code = acc.Contract.Code
+		j.CodeHash = &codeHash
+		j.InitCode = acc.Contract.InitCode
+	}
+
+	return j
+}
+
+func (j *accountJSON) toAccount(address types.Address) *environment.Account {
+	acc := newDecodedAccount(address)
+	if j.Balance != nil {
+		acc.Balance = j.Balance
+	}
+
+	if j.CodeHash != nil {
+		acc.Contract = &environment.Contract{
+			Code:     j.Code,
+			CodeHash: *j.CodeHash,
+			CodeSize: uint64(len(j.Code)),
+			InitCode: j.InitCode,
+		}
+	}
+
+	for slot, val := range j.Slots {
+		acc.Slots[slot] = val
+	}
+
+	return acc
+}
+
+func resultCacheToJSON(c *cache.ResultCache) *resultCacheJSON {
+	j := &resultCacheJSON{
+		OriginalAccounts: map[types.Address]*accountJSON{},
+		CachedAccounts:   map[types.Address]*accountJSON{},
+		Destructs:        c.Destructs,
+		DataBlocks:       c.DataBlockCache,
+	}
+
+	for addr, acc := range c.OriginalAccounts {
+		if acc != nil {
+			j.OriginalAccounts[addr] = accountToJSON(acc)
+		}
+	}
+
+	for addr, acc := range c.CachedAccounts {
+		if acc != nil {
+			j.CachedAccounts[addr] = accountToJSON(acc)
+		}
+	}
+
+	j.NewContracts = sortedAddresses(c.NewContractAccounts)
+
+	if c.Logs != nil {
+		for _, l := range *c.Logs {
+			j.Logs = append(j.Logs, logToJSON(l))
+		}
+	}
+
+	return j
+}
+
+func (j *resultCacheJSON) toResultCache() *cache.ResultCache {
+	c := newDecodedCache()
+
+	for addr, acc := range j.OriginalAccounts {
+		if acc != nil {
+			c.OriginalAccounts[addr] = acc.toAccount(addr)
+		}
+	}
+
+	for addr, acc := range j.CachedAccounts {
+		if acc != nil {
+			c.CachedAccounts[addr] = acc.toAccount(addr)
+		}
+	}
+
+	//new contract accounts share the cached account, as ResultCache.Clone does
+	for _, addr := range j.NewContracts {
+		if acc := c.CachedAccounts.Get(addr); acc != nil {
+			c.NewContractAccounts[addr] = acc
+		}
+	}
+
+	for _, l := range j.Logs {
+		*c.Logs = append(*c.Logs, l.toLog())
+	}
+
+	for k, v := range j.Destructs {
+		c.Destructs[k] = v
+	}
+
+	for k, v := range j.DataBlocks {
+		c.DataBlockCache[k] = v
+	}
+
+	return c
+}
+
+func faultToJSON(f *FaultReport) *faultJSON {
+	j := &faultJSON{
+		Error:        f.Error,
+		PC:           f.PC,
+		OpCode:       f.OpCode,
+		Address:      f.Address,
+		Depth:        f.Depth,
+		GasLeft:      f.GasLeft,
+		Stack:        f.Stack,
+		Memory:       f.Memory,
+		MemoryOffset: f.MemoryOffset,
+		MemorySize:   f.MemorySize,
+	}
+
+	for _, frame := range f.Frames {
+		j.Frames = append(j.Frames, &faultFrameJSON{
+			Type:    frame.Type.String(),
+			Depth:   frame.Depth,
+			From:    frame.From,
+			To:      frame.To,
+			PC:      frame.PC,
+			GasLeft: frame.GasLeft,
+		})
+	}
+
+	return j
+}
+
+func (j *faultJSON) toFault() (*FaultReport, error) {
+	f := &FaultReport{
+		Error:        j.Error,
+		PC:           j.PC,
+		OpCode:       j.OpCode,
+		Address:      j.Address,
+		Depth:        j.Depth,
+		GasLeft:      j.GasLeft,
+		Stack:        j.Stack,
+		Memory:       j.Memory,
+		MemoryOffset: j.MemoryOffset,
+		MemorySize:   j.MemorySize,
+	}
+
+	for _, frame := range j.Frames {
+		execType, ok := executionTypeFromName(frame.Type)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown execution type %q", ErrCorruptedNote, frame.Type)
+		}
+
+		f.Frames = append(f.Frames, FaultFrame{
+			Type:    execType,
+			Depth:   frame.Depth,
+			From:    frame.From,
+			To:      frame.To,
+			PC:      frame.PC,
+			GasLeft: frame.GasLeft,
+		})
+	}
+
+	return f, nil
+}
+
+func (l *legacyNoteJSON) toNote() *Note {
+	n := &Note{
+		Type:       l.Type,
+		From:       l.From,
+		To:         l.To,
+		Gas:        l.Gas,
+		Val:        l.Val,
+		Input:      l.Input,
+		ReturnData: l.ReturnData,
+		Fault:      l.Fault,
+		Depth:      l.Depth,
+		GasUsed:    l.GasUsed,
+		Logs:       l.Logs,
+		Storage:    l.Storage,
+	}
+
+	//encoding/json wrote errors as {}, a string is kept if some encoder wrote one
+	if len(l.ExecutionError) > 0 && string(l.ExecutionError) != "null" {
+		var msg string
+		if json.Unmarshal(l.ExecutionError, &msg) == nil && msg != "" {
+			n.ExecutionError = restoreError(msg)
+		} else {
+			n.ExecutionError = ErrLegacyExecutionError
+		}
+	}
+
+	if l.StorageCache != nil {
+		n.StorageCache = legacyResultCache(l.StorageCache)
+	}
+
+	n.SubNotes = l.SubNotes
+	return n
+}
+
+func legacyResultCache(l *cache.ResultCache) *cache.ResultCache {
+	c := newDecodedCache()
+	c.OriginalAccounts.Merge(l.OriginalAccounts)
+	c.CachedAccounts.Merge(l.CachedAccounts)
+	c.Destructs.Merge(l.Destructs)
+	c.DataBlockCache.Merge(l.DataBlockCache)
+
+	if l.Logs != nil {
+		*c.Logs = *l.Logs
+	}
+
+	for _, acc := range c.CachedAccounts {
+		if acc.Slots == nil {
+			acc.Slots = map[types.Slot]*evmInt256.Int{}
+		}
+	}
+
+	for _, acc := range c.OriginalAccounts {
+		if acc.Slots == nil {
+			acc.Slots = map[types.Slot]*evmInt256.Int{}
+		}
+	}
+
+	for addr := range l.NewContractAccounts {
+		if acc := c.CachedAccounts.Get(addr); acc != nil {
+			c.NewContractAccounts[addr] = acc
+		}
+	}
+
+	return c
+}
@@ -0,0 +1,175 @@
+package executionNote_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/SealSC/SealEVM"
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmErrors"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/executionNote"
+	"github.com/SealSC/SealEVM/statedb/memory"
+	"github.com/SealSC/SealEVM/types"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	caller = types.Address{0xca}
+	a      = types.Address{19: 0xa0}
+	b      = types.Address{19: 0xb0}
+	c      = types.Address{19: 0xc0}
+	e      = types.Address{19: 0xe0}
+)
+
+func log1(topic string) string {
+	return "60" + topic + "5f5fa1"
+}
+
+func call(to types.Address) string {
+	return "5f5f5f5f5f73" + common.Bytes2Hex(to[:]) + "5af150"
+}
+
+func delegateCall(to types.Address) string {
+	return "5f5f5f5f73" + common.Bytes2Hex(to[:]) + "5af450"
+}
+
+// a logs and calls b then c. b logs and calls e, c logs, delegates to e then reverts. e logs.
+var codes = map[types.Address]string{
+	a: log1("a0") + call(b) + call(c) + "00",
+	b: log1("b0") + call(e) + "00",
+	c: log1("c0") + delegateCall(e) + "5f5ffd",
+	e: log1("e0") + "00",
+}
+
+func init() {
+	SealEVM.Load()
+}
+
+func execute(t *testing.T, cfg executionNote.NoteConfig) *executionNote.Note {
+	t.Helper()
+
+	db := memory.NewDB()
+	for addr, code := range codes {
+		db.SetCode(addr, common.FromHex(code))
+	}
+
+	const gasLimit = 1000000
+	evm := SealEVM.New(SealEVM.EVMParam{
+		MaxStackDepth: 1024,
+		ExternalStore: db,
+		NoteConfig:    &cfg,
+		Context: &environment.Context{
+			Block: environment.Block{
+				ChainID:     evmInt256.New(1),
+				Difficulty:  evmInt256.New(0),
+				GasLimit:    evmInt256.New(gasLimit),
+				BaseFee:     evmInt256.New(0),
+				BlobBaseFee: evmInt256.New(1),
+			},
+			Transaction: environment.Transaction{
+				Origin:   caller,
+				To:       &a,
+				GasPrice: evmInt256.New(0),
+				GasLimit: evmInt256.New(gasLimit),
+			},
+			Message: environment.Message{Caller: caller, Value: evmInt256.New(0)},
+		},
+	})
+
+	result, err := evm.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Note == nil {
+		t.Fatalf("no note")
+	}
+
+	return result.Note
+}
+
+// frame is what a test checks of a note, with its sub frames.
+type frame struct {
+	to     types.Address
+	depth  uint64
+	topics []byte
+	subs   []frame
+}
+
+func check(t *testing.T, name string, n *executionNote.Note, want frame) {
+	t.Helper()
+
+	if n.To == nil || *n.To != want.to || n.Depth != want.depth {
+		t.Fatalf("%s: frame to %v at depth %d, want %s at %d", name, n.To, n.Depth, want.to, want.depth)
+	}
+
+	if len(n.Logs) != len(want.topics) {
+		t.Fatalf("%s: frame to %s has %d logs, want %d", name, want.to, len(n.Logs), len(want.topics))
+	}
+
+	for i, l := range n.Logs {
+		if l.Topics[0][31] != want.topics[i] {
+			t.Fatalf("%s: frame to %s logged %s, want %x", name, want.to, l.Topics[0], want.topics[i])
+		}
+	}
+
+	if len(n.SubNotes) != len(want.subs) {
+		t.Fatalf("%s: frame to %s has %d sub notes, want %d", name, want.to, len(n.SubNotes), len(want.subs))
+	}
+
+	for i, sub := range n.SubNotes {
+		check(t, name, sub, want.subs[i])
+	}
+}
+
+func TestNoteDropsLogsOfFailedFrames(t *testing.T) {
+	note := execute(t, executionNote.NoteConfig{RecordLogs: true})
+
+	//c reverted, its logs and those of its delegate are gone
+	check(t, "all frames", note, frame{a, 0, []byte{0xa0}, []frame{
+		{b, 1, []byte{0xb0}, []frame{{e, 2, []byte{0xe0}, nil}}},
+		{c, 1, nil, []frame{{e, 2, nil, nil}}},
+	}})
+
+	if !errors.Is(note.SubNotes[1].ExecutionError, evmErrors.RevertErr) {
+		t.Fatalf("c ended with %v, want %v", note.SubNotes[1].ExecutionError, evmErrors.RevertErr)
+	}
+}
+
+func TestNoteMaxDepth(t *testing.T) {
+	note := execute(t, executionNote.NoteConfig{RecordLogs: true, MaxDepth: 1})
+	check(t, "max depth", note, frame{a, 0, []byte{0xa0}, []frame{
+		{b, 1, []byte{0xb0}, nil},
+		{c, 1, nil, nil},
+	}})
+}
+
+func TestNoteExecutionTypes(t *testing.T) {
+	//the delegate of c is attached to a, c was not recorded but its revert still drops the logs
+	note := execute(t, executionNote.NoteConfig{RecordLogs: true, ExecutionTypes: []executionNote.ExecutionType{executionNote.DelegateCall}})
+	check(t, "delegate calls", note, frame{a, 0, []byte{0xa0}, []frame{
+		{e, 2, nil, nil},
+	}})
+
+	if note.SubNotes[0].Type != executionNote.DelegateCall {
+		t.Fatalf("sub note of type %s, want %s", note.SubNotes[0].Type, executionNote.DelegateCall)
+	}
+}
+
+func TestNoteAddresses(t *testing.T) {
+	//the frames to b and from b
+	note := execute(t, executionNote.NoteConfig{RecordLogs: true, Addresses: []types.Address{b}})
+	check(t, "addresses", note, frame{a, 0, []byte{0xa0}, []frame{
+		{b, 1, []byte{0xb0}, []frame{{e, 2, []byte{0xe0}, nil}}},
+	}})
+}
+
+func TestNoteReanchorsUnderRecordedAncestor(t *testing.T) {
+	//only the frames to e are recorded, they hang from the top frame
+	note := execute(t, executionNote.NoteConfig{RecordLogs: true, Addresses: []types.Address{e}})
+	check(t, "re-anchored", note, frame{a, 0, []byte{0xa0}, []frame{
+		{e, 2, []byte{0xe0}, nil},
+		{e, 2, nil, nil},
+	}})
+}
@@ -0,0 +1,86 @@
+package SealEVM
+
+import (
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/executionNote"
+	"github.com/SealSC/SealEVM/opcodes"
+	"github.com/SealSC/SealEVM/types"
+)
+
+func (e *EVM) faultFrame() executionNote.FaultFrame {
+	frame := executionNote.FaultFrame{
+		Type:    e.execType,
+		From:    e.context.Message.Caller,
+		PC:      e.instructions.PC(),
+		GasLeft: e.instructions.GetGasLeft(),
+	}
+
+	if e.context.Transaction.To != nil {
+		frame.To = *e.context.Transaction.To
+	} else if e.context.Account() != nil {
+		frame.To = e.context.Address()
+	}
+
+	return frame
+}
+
+func (e *EVM) faultReport(err error) *executionNote.FaultReport {
+	pc := e.instructions.PC()
+	stackItems := e.stack.All()
+	mem := e.memory.All()
+
+	opCode := opcodes.STOP
+	if contract := e.context.Contract(); contract != nil {
+		opCode = opcodes.OpCode(contract.GetOpCode(pc))
+	}
+
+	report := &executionNote.FaultReport{
+		Error:      err.Error(),
+		PC:         pc,
+		OpCode:     opCode,
+		Address:    e.context.Address(),
+		Depth:      e.depth,
+		GasLeft:    e.instructions.GetGasLeft(),
+		Stack:      make([]*evmInt256.Int, len(stackItems)),
+		MemorySize: uint64(len(mem)),
+	}
+
+	for i, item := range stackItems {
+		report.Stack[i] = item.Clone()
+	}
+
+	if len(mem) > executionNote.MaxFaultMemoryExcerpt {
+		report.MemoryOffset = uint64(len(mem) - executionNote.MaxFaultMemoryExcerpt)
+	}
+	report.Memory = types.Bytes(mem[report.MemoryOffset:]).Clone()
+
+	report.Frames = e.faultFrames()
+	return report
+}
+
+// frameFaultReport reports a frame which failed before running any code, on the intrinsic
+// gas, the value transfer or a precompiled contract, it has no opcode, stack or memory.
+func (e *EVM) frameFaultReport(err error) *executionNote.FaultReport {
+	frame := e.faultFrame()
+	return &executionNote.FaultReport{
+		Error:   err.Error(),
+		Address: frame.To,
+		Depth:   e.depth,
+		GasLeft: frame.GasLeft,
+		Frames:  e.faultFrames(),
+	}
+}
+
+func (e *EVM) faultFrames() []executionNote.FaultFrame {
+	var frames []executionNote.FaultFrame
+	for frame := e; frame != nil; frame = frame.parent {
+		frames = append([]executionNote.FaultFrame{frame.faultFrame()}, frames...)
+	}
+
+	//the depth of a calling frame is increased during the call, so take it from the chain
+	for i := range frames {
+		frames[i].Depth = uint64(i)
+	}
+
+	return frames
+}
@@ -0,0 +1,122 @@
+package SealEVM_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/SealSC/SealEVM"
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmErrors"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/executionNote"
+	"github.com/SealSC/SealEVM/opcodes"
+	"github.com/SealSC/SealEVM/statedb/memory"
+	"github.com/SealSC/SealEVM/types"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	faultCaller = types.Address{0xca}
+	faultParent = types.Address{19: 0xc0}
+	faultChild  = types.Address{19: 0xc1}
+	identity    = types.Address{19: 0x04}
+)
+
+func init() {
+	SealEVM.Load()
+}
+
+// executeFault sends a transaction to with gasLimit, the parent sends 1 wei it doesn't have
+// to the child with a CALL at pc 28.
+func executeFault(t *testing.T, to types.Address, gasLimit uint64) (SealEVM.ExecuteResult, error) {
+	t.Helper()
+
+	db := memory.NewDB()
+	db.SetCode(faultParent, common.FromHex("5f5f5f5f600173"+common.Bytes2Hex(faultChild[:])+"5af15000"))
+	db.SetCode(faultChild, common.FromHex("00"))
+
+	evm := SealEVM.New(SealEVM.EVMParam{
+		MaxStackDepth: 1024,
+		ExternalStore: db,
+		NoteConfig:    &executionNote.NoteConfig{},
+		Context: &environment.Context{
+			Block: environment.Block{
+				ChainID:     evmInt256.New(1),
+				Difficulty:  evmInt256.New(0),
+				GasLimit:    evmInt256.New(1000000),
+				BaseFee:     evmInt256.New(0),
+				BlobBaseFee: evmInt256.New(1),
+			},
+			Transaction: environment.Transaction{
+				Origin:   faultCaller,
+				To:       &to,
+				GasPrice: evmInt256.New(0),
+				GasLimit: evmInt256.New(gasLimit),
+			},
+			Message: environment.Message{Caller: faultCaller, Value: evmInt256.New(0)},
+		},
+	})
+
+	return evm.Execute()
+}
+
+// checkFrameFault checks the report of a frame which failed before running any code.
+func checkFrameFault(t *testing.T, name string, fault *executionNote.FaultReport, want error, addr types.Address, depth uint64) {
+	t.Helper()
+
+	if fault == nil {
+		t.Fatalf("%s: no fault report", name)
+	}
+
+	if fault.Error != want.Error() || fault.Address != addr || fault.Depth != depth {
+		t.Fatalf("%s: %q in %s at depth %d, want %q in %s at %d", name, fault.Error, fault.Address, fault.Depth, want, addr, depth)
+	}
+
+	if fault.PC != 0 || fault.OpCode != opcodes.STOP || len(fault.Stack) != 0 || fault.MemorySize != 0 {
+		t.Fatalf("%s: fault at pc %d on %s with %d items and %d bytes of memory, want none", name,
+			fault.PC, fault.OpCode, len(fault.Stack), fault.MemorySize)
+	}
+
+	if len(fault.Frames) != int(depth)+1 || fault.Frames[depth].To != addr {
+		t.Fatalf("%s: fault frames %+v, want %d ending in %s", name, fault.Frames, depth+1, addr)
+	}
+}
+
+func TestFaultIntrinsicGas(t *testing.T) {
+	result, err := executeFault(t, faultParent, 20000)
+	if !errors.Is(err, evmErrors.OutOfGas) {
+		t.Fatalf("got %v, want %v", err, evmErrors.OutOfGas)
+	}
+
+	checkFrameFault(t, "intrinsic gas", result.Fault, evmErrors.OutOfGas, faultParent, 0)
+	if result.Note == nil || result.Note.Fault != result.Fault {
+		t.Fatalf("the note doesn't hold the fault")
+	}
+}
+
+func TestFaultPrecompiledOutOfGas(t *testing.T) {
+	//nothing is left for the 15 gas of the identity after the intrinsic gas
+	result, err := executeFault(t, identity, 21000)
+	if !errors.Is(err, evmErrors.OutOfGas) {
+		t.Fatalf("got %v, want %v", err, evmErrors.OutOfGas)
+	}
+
+	checkFrameFault(t, "precompiled", result.Fault, evmErrors.OutOfGas, identity, 0)
+}
+
+func TestFaultInsufficientBalance(t *testing.T) {
+	result, err := executeFault(t, faultParent, 100000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Fault != nil || len(result.Note.SubNotes) != 1 {
+		t.Fatalf("got a fault %+v and %d sub notes, want the fault in the note of the child", result.Fault, len(result.Note.SubNotes))
+	}
+
+	fault := result.Note.SubNotes[0].Fault
+	checkFrameFault(t, "value transfer", fault, evmErrors.InsufficientBalance, faultChild, 1)
+	if fault.Frames[0].To != faultParent || fault.Frames[0].PC != 28 {
+		t.Fatalf("the parent frame is %+v, want the CALL at pc 28 of %s", fault.Frames[0], faultParent)
+	}
+}
@@ -2,6 +2,7 @@ package dynamicGasSetting
 
 import (
 	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmInt256"
 	"github.com/SealSC/SealEVM/memory"
 	"github.com/SealSC/SealEVM/stack"
 	"github.com/SealSC/SealEVM/storage"
@@ -24,9 +25,9 @@ func gasOfSLoad(
 	slot := stx.PeekPos(0)
 	org, _ := store.CachedData(contract.Address, types.Int256ToSlot(slot))
 	if org == nil {
-		return 0, 100, nil
-	} else {
 		return 0, 2100, nil
+	} else {
+		return 0, 100, nil
 	}
 }
 
@@ -71,3 +72,41 @@ func gasOfSStore(
 
 	return 0, gasCost, nil
 }
+
+// sStoreClearsSchedule is the refund for clearing a slot (EIP-3529).
+const sStoreClearsSchedule = 4800
+
+// SStoreRefund changes the refund of store for writing newVal to slot of the account addr,
+// it is called before the write. The rules are those of EIP-2200 with the amounts of
+// EIP-2929 and EIP-3529.
+func SStoreRefund(addr types.Address, slot types.Slot, newVal *evmInt256.Int, store *storage.Storage) {
+	org, current := store.CachedData(addr, slot)
+	if org == nil || current == nil || newVal.EQ(current) {
+		return
+	}
+
+	if current.EQ(org) {
+		if !org.IsZero() && newVal.IsZero() {
+			store.AddRefund(sStoreClearsSchedule)
+		}
+
+		return
+	}
+
+	if !org.IsZero() {
+		if current.IsZero() {
+			store.SubRefund(sStoreClearsSchedule)
+		} else if newVal.IsZero() {
+			store.AddRefund(sStoreClearsSchedule)
+		}
+	}
+
+	//the slot is set back to its original value
+	if newVal.EQ(org) {
+		if org.IsZero() {
+			store.AddRefund(20000 - 100)
+		} else {
+			store.AddRefund(2900 - 100)
+		}
+	}
+}
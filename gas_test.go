@@ -0,0 +1,123 @@
+package SealEVM_test
+
+import (
+	"testing"
+
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/sim"
+	"github.com/SealSC/SealEVM/types"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// gasUsed runs code as a contract with slot 0 set to 5, the slots returned by GetAccount must
+// not be taken as warm.
+func gasUsed(t *testing.T, code string) uint64 {
+	t.Helper()
+
+	sender := types.Address{0x5e}
+	contract := types.Address{0xc0}
+	state := sim.NewState(sim.Alloc{
+		sender: {Balance: evmInt256.New(1e18)},
+		contract: {
+			Balance: evmInt256.New(0),
+			Code:    common.FromHex(code),
+			Storage: map[types.Slot]*evmInt256.Int{{}: evmInt256.New(5)},
+		},
+	})
+
+	msg := &sim.Message{From: sender, To: &contract, GasLimit: 100000, GasPrice: evmInt256.New(0)}
+	result, err := sim.ApplyMessage(state, &sim.BlockEnv{GasLimit: 1000000}, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Err != nil {
+		t.Fatalf("%s: %v", code, result.Err)
+	}
+
+	return result.GasUsed
+}
+
+func TestSLoadColdAndWarm(t *testing.T) {
+	stop := gasUsed(t, "00")
+
+	//PUSH0 SLOAD POP
+	cold := gasUsed(t, "5f545000")
+	if got := cold - stop; got != 2+2100+2 {
+		t.Errorf("first SLOAD of a slot: got %d gas, want %d", got, 2+2100+2)
+	}
+
+	warm := gasUsed(t, "5f54505f545000")
+	if got := warm - cold; got != 2+100+2 {
+		t.Errorf("second SLOAD of a slot: got %d gas, want %d", got, 2+100+2)
+	}
+
+	//PUSH1 1 SLOAD POP, another slot is cold again
+	other := gasUsed(t, "5f545060015450"+"00")
+	if got := other - cold; got != 3+2100+2 {
+		t.Errorf("SLOAD of another slot: got %d gas, want %d", got, 3+2100+2)
+	}
+}
+
+func TestSStoreAfterSLoad(t *testing.T) {
+	//PUSH0 SLOAD POP, then PUSH1 5 PUSH0 SSTORE writing the value the slot has
+	load := gasUsed(t, "5f545000")
+	store := gasUsed(t, "5f5450"+"60055f55"+"00")
+	if got := store - load; got != 3+2+100 {
+		t.Errorf("no-op SSTORE of a warm slot: got %d gas, want %d", got, 3+2+100)
+	}
+}
+
+// the gas used by the transactions, checked against go-ethereum, with the refund of EIP-3529
+// which is at most a fifth of the gas used
+func TestSStoreRefunds(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		code string
+		want uint64
+	}{
+		{"clear", "6000600055", 26006 - 4800},
+		{"clear and restore", "6000600055" + "6005600055", 26112 - 2800},
+		{"change and clear", "6002600055" + "6000600055", 26112 - 4800},
+		{"clear and change", "6000600055" + "6002600055", 26112},
+		{"change and restore", "6002600055" + "6005600055", 26112 - 2800},
+		{"set and unset a zero slot", "6001600155" + "6000600155", 43212 - 43212/5},
+	} {
+		if got := gasUsed(t, c.code); got != c.want {
+			t.Errorf("%s: got %d gas, want %d", c.name, got, c.want)
+		}
+	}
+}
+
+// the refund of a reverted call is dropped with its writes
+func TestSStoreRefundOfRevertedCall(t *testing.T) {
+	sender := types.Address{0x5e}
+	contract := types.Address{19: 0xc0}
+	child := types.Address{19: 0xc1}
+
+	for _, c := range []struct {
+		child string
+		want  uint64
+	}{
+		//clears its slot then reverts
+		{"600060005560006000fd", 33640 - 4800},
+		//clears its slot
+		{"600060005500", 33634 - 33634/5},
+	} {
+		state := sim.NewState(sim.Alloc{
+			sender:   {Balance: evmInt256.New(1e18)},
+			contract: {Code: common.FromHex("600060006000600060007300000000000000000000000000000000000000c15af1506000600055"), Storage: map[types.Slot]*evmInt256.Int{{}: evmInt256.New(1)}},
+			child:    {Code: common.FromHex(c.child), Storage: map[types.Slot]*evmInt256.Int{{}: evmInt256.New(1)}},
+		})
+
+		msg := &sim.Message{From: sender, To: &contract, GasLimit: 100000, GasPrice: evmInt256.New(0)}
+		result, err := sim.ApplyMessage(state, &sim.BlockEnv{GasLimit: 1000000}, msg)
+		if err != nil || result.Err != nil {
+			t.Fatalf("%s: %v %v", c.child, err, result.Err)
+		}
+
+		if result.GasUsed != c.want {
+			t.Errorf("calling %s: got %d gas, want %d", c.child, result.GasUsed, c.want)
+		}
+	}
+}
@@ -4,6 +4,7 @@ go 1.21
 
 require (
 	github.com/ethereum/go-ethereum v1.13.15
+	github.com/gorilla/websocket v1.4.2
 	golang.org/x/crypto v0.31.0
 )
 
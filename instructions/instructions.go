@@ -17,6 +17,8 @@
 package instructions
 
 import (
+	"fmt"
+
 	"github.com/SealSC/SealEVM/environment"
 	"github.com/SealSC/SealEVM/evmErrors"
 	"github.com/SealSC/SealEVM/evmInt256"
@@ -43,6 +45,9 @@ type instructionsContext struct {
 	callGasLimit uint64
 	closureExec  ClosureExecute
 	exitOpCode   opcodes.OpCode
+
+	depth    uint64
+	stepHook IStepHook
 }
 
 type opCodeAction func(ctx *instructionsContext) ([]byte, error)
@@ -68,6 +73,9 @@ type IInstructions interface {
 	SetReadOnly()
 	IsReadOnly() bool
 	ExitOpCode() opcodes.OpCode
+	SetDepth(uint64)
+	SetStepHook(IStepHook)
+	PC() uint64
 }
 
 var instructionTable [opcodes.MaxOpCodesCount]opCodeInstruction
@@ -100,6 +108,18 @@ func (i *instructionsContext) ExitOpCode() opcodes.OpCode {
 	return i.exitOpCode
 }
 
+func (i *instructionsContext) PC() uint64 {
+	return i.pc
+}
+
+func (i *instructionsContext) SetDepth(depth uint64) {
+	i.depth = depth
+}
+
+func (i *instructionsContext) SetStepHook(hook IStepHook) {
+	i.stepHook = hook
+}
+
 func (i *instructionsContext) calcGas(code opcodes.OpCode, gasRemaining uint64) (uint64, error) {
 	if code == opcodes.CALL || code == opcodes.CALLCODE || code == opcodes.STATICCALL || code == opcodes.DELEGATECALL {
 		if callCost := i.gasSetting.CallCost[code]; callCost != nil {
@@ -153,7 +173,12 @@ func (i *instructionsContext) calcGas(code opcodes.OpCode, gasRemaining uint64)
 func (i *instructionsContext) ExecuteContract() (ret []byte, gasRemaining uint64, err error) {
 	defer func() {
 		if e := recover(); e != nil {
-			err = evmErrors.Panicked(e.(error))
+			pErr, ok := e.(error)
+			if !ok {
+				pErr = fmt.Errorf("%v", e)
+			}
+
+			err = evmErrors.Panicked(pErr)
 			gasRemaining = i.gasRemaining.Uint64()
 		}
 	}()
@@ -161,41 +186,60 @@ func (i *instructionsContext) ExecuteContract() (ret []byte, gasRemaining uint64
 	i.pc = 0
 	contract := i.environment.Contract()
 
-	if len(contract.Code) == 0 {
+	//a plain transfer to an account without code
+	if contract == nil || len(contract.Code) == 0 {
 		return nil, i.gasRemaining.Uint64(), nil
 	}
 
 	for {
 		opCode := contract.GetOpCode(i.pc)
+		step := i.newExecutionStep(opcodes.OpCode(opCode))
 
 		instruction := instructionTable[opCode]
 		if !instruction.enabled {
-			return nil, i.gasRemaining.Uint64(), evmErrors.InvalidOpCode(opCode)
+			err = evmErrors.InvalidOpCode(opCode)
+			i.afterStep(step, err)
+			return nil, i.gasRemaining.Uint64(), err
 		}
 
 		if instruction.isWriter && i.readOnly {
+			i.afterStep(step, evmErrors.WriteProtection)
 			return nil, i.gasRemaining.Uint64(), evmErrors.WriteProtection
 		}
 
 		err = i.stack.CheckStackDepth(instruction.requireStackDepth, instruction.willIncreaseStack)
 		if err != nil {
+			i.afterStep(step, err)
 			break
 		}
 
 		gasLeft, gasErr := i.calcGas(opcodes.OpCode(opCode), i.gasRemaining.Uint64())
 		if gasErr != nil {
 			err = gasErr
+			i.afterStep(step, err)
 			break
 		}
 
 		i.gasRemaining.SetUint64(gasLeft)
 
+		if step != nil {
+			step.GasCost = step.Gas - gasLeft
+		}
+
+		err = i.beforeStep(step)
+		if err != nil {
+			i.afterStep(step, err)
+			break
+		}
+
 		ret, err = instruction.action(i)
 
 		if instruction.returns {
 			i.lastReturn = ret
 		}
 
+		i.afterStep(step, err)
+
 		if err != nil {
 			break
 		}
@@ -0,0 +1,71 @@
+package instructions
+
+import (
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/memory"
+	"github.com/SealSC/SealEVM/opcodes"
+	"github.com/SealSC/SealEVM/stack"
+	"github.com/SealSC/SealEVM/storage"
+)
+
+// ExecutionStep describes the instruction about to be (or just) executed.
+// Stack, Memory, Storage and Context point to the live execution state and
+// must be treated as read only by hooks.
+type ExecutionStep struct {
+	PC      uint64
+	OpCode  opcodes.OpCode
+	Depth   uint64
+	Gas     uint64
+	GasCost uint64
+
+	ReturnData []byte
+
+	Context *environment.Context
+	Stack   *stack.Stack
+	Memory  *memory.Memory
+	Storage *storage.Storage
+}
+
+// IStepHook is notified around every executed instruction.
+// BeforeStep is called after the gas of the instruction has been charged, a non-nil
+// error aborts the current frame with that error.
+// AfterStep is called with the result of the instruction, it is also called for
+// instructions which failed before BeforeStep (invalid opcode, stack or gas check).
+type IStepHook interface {
+	BeforeStep(step *ExecutionStep) error
+	AfterStep(step *ExecutionStep, err error)
+}
+
+func (i *instructionsContext) newExecutionStep(opCode opcodes.OpCode) *ExecutionStep {
+	if i.stepHook == nil {
+		return nil
+	}
+
+	return &ExecutionStep{
+		PC:         i.pc,
+		OpCode:     opCode,
+		Depth:      i.depth,
+		Gas:        i.gasRemaining.Uint64(),
+		ReturnData: i.lastReturn,
+		Context:    i.environment,
+		Stack:      i.stack,
+		Memory:     i.memory,
+		Storage:    i.storage,
+	}
+}
+
+func (i *instructionsContext) beforeStep(step *ExecutionStep) error {
+	if step == nil {
+		return nil
+	}
+
+	return i.stepHook.BeforeStep(step)
+}
+
+func (i *instructionsContext) afterStep(step *ExecutionStep, err error) {
+	if step == nil {
+		return
+	}
+
+	i.stepHook.AfterStep(step, err)
+}
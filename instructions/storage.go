@@ -17,6 +17,7 @@
 package instructions
 
 import (
+	"github.com/SealSC/SealEVM/gasSetting/dynamicGasSetting"
 	"github.com/SealSC/SealEVM/opcodes"
 	"github.com/SealSC/SealEVM/storage/cache"
 	"github.com/SealSC/SealEVM/types"
@@ -56,6 +57,7 @@ func sStoreAction(ctx *instructionsContext) ([]byte, error) {
 
 	slot := types.Int256ToSlot(k)
 
+	dynamicGasSetting.SStoreRefund(ctx.environment.Address(), slot, v, ctx.storage)
 	ctx.storage.XStore(ctx.environment.Address(), slot, v, cache.SStorage)
 	return nil, nil
 }
@@ -0,0 +1,164 @@
+// Package override changes the state and the block a call runs in without touching the
+// storage behind them. The JSON formats are the stateOverride and blockOverrides of
+// go-ethereum's eth_call.
+package override
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/types"
+)
+
+var (
+	ErrStateAndStateDiff = errors.New("both state and stateDiff overridden")
+	ErrUnsupported       = errors.New("unsupported override")
+)
+
+// quantity is an uint64 in the hex format of JSON-RPC.
+type quantity uint64
+
+func (q quantity) MarshalText() ([]byte, error) {
+	return []byte("0x" + strconv.FormatUint(uint64(q), 16)), nil
+}
+
+func (q *quantity) UnmarshalText(text []byte) error {
+	s := string(text)
+	if !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") {
+		return fmt.Errorf("invalid quantity %q: missing 0x prefix", s)
+	}
+
+	v, err := strconv.ParseUint(s[2:], 16, 64)
+	if err != nil {
+		return fmt.Errorf("invalid quantity %q", s)
+	}
+
+	*q = quantity(v)
+	return nil
+}
+
+func uint64Of(q *quantity) *uint64 {
+	if q == nil {
+		return nil
+	}
+
+	v := uint64(*q)
+	return &v
+}
+
+func quantityOf(v *uint64) *quantity {
+	if v == nil {
+		return nil
+	}
+
+	q := quantity(*v)
+	return &q
+}
+
+// Account overrides the fields of an account which are not nil. An empty Code removes the
+// code, State replaces the whole storage, even when empty, StateDiff only the given slots.
+type Account struct {
+	Balance   *evmInt256.Int
+	Nonce     *uint64
+	Code      *types.Bytes
+	State     map[types.Slot]*evmInt256.Int
+	StateDiff map[types.Slot]*evmInt256.Int
+}
+
+type accountJSON struct {
+	Balance   *evmInt256.Int             `json:"balance,omitempty"`
+	Nonce     *quantity                  `json:"nonce,omitempty"`
+	Code      *types.Bytes               `json:"code,omitempty"`
+	State     *map[types.Slot]types.Hash `json:"state,omitempty"`
+	StateDiff *map[types.Slot]types.Hash `json:"stateDiff,omitempty"`
+
+	MovePrecompileTo *types.Address `json:"movePrecompileToAddress,omitempty"`
+}
+
+// slotsOf and valuesOf keep an empty State apart from a missing one, an empty State clears
+// the storage.
+func slotsOf(values *map[types.Slot]types.Hash) map[types.Slot]*evmInt256.Int {
+	if values == nil {
+		return nil
+	}
+
+	slots := map[types.Slot]*evmInt256.Int{}
+	for slot, val := range *values {
+		slots[slot] = val.Int256()
+	}
+
+	return slots
+}
+
+func valuesOf(slots map[types.Slot]*evmInt256.Int) *map[types.Slot]types.Hash {
+	if slots == nil {
+		return nil
+	}
+
+	values := map[types.Slot]types.Hash{}
+	for slot, val := range slots {
+		values[slot] = types.Int256ToHash(val)
+	}
+
+	return &values
+}
+
+func (a *Account) UnmarshalJSON(data []byte) error {
+	var j accountJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	if j.MovePrecompileTo != nil {
+		return fmt.Errorf("%w: movePrecompileToAddress", ErrUnsupported)
+	}
+
+	*a = Account{
+		Balance:   j.Balance,
+		Nonce:     uint64Of(j.Nonce),
+		Code:      j.Code,
+		State:     slotsOf(j.State),
+		StateDiff: slotsOf(j.StateDiff),
+	}
+
+	return a.Validate()
+}
+
+func (a *Account) MarshalJSON() ([]byte, error) {
+	return json.Marshal(accountJSON{
+		Balance:   a.Balance,
+		Nonce:     quantityOf(a.Nonce),
+		Code:      a.Code,
+		State:     valuesOf(a.State),
+		StateDiff: valuesOf(a.StateDiff),
+	})
+}
+
+func (a *Account) Validate() error {
+	if a.State != nil && a.StateDiff != nil {
+		return ErrStateAndStateDiff
+	}
+
+	return nil
+}
+
+// StateOverride is the set of overridden accounts.
+type StateOverride map[types.Address]*Account
+
+func (o StateOverride) Validate() error {
+	for addr, acc := range o {
+		if acc == nil {
+			continue
+		}
+
+		if err := acc.Validate(); err != nil {
+			return fmt.Errorf("%w: account %s", err, addr)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package override
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/types"
+)
+
+// BlockOverride overrides the fields of a block which are not nil. PrevRandao is carried
+// by the Difficulty of the block, it wins over Difficulty when both are set.
+type BlockOverride struct {
+	Number        *uint64
+	Difficulty    *evmInt256.Int
+	Time          *uint64
+	GasLimit      *uint64
+	FeeRecipient  *types.Address
+	PrevRandao    *types.Hash
+	BaseFeePerGas *evmInt256.Int
+	BlobBaseFee   *evmInt256.Int
+}
+
+type blockOverrideJSON struct {
+	Number        *evmInt256.Int `json:"number,omitempty"`
+	Difficulty    *evmInt256.Int `json:"difficulty,omitempty"`
+	Time          *quantity      `json:"time,omitempty"`
+	GasLimit      *quantity      `json:"gasLimit,omitempty"`
+	FeeRecipient  *types.Address `json:"feeRecipient,omitempty"`
+	PrevRandao    *types.Hash    `json:"prevRandao,omitempty"`
+	BaseFeePerGas *evmInt256.Int `json:"baseFeePerGas,omitempty"`
+	BlobBaseFee   *evmInt256.Int `json:"blobBaseFee,omitempty"`
+}
+
+func (o *BlockOverride) UnmarshalJSON(data []byte) error {
+	var j blockOverrideJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	*o = BlockOverride{
+		Difficulty:    j.Difficulty,
+		Time:          uint64Of(j.Time),
+		GasLimit:      uint64Of(j.GasLimit),
+		FeeRecipient:  j.FeeRecipient,
+		PrevRandao:    j.PrevRandao,
+		BaseFeePerGas: j.BaseFeePerGas,
+		BlobBaseFee:   j.BlobBaseFee,
+	}
+
+	if j.Number != nil {
+		if !j.Number.IsUint64() {
+			return fmt.Errorf("invalid block number %s", j.Number.Text(10))
+		}
+
+		number := j.Number.Uint64()
+		o.Number = &number
+	}
+
+	return nil
+}
+
+func (o *BlockOverride) MarshalJSON() ([]byte, error) {
+	j := blockOverrideJSON{
+		Difficulty:    o.Difficulty,
+		Time:          quantityOf(o.Time),
+		GasLimit:      quantityOf(o.GasLimit),
+		FeeRecipient:  o.FeeRecipient,
+		PrevRandao:    o.PrevRandao,
+		BaseFeePerGas: o.BaseFeePerGas,
+		BlobBaseFee:   o.BlobBaseFee,
+	}
+
+	if o.Number != nil {
+		j.Number = evmInt256.New(*o.Number)
+	}
+
+	return json.Marshal(j)
+}
+
+// Apply patches block, a nil override leaves it as it is.
+func (o *BlockOverride) Apply(block *environment.Block) {
+	if o == nil {
+		return
+	}
+
+	if o.Number != nil {
+		block.Number = *o.Number
+	}
+
+	if o.Difficulty != nil {
+		block.Difficulty = o.Difficulty.Clone()
+	}
+
+	if o.Time != nil {
+		block.Timestamp = *o.Time
+	}
+
+	if o.GasLimit != nil {
+		block.GasLimit = evmInt256.New(*o.GasLimit)
+	}
+
+	if o.FeeRecipient != nil {
+		block.Coinbase = *o.FeeRecipient
+	}
+
+	if o.PrevRandao != nil {
+		block.Difficulty = o.PrevRandao.Int256()
+	}
+
+	if o.BaseFeePerGas != nil {
+		block.BaseFee = o.BaseFeePerGas.Clone()
+	}
+
+	if o.BlobBaseFee != nil {
+		block.BlobBaseFee = o.BlobBaseFee.Clone()
+	}
+}
@@ -0,0 +1,103 @@
+package override
+
+import (
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/types"
+)
+
+// journalEntry is the content of the layer for an address before its first write under a
+// snapshot, the has fields tell the entries the layer had from the ones it had not.
+type journalEntry struct {
+	addr types.Address
+
+	account    *environment.Account
+	hasAccount bool
+	nonce      uint64
+	hasNonce   bool
+	cleared    bool
+	dataBlock  types.DataBlock
+}
+
+// snapshot is the length of the journal when it was taken, with the addresses journaled
+// since.
+type snapshot struct {
+	mark      int
+	journaled map[types.Address]bool
+}
+
+// record keeps the content of the layer for addr before a write, once for each snapshot.
+func (s *Storage) record(addr types.Address) {
+	if len(s.snapshots) == 0 {
+		return
+	}
+
+	top := &s.snapshots[len(s.snapshots)-1]
+	if top.journaled[addr] {
+		return
+	}
+
+	e := journalEntry{addr: addr, cleared: s.cleared[addr]}
+	if e.account, e.hasAccount = s.accounts[addr]; e.account != nil {
+		e.account = e.account.Clone()
+	}
+
+	e.nonce, e.hasNonce = s.nonces[addr]
+	if block := s.dataBlocks[addr]; block != nil {
+		e.dataBlock = block.Clone()
+	}
+
+	s.journal = append(s.journal, e)
+	top.journaled[addr] = true
+}
+
+func (s *Storage) revert(e *journalEntry) {
+	delete(s.accounts, e.addr)
+	if e.hasAccount {
+		s.accounts[e.addr] = e.account
+	}
+
+	delete(s.nonces, e.addr)
+	if e.hasNonce {
+		s.nonces[e.addr] = e.nonce
+	}
+
+	delete(s.cleared, e.addr)
+	if e.cleared {
+		s.cleared[e.addr] = true
+	}
+
+	delete(s.dataBlocks, e.addr)
+	if e.dataBlock != nil {
+		s.dataBlocks[e.addr] = e.dataBlock
+	}
+}
+
+// Snapshot returns the id of the current content of the layer, for RevertToSnapshot.
+// Snapshots nest, every write is journaled until the outermost one is reverted or
+// ClearSnapshots is called. A failed read of the base stays in Err.
+func (s *Storage) Snapshot() int {
+	s.snapshots = append(s.snapshots, snapshot{mark: len(s.journal), journaled: map[types.Address]bool{}})
+	return len(s.snapshots) - 1
+}
+
+// RevertToSnapshot undoes the writes made since the snapshot id was taken, it and the
+// snapshots taken after it are dropped. An unknown id is ignored.
+func (s *Storage) RevertToSnapshot(id int) {
+	if id < 0 || id >= len(s.snapshots) {
+		return
+	}
+
+	mark := s.snapshots[id].mark
+	for i := len(s.journal) - 1; i >= mark; i-- {
+		s.revert(&s.journal[i])
+	}
+
+	s.journal = s.journal[:mark]
+	s.snapshots = s.snapshots[:id]
+}
+
+// ClearSnapshots keeps the current content and drops every snapshot with the journal.
+func (s *Storage) ClearSnapshots() {
+	s.journal = nil
+	s.snapshots = nil
+}
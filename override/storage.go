@@ -0,0 +1,518 @@
+package override
+
+import (
+	"github.com/SealSC/SealEVM/crypto/hashes"
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/statedb"
+	"github.com/SealSC/SealEVM/storage"
+	"github.com/SealSC/SealEVM/storage/cache"
+	"github.com/SealSC/SealEVM/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const blockHashWindow = 256
+
+// NonceReader is a storage keeping the nonces of the accounts, as statedb/memory.DB does.
+type NonceReader interface {
+	Nonce(addr types.Address) uint64
+}
+
+// Storage is a layer over an IExternalStorage holding the overridden accounts and every
+// change made afterwards, the base storage is only read. It has the methods of
+// sim.StateDB, so transactions can be applied to it and the results committed.
+//
+// The nonces of the base are read when it is a NonceReader, they are zero otherwise.
+// Addresses of created contracts are derived from the nonces of the layer by the rules of
+// Ethereum, the base is never asked for them since that would change its nonces. They are
+// changed at once during the execution, take a Snapshot before an execution and revert to
+// it when the execution fails.
+//
+// Err returns the first failed read of the base, including the ones of the methods of
+// sim.StateDB returning no error, and the failures the base records itself when it has an
+// Err method, as statedb/fork.Source has. The content of the layer is wrong from then on.
+type Storage struct {
+	base storage.IExternalStorage
+
+	//the accounts of the layer, nil for a deleted account
+	accounts map[types.Address]*environment.Account
+	nonces   map[types.Address]uint64
+
+	//the accounts of which the storage of the base is hidden
+	cleared    map[types.Address]bool
+	dataBlocks map[types.Address]types.DataBlock
+
+	journal   []journalEntry
+	snapshots []snapshot
+
+	//the hashes of the blocks built on the layer, used once currentBlock is set
+	blockHashes     map[uint64]types.Hash
+	currentBlock    uint64
+	hasCurrentBlock bool
+
+	err error
+}
+
+// NewStorage is a layer over base with the accounts of overrides, which may be nil.
+func NewStorage(base storage.IExternalStorage, overrides StateOverride) (*Storage, error) {
+	s := &Storage{
+		base:        base,
+		accounts:    map[types.Address]*environment.Account{},
+		nonces:      map[types.Address]uint64{},
+		cleared:     map[types.Address]bool{},
+		dataBlocks:  map[types.Address]types.DataBlock{},
+		blockHashes: map[uint64]types.Hash{},
+	}
+
+	if err := s.Override(overrides); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Override applies overrides on top of the current content of the layer.
+func (s *Storage) Override(overrides StateOverride) error {
+	if err := overrides.Validate(); err != nil {
+		return err
+	}
+
+	for addr, acc := range overrides {
+		if acc == nil {
+			continue
+		}
+
+		if err := s.override(addr, acc); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) override(addr types.Address, o *Account) error {
+	s.record(addr)
+
+	acc, err := s.load(addr)
+	if err != nil {
+		return err
+	}
+
+	if o.Balance != nil {
+		acc.Balance = o.Balance.Clone()
+	}
+
+	if o.Nonce != nil {
+		s.nonces[addr] = *o.Nonce
+	}
+
+	if o.Code != nil {
+		s.setCode(acc, *o.Code)
+	}
+
+	if o.State != nil {
+		s.clearStorage(acc)
+	}
+
+	for slot, val := range o.State {
+		acc.Slots[slot] = val.Clone()
+	}
+
+	for slot, val := range o.StateDiff {
+		acc.Slots[slot] = val.Clone()
+	}
+
+	return nil
+}
+
+// errRecorder is a base recording the failed reads of its methods which return no error.
+type errRecorder interface {
+	Err() error
+}
+
+// Err is the first read of the base which failed, or the failure recorded by the base.
+func (s *Storage) Err() error {
+	if s.err != nil {
+		return s.err
+	}
+
+	if recorder, ok := s.base.(errRecorder); ok {
+		return recorder.Err()
+	}
+
+	return nil
+}
+
+// fail records err for Err.
+func (s *Storage) fail(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// Base is the storage under the layer.
+func (s *Storage) Base() storage.IExternalStorage {
+	return s.base
+}
+
+// Copy is a layer over the same base with a copy of the content of s, without its snapshots.
+func (s *Storage) Copy() *Storage {
+	replica := &Storage{
+		base:            s.base,
+		accounts:        map[types.Address]*environment.Account{},
+		nonces:          map[types.Address]uint64{},
+		cleared:         map[types.Address]bool{},
+		dataBlocks:      map[types.Address]types.DataBlock{},
+		blockHashes:     map[uint64]types.Hash{},
+		currentBlock:    s.currentBlock,
+		hasCurrentBlock: s.hasCurrentBlock,
+		err:             s.err,
+	}
+
+	for addr, acc := range s.accounts {
+		if acc != nil {
+			acc = acc.Clone()
+		}
+		replica.accounts[addr] = acc
+	}
+
+	for addr, nonce := range s.nonces {
+		replica.nonces[addr] = nonce
+	}
+
+	for addr := range s.cleared {
+		replica.cleared[addr] = true
+	}
+
+	for addr, block := range s.dataBlocks {
+		replica.dataBlocks[addr] = block.Clone()
+	}
+
+	for number, hash := range s.blockHashes {
+		replica.blockHashes[number] = hash
+	}
+
+	return replica
+}
+
+// load returns the account of the layer, copying it from the base when the layer has none.
+// The copy starts without slots, they are still read from the base.
+func (s *Storage) load(addr types.Address) (*environment.Account, error) {
+	acc, ok := s.accounts[addr]
+	if acc != nil {
+		return acc, nil
+	}
+
+	if ok {
+		//deleted, nothing of the base is visible anymore
+		acc = environment.NewAccount(addr, nil, nil)
+	} else {
+		baseAcc, err := s.base.GetAccount(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		acc = environment.NewAccount(addr, nil, nil)
+		if baseAcc != nil {
+			if baseAcc.Balance != nil {
+				acc.Balance = baseAcc.Balance.Clone()
+			}
+
+			if baseAcc.Contract != nil {
+				acc.Contract = baseAcc.Contract.Clone()
+			}
+		}
+	}
+
+	s.accounts[addr] = acc
+	return acc, nil
+}
+
+// account is load for the writes of StateDB, which have no error to return. When the base
+// fails to read the account, the failure is recorded for Err and the write goes to an
+// account which is not kept.
+func (s *Storage) account(addr types.Address) *environment.Account {
+	s.record(addr)
+
+	acc, err := s.load(addr)
+	if err != nil {
+		s.fail(err)
+		return environment.NewAccount(addr, nil, nil)
+	}
+
+	return acc
+}
+
+func (s *Storage) Nonce(addr types.Address) uint64 {
+	if nonce, ok := s.nonces[addr]; ok {
+		return nonce
+	}
+
+	if acc, ok := s.accounts[addr]; ok && acc == nil {
+		return 0
+	}
+
+	if reader, ok := s.base.(NonceReader); ok {
+		return reader.Nonce(addr)
+	}
+
+	return 0
+}
+
+func (s *Storage) SetNonce(addr types.Address, nonce uint64) {
+	s.account(addr)
+	s.nonces[addr] = nonce
+}
+
+func (s *Storage) setCode(acc *environment.Account, code []byte) {
+	acc.Contract = nil
+	if len(code) > 0 {
+		code = types.Bytes(code).Clone()
+		acc.Contract = &environment.Contract{
+			Code:     code,
+			CodeHash: s.base.HashOfCode(code),
+			CodeSize: uint64(len(code)),
+		}
+	}
+}
+
+// clearStorage hides the slots and the data blocks of the base for acc.
+func (s *Storage) clearStorage(acc *environment.Account) {
+	acc.Slots = map[types.Slot]*evmInt256.Int{}
+	s.cleared[acc.Address] = true
+	delete(s.dataBlocks, acc.Address)
+}
+
+// SetCode sets the code of addr, empty code removes it.
+func (s *Storage) SetCode(addr types.Address, code []byte) {
+	s.setCode(s.account(addr), code)
+}
+
+func (s *Storage) SetState(addr types.Address, slot types.Slot, val *evmInt256.Int) {
+	s.account(addr).Slots[slot] = val.Clone()
+}
+
+func (s *Storage) SetDataBlock(addr types.Address, slot types.Slot, data types.Bytes) {
+	s.account(addr)
+
+	block := s.dataBlocks[addr]
+	if block == nil {
+		block = types.DataBlock{}
+		s.dataBlocks[addr] = block
+	}
+
+	block[slot] = data.Clone()
+}
+
+// ClearStorage drops the slots and the data blocks of addr, the ones of the base with them.
+func (s *Storage) ClearStorage(addr types.Address) {
+	s.clearStorage(s.account(addr))
+}
+
+func (s *Storage) Balance(addr types.Address) *evmInt256.Int {
+	acc, err := s.GetAccount(addr)
+	if err != nil || acc.Balance == nil {
+		return evmInt256.New(0)
+	}
+
+	return acc.Balance.Clone()
+}
+
+func (s *Storage) SetBalance(addr types.Address, balance *evmInt256.Int) {
+	s.account(addr).Balance = balance.Clone()
+}
+
+func (s *Storage) AddBalance(addr types.Address, amount *evmInt256.Int) {
+	acc := s.account(addr)
+	acc.Balance = evmInt256.FromBigInt(acc.Balance.Int).Add(amount)
+}
+
+// SubBalance does not check the balance, the caller has to.
+func (s *Storage) SubBalance(addr types.Address, amount *evmInt256.Int) {
+	acc := s.account(addr)
+	acc.Balance = evmInt256.FromBigInt(acc.Balance.Int).Sub(amount)
+}
+
+func (s *Storage) Code(addr types.Address) []byte {
+	acc, err := s.GetAccount(addr)
+	if err != nil || acc.Contract == nil {
+		return nil
+	}
+
+	return acc.Contract.Code
+}
+
+// DeleteAccount removes an account with its storage, the base keeps it.
+func (s *Storage) DeleteAccount(addr types.Address) {
+	s.record(addr)
+	s.accounts[addr] = nil
+	s.nonces[addr] = 0
+	s.cleared[addr] = true
+	delete(s.dataBlocks, addr)
+}
+
+// SetBlockHash sets the hash BLOCKHASH returns for a block built on the layer, as a simulated
+// one. Block hashes are not part of the snapshots.
+func (s *Storage) SetBlockHash(number uint64, hash types.Hash) {
+	s.blockHashes[number] = hash
+}
+
+// SetCurrentBlock sets the number of the block in execution on the layer, BLOCKHASH only reads
+// the 256 blocks before it. Until it is called the base answers BLOCKHASH alone.
+func (s *Storage) SetCurrentBlock(number uint64) {
+	s.currentBlock = number
+	s.hasCurrentBlock = true
+}
+
+// GetBlockHash returns the hashes given to SetBlockHash, the base is asked for the others.
+func (s *Storage) GetBlockHash(block *evmInt256.Int) (*evmInt256.Int, error) {
+	if s.hasCurrentBlock {
+		if !block.IsUint64() {
+			return evmInt256.New(0), nil
+		}
+
+		number := block.Uint64()
+		if number >= s.currentBlock || number+blockHashWindow < s.currentBlock {
+			return evmInt256.New(0), nil
+		}
+
+		if hash, ok := s.blockHashes[number]; ok {
+			return evmInt256.FromBytes(hash[:]), nil
+		}
+	}
+
+	hash, err := s.base.GetBlockHash(block)
+	if err != nil {
+		s.fail(err)
+	}
+
+	return hash, err
+}
+
+// GetAccount returns the account of the layer without its slots, or the one of the base when
+// the layer has none.
+func (s *Storage) GetAccount(address types.Address) (*environment.Account, error) {
+	acc, ok := s.accounts[address]
+	if !ok {
+		baseAcc, err := s.base.GetAccount(address)
+		if err != nil {
+			s.fail(err)
+		}
+
+		return baseAcc, err
+	}
+
+	if acc == nil {
+		return environment.NewAccount(address, nil, nil), nil
+	}
+
+	var contract *environment.Contract
+	if acc.Contract != nil {
+		contract = acc.Contract.Clone()
+	}
+
+	return environment.NewAccount(address, acc.Balance.Clone(), contract), nil
+}
+
+func (s *Storage) AccountExist(address types.Address) bool {
+	if acc, ok := s.accounts[address]; ok {
+		return acc != nil
+	}
+
+	return s.base.AccountExist(address)
+}
+
+// AccountEmpty follows EIP-161, an account which does not exist is empty.
+func (s *Storage) AccountEmpty(address types.Address) bool {
+	acc, ok := s.accounts[address]
+	if !ok {
+		if s.nonces[address] > 0 {
+			return false
+		}
+
+		return s.base.AccountEmpty(address)
+	}
+
+	if acc == nil {
+		return true
+	}
+
+	return s.Nonce(address) == 0 && acc.Balance.IsZero() && (acc.Contract == nil || len(acc.Contract.Code) == 0)
+}
+
+func (s *Storage) HashOfCode(code []byte) types.Hash {
+	return s.base.HashOfCode(code)
+}
+
+// created accounts start with nonce 1 (EIP-161).
+func (s *Storage) created(addr types.Address) types.Address {
+	s.record(addr)
+	s.nonces[addr] = 1
+	return addr
+}
+
+// CreateAddress is the zero address when the nonce of caller fails to be read, a nonce made
+// up zero would collide with the first contract of caller.
+func (s *Storage) CreateAddress(caller types.Address, tx environment.Transaction) types.Address {
+	nonce := s.Nonce(caller)
+	if s.Err() != nil {
+		return types.Address{}
+	}
+
+	s.record(caller)
+	s.nonces[caller] = nonce + 1
+	return s.created(types.Address(crypto.CreateAddress(common.Address(caller), nonce)))
+}
+
+func (s *Storage) CreateFixedAddress(caller types.Address, salt types.Hash, code []byte, tx environment.Transaction) types.Address {
+	nonce := s.Nonce(caller)
+	s.record(caller)
+	s.nonces[caller] = nonce + 1
+	return s.created(types.Address(crypto.CreateAddress2(common.Address(caller), salt, hashes.Keccak256(code))))
+}
+
+func (s *Storage) Load(address types.Address, slot types.Slot) (*evmInt256.Int, error) {
+	if acc := s.accounts[address]; acc != nil && acc.Slots[slot] != nil {
+		return acc.Slots[slot].Clone(), nil
+	}
+
+	if s.cleared[address] {
+		return evmInt256.New(0), nil
+	}
+
+	val, err := s.base.Load(address, slot)
+	if err != nil {
+		s.fail(err)
+	}
+
+	return val, err
+}
+
+// GetDataBlock reads the base when it is an IExternalDataBlockStorage, data blocks are
+// empty otherwise. The data blocks of the layer are returned as copies.
+func (s *Storage) GetDataBlock(address types.Address, slot types.Slot) (types.Bytes, error) {
+	if data := s.dataBlocks[address][slot]; data != nil {
+		return data.Clone(), nil
+	}
+
+	if s.cleared[address] {
+		return nil, nil
+	}
+
+	if dataBlocks, ok := s.base.(storage.IExternalDataBlockStorage); ok {
+		data, err := dataBlocks.GetDataBlock(address, slot)
+		if err != nil {
+			s.fail(err)
+		}
+
+		return data, err
+	}
+
+	return nil, nil
+}
+
+// Commit applies the result of a successful execution to the layer, see statedb.Commit.
+func (s *Storage) Commit(result *cache.ResultCache) {
+	statedb.Commit(s, result)
+}
@@ -0,0 +1,140 @@
+package override_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/override"
+	"github.com/SealSC/SealEVM/sim"
+	"github.com/SealSC/SealEVM/statedb/memory"
+	"github.com/SealSC/SealEVM/storage/cache"
+	"github.com/SealSC/SealEVM/types"
+)
+
+var errFetch = errors.New("fetch failed")
+
+var (
+	sender   = types.Address{0x5e}
+	receiver = types.Address{0x7e}
+)
+
+// failingBase fails to read the account at receiver.
+type failingBase struct {
+	*memory.DB
+}
+
+func (b failingBase) GetAccount(address types.Address) (*environment.Account, error) {
+	if address == receiver {
+		return nil, errFetch
+	}
+
+	return b.DB.GetAccount(address)
+}
+
+func newLayer(t *testing.T) *override.Storage {
+	t.Helper()
+
+	db := memory.NewDB()
+	db.SetBalance(sender, evmInt256.New(1e18))
+
+	layer, err := override.NewStorage(failingBase{DB: db}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return layer
+}
+
+func TestStorageWriteAfterFailedRead(t *testing.T) {
+	layer := newLayer(t)
+	layer.AddBalance(receiver, evmInt256.New(5))
+
+	if !errors.Is(layer.Err(), errFetch) {
+		t.Fatalf("Err is %v, want %v", layer.Err(), errFetch)
+	}
+
+	if layer.AccountExist(receiver) {
+		t.Fatalf("the write made up an account the base failed to read")
+	}
+}
+
+func TestStorageFailedReadFailsExecution(t *testing.T) {
+	layer := newLayer(t)
+	msg := &sim.Message{
+		From:     sender,
+		To:       &receiver,
+		GasLimit: 21000,
+		GasPrice: evmInt256.New(0),
+		Value:    evmInt256.New(1),
+	}
+
+	_, err := sim.ApplyMessage(layer, &sim.BlockEnv{GasLimit: 30000000}, msg)
+	if !errors.Is(err, sim.ErrStateRead) || !errors.Is(err, errFetch) {
+		t.Fatalf("got %v, want %v with %v", err, sim.ErrStateRead, errFetch)
+	}
+}
+
+func TestStorageCommitSkipsAccountsOnlyRead(t *testing.T) {
+	layer := newLayer(t)
+	bob := types.Address{0xb0}
+
+	//bob was read by BALANCE, the sender received a value
+	result := cache.NewResultCache()
+	result.CacheAccount(environment.NewAccount(bob, nil, nil))
+	result.CacheAccount(environment.NewAccount(sender, evmInt256.New(1e18), nil)).Balance = evmInt256.New(2e18)
+
+	layer.Commit(&result)
+	if layer.AccountExist(bob) {
+		t.Fatalf("an account only read was written")
+	}
+
+	if layer.Balance(sender).Uint64() != 2e18 {
+		t.Fatalf("balance of the sender is %s, want %d", layer.Balance(sender).Text(10), uint64(2e18))
+	}
+}
+
+func TestStorageDataBlockCopy(t *testing.T) {
+	layer := newLayer(t)
+	layer.SetDataBlock(sender, types.Slot{}, types.Bytes{1, 2, 3})
+
+	data, err := layer.GetDataBlock(sender, types.Slot{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[0] = 9
+
+	if data, _ = layer.GetDataBlock(sender, types.Slot{}); data[0] != 1 {
+		t.Fatalf("writing the data block read changed the layer to %x", data)
+	}
+}
+
+func TestStorageSnapshot(t *testing.T) {
+	layer := newLayer(t)
+	layer.SetNonce(sender, 3)
+
+	snapshot := layer.Snapshot()
+	layer.SetBalance(sender, evmInt256.New(5))
+	created := layer.CreateAddress(sender, environment.Transaction{})
+	layer.SetDataBlock(created, types.Slot{}, types.Bytes{1})
+	layer.SetState(created, types.Slot{}, evmInt256.New(7))
+
+	inner := layer.Snapshot()
+	layer.DeleteAccount(sender)
+	layer.RevertToSnapshot(inner)
+	if !layer.AccountExist(sender) || layer.Nonce(sender) != 4 || layer.Balance(sender).Uint64() != 5 {
+		t.Fatalf("the inner revert left nonce %d and balance %s, want 4 and 5", layer.Nonce(sender), layer.Balance(sender).Text(10))
+	}
+
+	layer.RevertToSnapshot(snapshot)
+	if layer.Nonce(sender) != 3 || layer.Balance(sender).Uint64() != 1e18 {
+		t.Fatalf("nonce %d and balance %s after the revert, want 3 and 1e18", layer.Nonce(sender), layer.Balance(sender).Text(10))
+	}
+
+	data, _ := layer.GetDataBlock(created, types.Slot{})
+	val, _ := layer.Load(created, types.Slot{})
+	if layer.AccountExist(created) || layer.Nonce(created) != 0 || data != nil || !val.IsZero() {
+		t.Fatalf("the created account is left after the revert")
+	}
+}
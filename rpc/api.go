@@ -0,0 +1,584 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/override"
+	"github.com/SealSC/SealEVM/sim"
+	"github.com/SealSC/SealEVM/types"
+)
+
+const ClientVersion = "SealEVM/rpc"
+
+// parseParams decodes params into args in order, the ones after the first required may be
+// missing or null and are then left as they are.
+func parseParams(params []json.RawMessage, required int, args ...interface{}) error {
+	if len(params) < required {
+		return invalidParams("missing value for required argument %d", len(params))
+	}
+
+	if len(params) > len(args) {
+		return invalidParams("too many arguments, want at most %d", len(args))
+	}
+
+	for i, param := range params {
+		if string(param) == "null" {
+			if i < required {
+				return invalidParams("missing value for required argument %d", i)
+			}
+			continue
+		}
+
+		if err := json.Unmarshal(param, args[i]); err != nil {
+			return invalidParams("invalid argument %d: %v", i, err)
+		}
+	}
+
+	return nil
+}
+
+type txJSON struct {
+	Hash                 types.Hash     `json:"hash"`
+	BlockHash            types.Hash     `json:"blockHash"`
+	BlockNumber          Quantity       `json:"blockNumber"`
+	TransactionIndex     Quantity       `json:"transactionIndex"`
+	Type                 Quantity       `json:"type"`
+	From                 types.Address  `json:"from"`
+	To                   *types.Address `json:"to"`
+	Nonce                Quantity       `json:"nonce"`
+	Gas                  Quantity       `json:"gas"`
+	GasPrice             *evmInt256.Int `json:"gasPrice"`
+	MaxFeePerGas         *evmInt256.Int `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *evmInt256.Int `json:"maxPriorityFeePerGas,omitempty"`
+	MaxFeePerBlobGas     *evmInt256.Int `json:"maxFeePerBlobGas,omitempty"`
+	BlobVersionedHashes  []types.Hash   `json:"blobVersionedHashes,omitempty"`
+	Value                *evmInt256.Int `json:"value"`
+	Input                types.Bytes    `json:"input"`
+	ChainID              *evmInt256.Int `json:"chainId,omitempty"`
+	AccessList           *[]AccessTuple `json:"accessList,omitempty"`
+	V                    *evmInt256.Int `json:"v"`
+	R                    *evmInt256.Int `json:"r"`
+	S                    *evmInt256.Int `json:"s"`
+	YParity              *Quantity      `json:"yParity,omitempty"`
+}
+
+func newTxJSON(tx *Transaction, block *Block, index int) *txJSON {
+	msg := tx.Message
+	j := &txJSON{
+		Hash:                 tx.Hash,
+		BlockHash:            block.Hash,
+		BlockNumber:          Quantity(block.Number),
+		TransactionIndex:     Quantity(index),
+		Type:                 Quantity(msg.Type),
+		From:                 msg.From,
+		To:                   msg.To,
+		Nonce:                Quantity(msg.Nonce),
+		Gas:                  Quantity(msg.GasLimit),
+		GasPrice:             block.Receipts[index].EffectiveGasPrice,
+		MaxFeePerGas:         msg.GasFeeCap,
+		MaxPriorityFeePerGas: msg.GasTipCap,
+		MaxFeePerBlobGas:     msg.BlobGasFeeCap,
+		BlobVersionedHashes:  msg.BlobHashes,
+		Value:                msg.Value,
+		Input:                msg.Data,
+		ChainID:              tx.ChainID,
+		V:                    tx.V,
+		R:                    tx.R,
+		S:                    tx.S,
+	}
+
+	if msg.Type != 0 {
+		list := jsonAccessList(msg.AccessList)
+		parity := Quantity(tx.V.Uint64())
+		j.AccessList = &list
+		j.YParity = &parity
+	}
+
+	return j
+}
+
+type blockJSON struct {
+	Number           Quantity       `json:"number"`
+	Hash             types.Hash     `json:"hash"`
+	ParentHash       types.Hash     `json:"parentHash"`
+	Nonce            types.Bytes    `json:"nonce"`
+	MixHash          types.Hash     `json:"mixHash"`
+	Sha3Uncles       types.Hash     `json:"sha3Uncles"`
+	LogsBloom        types.Bytes    `json:"logsBloom"`
+	TransactionsRoot types.Hash     `json:"transactionsRoot"`
+	StateRoot        types.Hash     `json:"stateRoot"`
+	ReceiptsRoot     types.Hash     `json:"receiptsRoot"`
+	Miner            types.Address  `json:"miner"`
+	Difficulty       Quantity       `json:"difficulty"`
+	TotalDifficulty  Quantity       `json:"totalDifficulty"`
+	ExtraData        types.Bytes    `json:"extraData"`
+	Size             Quantity       `json:"size"`
+	GasLimit         Quantity       `json:"gasLimit"`
+	GasUsed          Quantity       `json:"gasUsed"`
+	Timestamp        Quantity       `json:"timestamp"`
+	BaseFeePerGas    *evmInt256.Int `json:"baseFeePerGas"`
+	Transactions     []interface{}  `json:"transactions"`
+	Uncles           []types.Hash   `json:"uncles"`
+}
+
+func newBlockJSON(b *Block, fullTx bool) *blockJSON {
+	j := &blockJSON{
+		Number:           Quantity(b.Number),
+		Hash:             b.Hash,
+		ParentHash:       b.ParentHash,
+		Nonce:            make(types.Bytes, 8),
+		Sha3Uncles:       emptyUncleHash,
+		LogsBloom:        b.Bloom,
+		TransactionsRoot: b.TxRoot,
+		StateRoot:        b.StateRoot,
+		ReceiptsRoot:     b.ReceiptsRoot,
+		Miner:            b.Coinbase,
+		ExtraData:        types.Bytes{},
+		Size:             Quantity(b.Size),
+		GasLimit:         Quantity(b.GasLimit),
+		GasUsed:          Quantity(b.GasUsed),
+		Timestamp:        Quantity(b.Timestamp),
+		BaseFeePerGas:    b.BaseFee,
+		Transactions:     []interface{}{},
+		Uncles:           []types.Hash{},
+	}
+
+	for i, tx := range b.Transactions {
+		if fullTx {
+			j.Transactions = append(j.Transactions, newTxJSON(tx, b, i))
+		} else {
+			j.Transactions = append(j.Transactions, tx.Hash)
+		}
+	}
+
+	return j
+}
+
+func (n *Node) stateAt(params []json.RawMessage, required int, addr *types.Address, extra ...interface{}) (*Block, error) {
+	ref := BlockRef{Number: LatestBlock}
+	args := append([]interface{}{addr}, extra...)
+	if err := parseParams(params, required, append(args, &ref)...); err != nil {
+		return nil, err
+	}
+
+	return n.Block(ref)
+}
+
+// Register adds the eth, net, web3 and debug methods of n to s.
+func (n *Node) Register(s *Server) {
+	chainID := n.config.ChainID
+
+	s.Register("web3_clientVersion", func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		return ClientVersion, nil
+	})
+
+	s.Register("net_version", func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		return strconv.FormatUint(chainID, 10), nil
+	})
+
+	s.Register("net_listening", func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		return true, nil
+	})
+
+	s.Register("eth_chainId", func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		return Quantity(chainID), nil
+	})
+
+	s.Register("eth_blockNumber", func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		return Quantity(n.BlockNumber()), nil
+	})
+
+	s.Register("eth_syncing", func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		return false, nil
+	})
+
+	s.Register("eth_accounts", func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		return []types.Address{}, nil
+	})
+
+	s.Register("eth_gasPrice", func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		return n.config.BaseFee, nil
+	})
+
+	s.Register("eth_maxPriorityFeePerGas", func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		return Quantity(0), nil
+	})
+
+	s.Register("eth_feeHistory", n.feeHistory)
+
+	s.Register("eth_getBalance", func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var addr types.Address
+		block, err := n.stateAt(params, 1, &addr)
+		if err != nil {
+			return nil, err
+		}
+
+		return block.State.Balance(addr), nil
+	})
+
+	s.Register("eth_getCode", func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var addr types.Address
+		block, err := n.stateAt(params, 1, &addr)
+		if err != nil {
+			return nil, err
+		}
+
+		return types.Bytes(block.State.Code(addr)), nil
+	})
+
+	s.Register("eth_getTransactionCount", func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var addr types.Address
+		block, err := n.stateAt(params, 1, &addr)
+		if err != nil {
+			return nil, err
+		}
+
+		return Quantity(block.State.Nonce(addr)), nil
+	})
+
+	s.Register("eth_getStorageAt", func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var addr types.Address
+		slot := evmInt256.New(0)
+		block, err := n.stateAt(params, 2, &addr, slot)
+		if err != nil {
+			return nil, err
+		}
+
+		val, err := block.State.Load(addr, types.Int256ToSlot(slot))
+		if err != nil {
+			return nil, err
+		}
+
+		return types.Int256ToHash(val), nil
+	})
+
+	s.Register("eth_call", func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var args CallArgs
+		var overrides CallOverrides
+		ref := BlockRef{Number: LatestBlock}
+		if err := parseParams(params, 1, &args, &ref, &overrides.State, &overrides.Block); err != nil {
+			return nil, err
+		}
+
+		result, err := n.Call(args.Message(), ref, &overrides, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if result.Err != nil {
+			return nil, executionError(result.Err, result.ReturnData)
+		}
+
+		return types.Bytes(result.ReturnData), nil
+	})
+
+	s.Register("eth_estimateGas", func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var args CallArgs
+		var overrides CallOverrides
+		ref := BlockRef{Number: LatestBlock}
+		if err := parseParams(params, 1, &args, &ref, &overrides.State, &overrides.Block); err != nil {
+			return nil, err
+		}
+
+		gas, err := n.EstimateGas(ctx, args.Message(), ref, &overrides)
+		if err != nil {
+			return nil, err
+		}
+
+		return Quantity(gas), nil
+	})
+
+	s.Register("eth_simulateV1", n.simulate)
+
+	s.Register("eth_createAccessList", func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var args CallArgs
+		ref := BlockRef{Number: LatestBlock}
+		if err := parseParams(params, 1, &args, &ref); err != nil {
+			return nil, err
+		}
+
+		result, err := n.CreateAccessList(ctx, args.Message(), ref)
+		if err != nil {
+			return nil, err
+		}
+
+		list := struct {
+			AccessList []AccessTuple `json:"accessList"`
+			GasUsed    Quantity      `json:"gasUsed"`
+			Error      string        `json:"error,omitempty"`
+		}{AccessList: jsonAccessList(result.AccessList), GasUsed: Quantity(result.GasUsed)}
+
+		if result.Err != nil {
+			list.Error = executionError(result.Err, result.ReturnData).Message
+		}
+
+		return list, nil
+	})
+
+	s.Register("eth_sendRawTransaction", func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var raw types.Bytes
+		if err := parseParams(params, 1, &raw); err != nil {
+			return nil, err
+		}
+
+		return n.SendRawTransaction(raw)
+	})
+
+	s.Register("eth_getTransactionByHash", func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var hash types.Hash
+		if err := parseParams(params, 1, &hash); err != nil {
+			return nil, err
+		}
+
+		tx, block, index, err := n.Transaction(hash)
+		if err != nil {
+			return nil, nil
+		}
+
+		return newTxJSON(tx, block, index), nil
+	})
+
+	s.Register("eth_getTransactionReceipt", func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var hash types.Hash
+		if err := parseParams(params, 1, &hash); err != nil {
+			return nil, err
+		}
+
+		receipt, err := n.Receipt(hash)
+		if err != nil {
+			return nil, nil
+		}
+
+		return receipt, nil
+	})
+
+	s.Register("eth_getBlockByNumber", func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var ref BlockRef
+		var fullTx bool
+		if err := parseParams(params, 1, &ref, &fullTx); err != nil {
+			return nil, err
+		}
+
+		block, err := n.Block(ref)
+		if err != nil {
+			return nil, nil
+		}
+
+		return newBlockJSON(block, fullTx), nil
+	})
+
+	s.Register("eth_getBlockByHash", func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var hash types.Hash
+		var fullTx bool
+		if err := parseParams(params, 1, &hash, &fullTx); err != nil {
+			return nil, err
+		}
+
+		block, err := n.Block(BlockRef{Hash: &hash})
+		if err != nil {
+			return nil, nil
+		}
+
+		return newBlockJSON(block, fullTx), nil
+	})
+
+	s.Register("eth_subscribe", n.subscribe)
+	s.Register("eth_unsubscribe", n.unsubscribe)
+
+	s.Register("eth_getLogs", func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var q FilterQuery
+		if err := parseParams(params, 1, &q); err != nil {
+			return nil, err
+		}
+
+		return n.Logs(&q)
+	})
+
+	s.Register("debug_traceTransaction", func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var hash types.Hash
+		var cfg TraceConfig
+		if err := parseParams(params, 1, &hash, &cfg); err != nil {
+			return nil, err
+		}
+
+		return n.TraceTransaction(hash, &cfg)
+	})
+
+	s.Register("debug_traceCall", func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var args CallArgs
+		var cfg TraceConfig
+		ref := BlockRef{Number: LatestBlock}
+		if err := parseParams(params, 1, &args, &ref, &cfg); err != nil {
+			return nil, err
+		}
+
+		return n.TraceCall(args.Message(), ref, &cfg)
+	})
+}
+
+// feeHistory answers eth_feeHistory, the base fee is constant and no tips are paid.
+func (n *Node) feeHistory(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+	var count Quantity
+	var newest BlockRef
+	var percentiles []float64
+	if err := parseParams(params, 2, &count, &newest, &percentiles); err != nil {
+		return nil, err
+	}
+
+	last, err := n.Block(newest)
+	if err != nil {
+		return nil, err
+	}
+
+	if uint64(count) > last.Number+1 {
+		count = Quantity(last.Number + 1)
+	}
+
+	oldest := last.Number + 1 - uint64(count)
+	history := struct {
+		OldestBlock   Quantity           `json:"oldestBlock"`
+		BaseFeePerGas []*evmInt256.Int   `json:"baseFeePerGas"`
+		GasUsedRatio  []float64          `json:"gasUsedRatio"`
+		Reward        [][]*evmInt256.Int `json:"reward,omitempty"`
+	}{OldestBlock: Quantity(oldest)}
+
+	for number := oldest; number <= last.Number; number++ {
+		b, err := n.Block(BlockRef{Number: int64(number)})
+		if err != nil {
+			return nil, err
+		}
+
+		history.BaseFeePerGas = append(history.BaseFeePerGas, b.BaseFee)
+		history.GasUsedRatio = append(history.GasUsedRatio, float64(b.GasUsed)/float64(b.GasLimit))
+		if percentiles != nil {
+			rewards := make([]*evmInt256.Int, len(percentiles))
+			for i := range rewards {
+				rewards[i] = evmInt256.New(0)
+			}
+			history.Reward = append(history.Reward, rewards)
+		}
+	}
+
+	history.BaseFeePerGas = append(history.BaseFeePerGas, n.config.BaseFee)
+	return history, nil
+}
+
+type simulatedCall struct {
+	ReturnData types.Bytes `json:"returnData"`
+	Logs       []*Log      `json:"logs"`
+	GasUsed    Quantity    `json:"gasUsed"`
+	Status     Quantity    `json:"status"`
+	Error      *Error      `json:"error,omitempty"`
+}
+
+type simulatedBlock struct {
+	Number        Quantity         `json:"number"`
+	Timestamp     Quantity         `json:"timestamp"`
+	GasLimit      Quantity         `json:"gasLimit"`
+	GasUsed       Quantity         `json:"gasUsed"`
+	Miner         types.Address    `json:"miner"`
+	BaseFeePerGas *evmInt256.Int   `json:"baseFeePerGas"`
+	Calls         []*simulatedCall `json:"calls"`
+}
+
+// simulate answers eth_simulateV1 with SimulateBundle, the simulated blocks have no hash.
+func (n *Node) simulate(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+	var opts SimulateOptions
+	ref := BlockRef{Number: LatestBlock}
+	if err := parseParams(params, 1, &opts, &ref); err != nil {
+		return nil, err
+	}
+
+	if opts.Validation || opts.TraceTransfers {
+		return nil, invalidParams("validation and traceTransfers are not supported")
+	}
+
+	var calls []sim.Call
+	for _, b := range opts.BlockStateCalls {
+		block := b.BlockOverrides
+		if block == nil {
+			block = &override.BlockOverride{}
+		}
+
+		first := sim.Call{Block: block, State: b.StateOverrides}
+		if len(b.Calls) == 0 {
+			calls = append(calls, first)
+			continue
+		}
+
+		for i, args := range b.Calls {
+			call := sim.Call{Message: args.Message()}
+			if i == 0 {
+				call.Block, call.State = first.Block, first.State
+			}
+			calls = append(calls, call)
+		}
+	}
+
+	results, err := n.SimulateBundle(ctx, calls, ref, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks []*simulatedBlock
+	var current *simulatedBlock
+	for i, result := range results {
+		if calls[i].Block != nil {
+			env := result.Env
+			current = &simulatedBlock{
+				Number:        Quantity(env.Number),
+				Timestamp:     Quantity(env.Timestamp),
+				GasLimit:      Quantity(env.GasLimit),
+				Miner:         env.Coinbase,
+				BaseFeePerGas: env.BaseFee,
+				Calls:         []*simulatedCall{},
+			}
+			blocks = append(blocks, current)
+		}
+
+		if calls[i].Message == nil {
+			continue
+		}
+
+		call := &simulatedCall{
+			ReturnData: result.ReturnData,
+			Logs:       []*Log{},
+			GasUsed:    Quantity(result.GasUsed),
+			Status:     1,
+		}
+
+		if result.Err != nil {
+			call.Status = 0
+			call.Error = executionError(result.Err, result.ReturnData)
+		}
+
+		logIndex := 0
+		for _, c := range current.Calls {
+			logIndex += len(c.Logs)
+		}
+
+		for _, l := range result.Logs {
+			call.Logs = append(call.Logs, &Log{
+				Address:     l.Address,
+				Topics:      l.Topics,
+				Data:        l.Data,
+				BlockNumber: current.Number,
+				TxIndex:     Quantity(len(current.Calls)),
+				Index:       Quantity(logIndex),
+			})
+			logIndex++
+		}
+
+		current.GasUsed += call.GasUsed
+		current.Calls = append(current.Calls, call)
+	}
+
+	if blocks == nil {
+		blocks = []*simulatedBlock{}
+	}
+
+	return blocks, nil
+}
@@ -0,0 +1,73 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/SealSC/SealEVM/abi"
+	"github.com/SealSC/SealEVM/evmErrors"
+	"github.com/SealSC/SealEVM/sim"
+	"github.com/SealSC/SealEVM/types"
+)
+
+// JSON-RPC 2.0 error codes, ErrCodeRevert is the one Ethereum clients give to reverted calls.
+const (
+	ErrCodeParse          = -32700
+	ErrCodeInvalidRequest = -32600
+	ErrCodeMethodNotFound = -32601
+	ErrCodeInvalidParams  = -32602
+	ErrCodeInternal       = -32603
+	ErrCodeServer         = -32000
+	ErrCodeRevert         = 3
+)
+
+var (
+	ErrUnknownBlock       = errors.New("unknown block")
+	ErrUnknownTransaction = errors.New("unknown transaction")
+	ErrInvalidTransaction = errors.New("invalid transaction")
+)
+
+// Error is the error object of a response. Handlers may return one to choose the code,
+// other errors are sent with ErrCodeServer.
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func invalidParams(format string, args ...interface{}) *Error {
+	return &Error{Code: ErrCodeInvalidParams, Message: fmt.Sprintf(format, args...)}
+}
+
+func toError(err error) *Error {
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+
+	var execErr *sim.ExecutionError
+	if errors.As(err, &execErr) && errors.Is(execErr.Err, evmErrors.RevertErr) {
+		return executionError(execErr.Err, execErr.ReturnData)
+	}
+
+	return &Error{Code: ErrCodeServer, Message: err.Error()}
+}
+
+// executionError is the error of a failed call, a revert carries its data and reason as
+// geth gives them.
+func executionError(err error, returnData []byte) *Error {
+	if !errors.Is(err, evmErrors.RevertErr) {
+		return &Error{Code: ErrCodeServer, Message: err.Error()}
+	}
+
+	msg := "execution reverted"
+	if reason, unpackErr := abi.UnpackRevert(returnData); unpackErr == nil {
+		msg += ": " + reason
+	}
+
+	return &Error{Code: ErrCodeRevert, Message: msg, Data: types.Bytes(returnData)}
+}
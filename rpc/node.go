@@ -0,0 +1,634 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/SealSC/SealEVM/crypto/hashes"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/instructions"
+	"github.com/SealSC/SealEVM/override"
+	"github.com/SealSC/SealEVM/sim"
+	"github.com/SealSC/SealEVM/tracer"
+	"github.com/SealSC/SealEVM/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	DefaultChainID  = 1337
+	DefaultGasLimit = 30000000
+)
+
+var emptyUncleHash = func() types.Hash {
+	var h types.Hash
+	h.SetBytes(hashes.Keccak256([]byte{0xc0}))
+	return h
+}()
+
+var ErrChainIDMismatch = errors.New("invalid chain id")
+
+// Config is the chain of a Node, zero fields take the defaults: chain id DefaultChainID,
+// gas limit DefaultGasLimit, base fee 0 and the current time for the genesis block.
+type Config struct {
+	ChainID   uint64
+	GasLimit  uint64
+	BaseFee   *evmInt256.Int
+	Coinbase  types.Address
+	Timestamp uint64
+}
+
+// Log is a log of a mined transaction, with its position in the chain.
+type Log struct {
+	Address     types.Address `json:"address"`
+	Topics      []types.Hash  `json:"topics"`
+	Data        types.Bytes   `json:"data"`
+	BlockNumber Quantity      `json:"blockNumber"`
+	BlockHash   types.Hash    `json:"blockHash"`
+	TxHash      types.Hash    `json:"transactionHash"`
+	TxIndex     Quantity      `json:"transactionIndex"`
+	Index       Quantity      `json:"logIndex"`
+	Removed     bool          `json:"removed"`
+}
+
+// Receipt is the receipt of a mined transaction, Err and ReturnData are the outcome of
+// the execution, which are not part of the JSON receipt.
+type Receipt struct {
+	TxHash            types.Hash     `json:"transactionHash"`
+	TxIndex           Quantity       `json:"transactionIndex"`
+	BlockHash         types.Hash     `json:"blockHash"`
+	BlockNumber       Quantity       `json:"blockNumber"`
+	From              types.Address  `json:"from"`
+	To                *types.Address `json:"to"`
+	CumulativeGasUsed Quantity       `json:"cumulativeGasUsed"`
+	GasUsed           Quantity       `json:"gasUsed"`
+	EffectiveGasPrice *evmInt256.Int `json:"effectiveGasPrice"`
+	ContractAddress   *types.Address `json:"contractAddress"`
+	Logs              []*Log         `json:"logs"`
+	Bloom             types.Bytes    `json:"logsBloom"`
+	Status            Quantity       `json:"status"`
+	Type              Quantity       `json:"type"`
+
+	Err        error       `json:"-"`
+	ReturnData types.Bytes `json:"-"`
+}
+
+// Block is a block of the chain with the state after it.
+type Block struct {
+	Number       uint64
+	Hash         types.Hash
+	ParentHash   types.Hash
+	Coinbase     types.Address
+	StateRoot    types.Hash
+	TxRoot       types.Hash
+	ReceiptsRoot types.Hash
+	Bloom        []byte
+	GasLimit     uint64
+	GasUsed      uint64
+	Timestamp    uint64
+	BaseFee      *evmInt256.Int
+	Size         uint64
+
+	Transactions []*Transaction
+	Receipts     []*Receipt
+
+	State View
+}
+
+// Env is the environment the transactions of b run in.
+func (b *Block) Env(chainID uint64) *sim.BlockEnv {
+	return &sim.BlockEnv{
+		Coinbase:   b.Coinbase,
+		GasLimit:   b.GasLimit,
+		Number:     b.Number,
+		Timestamp:  b.Timestamp,
+		ChainID:    evmInt256.New(chainID),
+		BaseFee:    b.BaseFee.Clone(),
+		Difficulty: evmInt256.New(0),
+	}
+}
+
+// seal sets the roots, the bloom and the hash of b, as the hash of a London header.
+func (b *Block) seal(stateRoot types.Hash) {
+	txs := make([][]byte, len(b.Transactions))
+	receipts := make([][]byte, len(b.Receipts))
+	var logs []*types.Log
+	for i, tx := range b.Transactions {
+		txs[i] = tx.Raw
+		receipts[i] = sim.EncodeReceipt(tx.Message.Type, &sim.TxResult{Logs: b.Receipts[i].rawLogs(), Err: b.Receipts[i].Err}, uint64(b.Receipts[i].CumulativeGasUsed))
+		logs = append(logs, b.Receipts[i].rawLogs()...)
+	}
+
+	b.TxRoot = sim.ListRoot(txs)
+	b.ReceiptsRoot = sim.ListRoot(receipts)
+	b.Bloom = sim.LogsBloom(logs)
+	b.StateRoot = stateRoot
+
+	header, _ := rlp.EncodeToBytes([]interface{}{
+		b.ParentHash, emptyUncleHash, b.Coinbase, b.StateRoot, b.TxRoot, b.ReceiptsRoot, b.Bloom,
+		uint64(0), b.Number, b.GasLimit, b.GasUsed, b.Timestamp, []byte{}, types.Hash{}, make([]byte, 8), b.BaseFee.Int,
+	})
+
+	b.Hash.SetBytes(hashes.Keccak256(header))
+	b.Size = uint64(len(header))
+	for _, tx := range txs {
+		b.Size += uint64(len(tx))
+	}
+
+	for i, receipt := range b.Receipts {
+		receipt.BlockHash = b.Hash
+		receipt.BlockNumber = Quantity(b.Number)
+		receipt.TxIndex = Quantity(i)
+		for _, l := range receipt.Logs {
+			l.BlockHash = b.Hash
+			l.BlockNumber = Quantity(b.Number)
+			l.TxIndex = Quantity(i)
+		}
+	}
+}
+
+func (r *Receipt) rawLogs() []*types.Log {
+	logs := make([]*types.Log, len(r.Logs))
+	for i, l := range r.Logs {
+		logs[i] = &types.Log{Address: l.Address, Topics: l.Topics, Data: l.Data}
+	}
+
+	return logs
+}
+
+type txLocation struct {
+	block *Block
+	index int
+}
+
+// Node is a development chain over a Backend, served by Register. Each transaction sent is
+// mined at once in a block of its own, the backend keeps the state of every block for the
+// queries, calls and traces at past blocks.
+type Node struct {
+	lock    sync.RWMutex
+	config  Config
+	backend Backend
+	blocks  []*Block
+	byHash  map[types.Hash]*Block
+	txs     map[types.Hash]txLocation
+	nowFunc func() uint64
+
+	subLock sync.Mutex
+	subs    map[string]*subscription
+}
+
+// NewNode starts a chain on backend, the pending state of backend is committed as block 0.
+func NewNode(config Config, backend Backend) (*Node, error) {
+	if config.ChainID == 0 {
+		config.ChainID = DefaultChainID
+	}
+
+	if config.GasLimit == 0 {
+		config.GasLimit = DefaultGasLimit
+	}
+
+	if config.BaseFee == nil {
+		config.BaseFee = evmInt256.New(0)
+	}
+
+	n := &Node{
+		config:  config,
+		backend: backend,
+		byHash:  map[types.Hash]*Block{},
+		txs:     map[types.Hash]txLocation{},
+		subs:    map[string]*subscription{},
+		nowFunc: func() uint64 { return uint64(time.Now().Unix()) },
+	}
+
+	if config.Timestamp == 0 {
+		config.Timestamp = n.nowFunc()
+	}
+
+	err := n.addBlock(&Block{
+		GasLimit:  config.GasLimit,
+		Timestamp: config.Timestamp,
+		BaseFee:   config.BaseFee.Clone(),
+		Coinbase:  config.Coinbase,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+func (n *Node) Config() Config {
+	return n.config
+}
+
+// addBlock seals b, commits the pending state of the backend as its state and appends it to
+// the chain, the subscriptions are notified of it. The lock must be held.
+func (n *Node) addBlock(b *Block) error {
+	var root types.Hash
+	if rooter, ok := n.backend.(StateRooter); ok {
+		root = rooter.Root()
+	}
+
+	b.seal(root)
+	if err := n.backend.CommitBlock(b.Number, b.Hash); err != nil {
+		return err
+	}
+
+	var err error
+	if b.State, err = n.backend.At(b.Number); err != nil {
+		return err
+	}
+
+	n.blocks = append(n.blocks, b)
+	n.byHash[b.Hash] = b
+	for i, tx := range b.Transactions {
+		n.txs[tx.Hash] = txLocation{block: b, index: i}
+	}
+
+	n.publish(b)
+	return nil
+}
+
+func (n *Node) BlockNumber() uint64 {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	return uint64(len(n.blocks) - 1)
+}
+
+// Block is the block ref points to, "pending" is the latest block.
+func (n *Node) Block(ref BlockRef) (*Block, error) {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	if ref.Hash != nil {
+		if b := n.byHash[*ref.Hash]; b != nil {
+			return b, nil
+		}
+
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBlock, ref.Hash)
+	}
+
+	switch ref.Number {
+	case LatestBlock, PendingBlock:
+		return n.blocks[len(n.blocks)-1], nil
+	case EarliestBlock:
+		return n.blocks[0], nil
+	}
+
+	if ref.Number < 0 || ref.Number >= int64(len(n.blocks)) {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownBlock, ref.Number)
+	}
+
+	return n.blocks[ref.Number], nil
+}
+
+// Transaction is a mined transaction with its block and index.
+func (n *Node) Transaction(hash types.Hash) (*Transaction, *Block, int, error) {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	loc, ok := n.txs[hash]
+	if !ok {
+		return nil, nil, 0, fmt.Errorf("%w: %s", ErrUnknownTransaction, hash)
+	}
+
+	return loc.block.Transactions[loc.index], loc.block, loc.index, nil
+}
+
+// Receipt is the receipt of a mined transaction.
+func (n *Node) Receipt(hash types.Hash) (*Receipt, error) {
+	_, block, index, err := n.Transaction(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return block.Receipts[index], nil
+}
+
+// SendTransaction mines tx in a new block. Invalid transactions are rejected with the
+// error of sim.ApplyMessage, a failed execution is mined and reported in the receipt.
+func (n *Node) SendTransaction(tx *Transaction) (*Receipt, error) {
+	if tx.ChainID != nil && (!tx.ChainID.IsUint64() || tx.ChainID.Uint64() != n.config.ChainID) {
+		return nil, fmt.Errorf("%w: have %s, want %d", ErrChainIDMismatch, tx.ChainID.Text(10), n.config.ChainID)
+	}
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	parent := n.blocks[len(n.blocks)-1]
+	block := &Block{
+		Number:       parent.Number + 1,
+		ParentHash:   parent.Hash,
+		Coinbase:     n.config.Coinbase,
+		GasLimit:     n.config.GasLimit,
+		Timestamp:    n.nowFunc(),
+		BaseFee:      n.config.BaseFee.Clone(),
+		Transactions: []*Transaction{tx},
+	}
+
+	if block.Timestamp <= parent.Timestamp {
+		block.Timestamp = parent.Timestamp + 1
+	}
+
+	msg := tx.Message
+	result, err := sim.ApplyMessage(n.backend.Pending(), block.Env(n.config.ChainID), msg)
+	if err != nil {
+		//a failed read may have left changes behind
+		return nil, errors.Join(err, n.backend.Discard())
+	}
+
+	receipt := &Receipt{
+		TxHash:            tx.Hash,
+		From:              msg.From,
+		To:                msg.To,
+		CumulativeGasUsed: Quantity(result.GasUsed),
+		GasUsed:           Quantity(result.GasUsed),
+		EffectiveGasPrice: effectiveGasPrice(msg, block.BaseFee),
+		Logs:              []*Log{},
+		Type:              Quantity(msg.Type),
+		Err:               result.Err,
+		ReturnData:        result.ReturnData,
+	}
+
+	if result.Err == nil {
+		receipt.Status = 1
+		receipt.ContractAddress = result.ContractAddress
+	}
+
+	for i, l := range result.Logs {
+		receipt.Logs = append(receipt.Logs, &Log{
+			Address: l.Address,
+			Topics:  append([]types.Hash{}, l.Topics...),
+			Data:    l.Data,
+			TxHash:  tx.Hash,
+			Index:   Quantity(i),
+		})
+	}
+
+	receipt.Bloom = sim.LogsBloom(result.Logs)
+	block.GasUsed = result.GasUsed
+	block.Receipts = []*Receipt{receipt}
+
+	if err = n.addBlock(block); err != nil {
+		return nil, errors.Join(err, n.backend.Discard())
+	}
+
+	return receipt, nil
+}
+
+// SendRawTransaction decodes raw and mines it, see SendTransaction.
+func (n *Node) SendRawTransaction(raw []byte) (types.Hash, error) {
+	tx, err := DecodeTransaction(raw)
+	if err != nil {
+		return types.Hash{}, err
+	}
+
+	if _, err = n.SendTransaction(tx); err != nil {
+		return types.Hash{}, err
+	}
+
+	return tx.Hash, nil
+}
+
+// effectiveGasPrice is the price per gas paid by msg at baseFee.
+func effectiveGasPrice(msg *sim.Message, baseFee *evmInt256.Int) *evmInt256.Int {
+	if msg.GasFeeCap == nil {
+		if msg.GasPrice == nil {
+			return evmInt256.New(0)
+		}
+		return msg.GasPrice.Clone()
+	}
+
+	price := baseFee.Clone()
+	price.Add(msg.GasTipCap)
+	if price.GT(msg.GasFeeCap) {
+		return msg.GasFeeCap.Clone()
+	}
+
+	return price
+}
+
+// callState is a layer over the state of the block ref points to and the environment the
+// calls run in, "pending" gives the environment of the next block. overrides may be nil.
+func (n *Node) callState(ref BlockRef, overrides *CallOverrides) (*override.Storage, *sim.BlockEnv, error) {
+	block, err := n.Block(ref)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	env := block.Env(n.config.ChainID)
+	if ref.Hash == nil && ref.Number == PendingBlock {
+		env.Number++
+		env.Timestamp = n.nowFunc()
+		if env.Timestamp <= block.Timestamp {
+			env.Timestamp = block.Timestamp + 1
+		}
+	}
+
+	if overrides == nil {
+		overrides = &CallOverrides{}
+	}
+
+	env.Override(overrides.Block)
+
+	view, err := n.backend.At(block.Number)
+	if err != nil {
+		return nil, nil, err
+	}
+	view.SetCurrentBlock(env.Number)
+
+	layer, err := override.NewStorage(view, overrides.State)
+	if err != nil {
+		return nil, nil, invalidParams("%v", err)
+	}
+
+	return layer, env, nil
+}
+
+// Call runs msg on a layer over the state at ref, see sim.CallMessage. overrides and hook
+// may be nil.
+func (n *Node) Call(msg *sim.Message, ref BlockRef, overrides *CallOverrides, hook instructions.IStepHook) (*sim.TxResult, error) {
+	state, env, err := n.callState(ref, overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	return sim.CallMessage(state, env, msg, hook)
+}
+
+// EstimateGas is the lowest gas limit msg succeeds with at ref, see sim.GasEstimator.
+// overrides may be nil.
+func (n *Node) EstimateGas(ctx context.Context, msg *sim.Message, ref BlockRef, overrides *CallOverrides) (uint64, error) {
+	state, env, err := n.callState(ref, overrides)
+	if err != nil {
+		return 0, err
+	}
+
+	estimator := &sim.GasEstimator{
+		State: func() sim.StateDB { return state.Copy() },
+		Env:   env,
+	}
+
+	return estimator.EstimateGas(ctx, msg)
+}
+
+// CreateAccessList is the access list of msg at ref, see sim.CreateAccessList.
+func (n *Node) CreateAccessList(ctx context.Context, msg *sim.Message, ref BlockRef) (*sim.AccessListResult, error) {
+	state, env, err := n.callState(ref, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return sim.CreateAccessList(ctx, func() sim.StateDB { return state.Copy() }, env, msg)
+}
+
+func traceConfig(cfg *TraceConfig) (*tracer.Config, error) {
+	if cfg == nil {
+		cfg = &TraceConfig{}
+	}
+
+	if cfg.Tracer != "" {
+		return nil, invalidParams("unsupported tracer %q, only the struct logger is available", cfg.Tracer)
+	}
+
+	return &tracer.Config{
+		EnableMemory:     cfg.EnableMemory,
+		DisableStack:     cfg.DisableStack,
+		DisableStorage:   cfg.DisableStorage,
+		EnableReturnData: cfg.EnableReturnData,
+		Limit:            cfg.Limit,
+	}, nil
+}
+
+func structLogger(cfg *TraceConfig) (*tracer.StructLogger, error) {
+	config, err := traceConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return tracer.NewStructLogger(config), nil
+}
+
+// TraceTransaction replays a mined transaction on the state of the block before it.
+func (n *Node) TraceTransaction(hash types.Hash, cfg *TraceConfig) (*tracer.ExecutionResult, error) {
+	tx, block, _, err := n.Transaction(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	logger, err := structLogger(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	parent, err := n.Block(BlockRef{Number: int64(block.Number) - 1})
+	if err != nil {
+		return nil, err
+	}
+
+	view, err := n.backend.At(parent.Number)
+	if err != nil {
+		return nil, err
+	}
+	view.SetCurrentBlock(block.Number)
+
+	//no overrides, nothing to validate
+	state, _ := override.NewStorage(view, nil)
+	result, err := sim.TraceMessage(state, block.Env(n.config.ChainID), tx.Message, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return logger.Result(result.GasUsed, result.ReturnData, result.Err), nil
+}
+
+// TraceCall traces msg run as Call does, with the overrides of cfg.
+func (n *Node) TraceCall(msg *sim.Message, ref BlockRef, cfg *TraceConfig) (*tracer.ExecutionResult, error) {
+	logger, err := structLogger(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var overrides *CallOverrides
+	if cfg != nil {
+		overrides = &CallOverrides{State: cfg.StateOverrides, Block: cfg.BlockOverrides}
+	}
+
+	result, err := n.Call(msg, ref, overrides, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return logger.Result(result.GasUsed, result.ReturnData, result.Err), nil
+}
+
+// SimulateBundle runs calls in order on one scratch state over the state at ref, see
+// sim.Simulator. Each call is traced when cfg is not nil.
+func (n *Node) SimulateBundle(ctx context.Context, calls []sim.Call, ref BlockRef, cfg *TraceConfig) ([]*sim.CallResult, error) {
+	state, env, err := n.callState(ref, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	simulator := &sim.Simulator{State: state, Env: env}
+	if cfg != nil {
+		if simulator.Trace, err = traceConfig(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	return simulator.SimulateBundle(ctx, calls)
+}
+
+// Logs are the logs of the mined transactions matching q, from the latest block when q
+// has no range.
+func (n *Node) Logs(q *FilterQuery) ([]*Log, error) {
+	var blocks []*Block
+	if q.BlockHash != nil {
+		b, err := n.Block(BlockRef{Hash: q.BlockHash})
+		if err != nil {
+			return nil, err
+		}
+		blocks = []*Block{b}
+	} else {
+		from, to := BlockRef{Number: LatestBlock}, BlockRef{Number: LatestBlock}
+		if q.FromBlock != nil {
+			from = *q.FromBlock
+		}
+		if q.ToBlock != nil {
+			to = *q.ToBlock
+		}
+
+		first, err := n.Block(from)
+		if err != nil {
+			return nil, err
+		}
+
+		last, err := n.Block(to)
+		if err != nil {
+			return nil, err
+		}
+
+		for number := first.Number; number <= last.Number; number++ {
+			b, _ := n.Block(BlockRef{Number: int64(number)})
+			blocks = append(blocks, b)
+		}
+	}
+
+	logs := []*Log{}
+	for _, b := range blocks {
+		for _, receipt := range b.Receipts {
+			for _, l := range receipt.Logs {
+				if q.Match(&types.Log{Address: l.Address, Topics: l.Topics}) {
+					logs = append(logs, l)
+				}
+			}
+		}
+	}
+
+	return logs, nil
+}
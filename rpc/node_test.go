@@ -0,0 +1,342 @@
+package rpc_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SealSC/SealEVM/crypto/hashes"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/rpc"
+	"github.com/SealSC/SealEVM/sim"
+	"github.com/SealSC/SealEVM/types"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/gorilla/websocket"
+)
+
+var (
+	key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	sender = types.Address(crypto.PubkeyToAddress(key.PublicKey))
+)
+
+// returns slot 0 when called without data, otherwise stores the first word of the data in it
+// and logs it with the topic 0xaa
+var (
+	runtimeCode = "36600c575f545f5260205ff35b5f35805f555f5260aa60205fa100"
+	deployCode  = hexutil.MustDecode("0x601b600a5f39601b5ff3" + runtimeCode)
+	logTopic    = types.Hash{31: 0xaa}
+)
+
+// signLegacy signs a legacy transaction with EIP-155 for the default chain of the node.
+func signLegacy(t *testing.T, nonce uint64, to *types.Address, data []byte) types.Bytes {
+	t.Helper()
+
+	var recipient []byte
+	if to != nil {
+		recipient = to[:]
+	}
+
+	chainID := uint64(rpc.DefaultChainID)
+	fields := []interface{}{nonce, uint64(1), uint64(200000), recipient, uint64(0), data}
+	payload, _ := rlp.EncodeToBytes(append(fields, chainID, uint(0), uint(0)))
+
+	sig, err := crypto.Sign(hashes.Keccak256(payload), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := uint64(sig[64]) + chainID*2 + 35
+	raw, _ := rlp.EncodeToBytes(append(fields, v, new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:64])))
+	return raw
+}
+
+type client struct {
+	t   *testing.T
+	url string
+}
+
+func newNode(t *testing.T) *client {
+	node, err := rpc.NewNode(rpc.Config{}, rpc.NewArchiveBackend(sim.Alloc{
+		sender: {Balance: evmInt256.New(1e18)},
+	}))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := rpc.NewServer()
+	node.Register(server)
+
+	srv := httptest.NewServer(server)
+	t.Cleanup(srv.Close)
+	return &client{t: t, url: srv.URL}
+}
+
+// call calls method and decodes its result into result, which may be nil.
+func (c *client) call(result interface{}, method string, params ...interface{}) *rpc.Error {
+	c.t.Helper()
+
+	body, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	resp, err := http.Post(c.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		Result json.RawMessage
+		Error  *rpc.Error
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		c.t.Fatal(err)
+	}
+
+	if out.Error == nil && result != nil {
+		if err = json.Unmarshal(out.Result, result); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+
+	return out.Error
+}
+
+func (c *client) mustCall(result interface{}, method string, params ...interface{}) {
+	c.t.Helper()
+
+	if err := c.call(result, method, params...); err != nil {
+		c.t.Fatalf("%s: %d %s", method, err.Code, err.Message)
+	}
+}
+
+type receipt struct {
+	Status          hexutil.Uint64 `json:"status"`
+	BlockNumber     hexutil.Uint64 `json:"blockNumber"`
+	ContractAddress *types.Address `json:"contractAddress"`
+	Logs            []struct {
+		Address types.Address `json:"address"`
+		Topics  []types.Hash  `json:"topics"`
+		Data    types.Bytes   `json:"data"`
+	} `json:"logs"`
+}
+
+// send sends a signed transaction and returns its receipt.
+func (c *client) send(nonce uint64, to *types.Address, data []byte) *receipt {
+	c.t.Helper()
+
+	var hash types.Hash
+	c.mustCall(&hash, "eth_sendRawTransaction", signLegacy(c.t, nonce, to, data))
+
+	var r *receipt
+	c.mustCall(&r, "eth_getTransactionReceipt", hash)
+	if r == nil {
+		c.t.Fatalf("no receipt for %s", hash)
+	}
+
+	return r
+}
+
+func (c *client) deploy() types.Address {
+	c.t.Helper()
+
+	r := c.send(0, nil, deployCode)
+	if r.Status != 1 || r.ContractAddress == nil {
+		c.t.Fatalf("deployment failed: %+v", r)
+	}
+
+	return *r.ContractAddress
+}
+
+func word(v byte) []byte {
+	w := make([]byte, 32)
+	w[31] = v
+	return w
+}
+
+func TestNodeSendRawTransaction(t *testing.T) {
+	c := newNode(t)
+	contract := c.deploy()
+
+	r := c.send(1, &contract, word(9))
+	if r.Status != 1 || r.BlockNumber != 2 {
+		t.Fatalf("receipt is %+v, want a success in block 2", r)
+	}
+
+	if len(r.Logs) != 1 || r.Logs[0].Address != contract || r.Logs[0].Topics[0] != logTopic || !bytes.Equal(r.Logs[0].Data, word(9)) {
+		t.Fatalf("logs are %+v", r.Logs)
+	}
+
+	var slot types.Hash
+	c.mustCall(&slot, "eth_getStorageAt", contract, "0x0", "latest")
+	if slot != types.Hash(word(9)) {
+		t.Fatalf("slot 0 is %s, want 9", slot)
+	}
+
+	var nonce hexutil.Uint64
+	c.mustCall(&nonce, "eth_getTransactionCount", sender, "latest")
+	if nonce != 2 {
+		t.Fatalf("nonce is %d, want 2", nonce)
+	}
+
+	//a rejected transaction is not mined and changes nothing
+	if err := c.call(nil, "eth_sendRawTransaction", signLegacy(t, 5, &contract, word(1))); err == nil {
+		t.Fatalf("a transaction with a future nonce was accepted")
+	}
+
+	var number hexutil.Uint64
+	c.mustCall(&number, "eth_blockNumber")
+	if number != 2 {
+		t.Fatalf("block number is %d, want 2", number)
+	}
+
+	if r = c.send(2, &contract, word(3)); r.Status != 1 || r.BlockNumber != 3 {
+		t.Fatalf("receipt is %+v, want a success in block 3", r)
+	}
+}
+
+func TestNodeCall(t *testing.T) {
+	c := newNode(t)
+	contract := c.deploy()
+	c.send(1, &contract, word(9))
+
+	call := map[string]interface{}{"from": sender, "to": contract}
+	for _, at := range []struct {
+		block string
+		want  byte
+	}{
+		{"latest", 9},
+		{"0x1", 0},
+		{"0x2", 9},
+	} {
+		var ret types.Bytes
+		c.mustCall(&ret, "eth_call", call, at.block)
+		if !bytes.Equal(ret, word(at.want)) {
+			t.Errorf("eth_call at %s returned %x, want %d", at.block, ret, at.want)
+		}
+	}
+
+	//the overrides and the writes of a call stay in the call
+	overrides := map[types.Address]interface{}{contract: map[string]interface{}{"stateDiff": map[types.Hash]types.Hash{{}: types.Hash(word(7))}}}
+	var ret types.Bytes
+	c.mustCall(&ret, "eth_call", call, "latest", overrides)
+	if !bytes.Equal(ret, word(7)) {
+		t.Fatalf("eth_call with an override returned %x, want 7", ret)
+	}
+
+	store := map[string]interface{}{"from": sender, "to": contract, "data": types.Bytes(word(5))}
+	c.mustCall(nil, "eth_call", store, "latest")
+
+	var slot types.Hash
+	c.mustCall(&slot, "eth_getStorageAt", contract, "0x0", "latest")
+	if slot != types.Hash(word(9)) {
+		t.Fatalf("slot 0 is %s after the calls, want 9", slot)
+	}
+}
+
+// readNotification reads a notification, it returns its subscription with its result.
+func readNotification(t *testing.T, conn *websocket.Conn) (string, json.RawMessage) {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var msg struct {
+		Method string
+		Params struct {
+			Subscription string
+			Result       json.RawMessage
+		}
+	}
+
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.Method != "eth_subscription" {
+		t.Fatalf("got %+v, want a notification", msg)
+	}
+
+	return msg.Params.Subscription, msg.Params.Result
+}
+
+func subscribe(t *testing.T, conn *websocket.Conn, params ...interface{}) string {
+	t.Helper()
+
+	if err := conn.WriteJSON(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": "eth_subscribe", "params": params}); err != nil {
+		t.Fatal(err)
+	}
+
+	var resp struct {
+		Result string
+		Error  *rpc.Error
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := conn.ReadJSON(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Error != nil {
+		t.Fatalf("eth_subscribe: %s", resp.Error.Message)
+	}
+
+	return resp.Result
+}
+
+func TestNodeSubscriptions(t *testing.T) {
+	c := newNode(t)
+	contract := c.deploy()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(c.url, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	heads := subscribe(t, conn, rpc.NewHeadsSubscription)
+	logs := subscribe(t, conn, rpc.LogsSubscription, map[string]interface{}{"address": contract, "topics": []types.Hash{logTopic}})
+
+	c.send(1, &contract, word(9))
+
+	//each subscription is written on its own, the notifications come in any order
+	results := map[string]json.RawMessage{}
+	for i := 0; i < 2; i++ {
+		id, result := readNotification(t, conn)
+		results[id] = result
+	}
+
+	var head struct {
+		Number hexutil.Uint64 `json:"number"`
+		Hash   types.Hash     `json:"hash"`
+	}
+
+	if err := json.Unmarshal(results[heads], &head); err != nil || head.Number != 2 {
+		t.Fatalf("newHeads %s got %s, want block 2", heads, results[heads])
+	}
+
+	var l struct {
+		Address     types.Address  `json:"address"`
+		Data        types.Bytes    `json:"data"`
+		BlockHash   types.Hash     `json:"blockHash"`
+		BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	}
+
+	if err := json.Unmarshal(results[logs], &l); err != nil || l.Address != contract || !bytes.Equal(l.Data, word(9)) {
+		t.Fatalf("logs %s got %s, want the log of the contract", logs, results[logs])
+	}
+
+	if l.BlockNumber != 2 || l.BlockHash != head.Hash {
+		t.Fatalf("the log is in block %d %s, want 2 %s", l.BlockNumber, l.BlockHash, head.Hash)
+	}
+
+	//over HTTP there is nothing to notify
+	if err := c.call(nil, "eth_subscribe", rpc.NewHeadsSubscription); err == nil || err.Code != rpc.ErrCodeMethodNotFound {
+		t.Fatalf("eth_subscribe over HTTP got %v", err)
+	}
+}
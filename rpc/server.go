@@ -0,0 +1,340 @@
+// Package rpc serves the Ethereum JSON-RPC API, over HTTP and WebSocket, from a development
+// chain executing on SealEVM over a pluggable Backend. Each transaction is mined at once.
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	maxRequestSize = 5 * 1024 * 1024
+	writeTimeout   = 10 * time.Second
+)
+
+// DefaultOrigins are the origins a new Server allows, the pages served from the local host.
+var DefaultOrigins = []string{"localhost", "127.0.0.1", "::1"}
+
+// Handler serves one method, params are the positional parameters of the request. ctx is
+// done when the client goes away.
+type Handler func(ctx context.Context, params []json.RawMessage) (interface{}, error)
+
+type request struct {
+	JSONRPC string            `json:"jsonrpc"`
+	ID      json.RawMessage   `json:"id"`
+	Method  string            `json:"method"`
+	Params  []json.RawMessage `json:"params"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+type notification struct {
+	JSONRPC string             `json:"jsonrpc"`
+	Method  string             `json:"method"`
+	Params  notificationParams `json:"params"`
+}
+
+type notificationParams struct {
+	Subscription string      `json:"subscription"`
+	Result       interface{} `json:"result"`
+}
+
+// Notifier sends the notifications of the subscriptions made on a WebSocket connection, the
+// handlers find it in their ctx with NotifierFromContext.
+type Notifier struct {
+	lock   sync.Mutex
+	conn   *websocket.Conn
+	closed chan struct{}
+}
+
+type notifierKey struct{}
+
+// NotifierFromContext is the notifier of the connection a request came from, ok is false for
+// the requests over HTTP, which can not be notified.
+func NotifierFromContext(ctx context.Context) (notifier *Notifier, ok bool) {
+	notifier, ok = ctx.Value(notifierKey{}).(*Notifier)
+	return notifier, ok
+}
+
+// write sends v on the connection, a write taking longer than writeTimeout fails.
+func (n *Notifier) write(v interface{}) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return n.conn.WriteJSON(v)
+}
+
+// Notify sends result as a notification of the subscription id, with the eth_subscription
+// method.
+func (n *Notifier) Notify(id string, result interface{}) error {
+	return n.write(&notification{
+		JSONRPC: "2.0",
+		Method:  "eth_subscription",
+		Params:  notificationParams{Subscription: id, Result: result},
+	})
+}
+
+// Closed is closed when the connection is, the subscriptions made on it end then.
+func (n *Notifier) Closed() <-chan struct{} {
+	return n.closed
+}
+
+// Server is a JSON-RPC 2.0 server, an http.Handler answering POST requests, single or
+// batched, and WebSocket connections upgraded on the same endpoint. Requests from browsers
+// are only answered for the allowed origins, DefaultOrigins unless AllowOrigins changes
+// them, the ones without an Origin header always are.
+type Server struct {
+	lock     sync.RWMutex
+	methods  map[string]Handler
+	origins  []string
+	upgrader websocket.Upgrader
+}
+
+func NewServer() *Server {
+	s := &Server{
+		methods: map[string]Handler{},
+		origins: DefaultOrigins,
+	}
+
+	s.upgrader = websocket.Upgrader{
+		ReadBufferSize:  4096,
+		WriteBufferSize: 4096,
+		CheckOrigin:     func(r *http.Request) bool { return s.allowed(r.Header.Get("Origin")) },
+	}
+
+	return s
+}
+
+// AllowOrigins replaces the origins allowed, for CORS and WebSocket. An origin is allowed
+// when it is one of origins, as "https://app.example:8080", or when its host is, as
+// "localhost". "*" allows any origin.
+func (s *Server) AllowOrigins(origins ...string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.origins = append([]string{}, origins...)
+}
+
+// allowed tells whether a request from origin is answered, an empty origin is not a browser.
+func (s *Server) allowed(origin string) bool {
+	if origin == "" {
+		return true
+	}
+
+	var host string
+	if u, err := url.Parse(origin); err == nil {
+		host = u.Hostname()
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	for _, allowed := range s.origins {
+		if allowed == "*" || allowed == origin || (host != "" && allowed == host) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Register sets the handler of method, replacing the one registered before.
+func (s *Server) Register(method string, handler Handler) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.methods[method] = handler
+}
+
+// Methods are the names of the registered methods, sorted.
+func (s *Server) Methods() []string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	names := make([]string, 0, len(s.methods))
+	for name := range s.methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (s *Server) call(ctx context.Context, req *request) *response {
+	resp := &response{JSONRPC: "2.0", ID: req.ID}
+	if resp.ID == nil {
+		resp.ID = json.RawMessage("null")
+	}
+
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		resp.Error = &Error{Code: ErrCodeInvalidRequest, Message: "invalid request"}
+		return resp
+	}
+
+	s.lock.RLock()
+	handler := s.methods[req.Method]
+	s.lock.RUnlock()
+
+	if handler == nil {
+		resp.Error = &Error{Code: ErrCodeMethodNotFound, Message: "the method " + req.Method + " does not exist/is not available"}
+		return resp
+	}
+
+	result, err := handler(ctx, req.Params)
+	if err != nil {
+		resp.Error = toError(err)
+		return resp
+	}
+
+	if result == nil {
+		//a null result is still a result
+		result = json.RawMessage("null")
+	}
+
+	resp.Result = result
+	return resp
+}
+
+// handle answers a message, a single request or a batch. Notifications, requests without
+// id, get no response, nil is returned when there is nothing to send back.
+func (s *Server) handle(ctx context.Context, msg []byte) interface{} {
+	msg = bytes.TrimSpace(msg)
+	if len(msg) > 0 && msg[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(msg, &batch); err != nil {
+			return &response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &Error{Code: ErrCodeParse, Message: err.Error()}}
+		}
+
+		if len(batch) == 0 {
+			return &response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &Error{Code: ErrCodeInvalidRequest, Message: "empty batch"}}
+		}
+
+		var responses []*response
+		for _, item := range batch {
+			if resp := s.handleOne(ctx, item); resp != nil {
+				responses = append(responses, resp)
+			}
+		}
+
+		if len(responses) == 0 {
+			return nil
+		}
+
+		return responses
+	}
+
+	if resp := s.handleOne(ctx, msg); resp != nil {
+		return resp
+	}
+
+	return nil
+}
+
+func (s *Server) handleOne(ctx context.Context, msg []byte) *response {
+	var req request
+	if err := json.Unmarshal(msg, &req); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return &response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &Error{Code: ErrCodeParse, Message: err.Error()}}
+		}
+
+		return &response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &Error{Code: ErrCodeInvalidRequest, Message: err.Error()}}
+	}
+
+	resp := s.call(ctx, &req)
+	if req.ID == nil {
+		return nil
+	}
+
+	return resp
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if !s.allowed(origin) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	if origin != "" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Vary", "Origin")
+	}
+
+	if websocket.IsWebSocketUpgrade(r) {
+		s.serveWebSocket(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodOptions:
+		w.WriteHeader(http.StatusOK)
+		return
+	case http.MethodPost:
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	result := s.handle(r.Context(), body)
+	if result == nil {
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// serveWebSocket answers the requests of a connection in order until it is closed.
+func (s *Server) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	notifier := &Notifier{conn: conn, closed: make(chan struct{})}
+	defer close(notifier.closed)
+
+	ctx := context.WithValue(r.Context(), notifierKey{}, notifier)
+	conn.SetReadLimit(maxRequestSize)
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		result := s.handle(ctx, msg)
+		if result == nil {
+			continue
+		}
+
+		if err = notifier.write(result); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package rpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SealSC/SealEVM/rpc"
+	"github.com/gorilla/websocket"
+)
+
+func newEchoServer(t *testing.T, origins ...string) *httptest.Server {
+	server := rpc.NewServer()
+	if origins != nil {
+		server.AllowOrigins(origins...)
+	}
+
+	server.Register("echo", func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		return params, nil
+	})
+
+	srv := httptest.NewServer(server)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func post(t *testing.T, url string, origin string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"echo","params":[1]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	return resp
+}
+
+func dialOrigin(url string, origin string) error {
+	header := http.Header{}
+	header.Set("Origin", origin)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(url, "http"), header)
+	if err == nil {
+		conn.Close()
+	}
+
+	return err
+}
+
+func TestServerDefaultOrigins(t *testing.T) {
+	srv := newEchoServer(t)
+
+	for _, c := range []struct {
+		origin  string
+		allowed bool
+	}{
+		{"", true},
+		{"http://localhost:3000", true},
+		{"http://127.0.0.1", true},
+		{"http://[::1]:8080", true},
+		{"https://evil.example", false},
+		{"http://localhost.evil.example", false},
+	} {
+		resp := post(t, srv.URL, c.origin)
+		if allowed := resp.StatusCode == http.StatusOK; allowed != c.allowed {
+			t.Errorf("POST from %q got %s", c.origin, resp.Status)
+		}
+
+		if c.origin != "" && c.allowed && resp.Header.Get("Access-Control-Allow-Origin") != c.origin {
+			t.Errorf("POST from %q allowed %q", c.origin, resp.Header.Get("Access-Control-Allow-Origin"))
+		}
+
+		if c.origin == "" {
+			continue
+		}
+
+		if err := dialOrigin(srv.URL, c.origin); (err == nil) != c.allowed {
+			t.Errorf("WebSocket from %q got %v", c.origin, err)
+		}
+	}
+}
+
+func TestServerAllowOrigins(t *testing.T) {
+	srv := newEchoServer(t, "https://app.example")
+
+	if resp := post(t, srv.URL, "https://app.example"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST from the allowed origin got %s", resp.Status)
+	}
+
+	if resp := post(t, srv.URL, "http://localhost"); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("POST from localhost got %s, it is not allowed anymore", resp.Status)
+	}
+
+	if err := dialOrigin(srv.URL, "https://other.example"); err == nil {
+		t.Fatalf("WebSocket from another origin was accepted")
+	}
+
+	srv = newEchoServer(t, "*")
+	if resp := post(t, srv.URL, "https://any.example"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST with * got %s", resp.Status)
+	}
+}
@@ -0,0 +1,97 @@
+package rpc
+
+import (
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/override"
+	"github.com/SealSC/SealEVM/sim"
+	"github.com/SealSC/SealEVM/statedb/archive"
+	"github.com/SealSC/SealEVM/storage"
+	"github.com/SealSC/SealEVM/types"
+)
+
+// Backend is the state of the chain of a Node. The transactions of a new block run on
+// Pending, CommitBlock adds their changes to the chain as the block, Discard drops them when
+// the transaction is rejected. The calls, queries and traces read the state of a block
+// through At and run on override layers above it, the state is never copied.
+//
+// BLOCKHASH of Pending reads the hashes given to CommitBlock. A backend which also
+// implements StateRooter gives the state roots of the blocks.
+type Backend interface {
+	Pending() sim.StateDB
+	CommitBlock(number uint64, hash types.Hash) error
+	Discard() error
+	At(number uint64) (View, error)
+}
+
+// View is the state at the end of a committed block, which is only read. SetCurrentBlock
+// sets the block BLOCKHASH runs in, the block of the view by default.
+type View interface {
+	storage.IExternalStorage
+	override.NonceReader
+
+	Balance(addr types.Address) *evmInt256.Int
+	Code(addr types.Address) []byte
+	SetCurrentBlock(number uint64)
+}
+
+// StateRooter is a backend able to compute the root of Pending, the root of the blocks is
+// zero otherwise.
+type StateRooter interface {
+	Root() types.Hash
+}
+
+// ArchiveBackend is a Backend on a statedb/archive.DB, which keeps each block as the diff of
+// the accounts it changed.
+type ArchiveBackend struct {
+	*archive.DB
+}
+
+// NewArchiveBackend is a backend whose pending state holds the accounts of alloc, the
+// genesis block of the node.
+func NewArchiveBackend(alloc sim.Alloc) *ArchiveBackend {
+	db := archive.NewDB()
+	for addr, acc := range alloc {
+		//creates the account, which exists even when it has nothing
+		db.SetNonce(addr, acc.Nonce)
+		if acc.Balance != nil {
+			db.SetBalance(addr, acc.Balance)
+		}
+
+		if len(acc.Code) > 0 {
+			db.SetCode(addr, acc.Code)
+		}
+
+		for slot, val := range acc.Storage {
+			db.SetState(addr, slot, val)
+		}
+	}
+
+	return &ArchiveBackend{DB: db}
+}
+
+func (b *ArchiveBackend) Pending() sim.StateDB {
+	return b.DB
+}
+
+// Discard drops the changes made since the last block committed.
+func (b *ArchiveBackend) Discard() error {
+	head, ok := b.Head()
+	if !ok {
+		return nil
+	}
+
+	return b.Rollback(head)
+}
+
+func (b *ArchiveBackend) At(number uint64) (View, error) {
+	view, err := b.DB.At(number)
+	if err != nil {
+		return nil, err
+	}
+
+	return view, nil
+}
+
+func (b *ArchiveBackend) Root() types.Hash {
+	return sim.StateRoot(b.DB)
+}
@@ -0,0 +1,181 @@
+package rpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+
+	"github.com/SealSC/SealEVM/types"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Subscription kinds of eth_subscribe.
+const (
+	NewHeadsSubscription = "newHeads"
+	LogsSubscription     = "logs"
+)
+
+// subscriptionQueueSize is the number of notifications a subscription can have waiting to
+// be written, a subscription falling further behind is dropped.
+const subscriptionQueueSize = 1024
+
+// subscription is a subscription of a WebSocket connection, logs is the filter of the logs
+// subscriptions, nil for newHeads. queue holds the notifications waiting to be written, it
+// is closed when the subscription ends.
+type subscription struct {
+	id       string
+	notifier *Notifier
+	logs     *FilterQuery
+	queue    chan interface{}
+}
+
+func newSubscriptionID() (string, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", fmt.Errorf("subscription id: %w", err)
+	}
+
+	return hexutil.Encode(id[:]), nil
+}
+
+// subscribe answers eth_subscribe, the subscription ends with eth_unsubscribe or with the
+// connection.
+func (n *Node) subscribe(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+	notifier, ok := NotifierFromContext(ctx)
+	if !ok {
+		return nil, &Error{Code: ErrCodeMethodNotFound, Message: "notifications not supported"}
+	}
+
+	var kind string
+	var q FilterQuery
+	if err := parseParams(params, 1, &kind, &q); err != nil {
+		return nil, err
+	}
+
+	id, err := newSubscriptionID()
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &subscription{id: id, notifier: notifier, queue: make(chan interface{}, subscriptionQueueSize)}
+	switch kind {
+	case NewHeadsSubscription:
+	case LogsSubscription:
+		if q.FromBlock != nil || q.ToBlock != nil || q.BlockHash != nil {
+			return nil, invalidParams("a logs subscription takes no block range")
+		}
+		sub.logs = &q
+	default:
+		return nil, invalidParams("unsupported subscription %q", kind)
+	}
+
+	n.subLock.Lock()
+	n.subs[sub.id] = sub
+	n.subLock.Unlock()
+
+	go n.notify(sub)
+	return sub.id, nil
+}
+
+// notify writes the notifications of sub in order until it ends, with its connection or
+// with the first write failing.
+func (n *Node) notify(sub *subscription) {
+	for {
+		select {
+		case result, ok := <-sub.queue:
+			if !ok {
+				return
+			}
+
+			if sub.notifier.Notify(sub.id, result) != nil {
+				n.removeSubscription(sub.id, sub.notifier)
+				return
+			}
+		case <-sub.notifier.Closed():
+			n.removeSubscription(sub.id, sub.notifier)
+			return
+		}
+	}
+}
+
+// removeSubscription ends the subscription id made on the connection of notifier.
+func (n *Node) removeSubscription(id string, notifier *Notifier) bool {
+	n.subLock.Lock()
+	defer n.subLock.Unlock()
+
+	sub := n.subs[id]
+	if sub == nil || sub.notifier != notifier {
+		return false
+	}
+
+	n.dropSubscription(sub)
+	return true
+}
+
+// dropSubscription ends sub, the lock of the subscriptions must be held.
+func (n *Node) dropSubscription(sub *subscription) {
+	delete(n.subs, sub.id)
+	close(sub.queue)
+}
+
+// enqueue queues result for sub without waiting, sub is dropped when its queue is full. The
+// lock of the subscriptions must be held.
+func (n *Node) enqueue(sub *subscription, result interface{}) bool {
+	select {
+	case sub.queue <- result:
+		return true
+	default:
+		n.dropSubscription(sub)
+		return false
+	}
+}
+
+// publish queues the notifications of b for the subscriptions, the lock of the chain must be
+// held so that the blocks are notified in order. The notifications are written by the
+// goroutine of each subscription, a slow connection never holds up the chain.
+func (n *Node) publish(b *Block) {
+	n.subLock.Lock()
+	defer n.subLock.Unlock()
+
+	var head interface{}
+	for _, sub := range n.subs {
+		if sub.logs == nil {
+			if head == nil {
+				head = newBlockJSON(b, false)
+			}
+
+			n.enqueue(sub, head)
+			continue
+		}
+
+	logs:
+		for _, receipt := range b.Receipts {
+			for _, l := range receipt.Logs {
+				if sub.logs.Match(&types.Log{Address: l.Address, Topics: l.Topics}) && !n.enqueue(sub, l) {
+					break logs
+				}
+			}
+		}
+	}
+}
+
+// unsubscribe answers eth_unsubscribe, only the subscriptions of the same connection can be
+// ended.
+func (n *Node) unsubscribe(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+	notifier, ok := NotifierFromContext(ctx)
+	if !ok {
+		return nil, &Error{Code: ErrCodeMethodNotFound, Message: "notifications not supported"}
+	}
+
+	var id string
+	if err := parseParams(params, 1, &id); err != nil {
+		return nil, err
+	}
+
+	if !n.removeSubscription(id, notifier) {
+		return nil, fmt.Errorf("subscription %s not found", id)
+	}
+
+	return true, nil
+}
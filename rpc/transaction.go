@@ -0,0 +1,29 @@
+package rpc
+
+import (
+	"fmt"
+
+	"github.com/SealSC/SealEVM/sim"
+	"github.com/SealSC/SealEVM/txcodec"
+)
+
+// Transaction is a transaction of the node, Message is what it executes.
+type Transaction struct {
+	*txcodec.Transaction
+	Message *sim.Message
+}
+
+// DecodeTransaction decodes raw with txcodec.Decode. EIP-7702 transactions are rejected,
+// SealEVM does not follow the delegations they set.
+func DecodeTransaction(raw []byte) (*Transaction, error) {
+	tx, err := txcodec.Decode(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTransaction, err)
+	}
+
+	if tx.Type == txcodec.SetCodeTxType {
+		return nil, fmt.Errorf("%w: set code transactions are not supported", ErrInvalidTransaction)
+	}
+
+	return &Transaction{Transaction: tx, Message: sim.TxMessage(tx)}, nil
+}
@@ -0,0 +1,329 @@
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/override"
+	"github.com/SealSC/SealEVM/sim"
+	"github.com/SealSC/SealEVM/types"
+)
+
+// Quantity is an unsigned integer in the hex format of JSON-RPC, as "0x1f".
+type Quantity uint64
+
+func (q Quantity) MarshalText() ([]byte, error) {
+	return []byte("0x" + strconv.FormatUint(uint64(q), 16)), nil
+}
+
+func (q *Quantity) UnmarshalText(text []byte) error {
+	s := string(text)
+	if !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") {
+		return fmt.Errorf("invalid quantity %q: missing 0x prefix", s)
+	}
+
+	v, err := strconv.ParseUint(s[2:], 16, 64)
+	if err != nil {
+		return fmt.Errorf("invalid quantity %q", s)
+	}
+
+	*q = Quantity(v)
+	return nil
+}
+
+// block tags, a BlockRef to a number below zero
+const (
+	LatestBlock   = -1
+	PendingBlock  = -2
+	EarliestBlock = -3
+)
+
+// BlockRef is a block parameter: a tag ("latest", "pending", "earliest", "safe" or
+// "finalized"), a number, or an EIP-1898 object with blockNumber or blockHash. "safe" and
+// "finalized" are the latest block, each block is final at once.
+type BlockRef struct {
+	Number int64
+	Hash   *types.Hash
+}
+
+func (b *BlockRef) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '{' {
+		var obj struct {
+			BlockNumber *BlockRef   `json:"blockNumber"`
+			BlockHash   *types.Hash `json:"blockHash"`
+		}
+
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+
+		switch {
+		case obj.BlockHash != nil && obj.BlockNumber != nil:
+			return fmt.Errorf("both blockNumber and blockHash given")
+		case obj.BlockHash != nil:
+			*b = BlockRef{Hash: obj.BlockHash}
+		case obj.BlockNumber != nil:
+			*b = *obj.BlockNumber
+		default:
+			return fmt.Errorf("blockNumber or blockHash required")
+		}
+
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	switch s {
+	case "latest", "safe", "finalized":
+		b.Number = LatestBlock
+	case "pending":
+		b.Number = PendingBlock
+	case "earliest":
+		b.Number = EarliestBlock
+	default:
+		var q Quantity
+		if err := q.UnmarshalText([]byte(s)); err != nil {
+			return err
+		}
+
+		if q > 1<<62 {
+			return fmt.Errorf("block number %s too large", s)
+		}
+		b.Number = int64(q)
+	}
+
+	return nil
+}
+
+// AccessTuple is an entry of an EIP-2930 access list.
+type AccessTuple struct {
+	Address     types.Address `json:"address"`
+	StorageKeys []types.Hash  `json:"storageKeys"`
+}
+
+func messageAccessList(list []AccessTuple) []sim.AccessTuple {
+	if list == nil {
+		return nil
+	}
+
+	out := make([]sim.AccessTuple, len(list))
+	for i, t := range list {
+		out[i] = sim.AccessTuple{Address: t.Address, StorageKeys: t.StorageKeys}
+	}
+
+	return out
+}
+
+func jsonAccessList(list []sim.AccessTuple) []AccessTuple {
+	out := make([]AccessTuple, len(list))
+	for i, t := range list {
+		out[i] = AccessTuple{Address: t.Address, StorageKeys: t.StorageKeys}
+		if out[i].StorageKeys == nil {
+			out[i].StorageKeys = []types.Hash{}
+		}
+	}
+
+	return out
+}
+
+// CallArgs are the transaction fields of eth_call, eth_estimateGas and debug_traceCall.
+// Input is preferred to Data when both are given.
+type CallArgs struct {
+	From                 *types.Address `json:"from"`
+	To                   *types.Address `json:"to"`
+	Gas                  *Quantity      `json:"gas"`
+	GasPrice             *evmInt256.Int `json:"gasPrice"`
+	MaxFeePerGas         *evmInt256.Int `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *evmInt256.Int `json:"maxPriorityFeePerGas"`
+	Value                *evmInt256.Int `json:"value"`
+	Nonce                *Quantity      `json:"nonce"`
+	Data                 *types.Bytes   `json:"data"`
+	Input                *types.Bytes   `json:"input"`
+	AccessList           []AccessTuple  `json:"accessList"`
+}
+
+// Message is args as the message of a call, a zero GasLimit when no gas is given.
+func (args *CallArgs) Message() *sim.Message {
+	msg := &sim.Message{
+		To:         args.To,
+		GasPrice:   args.GasPrice,
+		GasFeeCap:  args.MaxFeePerGas,
+		GasTipCap:  args.MaxPriorityFeePerGas,
+		Value:      args.Value,
+		AccessList: messageAccessList(args.AccessList),
+	}
+
+	if args.From != nil {
+		msg.From = *args.From
+	}
+
+	if args.Gas != nil {
+		msg.GasLimit = uint64(*args.Gas)
+	}
+
+	if args.Nonce != nil {
+		msg.Nonce = uint64(*args.Nonce)
+	}
+
+	if args.Input != nil {
+		msg.Data = *args.Input
+	} else if args.Data != nil {
+		msg.Data = *args.Data
+	}
+
+	switch {
+	case args.MaxFeePerGas != nil || args.MaxPriorityFeePerGas != nil:
+		msg.Type = 2
+	case args.AccessList != nil:
+		msg.Type = 1
+	}
+
+	return msg
+}
+
+// FilterQuery is the filter of eth_getLogs. BlockHash excludes FromBlock and ToBlock,
+// Topics match by position, a nil position matches any topic.
+type FilterQuery struct {
+	FromBlock *BlockRef
+	ToBlock   *BlockRef
+	BlockHash *types.Hash
+	Addresses []types.Address
+	Topics    [][]types.Hash
+}
+
+func (q *FilterQuery) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		FromBlock *BlockRef         `json:"fromBlock"`
+		ToBlock   *BlockRef         `json:"toBlock"`
+		BlockHash *types.Hash       `json:"blockHash"`
+		Address   json.RawMessage   `json:"address"`
+		Topics    []json.RawMessage `json:"topics"`
+	}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	if raw.BlockHash != nil && (raw.FromBlock != nil || raw.ToBlock != nil) {
+		return fmt.Errorf("blockHash excludes fromBlock and toBlock")
+	}
+
+	*q = FilterQuery{FromBlock: raw.FromBlock, ToBlock: raw.ToBlock, BlockHash: raw.BlockHash}
+	if len(raw.Address) > 0 && string(raw.Address) != "null" {
+		if raw.Address[0] == '[' {
+			if err := json.Unmarshal(raw.Address, &q.Addresses); err != nil {
+				return err
+			}
+		} else {
+			var addr types.Address
+			if err := json.Unmarshal(raw.Address, &addr); err != nil {
+				return err
+			}
+			q.Addresses = []types.Address{addr}
+		}
+	}
+
+	for _, topic := range raw.Topics {
+		var alternatives []types.Hash
+		switch {
+		case string(topic) == "null":
+		case len(topic) > 0 && topic[0] == '[':
+			if err := json.Unmarshal(topic, &alternatives); err != nil {
+				return err
+			}
+		default:
+			var hash types.Hash
+			if err := json.Unmarshal(topic, &hash); err != nil {
+				return err
+			}
+			alternatives = []types.Hash{hash}
+		}
+
+		q.Topics = append(q.Topics, alternatives)
+	}
+
+	return nil
+}
+
+// Match tells whether log passes the address and topic filters of q.
+func (q *FilterQuery) Match(log *types.Log) bool {
+	if len(q.Addresses) > 0 {
+		found := false
+		for _, addr := range q.Addresses {
+			if addr == log.Address {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if len(q.Topics) > len(log.Topics) {
+		return false
+	}
+
+	for i, alternatives := range q.Topics {
+		if len(alternatives) == 0 {
+			continue
+		}
+
+		found := false
+		for _, topic := range alternatives {
+			if topic == log.Topics[i] {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// TraceConfig is the configuration of debug_traceTransaction and debug_traceCall, only
+// the default struct logger is supported. The overrides only apply to debug_traceCall.
+type TraceConfig struct {
+	DisableStack     bool   `json:"disableStack"`
+	DisableStorage   bool   `json:"disableStorage"`
+	EnableMemory     bool   `json:"enableMemory"`
+	EnableReturnData bool   `json:"enableReturnData"`
+	Limit            int    `json:"limit"`
+	Tracer           string `json:"tracer"`
+
+	StateOverrides override.StateOverride  `json:"stateOverrides"`
+	BlockOverrides *override.BlockOverride `json:"blockOverrides"`
+}
+
+// CallOverrides are the state and block overrides of a call, nil fields override nothing.
+type CallOverrides struct {
+	State override.StateOverride
+	Block *override.BlockOverride
+}
+
+// SimulateOptions are the parameters of eth_simulateV1. Each of BlockStateCalls is a new
+// simulated block after the one of the block parameter. The calls run as in eth_call,
+// validation and transfer traces are not supported.
+type SimulateOptions struct {
+	BlockStateCalls        []SimulateBlock `json:"blockStateCalls"`
+	TraceTransfers         bool            `json:"traceTransfers"`
+	Validation             bool            `json:"validation"`
+	ReturnFullTransactions bool            `json:"returnFullTransactions"`
+}
+
+type SimulateBlock struct {
+	BlockOverrides *override.BlockOverride `json:"blockOverrides"`
+	StateOverrides override.StateOverride  `json:"stateOverrides"`
+	Calls          []CallArgs              `json:"calls"`
+}
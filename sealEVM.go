@@ -42,6 +42,7 @@ type EVMParam struct {
 	Context        *environment.Context
 	GasSetting     *gasSetting.Setting
 	NoteConfig     *executionNote.NoteConfig
+	StepHook       instructions.IStepHook
 }
 
 type EVM struct {
@@ -52,7 +53,14 @@ type EVM struct {
 	context      *environment.Context
 	instructions instructions.IInstructions
 	note         *executionNote.Note
+	noteAnchor   *executionNote.Note
 	resultNotify EVMResultCallback
+	stepHook     instructions.IStepHook
+	parent       *EVM
+	execType     executionNote.ExecutionType
+
+	//the sub notes the anchor had when this frame, which is not recorded, started
+	noteMark int
 }
 
 type ExecuteResult struct {
@@ -61,7 +69,9 @@ type ExecuteResult struct {
 	GasLeft         uint64
 	StorageCache    cache.ResultCache
 	ExitOpCode      opcodes.OpCode
+	Refund          uint64
 	Note            *executionNote.Note
+	Fault           *executionNote.FaultReport
 }
 
 func Load() {
@@ -91,9 +101,15 @@ func New(param EVMParam) *EVM {
 		instructions: nil,
 		note:         note,
 		resultNotify: param.ResultCallback,
+		stepHook:     param.StepHook,
 	}
 
 	evm.instructions = instructions.New(evm, evm.stack, evm.memory, evm.storage, evm.context, param.GasSetting, closure)
+	evm.instructions.SetStepHook(param.StepHook)
+
+	if note != nil && param.NoteConfig.JournalRequired() {
+		evm.storage.EnableJournal()
+	}
 
 	return evm
 }
@@ -110,9 +126,11 @@ func newWithCache(param EVMParam, s *storage.Storage) *EVM {
 		context:      param.Context,
 		instructions: nil,
 		resultNotify: param.ResultCallback,
+		stepHook:     param.StepHook,
 	}
 
 	evm.instructions = instructions.New(evm, evm.stack, evm.memory, evm.storage, evm.context, param.GasSetting, closure)
+	evm.instructions.SetStepHook(param.StepHook)
 
 	return evm
 }
@@ -120,6 +138,7 @@ func newWithCache(param EVMParam, s *storage.Storage) *EVM {
 func (e *EVM) subResult(result ExecuteResult, err error) {
 	if err == nil && result.ExitOpCode != opcodes.REVERT {
 		cache.MergeResultCache(&result.StorageCache, &e.storage.ResultCache)
+		e.storage.SetRefund(result.Refund)
 	}
 }
 
@@ -169,6 +188,8 @@ func (e *EVM) executePreCompiledWithStorage(address types.Address, input []byte)
 	if err != nil {
 		result.StorageCache = cache.NewResultCache()
 		e.storage.ClearCache()
+	} else {
+		result.Refund = e.storage.Refund()
 	}
 
 	if e.resultNotify != nil {
@@ -225,16 +246,30 @@ func (e *EVM) Execute() (result ExecuteResult, err error) {
 		StorageCache: e.storage.ResultCache,
 	}
 
+	gasStart := e.instructions.GetGasLeft()
 	gasLeft, err := e.getGasLeft()
 	result.GasLeft = gasLeft
 
 	defer func() {
+		if err != nil && err != evmErrors.RevertErr && result.Fault == nil {
+			result.Fault = e.frameFaultReport(err)
+		}
+
 		if e.note != nil {
+			var gasUsed uint64
+			if gasStart > result.GasLeft {
+				gasUsed = gasStart - result.GasLeft
+			}
+
 			e.note.SetResult(result.ResultData, err, e.storage.ResultCache)
+			e.note.SetFault(result.Fault)
+			e.note.SetFrameRecord(e.storage.Journal(), e.storage.ResultCache, gasUsed)
 
 			if e.depth == 0 {
 				result.Note = e.note
 			}
+		} else if e.noteAnchor != nil && err != nil {
+			e.noteAnchor.DropLogsFrom(e.noteMark)
 		}
 	}()
 
@@ -249,6 +284,10 @@ func (e *EVM) Execute() (result ExecuteResult, err error) {
 		}
 
 		e.storage.CacheAccount(callerAcc, false)
+
+		if err = e.warmAccessList(); err != nil {
+			return result, err
+		}
 	}
 
 	toAddr := e.context.Transaction.To
@@ -329,9 +368,15 @@ func (e *EVM) Execute() (result ExecuteResult, err error) {
 	result.ResultData = execRet
 	result.ExitOpCode = e.instructions.ExitOpCode()
 
+	if err != nil && err != evmErrors.RevertErr {
+		result.Fault = e.faultReport(err)
+	}
+
 	if err != nil {
 		result.StorageCache = cache.NewResultCache()
 		e.storage.ClearCache()
+	} else {
+		result.Refund = e.storage.Refund()
 	}
 
 	if e.resultNotify != nil {
@@ -341,6 +386,26 @@ func (e *EVM) Execute() (result ExecuteResult, err error) {
 	return result, err
 }
 
+// warmAccessList loads the accounts and slots of the access list into the cache, which
+// makes them warm for the gas of the accesses.
+func (e *EVM) warmAccessList() error {
+	for _, tuple := range e.context.Transaction.AccessList {
+		acc, err := e.storage.GetAccount(tuple.Address)
+		if err != nil {
+			return err
+		}
+
+		e.storage.CacheAccount(acc, false)
+		for _, slot := range tuple.StorageKeys {
+			if _, err = e.storage.XLoad(tuple.Address, slot, cache.SStorage); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 func (e *EVM) getClosureDefaultEVM(param instructions.ClosureParam) *EVM {
 	newEVM := newWithCache(EVMParam{
 		MaxStackDepth:  1024,
@@ -352,6 +417,7 @@ func (e *EVM) getClosureDefaultEVM(param instructions.ClosureParam) *EVM {
 			Message:     *param.Message,
 		},
 		GasSetting: e.instructions.GetGasSetting(),
+		StepHook:   e.stepHook,
 	}, e.storage)
 
 	newEVM.instructions.SetGasLimit(param.GasLimit.Uint64())
@@ -359,9 +425,37 @@ func (e *EVM) getClosureDefaultEVM(param instructions.ClosureParam) *EVM {
 	return newEVM
 }
 
+func (e *EVM) genSubNote(newEVM *EVM, execType executionNote.ExecutionType, depth uint64) {
+	anchor := e.note
+	if anchor == nil {
+		anchor = e.noteAnchor
+	}
+
+	if anchor == nil {
+		return
+	}
+
+	newEVM.noteAnchor = anchor
+	cfg := anchor.Config()
+	if !cfg.ShouldRecord(execType, newEVM.context.Message.Caller, newEVM.context.Transaction.To, depth) {
+		newEVM.noteMark = len(anchor.SubNotes)
+		return
+	}
+
+	newEVM.note = anchor.GenSubNote(execType, &newEVM.context.Transaction, &newEVM.context.Message)
+	newEVM.note.Depth = depth
+
+	if cfg.JournalRequired() {
+		newEVM.storage.EnableJournal()
+	}
+}
+
 func (e *EVM) commonCall(param instructions.ClosureParam, depth uint64) ([]byte, error) {
 	newEVM := e.getClosureDefaultEVM(param)
 	newEVM.depth = depth
+	newEVM.instructions.SetDepth(depth)
+	newEVM.parent = e
+	newEVM.execType = executionNote.ExecutionType(param.OpCode)
 
 	calledAcc, _ := newEVM.storage.GetAccount(param.Called)
 	runtimeAcc := calledAcc.Clone()
@@ -377,13 +471,7 @@ func (e *EVM) commonCall(param instructions.ClosureParam, depth uint64) ([]byte,
 		newEVM.instructions.SetReadOnly()
 	}
 
-	if e.note != nil {
-		newEVM.note = e.note.GenSubNote(
-			executionNote.ExecutionType(param.OpCode),
-			&newEVM.context.Transaction,
-			&newEVM.context.Message,
-		)
-	}
+	e.genSubNote(newEVM, executionNote.ExecutionType(param.OpCode), depth)
 
 	ret, err := newEVM.Execute()
 	if ret.ExitOpCode == opcodes.REVERT {
@@ -401,14 +489,11 @@ func (e *EVM) commonCreate(param instructions.ClosureParam, depth uint64) ([]byt
 	newEVM.context.SetRuntimeAccount(runtimeAcc.Clone())
 
 	newEVM.depth = depth
+	newEVM.instructions.SetDepth(depth)
+	newEVM.parent = e
+	newEVM.execType = executionNote.ExecutionType(param.OpCode)
 
-	if e.note != nil {
-		newEVM.note = e.note.GenSubNote(
-			executionNote.ExecutionType(param.OpCode),
-			&newEVM.context.Transaction,
-			&newEVM.context.Message,
-		)
-	}
+	e.genSubNote(newEVM, executionNote.ExecutionType(param.OpCode), depth)
 
 	ret, err := newEVM.Execute()
 
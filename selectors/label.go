@@ -0,0 +1,94 @@
+package selectors
+
+import (
+	"github.com/SealSC/SealEVM/executionNote"
+	"github.com/SealSC/SealEVM/types"
+)
+
+// CodeOf returns the code at an address, Storage.GetCode fits.
+type CodeOf func(address types.Address) ([]byte, error)
+
+// Label names the call of a note by the selector of its input.
+type Label struct {
+	Note  *executionNote.Note
+	Depth uint64
+
+	Selector   Selector
+	Signatures []string
+
+	//Function is the dispatched function of the callee, nil when its dispatcher does not
+	//handle the selector, the call then goes to the fallback
+	Function *Function
+	//Proxy is the fallback of the callee when it forwards calls
+	Proxy *Proxy
+}
+
+// Labeler labels the calls of execution notes, with the signature database and, when Code
+// is set, the selectors extracted from the code of the callees.
+type Labeler struct {
+	DB   SignatureDB
+	Code CodeOf
+
+	contracts map[types.Address]*Contract
+}
+
+func NewLabeler(db SignatureDB, code CodeOf) *Labeler {
+	return &Labeler{
+		DB:        db,
+		Code:      code,
+		contracts: map[types.Address]*Contract{},
+	}
+}
+
+func (l *Labeler) contract(address types.Address) (*Contract, error) {
+	if c, ok := l.contracts[address]; ok {
+		return c, nil
+	}
+
+	code, err := l.Code(address)
+	if err != nil {
+		return nil, err
+	}
+
+	c := Extract(code)
+	l.contracts[address] = c
+	return c, nil
+}
+
+// LabelNote labels note and its sub notes, in the order of the calls. Creations and calls
+// with less than four bytes of input get no label.
+func (l *Labeler) LabelNote(note *executionNote.Note) ([]*Label, error) {
+	var labels []*Label
+	var visit func(n *executionNote.Note, depth uint64) error
+	visit = func(n *executionNote.Note, depth uint64) error {
+		if n.Type != executionNote.Create && n.Type != executionNote.Create2 && n.To != nil && len(n.Input) >= 4 {
+			label := &Label{Note: n, Depth: depth}
+			copy(label.Selector[:], n.Input)
+			label.Signatures = l.DB.Lookup(label.Selector)
+
+			if l.Code != nil {
+				c, err := l.contract(*n.To)
+				if err != nil {
+					return err
+				}
+
+				label.Function = c.Function(label.Selector)
+				if label.Function == nil {
+					label.Proxy = c.Proxy
+				}
+			}
+
+			labels = append(labels, label)
+		}
+
+		for _, sub := range n.SubNotes {
+			if err := visit(sub, depth+1); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return labels, visit(note, 0)
+}
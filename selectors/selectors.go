@@ -0,0 +1,298 @@
+// Package selectors lists the function selectors a contract dispatches, from its runtime
+// code alone, with hints on payable and view functions and on proxy fallbacks.
+package selectors
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"sort"
+
+	"github.com/SealSC/SealEVM/cfg"
+	"github.com/SealSC/SealEVM/crypto/hashes"
+	"github.com/SealSC/SealEVM/opcodes"
+	"github.com/SealSC/SealEVM/storage"
+	"github.com/SealSC/SealEVM/types"
+)
+
+type Selector [4]byte
+
+// SelectorOf is the selector of a canonical signature such as "transfer(address,uint256)".
+func SelectorOf(signature string) Selector {
+	var s Selector
+	copy(s[:], hashes.Keccak256([]byte(signature)))
+	return s
+}
+
+func (s Selector) String() string {
+	return "0x" + hex.EncodeToString(s[:])
+}
+
+func (s Selector) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Hint is the answer of a static guess, Unknown when the code does not tell.
+type Hint byte
+
+const (
+	Unknown Hint = iota
+	Yes
+	No
+)
+
+func (h Hint) String() string {
+	switch h {
+	case Yes:
+		return "yes"
+	case No:
+		return "no"
+	}
+
+	return "unknown"
+}
+
+func (h Hint) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+type Function struct {
+	Selector Selector `json:"selector"`
+	Entry    uint64   `json:"entry"`
+
+	//Payable is No when the function, or the whole contract before the dispatch, reverts
+	//on call value
+	Payable Hint `json:"payable"`
+	//View is Yes when no path of the function writes state, logs, creates or calls with
+	//value, No when one does
+	View Hint `json:"view"`
+}
+
+type ProxyKind string
+
+const (
+	//MinimalProxy is an EIP-1167 clone, the implementation is in the code
+	MinimalProxy ProxyKind = "eip1167"
+	//SlotProxy delegates to the implementation stored at the EIP-1967 slot
+	SlotProxy ProxyKind = "eip1967"
+	//BeaconProxy delegates to the implementation of the beacon stored at the EIP-1967 slot
+	BeaconProxy ProxyKind = "eip1967-beacon"
+	//DelegatingFallback is any other fallback which reaches a DELEGATECALL
+	DelegatingFallback ProxyKind = "delegatecall"
+)
+
+// Proxy tells how a contract forwards the calls its dispatcher does not handle.
+type Proxy struct {
+	Kind           ProxyKind      `json:"kind"`
+	Implementation *types.Address `json:"implementation,omitempty"`
+	Slot           *types.Hash    `json:"slot,omitempty"`
+
+	//DelegateCall is the offset of the DELEGATECALL of the fallback
+	DelegateCall uint64 `json:"delegateCall"`
+}
+
+type Contract struct {
+	Functions []*Function `json:"functions"`
+	Proxy     *Proxy      `json:"proxy,omitempty"`
+
+	//Complete is set when every jump was resolved, the hints are then reliable
+	Complete bool `json:"complete"`
+}
+
+// Function is the dispatched function of selector, nil when the dispatcher has none.
+func (c *Contract) Function(selector Selector) *Function {
+	for _, f := range c.Functions {
+		if f.Selector == selector {
+			return f
+		}
+	}
+
+	return nil
+}
+
+var (
+	minimalProxyPrefix = []byte{0x36, 0x3d, 0x3d, 0x37, 0x3d, 0x3d, 0x3d, 0x36, 0x3d, 0x73}
+	minimalProxySuffix = []byte{0x5a, 0xf4, 0x3d, 0x82, 0x80, 0x3e, 0x90, 0x3d, 0x91, 0x60, 0x2b, 0x57, 0xfd, 0x5b, 0xf3}
+
+	//bytes32(uint256(keccak256("eip1967.proxy.implementation")) - 1) and the beacon one
+	implementationSlot = slotOf("eip1967.proxy.implementation")
+	beaconSlot         = slotOf("eip1967.proxy.beacon")
+)
+
+func slotOf(name string) *big.Int {
+	v := new(big.Int).SetBytes(hashes.Keccak256([]byte(name)))
+	return v.Sub(v, big.NewInt(1))
+}
+
+// ExtractAt extracts the selectors of the code deployed at address.
+func ExtractAt(s *storage.Storage, address types.Address) (*Contract, error) {
+	code, err := s.GetCode(address)
+	if err != nil {
+		return nil, err
+	}
+
+	return Extract(code), nil
+}
+
+// Extract lists the functions dispatched by runtime code.
+func Extract(code []byte) *Contract {
+	contract := &Contract{Complete: true}
+	if len(code) == len(minimalProxyPrefix)+types.AddressBytesLen+len(minimalProxySuffix) &&
+		bytes.HasPrefix(code, minimalProxyPrefix) && bytes.HasSuffix(code, minimalProxySuffix) {
+		var impl types.Address
+		impl.SetBytes(code[len(minimalProxyPrefix) : len(minimalProxyPrefix)+types.AddressBytesLen])
+
+		contract.Proxy = &Proxy{
+			Kind:           MinimalProxy,
+			Implementation: &impl,
+			DelegateCall:   uint64(len(minimalProxyPrefix) + types.AddressBytesLen + 1),
+		}
+		return contract
+	}
+
+	g := cfg.Build(code)
+	contract.Complete = g.Complete
+
+	entries := map[uint64]bool{}
+	dispatchStart := ^uint64(0)
+	for _, f := range g.Functions {
+		entries[f.Entry] = true
+		if f.Block < dispatchStart {
+			dispatchStart = f.Block
+		}
+	}
+
+	//the paths which take no function entry are the ones before the dispatch and the
+	//fallback
+	outside, complete := g.ReachableFrom(0, func(b *cfg.Block) bool {
+		return entries[b.Start]
+	})
+	contract.Complete = contract.Complete && complete
+
+	payableAll := Yes
+	for _, b := range outside {
+		if b.Start < dispatchStart && revertsOnValue(g, b) {
+			payableAll = No
+		}
+	}
+
+	for _, f := range g.Functions {
+		if contract.Function(Selector(f.Selector)) != nil {
+			continue
+		}
+
+		fn := &Function{
+			Selector: f.Selector,
+			Entry:    f.Entry,
+			Payable:  payableAll,
+			View:     viewHint(g, f.Entry),
+		}
+
+		if fn.Payable == Yes && revertsOnValue(g, g.Block(f.Entry)) {
+			fn.Payable = No
+		}
+
+		contract.Functions = append(contract.Functions, fn)
+	}
+
+	sort.Slice(contract.Functions, func(i, j int) bool {
+		return bytes.Compare(contract.Functions[i].Selector[:], contract.Functions[j].Selector[:]) < 0
+	})
+
+	contract.Proxy = findProxy(g, outside)
+	return contract
+}
+
+// revertsOnValue matches the call value checks of solc: a block reading CALLVALUE and
+// ending with a JUMPI one of whose ways reverts at once.
+func revertsOnValue(g *cfg.Graph, b *cfg.Block) bool {
+	if b.Last().OpCode != opcodes.JUMPI {
+		return false
+	}
+
+	readsValue := false
+	for _, ins := range b.Instructions {
+		if ins.OpCode == opcodes.CALLVALUE {
+			readsValue = true
+		}
+	}
+
+	if !readsValue {
+		return false
+	}
+
+	for _, next := range b.Successors {
+		if n := g.Block(next); n != nil && n.Last().OpCode == opcodes.REVERT {
+			return true
+		}
+	}
+
+	return false
+}
+
+func changesState(op opcodes.OpCode) bool {
+	switch op {
+	case opcodes.SSTORE, opcodes.TSTORE, opcodes.CREATE, opcodes.CREATE2, opcodes.CALL, opcodes.CALLCODE,
+		opcodes.DELEGATECALL, opcodes.SELFDESTRUCT:
+		return true
+	}
+
+	return op >= opcodes.LOG0 && op <= opcodes.LOG4
+}
+
+func viewHint(g *cfg.Graph, entry uint64) Hint {
+	blocks, complete := g.ReachableFrom(entry, nil)
+	for _, b := range blocks {
+		for _, ins := range b.Instructions {
+			if changesState(ins.OpCode) {
+				return No
+			}
+		}
+	}
+
+	if !complete {
+		return Unknown
+	}
+
+	return Yes
+}
+
+func findProxy(g *cfg.Graph, fallback []*cfg.Block) *Proxy {
+	var proxy *Proxy
+	for _, b := range fallback {
+		for _, ins := range b.Instructions {
+			if ins.OpCode == opcodes.DELEGATECALL {
+				proxy = &Proxy{Kind: DelegatingFallback, DelegateCall: ins.Offset}
+				break
+			}
+		}
+
+		if proxy != nil {
+			break
+		}
+	}
+
+	if proxy == nil {
+		return nil
+	}
+
+	for _, ins := range g.Listing.Instructions {
+		if ins.OpCode != opcodes.PUSH32 {
+			continue
+		}
+
+		v := new(big.Int).SetBytes(ins.Data)
+		var slot types.Hash
+		slot.SetBytes(ins.Data)
+
+		switch {
+		case v.Cmp(implementationSlot) == 0:
+			proxy.Kind, proxy.Slot = SlotProxy, &slot
+			return proxy
+		case v.Cmp(beaconSlot) == 0:
+			proxy.Kind, proxy.Slot = BeaconProxy, &slot
+		}
+	}
+
+	return proxy
+}
@@ -0,0 +1,134 @@
+package selectors
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// SignatureDB maps selectors to the signatures known to hash to them, several signatures
+// may share a selector.
+type SignatureDB map[Selector][]string
+
+// Add records signature under its selector.
+func (db SignatureDB) Add(signature string) {
+	db.addAs(SelectorOf(signature), signature)
+}
+
+func (db SignatureDB) addAs(selector Selector, signature string) {
+	for _, known := range db[selector] {
+		if known == signature {
+			return
+		}
+	}
+
+	db[selector] = append(db[selector], signature)
+	sort.Strings(db[selector])
+}
+
+// Lookup is the signatures of selector.
+func (db SignatureDB) Lookup(selector Selector) []string {
+	return db[selector]
+}
+
+func parseSelector(s string) (Selector, error) {
+	var selector Selector
+	b, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X"))
+	if err != nil || len(b) != len(selector) {
+		return selector, fmt.Errorf("invalid selector %q", s)
+	}
+
+	copy(selector[:], b)
+	return selector, nil
+}
+
+// LoadSignatureDB reads a signature file. JSON files are an array of signatures or an
+// object of selectors to a signature or an array of them. Other files have a line per
+// signature, optionally after its selector, '#' starts a comment:
+//
+//	transfer(address,uint256)
+//	0x095ea7b3 approve(address,uint256)
+func LoadSignatureDB(path string) (SignatureDB, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	db := SignatureDB{}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return db, db.loadJSON(trimmed)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for num := 1; scanner.Scan(); num++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		fields := strings.Fields(line)
+		switch len(fields) {
+		case 0:
+		case 1:
+			db.Add(fields[0])
+		case 2:
+			selector, err := parseSelector(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, num, err)
+			}
+			db.addAs(selector, fields[1])
+		default:
+			return nil, fmt.Errorf("%s:%d: expect a signature, optionally after its selector", path, num)
+		}
+	}
+
+	return db, scanner.Err()
+}
+
+func (db SignatureDB) loadJSON(data []byte) error {
+	if data[0] == '[' {
+		var signatures []string
+		if err := json.Unmarshal(data, &signatures); err != nil {
+			return err
+		}
+
+		for _, signature := range signatures {
+			db.Add(signature)
+		}
+
+		return nil
+	}
+
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+
+	for key, raw := range entries {
+		selector, err := parseSelector(key)
+		if err != nil {
+			return err
+		}
+
+		var signatures []string
+		if err = json.Unmarshal(raw, &signatures); err != nil {
+			var signature string
+			if err = json.Unmarshal(raw, &signature); err != nil {
+				return fmt.Errorf("%s: expect a signature or an array of them", key)
+			}
+			signatures = []string{signature}
+		}
+
+		for _, signature := range signatures {
+			db.addAs(selector, signature)
+		}
+	}
+
+	return nil
+}
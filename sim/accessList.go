@@ -0,0 +1,61 @@
+package sim
+
+import (
+	"context"
+
+	"github.com/SealSC/SealEVM/tracer"
+	"github.com/SealSC/SealEVM/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// AccessListResult is the access list of a message and the outcome of the message run
+// with it, GasUsed includes the cost of the list.
+type AccessListResult struct {
+	AccessList []AccessTuple
+	GasUsed    uint64
+	ReturnData []byte
+
+	//Err is the execution error of the last run
+	Err error
+}
+
+// CreateAccessList runs msg, from the access list it has, with the list of the accesses
+// of the run before until no new account or slot is accessed, as eth_createAccessList of
+// go-ethereum does. The sender, the recipient, or the created contract, and the precompiled
+// contracts are left out. state must give a fresh copy each time, see GasEstimator.
+func CreateAccessList(ctx context.Context, state func() StateDB, env *BlockEnv, msg *Message) (*AccessListResult, error) {
+	to := msg.To
+	if to == nil {
+		created := types.Address(crypto.CreateAddress(common.Address(msg.From), state().Nonce(msg.From)))
+		to = &created
+	}
+
+	prev := tracer.NewAccessListTracer(msg.AccessList, msg.From, *to)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		list := prev.AccessList()
+		try := *msg
+		try.AccessList = list
+
+		t := tracer.NewAccessListTracer(list, msg.From, *to)
+		result, err := CallMessage(state(), env, &try, t)
+		if err != nil {
+			return nil, err
+		}
+
+		if t.Equal(prev) {
+			return &AccessListResult{
+				AccessList: list,
+				GasUsed:    result.GasUsed,
+				ReturnData: result.ReturnData,
+				Err:        result.Err,
+			}, nil
+		}
+
+		prev = t
+	}
+}
@@ -0,0 +1,222 @@
+package sim
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/statedb/memory"
+	"github.com/SealSC/SealEVM/types"
+)
+
+// number is an uint64 of an alloc, hex or decimal, quoted or not.
+type number uint64
+
+func (n *number) UnmarshalJSON(data []byte) error {
+	str := strings.Trim(string(data), `"`)
+
+	var v uint64
+	var err error
+	if strings.HasPrefix(str, "0x") || strings.HasPrefix(str, "0X") {
+		v, err = strconv.ParseUint(str[2:], 16, 64)
+	} else {
+		v, err = strconv.ParseUint(str, 10, 64)
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid number %s: %w", data, err)
+	}
+
+	*n = number(v)
+	return nil
+}
+
+func (n number) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fmt.Sprintf("0x%x", uint64(n)))
+}
+
+func parseAddress(s string) (types.Address, error) {
+	var addr types.Address
+
+	str := strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	b, err := hex.DecodeString(str)
+	if err != nil || len(b) != types.AddressBytesLen {
+		return addr, fmt.Errorf("invalid address %q", s)
+	}
+
+	addr.SetBytes(b)
+	return addr, nil
+}
+
+// GenesisAccount is an account of a geth style alloc (the alloc section of a genesis),
+// the pre and post states of the fixtures have the same format.
+type GenesisAccount struct {
+	Balance *evmInt256.Int
+	Code    types.Bytes
+	Nonce   uint64
+	Storage map[types.Slot]*evmInt256.Int
+}
+
+type genesisAccountJSON struct {
+	Balance *evmInt256.Int            `json:"balance"`
+	Code    types.Bytes               `json:"code,omitempty"`
+	Nonce   *number                   `json:"nonce,omitempty"`
+	Storage map[string]*evmInt256.Int `json:"storage,omitempty"`
+}
+
+func (a *GenesisAccount) UnmarshalJSON(data []byte) error {
+	var j genesisAccountJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	a.Balance = j.Balance
+	if a.Balance == nil {
+		a.Balance = evmInt256.New(0)
+	}
+
+	a.Code = j.Code
+	if j.Nonce != nil {
+		a.Nonce = uint64(*j.Nonce)
+	}
+
+	a.Storage = map[types.Slot]*evmInt256.Int{}
+	for key, val := range j.Storage {
+		slot := evmInt256.New(0)
+		if err := slot.UnmarshalJSON([]byte(strconv.Quote(key))); err != nil {
+			return fmt.Errorf("invalid storage slot %s: %w", key, err)
+		}
+
+		if val != nil && !val.IsZero() {
+			a.Storage[types.Int256ToSlot(slot)] = val
+		}
+	}
+
+	return nil
+}
+
+func (a *GenesisAccount) MarshalJSON() ([]byte, error) {
+	j := struct {
+		Balance *evmInt256.Int            `json:"balance"`
+		Code    types.Bytes               `json:"code,omitempty"`
+		Nonce   *number                   `json:"nonce,omitempty"`
+		Storage map[types.Slot]types.Hash `json:"storage,omitempty"`
+	}{
+		Balance: a.Balance,
+		Code:    a.Code,
+	}
+
+	if a.Nonce > 0 {
+		nonce := number(a.Nonce)
+		j.Nonce = &nonce
+	}
+
+	for slot, val := range a.Storage {
+		if j.Storage == nil {
+			j.Storage = map[types.Slot]types.Hash{}
+		}
+		j.Storage[slot] = types.Int256ToHash(val)
+	}
+
+	return json.Marshal(j)
+}
+
+// Alloc is a set of accounts in the geth alloc.json format. Addresses are accepted with
+// or without the 0x prefix.
+type Alloc map[types.Address]*GenesisAccount
+
+func (a *Alloc) UnmarshalJSON(data []byte) error {
+	var accounts map[string]*GenesisAccount
+	if err := json.Unmarshal(data, &accounts); err != nil {
+		return err
+	}
+
+	*a = Alloc{}
+	for addrStr, acc := range accounts {
+		addr, err := parseAddress(addrStr)
+		if err != nil {
+			return err
+		}
+
+		if acc == nil {
+			acc = &GenesisAccount{Balance: evmInt256.New(0)}
+		}
+		(*a)[addr] = acc
+	}
+
+	return nil
+}
+
+func LoadAlloc(path string) (Alloc, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var alloc Alloc
+	if err = json.Unmarshal(data, &alloc); err != nil {
+		return nil, fmt.Errorf("invalid alloc %s: %w", path, err)
+	}
+
+	return alloc, nil
+}
+
+// NewState is a memory.DB holding the accounts of alloc.
+func NewState(alloc Alloc) *memory.DB {
+	db := memory.NewDB()
+	for addr, acc := range alloc {
+		var contract *environment.Contract
+		if len(acc.Code) > 0 {
+			contract = &environment.Contract{
+				Code:     acc.Code,
+				CodeHash: db.HashOfCode(acc.Code),
+				CodeSize: uint64(len(acc.Code)),
+			}
+		}
+
+		stored := environment.NewAccount(addr, acc.Balance, contract)
+		for slot, val := range acc.Storage {
+			stored.Slots[slot] = val
+		}
+
+		//SetAccount stores a copy
+		db.SetAccount(stored, acc.Nonce)
+	}
+
+	return db
+}
+
+// Dump returns the accounts of db in the alloc format, zero slots are left out.
+func Dump(db *memory.DB) Alloc {
+	alloc := Alloc{}
+	db.ForEachAccount(func(acc *environment.Account, nonce uint64) {
+		alloc[acc.Address] = dumpAccount(acc, nonce)
+	})
+
+	return alloc
+}
+
+func dumpAccount(acc *environment.Account, nonce uint64) *GenesisAccount {
+	d := &GenesisAccount{
+		Balance: acc.Balance.Clone(),
+		Nonce:   nonce,
+		Storage: map[types.Slot]*evmInt256.Int{},
+	}
+
+	if acc.Contract != nil && len(acc.Contract.Code) > 0 {
+		d.Code = acc.Contract.Code
+	}
+
+	for slot, val := range acc.Slots {
+		if val != nil && !val.IsZero() {
+			d.Storage[slot] = val.Clone()
+		}
+	}
+
+	return d
+}
@@ -0,0 +1,187 @@
+package sim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/SealSC/SealEVM/abi"
+	"github.com/SealSC/SealEVM/evmErrors"
+)
+
+// callStipend is the gas given for free to the callee of a CALL with value.
+const callStipend = 2300
+
+var ErrGasAllowance = errors.New("gas required exceeds allowance")
+
+// ExecutionError is the failure of a message at the highest gas limit an estimation tried.
+// Reason is the decoded Error(string) or Panic(uint256) of a revert, if any.
+type ExecutionError struct {
+	Err        error
+	ReturnData []byte
+	Reason     string
+}
+
+func newExecutionError(result *TxResult) *ExecutionError {
+	e := &ExecutionError{Err: result.Err, ReturnData: result.ReturnData}
+	if errors.Is(result.Err, evmErrors.RevertErr) {
+		e.Reason, _ = abi.UnpackRevert(result.ReturnData)
+	}
+
+	return e
+}
+
+func (e *ExecutionError) Error() string {
+	if !errors.Is(e.Err, evmErrors.RevertErr) {
+		return "execution failed: " + e.Err.Error()
+	}
+
+	if e.Reason != "" {
+		return "execution reverted: " + e.Reason
+	}
+
+	return "execution reverted"
+}
+
+func (e *ExecutionError) Unwrap() error {
+	return e.Err
+}
+
+// GasEstimator finds the gas limits messages need in Env. State gives the state each
+// try runs on, it must be a fresh copy every time: the executions are committed to it.
+type GasEstimator struct {
+	State func() StateDB
+	Env   *BlockEnv
+}
+
+// run executes msg with gas on a new copy of the state, a message rejected before the
+// execution counts as failed. A failed read of the state is returned.
+func (e *GasEstimator) run(msg *Message, gas uint64) (bool, error) {
+	try := *msg
+	try.GasLimit = gas
+
+	result, err := CallMessage(e.State(), e.Env, &try, nil)
+	if errors.Is(err, ErrStateRead) {
+		return false, err
+	}
+
+	return err == nil && result.Err == nil, nil
+}
+
+// allowance is the highest gas limit the balance of the sender pays for at the price of
+// msg, with the value sent.
+func (e *GasEstimator) allowance(msg *Message) (uint64, error) {
+	price := bigOf(msg.GasPrice)
+	if msg.GasFeeCap != nil {
+		price = bigOf(msg.GasFeeCap)
+	}
+
+	if price.Sign() == 0 {
+		return e.Env.GasLimit, nil
+	}
+
+	state := e.State()
+	available := bigOf(state.Balance(msg.From))
+	if err := stateErr(state); err != nil {
+		return 0, err
+	}
+
+	available.Sub(available, bigOf(msg.Value))
+	if available.Sign() < 0 {
+		return 0, fmt.Errorf("%w: address %s", ErrInsufficientFunds, msg.From)
+	}
+
+	allowance := available.Div(available, price)
+	if !allowance.IsUint64() {
+		return e.Env.GasLimit, nil
+	}
+
+	return allowance.Uint64(), nil
+}
+
+// EstimateGas is the lowest gas limit msg succeeds with, found by a binary search between
+// the intrinsic gas and the gas limit of msg, or of the block when it has none, capped by
+// what the sender can pay for. Success is tried rather than derived from the gas used: the
+// 63/64 kept by each CALL, the refunds and the contracts checking gasleft() all need more
+// than the execution ends up using. A message failing at the highest limit returns an
+// *ExecutionError, with the revert reason for a revert. The state is never written.
+func (e *GasEstimator) EstimateGas(ctx context.Context, msg *Message) (uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	hi := msg.GasLimit
+	if hi == 0 || hi > e.Env.GasLimit {
+		hi = e.Env.GasLimit
+	}
+
+	allowance, err := e.allowance(msg)
+	if err != nil {
+		return 0, err
+	}
+
+	if allowance < hi {
+		hi = allowance
+	}
+
+	intrinsic := IntrinsicGas(msg)
+	if hi < intrinsic {
+		return 0, fmt.Errorf("%w: have %d, want %d", ErrIntrinsicGas, hi, intrinsic)
+	}
+
+	try := *msg
+	try.GasLimit = hi
+	result, err := CallMessage(e.State(), e.Env, &try, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	if result.Err != nil {
+		if errors.Is(result.Err, evmErrors.RevertErr) {
+			return 0, newExecutionError(result)
+		}
+
+		return 0, fmt.Errorf("%w (%d): %w", ErrGasAllowance, hi, newExecutionError(result))
+	}
+
+	//nothing runs with less than what the execution used
+	lo := result.GasUsed - 1
+	if lo < intrinsic-1 {
+		lo = intrinsic - 1
+	}
+
+	//most executions succeed with the gas used and the 1/64 kept by the calls
+	guess := (result.GasUsed + callStipend) * 64 / 63
+	if guess < hi {
+		ok, err := e.run(msg, guess)
+		if err != nil {
+			return 0, err
+		}
+
+		if ok {
+			hi = guess
+		} else {
+			lo = guess
+		}
+	}
+
+	for lo+1 < hi {
+		if err = ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		mid := lo + (hi-lo)/2
+		ok, err := e.run(msg, mid)
+		if err != nil {
+			return 0, err
+		}
+
+		if ok {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+
+	return hi, nil
+}
@@ -0,0 +1,113 @@
+package sim_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/SealSC/SealEVM/evmErrors"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/sim"
+	"github.com/SealSC/SealEVM/types"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var child = types.Address{19: 0xc1}
+
+// newEstimator runs on the funds of sender, the contract with code and the child with
+// childCode, both holding 1 in slot 0.
+func newEstimator(code string, childCode string) *sim.GasEstimator {
+	slots := map[types.Slot]*evmInt256.Int{{}: evmInt256.New(1)}
+	alloc := sim.Alloc{
+		sender:   {Balance: evmInt256.New(1e18)},
+		contract: {Code: common.FromHex(code), Storage: slots},
+		child:    {Code: common.FromHex(childCode), Storage: slots},
+	}
+
+	return &sim.GasEstimator{
+		State: func() sim.StateDB { return sim.NewState(alloc) },
+		Env:   newEnv(),
+	}
+}
+
+// estimate checks that the estimation of a call to the contract is the lowest gas limit it
+// succeeds with, it returns the estimation and the gas used at it.
+func estimate(t *testing.T, e *sim.GasEstimator) (uint64, uint64) {
+	t.Helper()
+
+	msg := &sim.Message{From: sender, To: &contract}
+	gas, err := e.EstimateGas(context.Background(), msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	call := func(limit uint64) (*sim.TxResult, error) {
+		try := *msg
+		try.GasLimit = limit
+		return sim.CallMessage(e.State(), e.Env, &try, nil)
+	}
+
+	result, err := call(gas)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Err != nil {
+		t.Fatalf("the call fails with the estimation %d: %v", gas, result.Err)
+	}
+
+	//below the intrinsic gas the message is rejected
+	if failed, err := call(gas - 1); err == nil && failed.Err == nil {
+		t.Fatalf("the call succeeds with %d, below the estimation %d", gas-1, gas)
+	}
+
+	return gas, result.GasUsed
+}
+
+func TestEstimateGasTransfer(t *testing.T) {
+	if gas, _ := estimate(t, newEstimator("", "")); gas != 21000 {
+		t.Fatalf("a transfer is estimated at %d, want 21000", gas)
+	}
+}
+
+func TestEstimateGasRefund(t *testing.T) {
+	//clears slot 0, the refund lowers the gas used below the gas needed
+	gas, used := estimate(t, newEstimator("6000600055", ""))
+	if used >= gas {
+		t.Fatalf("estimated %d, used %d, want the refund to lower the gas used", gas, used)
+	}
+}
+
+func TestEstimateGasCallKeeps64th(t *testing.T) {
+	//calls the child with all the gas left and reverts when the call fails, the child sets
+	//slot 1
+	gas, used := estimate(t, newEstimator("5f5f5f5f5f73"+common.Bytes2Hex(child[:])+"5af115602157005b5f5ffd", "600160015500"))
+	if used >= gas {
+		t.Fatalf("estimated %d, used %d, want the 1/64 kept by CALL on top", gas, used)
+	}
+}
+
+func TestEstimateGasLeftGuard(t *testing.T) {
+	//reverts when GAS is below 100000
+	gas, used := estimate(t, newEstimator("5a620186a01015600b57005b5f5ffd", ""))
+	if gas < 21000+100000 || used > 22000 {
+		t.Fatalf("estimated %d, used %d, want the 100000 checked by the contract", gas, used)
+	}
+}
+
+func TestEstimateGasRevert(t *testing.T) {
+	e := newEstimator("5f5ffd", "")
+	_, err := e.EstimateGas(context.Background(), &sim.Message{From: sender, To: &contract})
+
+	var execErr *sim.ExecutionError
+	if !errors.As(err, &execErr) || !errors.Is(err, evmErrors.RevertErr) || errors.Is(err, sim.ErrGasAllowance) {
+		t.Fatalf("a call reverting at any gas got %v, want the revert", err)
+	}
+
+	//JUMPDEST PUSH0 JUMP runs out of any gas
+	e = newEstimator("5b5f56", "")
+	_, err = e.EstimateGas(context.Background(), &sim.Message{From: sender, To: &contract})
+	if !errors.Is(err, sim.ErrGasAllowance) {
+		t.Fatalf("an endless loop got %v, want %v", err, sim.ErrGasAllowance)
+	}
+}
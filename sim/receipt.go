@@ -0,0 +1,78 @@
+package sim
+
+import (
+	"github.com/SealSC/SealEVM/crypto/hashes"
+	"github.com/SealSC/SealEVM/types"
+)
+
+type rlpLog struct {
+	Address []byte
+	Topics  [][]byte
+	Data    []byte
+}
+
+// LogsHash is keccak(rlp(logs)), the "logs" field of the state test fixtures.
+func LogsHash(logs []*types.Log) types.Hash {
+	list := make([]rlpLog, 0, len(logs))
+	for _, l := range logs {
+		item := rlpLog{
+			Address: l.Address[:],
+			Topics:  make([][]byte, 0, len(l.Topics)),
+			Data:    l.Data,
+		}
+
+		for i := range l.Topics {
+			item.Topics = append(item.Topics, l.Topics[i][:])
+		}
+
+		list = append(list, item)
+	}
+
+	return hashOf(hashes.Keccak256(mustRLP(list)))
+}
+
+// LogsBloom is the 2048 bits bloom filter of the addresses and topics of logs.
+func LogsBloom(logs []*types.Log) []byte {
+	bloom := make([]byte, 256)
+	add := func(data []byte) {
+		h := hashes.Keccak256(data)
+		for i := 0; i < 6; i += 2 {
+			bit := (uint(h[i])<<8 | uint(h[i+1])) & 2047
+			bloom[255-bit/8] |= 1 << (bit % 8)
+		}
+	}
+
+	for _, l := range logs {
+		add(l.Address[:])
+		for i := range l.Topics {
+			add(l.Topics[i][:])
+		}
+	}
+
+	return bloom
+}
+
+// EncodeReceipt is the consensus encoding of a receipt, prefixed with txType for typed
+// transactions, as hashed into the receipts root.
+func EncodeReceipt(txType byte, result *TxResult, cumulativeGas uint64) []byte {
+	var status uint64
+	if result.Err == nil {
+		status = 1
+	}
+
+	logs := make([]rlpLog, 0, len(result.Logs))
+	for _, l := range result.Logs {
+		item := rlpLog{Address: l.Address[:], Topics: [][]byte{}, Data: l.Data}
+		for i := range l.Topics {
+			item.Topics = append(item.Topics, l.Topics[i][:])
+		}
+		logs = append(logs, item)
+	}
+
+	enc := mustRLP([]interface{}{status, cumulativeGas, LogsBloom(result.Logs), logs})
+	if txType == 0 {
+		return enc
+	}
+
+	return append([]byte{txType}, enc...)
+}
@@ -0,0 +1,139 @@
+package sim
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/SealSC/SealEVM/crypto/hashes"
+	"github.com/SealSC/SealEVM/instructions"
+	"github.com/SealSC/SealEVM/override"
+	"github.com/SealSC/SealEVM/storage"
+	"github.com/SealSC/SealEVM/tracer"
+	"github.com/SealSC/SealEVM/types"
+)
+
+// simulatedBlockTime is the time between the simulated blocks when no override sets it.
+const simulatedBlockTime = 12
+
+// blockHashWindow is the number of blocks BLOCKHASH reads before the current one.
+const blockHashWindow = 256
+
+// simulatedBlockHash is the hash of a simulated block, keccak of its decimal number as the
+// hashes of FakeHashState.
+func simulatedBlockHash(number uint64) types.Hash {
+	return hashOf(hashes.Keccak256([]byte(strconv.FormatUint(number, 10))))
+}
+
+// Call is a call of a bundle. State is applied to the scratch state right before the call.
+// Block, when not nil, starts a new simulated block with the call: the previous block with
+// the number plus one and the timestamp plus 12 seconds, patched by Block, with the hash of
+// simulatedBlockHash. A Call without Message only applies its State and Block, its result
+// only has Env.
+type Call struct {
+	Message *Message
+	State   override.StateOverride
+	Block   *override.BlockOverride
+}
+
+// CallResult is the outcome of a call of a bundle, Env is the block it ran in. Trace is set
+// when the simulator traces.
+type CallResult struct {
+	Env             BlockEnv
+	GasUsed         uint64
+	ReturnData      []byte
+	Logs            []*types.Log
+	ContractAddress *types.Address
+
+	//Err is the execution error, the calls after it still run
+	Err   error
+	Trace *tracer.ExecutionResult
+}
+
+// Simulator runs bundles of calls in Env over State, which is only read. The calls of a
+// bundle share a scratch override.Storage layer, each one sees the changes of the previous
+// ones, and the layer is dropped at the end. The nonce of the sender is increased by each
+// call, failed or not, as by a transaction. BLOCKHASH is answered by State for the blocks up
+// to Env, the blocks after it have the hashes of the simulated blocks.
+type Simulator struct {
+	State storage.IExternalStorage
+	Env   *BlockEnv
+
+	//Trace gives each call a struct log with this configuration when set
+	Trace *tracer.Config
+}
+
+// SimulateBundle runs calls in order, as CallMessage does. A call rejected before its
+// execution, as one below the intrinsic gas, stops the bundle.
+func (s *Simulator) SimulateBundle(ctx context.Context, calls []Call) ([]*CallResult, error) {
+	scratch, err := override.NewStorage(s.State, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	env := *s.Env
+	results := make([]*CallResult, len(calls))
+	for i, call := range calls {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if call.Block != nil {
+			left, leftHash := env.Number, env.Hash
+			if leftHash == (types.Hash{}) {
+				leftHash = simulatedBlockHash(left)
+			}
+
+			env.Number++
+			env.Timestamp += simulatedBlockTime
+			env.Override(call.Block)
+			env.Hash = simulatedBlockHash(env.Number)
+
+			//the blocks skipped by an override of the number are simulated as well
+			scratch.SetBlockHash(left, leftHash)
+			for number := left + 1; number < env.Number; number++ {
+				if number+blockHashWindow >= env.Number {
+					scratch.SetBlockHash(number, simulatedBlockHash(number))
+				}
+			}
+			scratch.SetCurrentBlock(env.Number)
+		}
+
+		if err = scratch.Override(call.State); err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+
+		results[i] = &CallResult{Env: env}
+		if call.Message == nil {
+			continue
+		}
+
+		var hook instructions.IStepHook
+		var logger *tracer.StructLogger
+		if s.Trace != nil {
+			logger = tracer.NewStructLogger(s.Trace)
+			hook = logger
+		}
+
+		//CallMessage leaves the nonce of a call, and undoes the one of a failed creation
+		from := call.Message.From
+		nonce := scratch.Nonce(from)
+		result, err := CallMessage(scratch, &env, call.Message, hook)
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		scratch.SetNonce(from, nonce+1)
+
+		results[i].GasUsed = result.GasUsed
+		results[i].ReturnData = result.ReturnData
+		results[i].Logs = result.Logs
+		results[i].ContractAddress = result.ContractAddress
+		results[i].Err = result.Err
+
+		if logger != nil {
+			results[i].Trace = logger.Result(result.GasUsed, result.ReturnData, result.Err)
+		}
+	}
+
+	return results, nil
+}
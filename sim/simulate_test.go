@@ -0,0 +1,93 @@
+package sim_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/SealSC/SealEVM/crypto/hashes"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/override"
+	"github.com/SealSC/SealEVM/sim"
+	"github.com/SealSC/SealEVM/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestSimulateBundleNonces(t *testing.T) {
+	simulator := &sim.Simulator{State: newState("00"), Env: newEnv()}
+
+	//a call, a reverted creation then a creation, each one takes a nonce
+	results, err := simulator.SimulateBundle(context.Background(), []sim.Call{
+		{Message: &sim.Message{From: sender, To: &contract}},
+		{Message: &sim.Message{From: sender, Data: common.FromHex("5f5ffd")}},
+		{Message: &sim.Message{From: sender, Data: common.FromHex("00")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if results[1].Err == nil || results[2].Err != nil {
+		t.Fatalf("the creations got %v and %v, want a revert then a success", results[1].Err, results[2].Err)
+	}
+
+	want := types.Address(crypto.CreateAddress(common.Address(sender), 2))
+	if got := results[2].ContractAddress; got == nil || *got != want {
+		t.Fatalf("the creation is at %v, want %s of the nonce 2", got, want)
+	}
+}
+
+// returns BLOCKHASH of the number in the call data
+const blockHashCode = "5f35405f5260205ff3"
+
+func TestSimulateBundleBlockHashes(t *testing.T) {
+	state := newState(blockHashCode)
+	base := types.Hash{0xb0}
+	state.SetBlockHash(0, base)
+	state.SetCurrentBlock(1)
+
+	simulated := func(number uint64) types.Hash {
+		var h types.Hash
+		h.SetBytes(hashes.Keccak256([]byte(strconv.FormatUint(number, 10))))
+		return h
+	}
+
+	ask := func(number uint64) *sim.Message {
+		data := types.Int256ToHash(evmInt256.New(number))
+		return &sim.Message{From: sender, To: &contract, Data: data[:]}
+	}
+
+	ten := uint64(10)
+	calls := []sim.Call{
+		{Message: ask(0)},
+		{Message: ask(1), Block: &override.BlockOverride{}},
+		{Message: ask(9), Block: &override.BlockOverride{Number: &ten}},
+		{Message: ask(2)},
+		{Message: ask(0)},
+		{Message: ask(10)},
+	}
+
+	//the block of Env is 1, the first simulated one 2, then 10 after the skipped ones
+	want := []types.Hash{base, simulated(1), simulated(9), simulated(2), base, {}}
+
+	results, err := (&sim.Simulator{State: state, Env: newEnv()}).SimulateBundle(context.Background(), calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, result := range results {
+		if result.Err != nil {
+			t.Fatalf("call %d: %v", i, result.Err)
+		}
+
+		var got types.Hash
+		got.SetBytes(result.ReturnData)
+		if got != want[i] {
+			t.Errorf("call %d in block %d got %s, want %s", i, result.Env.Number, got, want[i])
+		}
+	}
+
+	if results[2].Env.Hash != simulated(10) {
+		t.Errorf("block 10 has the hash %s, want %s", results[2].Env.Hash, simulated(10))
+	}
+}
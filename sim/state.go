@@ -0,0 +1,17 @@
+package sim
+
+import (
+	"github.com/SealSC/SealEVM/crypto/hashes"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/statedb/memory"
+)
+
+// FakeHashState is a memory.DB whose BLOCKHASH returns the hashes of geth's evm runner and
+// state tests, keccak of the decimal block number.
+type FakeHashState struct {
+	*memory.DB
+}
+
+func (s FakeHashState) GetBlockHash(block *evmInt256.Int) (*evmInt256.Int, error) {
+	return evmInt256.FromBytes(hashes.Keccak256([]byte(block.Text(10)))), nil
+}
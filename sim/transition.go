@@ -0,0 +1,538 @@
+// Package sim executes transactions and calls on SealEVM the way an Ethereum client does
+// around the EVM: the checks of a transaction, buying the gas, the refund, the fees and the
+// removal of the empty accounts. It also computes the state, receipts and logs roots.
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/SealSC/SealEVM"
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmErrors"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/gasSetting"
+	"github.com/SealSC/SealEVM/instructions"
+	"github.com/SealSC/SealEVM/override"
+	"github.com/SealSC/SealEVM/storage"
+	"github.com/SealSC/SealEVM/storage/cache"
+	"github.com/SealSC/SealEVM/txcodec"
+	"github.com/SealSC/SealEVM/types"
+)
+
+const (
+	txAccessListAddressGas    = 2400
+	txAccessListStorageKeyGas = 1900
+	initCodeWordGas           = 2
+	maxInitCodeSize           = 2 * 24576
+
+	minBlobGasPrice            = 1
+	blobGasPriceUpdateFraction = 3338477
+)
+
+// blob gas of EIP-4844 transactions
+const (
+	BlobGasPerBlob     = 1 << 17
+	MaxBlobGasPerBlock = 6 * BlobGasPerBlob
+)
+
+// errors of invalid transactions, the state is left untouched when one is returned.
+var (
+	ErrNonceMismatch     = errors.New("nonce mismatch")
+	ErrSenderHasCode     = errors.New("sender is not an EOA")
+	ErrIntrinsicGas      = errors.New("intrinsic gas too low")
+	ErrGasLimitExceeded  = errors.New("gas limit exceeds the block gas limit")
+	ErrInsufficientFunds = errors.New("insufficient funds for gas * price + value")
+	ErrFeeCapTooLow      = errors.New("max fee per gas less than block base fee")
+	ErrTipAboveFeeCap    = errors.New("max priority fee per gas higher than max fee per gas")
+	ErrMaxInitCodeSize   = errors.New("max initcode size exceeded")
+	ErrBlobFeeCapTooLow  = errors.New("max fee per blob gas less than block blob gas fee")
+	ErrInvalidBlobTx     = errors.New("invalid blob transaction")
+	ErrValueOverflow     = errors.New("value exceeds 256 bits")
+)
+
+// ErrStateRead is returned when the state failed to read an account or a slot during an
+// execution, see ErrorRecorder. The state is then in an undefined state and must be dropped.
+var ErrStateRead = errors.New("state read failed")
+
+// ErrorRecorder is a state recording the failed reads of the methods which return no error,
+// as override.Storage and statedb/fork.Source do. An execution on it fails with ErrStateRead
+// once Err is set.
+type ErrorRecorder interface {
+	Err() error
+}
+
+// stateErr is the failed read recorded by state, if it records them.
+func stateErr(state StateDB) error {
+	if recorder, ok := state.(ErrorRecorder); ok {
+		if err := recorder.Err(); err != nil {
+			return fmt.Errorf("%w: %w", ErrStateRead, err)
+		}
+	}
+
+	return nil
+}
+
+// StateDB is the state transactions are applied to, statedb/memory.DB implements it. The
+// nonces changed by CreateAddress during a failed execution are undone with RevertToSnapshot.
+type StateDB interface {
+	storage.IExternalStorage
+
+	Nonce(addr types.Address) uint64
+	SetNonce(addr types.Address, nonce uint64)
+	Balance(addr types.Address) *evmInt256.Int
+	AddBalance(addr types.Address, amount *evmInt256.Int)
+	SubBalance(addr types.Address, amount *evmInt256.Int)
+	Code(addr types.Address) []byte
+	DeleteAccount(addr types.Address)
+
+	//Commit applies the result of a successful execution
+	Commit(result *cache.ResultCache)
+
+	Snapshot() int
+	RevertToSnapshot(id int)
+}
+
+// BlockEnv is the block a transaction runs in. Difficulty carries PREVRANDAO after the merge.
+type BlockEnv struct {
+	Coinbase      types.Address
+	GasLimit      uint64
+	Number        uint64
+	Timestamp     uint64
+	ChainID       *evmInt256.Int
+	BaseFee       *evmInt256.Int
+	Difficulty    *evmInt256.Int
+	ExcessBlobGas uint64
+	Hash          types.Hash
+
+	//BlobBaseFee replaces the blob base fee derived from ExcessBlobGas when set
+	BlobBaseFee *evmInt256.Int
+}
+
+func (b *BlockEnv) blobBaseFee() *big.Int {
+	if b.BlobBaseFee != nil {
+		return bigOf(b.BlobBaseFee)
+	}
+
+	return fakeExponential(big.NewInt(minBlobGasPrice), new(big.Int).SetUint64(b.ExcessBlobGas), big.NewInt(blobGasPriceUpdateFraction))
+}
+
+// EVMBlock is the block of the execution context.
+func (b *BlockEnv) EVMBlock() environment.Block {
+	block := environment.Block{
+		ChainID:     b.ChainID,
+		Coinbase:    b.Coinbase,
+		Timestamp:   b.Timestamp,
+		Number:      b.Number,
+		Difficulty:  b.Difficulty,
+		GasLimit:    evmInt256.New(b.GasLimit),
+		Hash:        b.Hash,
+		BaseFee:     b.BaseFee,
+		BlobBaseFee: evmInt256.FromBigInt(b.blobBaseFee()),
+	}
+
+	if block.ChainID == nil {
+		block.ChainID = evmInt256.New(1)
+	}
+
+	if block.Difficulty == nil {
+		block.Difficulty = evmInt256.New(0)
+	}
+
+	if block.BaseFee == nil {
+		block.BaseFee = evmInt256.New(0)
+	}
+
+	return block
+}
+
+// Override patches b with o as override.BlockOverride.Apply patches an environment.Block.
+func (b *BlockEnv) Override(o *override.BlockOverride) {
+	if o == nil {
+		return
+	}
+
+	block := environment.Block{
+		Coinbase:   b.Coinbase,
+		Timestamp:  b.Timestamp,
+		Number:     b.Number,
+		Difficulty: b.Difficulty,
+		GasLimit:   evmInt256.New(b.GasLimit),
+		BaseFee:    b.BaseFee,
+	}
+
+	o.Apply(&block)
+
+	b.Coinbase = block.Coinbase
+	b.Timestamp = block.Timestamp
+	b.Number = block.Number
+	b.Difficulty = block.Difficulty
+	b.GasLimit = block.GasLimit.Uint64()
+	b.BaseFee = block.BaseFee
+	if block.BlobBaseFee != nil {
+		b.BlobBaseFee = block.BlobBaseFee
+	}
+}
+
+// fakeExponential approximates factor * e ** (numerator / denominator) (EIP-4844).
+func fakeExponential(factor, numerator, denominator *big.Int) *big.Int {
+	output := new(big.Int)
+	accum := new(big.Int).Mul(factor, denominator)
+	for i := int64(1); accum.Sign() > 0; i++ {
+		output.Add(output, accum)
+
+		accum.Mul(accum, numerator)
+		accum.Div(accum, denominator)
+		accum.Div(accum, big.NewInt(i))
+	}
+
+	return output.Div(output, denominator)
+}
+
+type AccessTuple = environment.AccessTuple
+
+// Message is a transaction with its sender recovered. GasPrice is set for legacy and
+// access list transactions, GasFeeCap and GasTipCap for the EIP-1559 ones. Type is the
+// EIP-2718 type, it only matters for the receipts of a block.
+type Message struct {
+	Type          byte
+	From          types.Address
+	To            *types.Address
+	Nonce         uint64
+	GasLimit      uint64
+	GasPrice      *evmInt256.Int
+	GasFeeCap     *evmInt256.Int
+	GasTipCap     *evmInt256.Int
+	Value         *evmInt256.Int
+	Data          []byte
+	AccessList    []AccessTuple
+	BlobHashes    []types.Hash
+	BlobGasFeeCap *evmInt256.Int
+}
+
+// TxMessage is the message of a decoded transaction.
+func TxMessage(tx *txcodec.Transaction) *Message {
+	return &Message{
+		Type:          tx.Type,
+		From:          tx.From,
+		To:            tx.To,
+		Nonce:         tx.Nonce,
+		GasLimit:      tx.GasLimit,
+		GasPrice:      tx.GasPrice,
+		GasFeeCap:     tx.GasFeeCap,
+		GasTipCap:     tx.GasTipCap,
+		Value:         tx.Value,
+		Data:          tx.Data,
+		AccessList:    tx.AccessList,
+		BlobHashes:    tx.BlobHashes,
+		BlobGasFeeCap: tx.BlobGasFeeCap,
+	}
+}
+
+type TxResult struct {
+	GasUsed         uint64
+	ReturnData      []byte
+	Logs            []*types.Log
+	ContractAddress *types.Address
+
+	//Err is the execution error, the transaction itself is valid and included
+	Err error
+}
+
+func bigOf(i *evmInt256.Int) *big.Int {
+	if i == nil {
+		return new(big.Int)
+	}
+
+	return new(big.Int).Set(i.Int)
+}
+
+// IntrinsicGas is the gas msg costs before its execution.
+func IntrinsicGas(msg *Message) uint64 {
+	return gasSetting.Get().IntrinsicCost(msg.Data, msg.To) + extraIntrinsicGas(msg)
+}
+
+// extraIntrinsicGas is the part of the intrinsic gas SealEVM does not charge itself.
+func extraIntrinsicGas(msg *Message) uint64 {
+	var gas uint64
+	for _, tuple := range msg.AccessList {
+		gas += txAccessListAddressGas + uint64(len(tuple.StorageKeys))*txAccessListStorageKeyGas
+	}
+
+	if msg.To == nil {
+		gas += (uint64(len(msg.Data)) + 31) / 32 * initCodeWordGas
+	}
+
+	return gas
+}
+
+// gasPrices returns the price paid per gas and the part of it paid to the coinbase.
+func gasPrices(env *BlockEnv, msg *Message) (*big.Int, *big.Int, error) {
+	baseFee := bigOf(env.BaseFee)
+	if msg.GasFeeCap == nil {
+		price := bigOf(msg.GasPrice)
+		if price.Cmp(baseFee) < 0 {
+			return nil, nil, ErrFeeCapTooLow
+		}
+
+		return price, new(big.Int).Sub(price, baseFee), nil
+	}
+
+	feeCap, tipCap := bigOf(msg.GasFeeCap), bigOf(msg.GasTipCap)
+	if tipCap.Cmp(feeCap) > 0 {
+		return nil, nil, ErrTipAboveFeeCap
+	}
+
+	if feeCap.Cmp(baseFee) < 0 {
+		return nil, nil, ErrFeeCapTooLow
+	}
+
+	price := new(big.Int).Add(baseFee, tipCap)
+	if price.Cmp(feeCap) > 0 {
+		price = feeCap
+	}
+
+	return price, new(big.Int).Sub(price, baseFee), nil
+}
+
+func validate(s StateDB, env *BlockEnv, msg *Message, intrinsic uint64, price *big.Int) error {
+	if nonce := s.Nonce(msg.From); nonce != msg.Nonce {
+		return fmt.Errorf("%w: address %s, tx %d, state %d", ErrNonceMismatch, msg.From, msg.Nonce, nonce)
+	}
+
+	if len(s.Code(msg.From)) > 0 {
+		return ErrSenderHasCode
+	}
+
+	if msg.GasLimit > env.GasLimit {
+		return ErrGasLimitExceeded
+	}
+
+	if msg.GasLimit < intrinsic {
+		return fmt.Errorf("%w: have %d, want %d", ErrIntrinsicGas, msg.GasLimit, intrinsic)
+	}
+
+	if msg.To == nil && len(msg.Data) > maxInitCodeSize {
+		return ErrMaxInitCodeSize
+	}
+
+	value := bigOf(msg.Value)
+	if value.BitLen() > 256 {
+		return ErrValueOverflow
+	}
+
+	//the balance must cover the highest price the sender agreed to
+	maxPrice := price
+	if msg.GasFeeCap != nil {
+		maxPrice = bigOf(msg.GasFeeCap)
+	}
+
+	cost := new(big.Int).Mul(new(big.Int).SetUint64(msg.GasLimit), maxPrice)
+	cost.Add(cost, value)
+
+	if msg.BlobHashes != nil {
+		if msg.To == nil || len(msg.BlobHashes) == 0 || uint64(len(msg.BlobHashes))*BlobGasPerBlob > MaxBlobGasPerBlock {
+			return ErrInvalidBlobTx
+		}
+
+		for _, h := range msg.BlobHashes {
+			if h[0] != 0x01 {
+				return ErrInvalidBlobTx
+			}
+		}
+
+		blobFeeCap := bigOf(msg.BlobGasFeeCap)
+		if blobFeeCap.Cmp(env.blobBaseFee()) < 0 {
+			return ErrBlobFeeCapTooLow
+		}
+
+		blobGas := new(big.Int).SetUint64(uint64(len(msg.BlobHashes)) * BlobGasPerBlob)
+		cost.Add(cost, blobGas.Mul(blobGas, blobFeeCap))
+	}
+
+	if s.Balance(msg.From).Cmp(cost) < 0 {
+		return fmt.Errorf("%w: address %s", ErrInsufficientFunds, msg.From)
+	}
+
+	return nil
+}
+
+// execute runs msg on the EVM at price, without the checks and the fees around it.
+func execute(state StateDB, env *BlockEnv, msg *Message, price *big.Int, hook instructions.IStepHook) (*TxResult, SealEVM.ExecuteResult) {
+	value := msg.Value
+	if value == nil {
+		value = evmInt256.New(0)
+	}
+
+	ctx := &environment.Context{
+		Block: env.EVMBlock(),
+		Transaction: environment.Transaction{
+			Origin:     msg.From,
+			To:         msg.To,
+			GasPrice:   evmInt256.FromBigInt(price),
+			GasLimit:   evmInt256.New(msg.GasLimit - extraIntrinsicGas(msg)),
+			BlobHashes: msg.BlobHashes,
+			AccessList: msg.AccessList,
+		},
+		Message: environment.Message{
+			Caller: msg.From,
+			Value:  value.Clone(),
+			Data:   msg.Data,
+		},
+	}
+
+	SealEVM.Load()
+	evm := SealEVM.New(SealEVM.EVMParam{
+		MaxStackDepth: 1024,
+		ExternalStore: state,
+		Context:       ctx,
+		StepHook:      hook,
+	})
+
+	result, execErr := evm.Execute()
+
+	//an exceptional halt consumes all gas, only REVERT gives the rest back
+	gasLeft := result.GasLeft
+	if execErr != nil && execErr != evmErrors.RevertErr {
+		gasLeft = 0
+	}
+
+	//EIP-3529, a successful execution gets back its refund up to a fifth of the gas used
+	gasUsed := msg.GasLimit - gasLeft
+	if execErr == nil {
+		refund := result.Refund
+		if refund > gasUsed/5 {
+			refund = gasUsed / 5
+		}
+
+		gasUsed -= refund
+	}
+
+	return &TxResult{
+		GasUsed:    gasUsed,
+		ReturnData: result.ResultData,
+		Err:        execErr,
+	}, result
+}
+
+// ApplyMessage validates and executes a transaction on state the way an Ethereum client
+// does around the EVM: gas is bought up front, left gas is refunded and the priority fee
+// goes to the coinbase. An invalid transaction returns an error and changes nothing, a
+// failed read of the state returns ErrStateRead.
+func ApplyMessage(state StateDB, env *BlockEnv, msg *Message) (*TxResult, error) {
+	return TraceMessage(state, env, msg, nil)
+}
+
+// TraceMessage is ApplyMessage with hook called on each step of the execution.
+func TraceMessage(state StateDB, env *BlockEnv, msg *Message, hook instructions.IStepHook) (*TxResult, error) {
+	intrinsic := IntrinsicGas(msg)
+
+	price, tip, err := gasPrices(env, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = validate(state, env, msg, intrinsic, price); err != nil {
+		return nil, err
+	}
+
+	if err = stateErr(state); err != nil {
+		return nil, err
+	}
+
+	gasCost := new(big.Int).Mul(new(big.Int).SetUint64(msg.GasLimit), price)
+	if msg.BlobHashes != nil {
+		blobGas := new(big.Int).SetUint64(uint64(len(msg.BlobHashes)) * BlobGasPerBlob)
+		gasCost.Add(gasCost, blobGas.Mul(blobGas, env.blobBaseFee()))
+	}
+
+	state.SubBalance(msg.From, evmInt256.FromBigInt(gasCost))
+
+	//SealEVM increases the nonce of a creation while deriving the address
+	if msg.To != nil {
+		state.SetNonce(msg.From, msg.Nonce+1)
+	}
+
+	snapshot := state.Snapshot()
+	txResult, result := execute(state, env, msg, price, hook)
+	if err = stateErr(state); err != nil {
+		return nil, err
+	}
+
+	//a failed execution keeps only the nonce of the sender and the gas it bought
+	execErr := txResult.Err
+	if execErr != nil {
+		state.RevertToSnapshot(snapshot)
+		state.SetNonce(msg.From, msg.Nonce+1)
+	}
+
+	touched := []types.Address{msg.From, env.Coinbase}
+	if execErr == nil {
+		state.Commit(&result.StorageCache)
+		txResult.ContractAddress = result.ContractAddress
+		if result.StorageCache.Logs != nil {
+			txResult.Logs = *result.StorageCache.Logs
+		}
+
+		for addr := range result.StorageCache.CachedAccounts {
+			touched = append(touched, addr)
+		}
+	}
+
+	refund := new(big.Int).Mul(new(big.Int).SetUint64(msg.GasLimit-txResult.GasUsed), price)
+	state.AddBalance(msg.From, evmInt256.FromBigInt(refund))
+
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(txResult.GasUsed), tip)
+	state.AddBalance(env.Coinbase, evmInt256.FromBigInt(fee))
+
+	//EIP-161, touched accounts which end up empty are removed
+	for _, addr := range touched {
+		if state.AccountExist(addr) && state.AccountEmpty(addr) {
+			state.DeleteAccount(addr)
+		}
+	}
+
+	return txResult, nil
+}
+
+// CallMessage executes msg on state as eth_call does: the nonce, the balance for the gas
+// and the fees are neither checked nor charged, the gas price is the one of the message
+// (GasPrice, or GasFeeCap when given) and a zero GasLimit means the one of the block. The
+// result of a successful execution is committed, state should be a copy to leave the
+// original untouched.
+func CallMessage(state StateDB, env *BlockEnv, msg *Message, hook instructions.IStepHook) (*TxResult, error) {
+	call := *msg
+	if call.GasLimit == 0 {
+		call.GasLimit = env.GasLimit
+	}
+
+	intrinsic := IntrinsicGas(&call)
+	if call.GasLimit < intrinsic {
+		return nil, fmt.Errorf("%w: have %d, want %d", ErrIntrinsicGas, call.GasLimit, intrinsic)
+	}
+
+	price := bigOf(call.GasPrice)
+	if call.GasFeeCap != nil {
+		price = bigOf(call.GasFeeCap)
+	}
+
+	snapshot := state.Snapshot()
+	txResult, result := execute(state, env, &call, price, hook)
+	if err := stateErr(state); err != nil {
+		return nil, err
+	}
+
+	if txResult.Err != nil {
+		state.RevertToSnapshot(snapshot)
+		return txResult, nil
+	}
+
+	state.Commit(&result.StorageCache)
+	txResult.ContractAddress = result.ContractAddress
+	if result.StorageCache.Logs != nil {
+		txResult.Logs = *result.StorageCache.Logs
+	}
+
+	return txResult, nil
+}
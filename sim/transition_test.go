@@ -0,0 +1,102 @@
+package sim_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/SealSC/SealEVM/evmErrors"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/sim"
+	"github.com/SealSC/SealEVM/statedb/memory"
+	"github.com/SealSC/SealEVM/types"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	sender   = types.Address{0x5e}
+	contract = types.Address{0xc0}
+	coinbase = types.Address{0xcb}
+)
+
+func newEnv() *sim.BlockEnv {
+	return &sim.BlockEnv{Coinbase: coinbase, GasLimit: 30000000, Number: 1, BaseFee: evmInt256.New(1)}
+}
+
+// newState holds the funds of sender and the contract with code.
+func newState(code string) *memory.DB {
+	return sim.NewState(sim.Alloc{
+		sender:   {Balance: evmInt256.New(1e18)},
+		contract: {Code: common.FromHex(code), Nonce: 1},
+	})
+}
+
+// CREATE of an empty contract, then REVERT
+const revertedCreate = "600060006000f0505f5ffd"
+
+func TestApplyMessageRevertsCreate(t *testing.T) {
+	state := newState(revertedCreate)
+	msg := &sim.Message{From: sender, To: &contract, GasLimit: 100000, GasPrice: evmInt256.New(2)}
+
+	result, err := sim.ApplyMessage(state, newEnv(), msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !errors.Is(result.Err, evmErrors.RevertErr) {
+		t.Fatalf("execution got %v, want %v", result.Err, evmErrors.RevertErr)
+	}
+
+	if nonce := state.Nonce(contract); nonce != 1 {
+		t.Fatalf("contract nonce is %d after the revert, want 1", nonce)
+	}
+
+	if nonce := state.Nonce(sender); nonce != 1 {
+		t.Fatalf("sender nonce is %d, want 1", nonce)
+	}
+
+	//the gas used is paid at the price of 2, the tip of 1 goes to the coinbase
+	paid := evmInt256.New(1e18).Sub(state.Balance(sender))
+	if paid.Uint64() != 2*result.GasUsed {
+		t.Fatalf("sender paid %s for %d gas, want %d", paid, result.GasUsed, 2*result.GasUsed)
+	}
+
+	if fee := state.Balance(coinbase); fee.Uint64() != result.GasUsed {
+		t.Fatalf("coinbase got %s, want %d", fee, result.GasUsed)
+	}
+}
+
+func TestApplyMessageRevertsFailedCreation(t *testing.T) {
+	state := newState("")
+
+	//the init code runs the CREATE then reverts
+	msg := &sim.Message{From: sender, GasLimit: 200000, GasPrice: evmInt256.New(1), Data: common.FromHex(revertedCreate)}
+	result, err := sim.ApplyMessage(state, newEnv(), msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Err == nil || result.ContractAddress != nil {
+		t.Fatalf("the creation got %+v, want a revert", result)
+	}
+
+	if nonce := state.Nonce(sender); nonce != 1 {
+		t.Fatalf("sender nonce is %d, want 1", nonce)
+	}
+}
+
+func TestCallMessageRevertsCreate(t *testing.T) {
+	state := newState(revertedCreate)
+
+	result, err := sim.CallMessage(state, newEnv(), &sim.Message{From: sender, To: &contract}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Err == nil {
+		t.Fatalf("the call succeeded, want a revert")
+	}
+
+	if nonce := state.Nonce(contract); nonce != 1 {
+		t.Fatalf("contract nonce is %d after the revert, want 1", nonce)
+	}
+}
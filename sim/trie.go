@@ -0,0 +1,197 @@
+package sim
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/SealSC/SealEVM/crypto/hashes"
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// EmptyRoot is the root of an empty Merkle Patricia trie, keccak(rlp("")).
+var EmptyRoot = hashOf(hashes.Keccak256([]byte{0x80}))
+
+// emptyCodeHash is keccak of empty code.
+var emptyCodeHash = hashOf(hashes.Keccak256(nil))
+
+func hashOf(b []byte) types.Hash {
+	var h types.Hash
+	h.SetBytes(b)
+	return h
+}
+
+type trieEntry struct {
+	key   []byte //in nibbles
+	value []byte
+}
+
+func keyNibbles(key []byte) []byte {
+	nibbles := make([]byte, 0, len(key)*2)
+	for _, b := range key {
+		nibbles = append(nibbles, b>>4, b&0x0f)
+	}
+
+	return nibbles
+}
+
+// hexPrefix is the compact encoding of a node path (yellow paper appendix C).
+func hexPrefix(nibbles []byte, leaf bool) []byte {
+	var flag byte
+	if leaf {
+		flag = 2
+	}
+
+	var out []byte
+	if len(nibbles)%2 == 1 {
+		out = append(out, (flag+1)<<4|nibbles[0])
+		nibbles = nibbles[1:]
+	} else {
+		out = append(out, flag<<4)
+	}
+
+	for i := 0; i < len(nibbles); i += 2 {
+		out = append(out, nibbles[i]<<4|nibbles[i+1])
+	}
+
+	return out
+}
+
+func mustRLP(v interface{}) []byte {
+	enc, err := rlp.EncodeToBytes(v)
+	if err != nil {
+		panic(err)
+	}
+
+	return enc
+}
+
+// nodeRef embeds nodes shorter than 32 bytes in their parent and refers others by hash.
+func nodeRef(enc []byte) interface{} {
+	if len(enc) < 32 {
+		return rlp.RawValue(enc)
+	}
+
+	return hashes.Keccak256(enc)
+}
+
+// encodeNode builds the node of sorted entries which share their first depth nibbles.
+// No key is a prefix of another, so no value is stored in a branch.
+func encodeNode(entries []trieEntry, depth int) []byte {
+	if len(entries) == 1 {
+		return mustRLP([]interface{}{hexPrefix(entries[0].key[depth:], true), entries[0].value})
+	}
+
+	first, last := entries[0].key, entries[len(entries)-1].key
+	prefix := depth
+	for prefix < len(first) && prefix < len(last) && first[prefix] == last[prefix] {
+		prefix++
+	}
+
+	if prefix > depth {
+		child := encodeNode(entries, prefix)
+		return mustRLP([]interface{}{hexPrefix(first[depth:prefix], false), nodeRef(child)})
+	}
+
+	branch := make([]interface{}, 17)
+	for i := range branch {
+		branch[i] = []byte{}
+	}
+
+	for start := 0; start < len(entries); {
+		nibble := entries[start].key[depth]
+		end := start
+		for end < len(entries) && entries[end].key[depth] == nibble {
+			end++
+		}
+
+		branch[nibble] = nodeRef(encodeNode(entries[start:end], depth+1))
+		start = end
+	}
+
+	return mustRLP(branch)
+}
+
+// trieRoot is the root of a secure trie, the keys are hashed before insertion.
+func trieRoot(items map[string][]byte) types.Hash {
+	hashed := make(map[string][]byte, len(items))
+	for key, value := range items {
+		hashed[string(hashes.Keccak256([]byte(key)))] = value
+	}
+
+	return rawTrieRoot(hashed)
+}
+
+// rawTrieRoot is the root of a trie of the keys as they are, as the transactions and receipts tries.
+func rawTrieRoot(items map[string][]byte) types.Hash {
+	if len(items) == 0 {
+		return EmptyRoot
+	}
+
+	entries := make([]trieEntry, 0, len(items))
+	for key, value := range items {
+		entries = append(entries, trieEntry{
+			key:   keyNibbles([]byte(key)),
+			value: value,
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].key, entries[j].key) < 0
+	})
+
+	return hashOf(hashes.Keccak256(encodeNode(entries, 0)))
+}
+
+// ListRoot is the root of the trie of items keyed by their RLP encoded index, as the
+// transactions and receipts roots of a block.
+func ListRoot(items [][]byte) types.Hash {
+	keyed := make(map[string][]byte, len(items))
+	for i, item := range items {
+		keyed[string(mustRLP(uint64(i)))] = item
+	}
+
+	return rawTrieRoot(keyed)
+}
+
+func storageRoot(slots map[types.Slot]*evmInt256.Int) types.Hash {
+	items := map[string][]byte{}
+	for slot, val := range slots {
+		if val == nil || val.IsZero() {
+			continue
+		}
+
+		items[string(slot[:])] = mustRLP(val.Int)
+	}
+
+	return trieRoot(items)
+}
+
+// AccountLister is a state able to list its accounts, as statedb/memory.DB and
+// statedb/archive.DB are.
+type AccountLister interface {
+	ForEachAccount(visit func(acc *environment.Account, nonce uint64))
+}
+
+// StateRoot is the state root of the accounts of db as computed by Ethereum clients.
+func StateRoot(db AccountLister) types.Hash {
+	items := map[string][]byte{}
+	db.ForEachAccount(func(acc *environment.Account, nonce uint64) {
+		codeHash := emptyCodeHash
+		if acc.Contract != nil && len(acc.Contract.Code) > 0 {
+			codeHash = hashOf(hashes.Keccak256(acc.Contract.Code))
+		}
+
+		root := storageRoot(acc.Slots)
+		items[string(acc.Address[:])] = mustRLP([]interface{}{
+			nonce,
+			acc.Balance.Int,
+			root[:],
+			codeHash[:],
+		})
+	})
+
+	return trieRoot(items)
+}
@@ -54,6 +54,10 @@ func (s *Stack) Len() int {
 	return len(s.data)
 }
 
+func (s *Stack) All() []*evmInt256.Int {
+	return s.data
+}
+
 func (s *Stack) Push(i *evmInt256.Int) {
 	s.data = append(s.data, i)
 	return
@@ -0,0 +1,381 @@
+// Package archive is a versioned state database for SealEVM, keeping the state of every block
+// for historical queries. Each block is kept as the diff of the accounts it changed, the state
+// as of any block is read through a View, and the chain can be rolled back on a reorg.
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/types"
+)
+
+var (
+	ErrUnknownBlock        = errors.New("unknown block")
+	ErrNonSequentialBlocks = errors.New("non sequential block")
+)
+
+// AccountDiff is the change of an account in a block, its fields are the values at the end of
+// the block. Cleared drops the storage the account had before, then Slots and DataBlocks are
+// written, a zero slot or an empty data block is removed. An account which does not exist at
+// the end of the block is cleared.
+type AccountDiff struct {
+	Exists  bool
+	Cleared bool
+	Balance *evmInt256.Int
+	Nonce   uint64
+
+	//nil for an account without code
+	Code     []byte
+	CodeHash types.Hash
+
+	Slots      map[types.Slot]*evmInt256.Int
+	DataBlocks types.DataBlock
+}
+
+// Diff is the change of the state made by a block.
+type Diff struct {
+	Number   uint64
+	Hash     types.Hash
+	Accounts map[types.Address]*AccountDiff
+}
+
+type accountVersion struct {
+	block    uint64
+	exists   bool
+	balance  *evmInt256.Int
+	nonce    uint64
+	codeHash types.Hash
+}
+
+type slotVersion struct {
+	block uint64
+	value *evmInt256.Int
+}
+
+type dataVersion struct {
+	block uint64
+	data  types.Bytes
+}
+
+// history is every version of an account, in the order of the blocks. resets are the blocks
+// which dropped the storage of the account, the slots written before are hidden from them on.
+type history struct {
+	versions []accountVersion
+	resets   []uint64
+	slots    map[types.Slot][]slotVersion
+	blocks   map[types.Slot][]dataVersion
+}
+
+// DB is the state of a chain, with the changes of the block in execution kept apart until
+// CommitBlock adds it to the chain. DB is the state of the block in execution, At gives the
+// state as of a committed block.
+//
+// Nonces are kept beside the accounts, the addresses of created contracts are derived from
+// them by the rules of Ethereum. CreateAddress and CreateFixedAddress are called during the
+// execution and change the nonces at once, take a Snapshot before an execution and revert to
+// it when the execution fails. DB is safe for concurrent use, a block is executed by one
+// goroutine at a time.
+type DB struct {
+	lock sync.RWMutex
+
+	histories   map[types.Address]*history
+	codes       map[types.Hash][]byte
+	blockHashes map[uint64]types.Hash
+	diffs       []*Diff
+
+	pending      map[types.Address]*AccountDiff
+	currentBlock uint64
+
+	journal   []journalEntry
+	snapshots []snapshot
+}
+
+func NewDB() *DB {
+	return &DB{
+		histories:   map[types.Address]*history{},
+		codes:       map[types.Hash][]byte{},
+		blockHashes: map[uint64]types.Hash{},
+		pending:     map[types.Address]*AccountDiff{},
+	}
+}
+
+// lastAt is the index of the last of n blocks which is not after number, -1 when there is none.
+func lastAt(n int, block func(i int) uint64, number uint64) int {
+	return sort.Search(n, func(i int) bool { return block(i) > number }) - 1
+}
+
+// head is the last block committed, the lock must be held.
+func (db *DB) head() (uint64, bool) {
+	if len(db.diffs) == 0 {
+		return 0, false
+	}
+
+	return db.diffs[len(db.diffs)-1].Number, true
+}
+
+// Head is the number of the last block committed, ok is false before the first one.
+func (db *DB) Head() (number uint64, ok bool) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	return db.head()
+}
+
+// diff is the diff of the block number, nil when it is not in the chain.
+func (db *DB) diff(number uint64) *Diff {
+	if len(db.diffs) == 0 || number < db.diffs[0].Number {
+		return nil
+	}
+
+	i := number - db.diffs[0].Number
+	if i >= uint64(len(db.diffs)) {
+		return nil
+	}
+
+	return db.diffs[i]
+}
+
+// Diff is the change made by the block number, it must not be modified.
+func (db *DB) Diff(number uint64) (*Diff, error) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	d := db.diff(number)
+	if d == nil {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownBlock, number)
+	}
+
+	return d, nil
+}
+
+// CommitBlock adds the changes made since the previous block to the chain as the block number
+// with hash, an account left as it was is not part of the diff. The first block may have any
+// number, the next ones follow it.
+func (db *DB) CommitBlock(number uint64, hash types.Hash) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if head, ok := db.head(); ok && number != head+1 {
+		return fmt.Errorf("%w: have %d, want %d", ErrNonSequentialBlocks, number, head+1)
+	}
+
+	for addr, acc := range db.pending {
+		if db.unchanged(addr, acc) {
+			delete(db.pending, addr)
+		}
+	}
+
+	d := &Diff{Number: number, Hash: hash, Accounts: db.pending}
+	for addr, acc := range d.Accounts {
+		db.apply(number, addr, acc)
+	}
+
+	db.diffs = append(db.diffs, d)
+	db.blockHashes[number] = hash
+	db.pending = map[types.Address]*AccountDiff{}
+	db.clearSnapshots()
+	db.currentBlock = number + 1
+	return nil
+}
+
+// unchanged tells whether acc leaves the account as the head has it, as an account given no
+// value, or one touched and removed as empty by EIP-161 in the same block. Such changes are
+// not kept, they would add a version for nothing. The lock must be held.
+func (db *DB) unchanged(addr types.Address, acc *AccountDiff) bool {
+	if len(acc.Slots) > 0 || len(acc.DataBlocks) > 0 {
+		return false
+	}
+
+	prev := accountVersion{balance: evmInt256.New(0)}
+	if head, ok := db.head(); ok {
+		prev = db.accountAt(addr, head)
+	}
+
+	if acc.Exists != prev.exists {
+		return false
+	}
+
+	//a missing account has no storage to clear
+	if !acc.Exists {
+		return true
+	}
+
+	return !acc.Cleared && acc.Balance.EQ(prev.balance) && acc.Nonce == prev.nonce && acc.CodeHash == prev.codeHash
+}
+
+func (db *DB) apply(number uint64, addr types.Address, acc *AccountDiff) {
+	h := db.histories[addr]
+	if h == nil {
+		h = &history{
+			slots:  map[types.Slot][]slotVersion{},
+			blocks: map[types.Slot][]dataVersion{},
+		}
+		db.histories[addr] = h
+	}
+
+	h.versions = append(h.versions, accountVersion{
+		block:    number,
+		exists:   acc.Exists,
+		balance:  acc.Balance,
+		nonce:    acc.Nonce,
+		codeHash: acc.CodeHash,
+	})
+
+	if acc.Code != nil {
+		db.codes[acc.CodeHash] = acc.Code
+	}
+
+	if acc.Cleared {
+		h.resets = append(h.resets, number)
+	}
+
+	for slot, val := range acc.Slots {
+		h.slots[slot] = append(h.slots[slot], slotVersion{block: number, value: val})
+	}
+
+	for slot, data := range acc.DataBlocks {
+		h.blocks[slot] = append(h.blocks[slot], dataVersion{block: number, data: data})
+	}
+}
+
+// Rollback removes the blocks after number from the chain, with the changes of the block in
+// execution, as a reorg does. The Views of the removed blocks read the blocks which replace
+// them.
+func (db *DB) Rollback(number uint64) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if db.diff(number) == nil {
+		return fmt.Errorf("%w: %d", ErrUnknownBlock, number)
+	}
+
+	for len(db.diffs) > 0 {
+		d := db.diffs[len(db.diffs)-1]
+		if d.Number <= number {
+			break
+		}
+
+		for addr, acc := range d.Accounts {
+			db.revert(addr, acc)
+		}
+
+		delete(db.blockHashes, d.Number)
+		db.diffs = db.diffs[:len(db.diffs)-1]
+	}
+
+	db.pending = map[types.Address]*AccountDiff{}
+	db.clearSnapshots()
+	db.currentBlock = number + 1
+	return nil
+}
+
+// revert drops the versions the last block added to the history of addr.
+func (db *DB) revert(addr types.Address, acc *AccountDiff) {
+	h := db.histories[addr]
+	h.versions = h.versions[:len(h.versions)-1]
+	if acc.Cleared {
+		h.resets = h.resets[:len(h.resets)-1]
+	}
+
+	for slot := range acc.Slots {
+		versions := h.slots[slot][:len(h.slots[slot])-1]
+		if len(versions) == 0 {
+			delete(h.slots, slot)
+		} else {
+			h.slots[slot] = versions
+		}
+	}
+
+	for slot := range acc.DataBlocks {
+		versions := h.blocks[slot][:len(h.blocks[slot])-1]
+		if len(versions) == 0 {
+			delete(h.blocks, slot)
+		} else {
+			h.blocks[slot] = versions
+		}
+	}
+
+	if len(h.versions) == 0 {
+		delete(db.histories, addr)
+	}
+}
+
+// accountAt is the account as of the block number, the lock must be held.
+func (db *DB) accountAt(addr types.Address, number uint64) accountVersion {
+	if h := db.histories[addr]; h != nil {
+		i := lastAt(len(h.versions), func(i int) uint64 { return h.versions[i].block }, number)
+		if i >= 0 {
+			return h.versions[i]
+		}
+	}
+
+	return accountVersion{balance: evmInt256.New(0)}
+}
+
+// reset is the last block not after number which dropped the storage of h, ok is false
+// when there is none.
+func (h *history) reset(number uint64) (uint64, bool) {
+	i := lastAt(len(h.resets), func(i int) uint64 { return h.resets[i] }, number)
+	if i < 0 {
+		return 0, false
+	}
+
+	return h.resets[i], true
+}
+
+func (db *DB) slotAt(addr types.Address, slot types.Slot, number uint64) *evmInt256.Int {
+	h := db.histories[addr]
+	if h == nil {
+		return evmInt256.New(0)
+	}
+
+	versions := h.slots[slot]
+	i := lastAt(len(versions), func(i int) uint64 { return versions[i].block }, number)
+	if i < 0 {
+		return evmInt256.New(0)
+	}
+
+	if reset, ok := h.reset(number); ok && versions[i].block < reset {
+		return evmInt256.New(0)
+	}
+
+	return versions[i].value.Clone()
+}
+
+func (db *DB) dataBlockAt(addr types.Address, slot types.Slot, number uint64) types.Bytes {
+	h := db.histories[addr]
+	if h == nil {
+		return nil
+	}
+
+	versions := h.blocks[slot]
+	i := lastAt(len(versions), func(i int) uint64 { return versions[i].block }, number)
+	if i < 0 || len(versions[i].data) == 0 {
+		return nil
+	}
+
+	if reset, ok := h.reset(number); ok && versions[i].block < reset {
+		return nil
+	}
+
+	return versions[i].data.Clone()
+}
+
+// blockHash is the hash BLOCKHASH returns for block in the block current.
+func (db *DB) blockHash(block *evmInt256.Int, current uint64) *evmInt256.Int {
+	if !block.IsUint64() {
+		return evmInt256.New(0)
+	}
+
+	number := block.Uint64()
+	if number >= current || number+blockHashWindow < current {
+		return evmInt256.New(0)
+	}
+
+	hash := db.blockHashes[number]
+	return evmInt256.FromBytes(hash[:])
+}
@@ -0,0 +1,177 @@
+package archive_test
+
+import (
+	"testing"
+
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/sim"
+	"github.com/SealSC/SealEVM/statedb/archive"
+	"github.com/SealSC/SealEVM/statedb/memory"
+	"github.com/SealSC/SealEVM/storage/cache"
+	"github.com/SealSC/SealEVM/types"
+)
+
+var (
+	alice    = types.Address{0xa1}
+	bob      = types.Address{0xb0}
+	coinbase = types.Address{0xcb}
+)
+
+// readResult is the result of an execution which read the balance of bob and the slot 1 of
+// alice, and changed nothing.
+func readResult(db *archive.DB) *cache.ResultCache {
+	result := cache.NewResultCache()
+	result.CacheAccount(environment.NewAccount(bob, nil, nil))
+
+	acc := environment.NewAccount(alice, db.Balance(alice), nil)
+	val, _ := db.Load(alice, types.Slot{31: 1})
+	acc.Slots[types.Slot{31: 1}] = val
+	result.CacheAccount(acc)
+
+	return &result
+}
+
+func TestCommitSkipsAccountsOnlyRead(t *testing.T) {
+	db := archive.NewDB()
+	db.SetBalance(alice, evmInt256.New(1))
+	db.SetState(alice, types.Slot{31: 1}, evmInt256.New(5))
+	if err := db.CommitBlock(1, types.Hash{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	db.Commit(readResult(db))
+
+	//the fee of a transaction without priority fee, the empty coinbase is removed (EIP-161)
+	db.AddBalance(coinbase, evmInt256.New(0))
+	if db.AccountEmpty(coinbase) {
+		db.DeleteAccount(coinbase)
+	}
+
+	if err := db.CommitBlock(2, types.Hash{2}); err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := db.Diff(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(diff.Accounts) != 0 {
+		for addr := range diff.Accounts {
+			t.Errorf("block 2 changed %s, which was only read", addr)
+		}
+	}
+
+	view, err := db.At(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if view.AccountExist(bob) || db.AccountExist(bob) {
+		t.Fatalf("BALANCE made bob exist")
+	}
+
+	if view.AccountExist(coinbase) {
+		t.Fatalf("a fee of zero made the coinbase exist")
+	}
+
+	if val, _ := view.Load(alice, types.Slot{31: 1}); val.Uint64() != 5 {
+		t.Fatalf("slot 1 of alice is %s, want 5", val.Text(10))
+	}
+}
+
+func TestCommitKeepsChanges(t *testing.T) {
+	db := archive.NewDB()
+
+	result := cache.NewResultCache()
+	result.CacheAccount(environment.NewAccount(bob, nil, nil)).Balance = evmInt256.New(3)
+	acc := result.CacheAccount(environment.NewAccount(alice, nil, nil))
+	acc.Slots[types.Slot{31: 1}] = evmInt256.New(7)
+	result.OriginalAccounts.Get(alice).Slots[types.Slot{31: 1}] = evmInt256.New(0)
+
+	db.Commit(&result)
+	if err := db.CommitBlock(1, types.Hash{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	diff, _ := db.Diff(1)
+	if len(diff.Accounts) != 2 {
+		t.Fatalf("block 1 changed %d accounts, want 2", len(diff.Accounts))
+	}
+
+	view, _ := db.At(1)
+	if view.Balance(bob).Uint64() != 3 {
+		t.Fatalf("balance of bob is %s, want 3", view.Balance(bob).Text(10))
+	}
+
+	if val, _ := view.Load(alice, types.Slot{31: 1}); val.Uint64() != 7 {
+		t.Fatalf("slot 1 of alice is %s, want 7", val.Text(10))
+	}
+}
+
+func TestForEachAccountListsPendingState(t *testing.T) {
+	db := archive.NewDB()
+	db.SetBalance(alice, evmInt256.New(1))
+	db.SetState(alice, types.Slot{31: 1}, evmInt256.New(5))
+	db.SetState(bob, types.Slot{31: 2}, evmInt256.New(6))
+	if err := db.CommitBlock(1, types.Hash{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	//bob is created again without his slot, alice gets a second one
+	db.DeleteAccount(bob)
+	db.SetNonce(bob, 1)
+	db.SetState(alice, types.Slot{31: 3}, evmInt256.New(7))
+
+	want := memory.NewDB()
+	want.SetBalance(alice, evmInt256.New(1))
+	want.SetState(alice, types.Slot{31: 1}, evmInt256.New(5))
+	want.SetState(alice, types.Slot{31: 3}, evmInt256.New(7))
+	want.SetNonce(bob, 1)
+
+	if root, wantRoot := sim.StateRoot(db), sim.StateRoot(want); root != wantRoot {
+		t.Fatalf("state root is %s, want %s", root, wantRoot)
+	}
+}
+
+func TestSnapshotRevertsPendingChanges(t *testing.T) {
+	db := archive.NewDB()
+	db.SetBalance(alice, evmInt256.New(5))
+	if err := db.CommitBlock(1, types.Hash{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	db.SetNonce(bob, 3)
+	outer := db.Snapshot()
+	db.SetBalance(alice, evmInt256.New(9))
+	created := db.CreateAddress(bob, environment.Transaction{})
+
+	inner := db.Snapshot()
+	db.SetState(alice, types.Slot{31: 1}, evmInt256.New(7))
+	db.DeleteAccount(created)
+
+	db.RevertToSnapshot(inner)
+	if val, _ := db.Load(alice, types.Slot{31: 1}); !val.IsZero() || !db.AccountExist(created) {
+		t.Fatalf("the inner snapshot kept slot 1 = %s, the created account exists: %v", val.Text(10), db.AccountExist(created))
+	}
+
+	if db.Balance(alice).Uint64() != 9 {
+		t.Fatalf("the inner snapshot reverted the balance to %s", db.Balance(alice).Text(10))
+	}
+
+	db.RevertToSnapshot(outer)
+	if db.Balance(alice).Uint64() != 5 || db.Nonce(bob) != 3 || db.AccountExist(created) {
+		t.Fatalf("balance %s, nonce %d, created account %v after the revert, want 5, 3 and none",
+			db.Balance(alice).Text(10), db.Nonce(bob), db.AccountExist(created))
+	}
+
+	//the changes made before the snapshot are committed
+	if err := db.CommitBlock(2, types.Hash{2}); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff, _ := db.Diff(2); len(diff.Accounts) != 1 || diff.Accounts[bob] == nil {
+		t.Fatalf("block 2 changed %d accounts, want bob only", len(diff.Accounts))
+	}
+}
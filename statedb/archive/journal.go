@@ -0,0 +1,104 @@
+package archive
+
+import (
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/types"
+)
+
+// journalEntry is the pending change of an account before its first write under a snapshot,
+// nil when it had none.
+type journalEntry struct {
+	addr types.Address
+	prev *AccountDiff
+}
+
+// snapshot is the length of the journal when it was taken, with the accounts journaled
+// since.
+type snapshot struct {
+	mark      int
+	journaled map[types.Address]bool
+}
+
+func (p *AccountDiff) clone() *AccountDiff {
+	c := *p
+	c.Slots = make(map[types.Slot]*evmInt256.Int, len(p.Slots))
+	for slot, val := range p.Slots {
+		c.Slots[slot] = val
+	}
+
+	c.DataBlocks = make(types.DataBlock, len(p.DataBlocks))
+	for slot, data := range p.DataBlocks {
+		c.DataBlocks[slot] = data
+	}
+
+	return &c
+}
+
+// record keeps the pending change of addr before a write, once for each snapshot. Values
+// are replaced and never changed in place, the copy only shares them.
+func (db *DB) record(addr types.Address) {
+	if len(db.snapshots) == 0 {
+		return
+	}
+
+	top := &db.snapshots[len(db.snapshots)-1]
+	if top.journaled[addr] {
+		return
+	}
+
+	var prev *AccountDiff
+	if p := db.pending[addr]; p != nil {
+		prev = p.clone()
+	}
+
+	db.journal = append(db.journal, journalEntry{addr: addr, prev: prev})
+	top.journaled[addr] = true
+}
+
+// Snapshot returns the id of the state of the block in execution, for RevertToSnapshot.
+// Snapshots nest, they are dropped by CommitBlock, Rollback and ClearSnapshots.
+func (db *DB) Snapshot() int {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.snapshots = append(db.snapshots, snapshot{mark: len(db.journal), journaled: map[types.Address]bool{}})
+	return len(db.snapshots) - 1
+}
+
+// RevertToSnapshot undoes the changes made since the snapshot id was taken, it and the
+// snapshots taken after it are dropped. An unknown id is ignored.
+func (db *DB) RevertToSnapshot(id int) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if id < 0 || id >= len(db.snapshots) {
+		return
+	}
+
+	mark := db.snapshots[id].mark
+	for i := len(db.journal) - 1; i >= mark; i-- {
+		e := db.journal[i]
+		if e.prev == nil {
+			delete(db.pending, e.addr)
+		} else {
+			db.pending[e.addr] = e.prev
+		}
+	}
+
+	db.journal = db.journal[:mark]
+	db.snapshots = db.snapshots[:id]
+}
+
+// ClearSnapshots keeps the current state and drops every snapshot with the journal.
+func (db *DB) ClearSnapshots() {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.clearSnapshots()
+}
+
+// clearSnapshots is ClearSnapshots with the lock held.
+func (db *DB) clearSnapshots() {
+	db.journal = nil
+	db.snapshots = nil
+}
@@ -0,0 +1,368 @@
+package archive
+
+import (
+	"github.com/SealSC/SealEVM/crypto/hashes"
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/statedb"
+	"github.com/SealSC/SealEVM/storage/cache"
+	"github.com/SealSC/SealEVM/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// blockHashWindow is the number of previous blocks BLOCKHASH can read.
+const blockHashWindow = 256
+
+// latest is the account in the block in execution, the lock must be held.
+func (db *DB) latest(addr types.Address) accountVersion {
+	if p := db.pending[addr]; p != nil {
+		return accountVersion{exists: p.Exists, balance: p.Balance, nonce: p.Nonce, codeHash: p.CodeHash}
+	}
+
+	head, ok := db.head()
+	if !ok {
+		return accountVersion{balance: evmInt256.New(0)}
+	}
+
+	return db.accountAt(addr, head)
+}
+
+func (db *DB) code(addr types.Address, codeHash types.Hash) []byte {
+	if p := db.pending[addr]; p != nil {
+		return p.Code
+	}
+
+	return db.codes[codeHash]
+}
+
+// touch returns the pending change of addr for a write, which makes it exist, the lock must
+// be held.
+func (db *DB) touch(addr types.Address) *AccountDiff {
+	db.record(addr)
+
+	p := db.pending[addr]
+	if p == nil {
+		acc := db.latest(addr)
+		p = &AccountDiff{
+			Balance:    acc.balance.Clone(),
+			Nonce:      acc.nonce,
+			Code:       db.codes[acc.codeHash],
+			CodeHash:   acc.codeHash,
+			Slots:      map[types.Slot]*evmInt256.Int{},
+			DataBlocks: types.DataBlock{},
+		}
+		db.pending[addr] = p
+	}
+
+	p.Exists = true
+	return p
+}
+
+func (db *DB) setCode(p *AccountDiff, code []byte) {
+	p.Code, p.CodeHash = nil, types.Hash{}
+	if len(code) > 0 {
+		p.Code = types.Bytes(code).Clone()
+		p.CodeHash = db.HashOfCode(code)
+	}
+}
+
+func clearStorage(p *AccountDiff) {
+	p.Cleared = true
+	p.Slots = map[types.Slot]*evmInt256.Int{}
+	p.DataBlocks = types.DataBlock{}
+}
+
+// deleteAccount is DeleteAccount with the lock held.
+func (db *DB) deleteAccount(addr types.Address) {
+	p := db.touch(addr)
+	*p = AccountDiff{Balance: evmInt256.New(0)}
+	clearStorage(p)
+}
+
+// created accounts start with nonce 1 (EIP-161).
+func (db *DB) created(addr types.Address) types.Address {
+	db.touch(addr).Nonce = 1
+	return addr
+}
+
+func (db *DB) SetCode(addr types.Address, code []byte) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.setCode(db.touch(addr), code)
+}
+
+func (db *DB) SetState(addr types.Address, slot types.Slot, val *evmInt256.Int) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.touch(addr).Slots[slot] = val.Clone()
+}
+
+func (db *DB) SetDataBlock(addr types.Address, slot types.Slot, data types.Bytes) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.touch(addr).DataBlocks[slot] = data.Clone()
+}
+
+func (db *DB) Nonce(addr types.Address) uint64 {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	return db.latest(addr).nonce
+}
+
+func (db *DB) SetNonce(addr types.Address, nonce uint64) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.touch(addr).Nonce = nonce
+}
+
+func (db *DB) Balance(addr types.Address) *evmInt256.Int {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	return db.latest(addr).balance.Clone()
+}
+
+func (db *DB) SetBalance(addr types.Address, balance *evmInt256.Int) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.touch(addr).Balance = balance.Clone()
+}
+
+func (db *DB) AddBalance(addr types.Address, amount *evmInt256.Int) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	p := db.touch(addr)
+	p.Balance = evmInt256.FromBigInt(p.Balance.Int).Add(amount)
+}
+
+// SubBalance does not check the balance, the caller has to.
+func (db *DB) SubBalance(addr types.Address, amount *evmInt256.Int) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	p := db.touch(addr)
+	p.Balance = evmInt256.FromBigInt(p.Balance.Int).Sub(amount)
+}
+
+func (db *DB) Code(addr types.Address) []byte {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	return db.code(addr, db.latest(addr).codeHash)
+}
+
+// DeleteAccount removes an account with its nonce, its storage and its data blocks.
+func (db *DB) DeleteAccount(addr types.Address) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.deleteAccount(addr)
+}
+
+// SetBlockHash sets the hash BLOCKHASH returns for the block number, CommitBlock sets the
+// hash of its block itself.
+func (db *DB) SetBlockHash(number uint64, hash types.Hash) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.blockHashes[number] = hash
+}
+
+// SetCurrentBlock sets the number of the block in execution, BLOCKHASH only reads the 256
+// blocks before it. It is the block after the last one committed by default.
+func (db *DB) SetCurrentBlock(number uint64) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.currentBlock = number
+}
+
+func (db *DB) GetBlockHash(block *evmInt256.Int) (*evmInt256.Int, error) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	return db.blockHash(block, db.currentBlock), nil
+}
+
+// ForEachAccount calls visit with each account of the block in execution and its nonce, in
+// no particular order. The accounts come with their slots, zero slots may be left out.
+func (db *DB) ForEachAccount(visit func(acc *environment.Account, nonce uint64)) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	slots := map[types.Address]map[types.Slot]bool{}
+	for addr, h := range db.histories {
+		slots[addr] = map[types.Slot]bool{}
+		for slot := range h.slots {
+			slots[addr][slot] = true
+		}
+	}
+
+	for addr, p := range db.pending {
+		if slots[addr] == nil {
+			slots[addr] = map[types.Slot]bool{}
+		}
+
+		for slot := range p.Slots {
+			slots[addr][slot] = true
+		}
+	}
+
+	for addr, keys := range slots {
+		version := db.latest(addr)
+		if !version.exists {
+			continue
+		}
+
+		acc := newAccount(addr, version, db.code(addr, version.codeHash))
+		for slot := range keys {
+			acc.Slots[slot] = db.load(addr, slot)
+		}
+
+		visit(acc, version.nonce)
+	}
+}
+
+// GetAccount returns an empty account when it does not exist.
+func (db *DB) GetAccount(address types.Address) (*environment.Account, error) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	acc := db.latest(address)
+	return newAccount(address, acc, db.code(address, acc.codeHash)), nil
+}
+
+func newAccount(address types.Address, acc accountVersion, code []byte) *environment.Account {
+	if !acc.exists {
+		return environment.NewAccount(address, nil, nil)
+	}
+
+	var contract *environment.Contract
+	if len(code) > 0 {
+		contract = &environment.Contract{
+			Code:     code,
+			CodeHash: acc.codeHash,
+			CodeSize: uint64(len(code)),
+		}
+	}
+
+	return environment.NewAccount(address, acc.balance.Clone(), contract)
+}
+
+func (db *DB) AccountExist(address types.Address) bool {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	return db.latest(address).exists
+}
+
+// empty follows EIP-161: an account is empty when it has no nonce, no balance and no code,
+// and an account which does not exist is empty too.
+func (acc accountVersion) empty() bool {
+	return !acc.exists || (acc.nonce == 0 && acc.balance.IsZero() && acc.codeHash == types.Hash{})
+}
+
+func (db *DB) AccountEmpty(address types.Address) bool {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	return db.latest(address).empty()
+}
+
+func (db *DB) HashOfCode(code []byte) types.Hash {
+	var hash types.Hash
+	hash.SetBytes(hashes.Keccak256(code))
+	return hash
+}
+
+func (db *DB) CreateAddress(caller types.Address, tx environment.Transaction) types.Address {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	p := db.touch(caller)
+	nonce := p.Nonce
+	p.Nonce++
+	return db.created(types.Address(crypto.CreateAddress(common.Address(caller), nonce)))
+}
+
+func (db *DB) CreateFixedAddress(caller types.Address, salt types.Hash, code []byte, tx environment.Transaction) types.Address {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.touch(caller).Nonce++
+	return db.created(types.Address(crypto.CreateAddress2(common.Address(caller), salt, hashes.Keccak256(code))))
+}
+
+// load is the slot in the block in execution, the lock must be held.
+func (db *DB) load(address types.Address, slot types.Slot) *evmInt256.Int {
+	if p := db.pending[address]; p != nil {
+		if val := p.Slots[slot]; val != nil {
+			return val.Clone()
+		}
+
+		if p.Cleared {
+			return evmInt256.New(0)
+		}
+	}
+
+	head, ok := db.head()
+	if !ok {
+		return evmInt256.New(0)
+	}
+
+	return db.slotAt(address, slot, head)
+}
+
+func (db *DB) Load(address types.Address, slot types.Slot) (*evmInt256.Int, error) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	return db.load(address, slot), nil
+}
+
+func (db *DB) GetDataBlock(address types.Address, slot types.Slot) (types.Bytes, error) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if p := db.pending[address]; p != nil {
+		if data, ok := p.DataBlocks[slot]; ok {
+			if len(data) == 0 {
+				return nil, nil
+			}
+			return data.Clone(), nil
+		}
+
+		if p.Cleared {
+			return nil, nil
+		}
+	}
+
+	head, ok := db.head()
+	if !ok {
+		return nil, nil
+	}
+
+	return db.dataBlockAt(address, slot, head), nil
+}
+
+// ClearStorage drops the slots and the data blocks of addr.
+func (db *DB) ClearStorage(addr types.Address) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	clearStorage(db.touch(addr))
+}
+
+// Commit applies the result of a successful execution to the block in execution, see
+// statedb.Commit.
+func (db *DB) Commit(result *cache.ResultCache) {
+	statedb.Commit(db, result)
+}
@@ -0,0 +1,126 @@
+package archive
+
+import (
+	"fmt"
+
+	"github.com/SealSC/SealEVM/crypto/hashes"
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// View is the state as of a committed block, an IExternalStorage which is only read. The
+// addresses of created contracts are derived without changing the nonces, executions run
+// over a writable layer, as override.NewStorage(view, nil), which reads the nonces of the
+// view.
+type View struct {
+	db           *DB
+	number       uint64
+	currentBlock uint64
+}
+
+// At is the state at the end of the block number. BLOCKHASH reads the blocks before it, as in
+// the execution of its transactions.
+func (db *DB) At(number uint64) (*View, error) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if db.diff(number) == nil {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownBlock, number)
+	}
+
+	return &View{db: db, number: number, currentBlock: number}, nil
+}
+
+// Number is the block of v.
+func (v *View) Number() uint64 {
+	return v.number
+}
+
+func (v *View) account(addr types.Address) accountVersion {
+	return v.db.accountAt(addr, v.number)
+}
+
+func (v *View) Nonce(addr types.Address) uint64 {
+	v.db.lock.RLock()
+	defer v.db.lock.RUnlock()
+
+	return v.account(addr).nonce
+}
+
+func (v *View) Balance(addr types.Address) *evmInt256.Int {
+	v.db.lock.RLock()
+	defer v.db.lock.RUnlock()
+
+	return v.account(addr).balance.Clone()
+}
+
+func (v *View) Code(addr types.Address) []byte {
+	v.db.lock.RLock()
+	defer v.db.lock.RUnlock()
+
+	return v.db.codes[v.account(addr).codeHash]
+}
+
+// SetCurrentBlock sets the number of the block BLOCKHASH runs in.
+func (v *View) SetCurrentBlock(number uint64) {
+	v.currentBlock = number
+}
+
+func (v *View) GetBlockHash(block *evmInt256.Int) (*evmInt256.Int, error) {
+	v.db.lock.RLock()
+	defer v.db.lock.RUnlock()
+
+	return v.db.blockHash(block, v.currentBlock), nil
+}
+
+// GetAccount returns the account without its slots, they are read by Load.
+func (v *View) GetAccount(address types.Address) (*environment.Account, error) {
+	v.db.lock.RLock()
+	defer v.db.lock.RUnlock()
+
+	acc := v.account(address)
+	return newAccount(address, acc, v.db.codes[acc.codeHash]), nil
+}
+
+func (v *View) AccountExist(address types.Address) bool {
+	v.db.lock.RLock()
+	defer v.db.lock.RUnlock()
+
+	return v.account(address).exists
+}
+
+func (v *View) AccountEmpty(address types.Address) bool {
+	v.db.lock.RLock()
+	defer v.db.lock.RUnlock()
+
+	return v.account(address).empty()
+}
+
+func (v *View) HashOfCode(code []byte) types.Hash {
+	return v.db.HashOfCode(code)
+}
+
+func (v *View) CreateAddress(caller types.Address, tx environment.Transaction) types.Address {
+	return types.Address(crypto.CreateAddress(common.Address(caller), v.Nonce(caller)))
+}
+
+func (v *View) CreateFixedAddress(caller types.Address, salt types.Hash, code []byte, tx environment.Transaction) types.Address {
+	return types.Address(crypto.CreateAddress2(common.Address(caller), salt, hashes.Keccak256(code)))
+}
+
+func (v *View) Load(address types.Address, slot types.Slot) (*evmInt256.Int, error) {
+	v.db.lock.RLock()
+	defer v.db.lock.RUnlock()
+
+	return v.db.slotAt(address, slot, v.number), nil
+}
+
+func (v *View) GetDataBlock(address types.Address, slot types.Slot) (types.Bytes, error) {
+	v.db.lock.RLock()
+	defer v.db.lock.RUnlock()
+
+	return v.db.dataBlockAt(address, slot, v.number), nil
+}
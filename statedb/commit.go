@@ -0,0 +1,86 @@
+// Package statedb holds what the state databases of its sub packages share: the rules by which
+// the result of an execution is applied to a state.
+package statedb
+
+import (
+	"bytes"
+
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/storage/cache"
+	"github.com/SealSC/SealEVM/types"
+)
+
+// Writer is a state database the result of an execution is committed to. A write makes the
+// account exist.
+type Writer interface {
+	SetBalance(addr types.Address, balance *evmInt256.Int)
+	SetCode(addr types.Address, code []byte)
+	SetState(addr types.Address, slot types.Slot, val *evmInt256.Int)
+	SetDataBlock(addr types.Address, slot types.Slot, data types.Bytes)
+
+	// ClearStorage drops the slots and the data blocks of addr.
+	ClearStorage(addr types.Address)
+	DeleteAccount(addr types.Address)
+}
+
+// Commit applies the result of a successful execution to db. Only the accounts the execution
+// created or changed are written, with the fields which changed: an account which was only
+// read, as by BALANCE or a call without value, is left as it is, and keeps not existing when
+// it did not. A new contract starts with only the slots of the result, whatever was stored at
+// its address before is dropped. Destructed accounts are only removed when they were created
+// by the same execution (EIP-6780), the others already gave their balance away.
+func Commit(db Writer, result *cache.ResultCache) {
+	for addr, acc := range result.CachedAccounts {
+		if acc == nil {
+			continue
+		}
+
+		created := result.NewContractAccounts[addr] != nil
+		original := result.OriginalAccounts.Get(addr)
+		if created || original == nil {
+			original = environment.NewAccount(addr, nil, nil)
+		}
+
+		if created {
+			db.ClearStorage(addr)
+		}
+
+		if created || !acc.Balance.EQ(original.Balance) {
+			db.SetBalance(addr, acc.Balance)
+		}
+
+		if code := contractCode(acc); created || !bytes.Equal(code, contractCode(original)) {
+			db.SetCode(addr, code)
+		}
+
+		for slot, val := range acc.Slots {
+			//slots only read hold the value they had
+			if prev := original.Slots[slot]; !created && prev != nil && prev.EQ(val) {
+				continue
+			}
+
+			db.SetState(addr, slot, val)
+		}
+	}
+
+	for addr, block := range result.DataBlockCache {
+		for slot, data := range block {
+			db.SetDataBlock(addr, slot, data)
+		}
+	}
+
+	for addr := range result.Destructs {
+		if result.NewContractAccounts[addr] != nil {
+			db.DeleteAccount(addr)
+		}
+	}
+}
+
+func contractCode(acc *environment.Account) []byte {
+	if acc.Contract == nil {
+		return nil
+	}
+
+	return acc.Contract.Code
+}
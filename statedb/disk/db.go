@@ -0,0 +1,744 @@
+// Package disk is a state database for SealEVM kept in a single append-only file, for
+// devnets and tools which need their state to survive restarts. Each block is written as
+// one checksummed batch, a batch cut by a crash is dropped when the file is opened again,
+// and Compact rewrites the file with only the live state.
+//
+// The index, from the keys to their place in the file, is kept in memory with the balances
+// and nonces, slot values, data blocks and code are read from the file.
+package disk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/SealSC/SealEVM/crypto/hashes"
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/statedb"
+	"github.com/SealSC/SealEVM/storage/cache"
+	"github.com/SealSC/SealEVM/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// blockHashWindow is the number of previous blocks BLOCKHASH can read.
+const blockHashWindow = 256
+
+var (
+	ErrCorrupted           = errors.New("corrupted state file")
+	ErrNonSequentialBlocks = errors.New("non sequential block")
+)
+
+// account is an account of the file.
+type account struct {
+	balance  *evmInt256.Int
+	nonce    uint64
+	codeHash types.Hash
+
+	slots  map[types.Slot]location
+	blocks map[types.Slot]location
+}
+
+// pendingAccount is an account changed since the last block, the storage of the file is
+// hidden when cleared.
+type pendingAccount struct {
+	exists   bool
+	cleared  bool
+	balance  *evmInt256.Int
+	nonce    uint64
+	codeHash types.Hash
+
+	slots  map[types.Slot]*evmInt256.Int
+	blocks types.DataBlock
+}
+
+// DB is a state database in a file. Changes are kept in memory until CommitBlock writes them
+// as a whole, or Rollback drops them. Nonces are kept beside the accounts, the addresses of
+// created contracts are derived from them by the rules of Ethereum. A zero code hash stands
+// for an account without code. CreateAddress and CreateFixedAddress are called during the
+// execution and change the nonces at once, take a Snapshot before an execution and revert to
+// it when the execution fails.
+//
+// Reads of the file which fail are reported by the methods returning an error, the others
+// return zero values. DB is not safe for concurrent use.
+type DB struct {
+	path string
+	file *os.File
+	end  int64
+
+	accounts    map[types.Address]*account
+	codes       map[types.Hash]location
+	blockHashes map[uint64]types.Hash
+	head        uint64
+	hasHead     bool
+
+	codeCache map[types.Hash][]byte
+
+	pending       map[types.Address]*pendingAccount
+	pendingCodes  map[types.Hash][]byte
+	pendingHashes map[uint64]types.Hash
+
+	journal   []journalEntry
+	snapshots []snapshot
+
+	currentBlock uint64
+}
+
+// Open opens the state file at path, creating it when missing. A batch at the end of the
+// file which is incomplete or fails its checksum was cut by a crash, it is dropped and the
+// file truncated to the last complete block. Damage before the last valid batch is not
+// repaired, Open returns ErrCorrupted and leaves the file as it is.
+func Open(path string) (*DB, error) {
+	//left by a compaction which did not finish, the file itself is intact
+	if err := os.Remove(compactPath(path)); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	db := &DB{
+		path:      path,
+		file:      file,
+		codeCache: map[types.Hash][]byte{},
+	}
+	db.Rollback()
+
+	if err = db.load(); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func compactPath(path string) string {
+	return path + ".compact"
+}
+
+// load builds the index from the file, writing the magic of a new one.
+func (db *DB) load() error {
+	info, err := db.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	size := info.Size()
+	if size == 0 {
+		if _, err = db.file.WriteAt([]byte(fileMagic), 0); err != nil {
+			return err
+		}
+
+		if err = db.file.Sync(); err != nil {
+			return err
+		}
+
+		size = int64(len(fileMagic))
+	}
+
+	magic := make([]byte, len(fileMagic))
+	if _, err = db.file.ReadAt(magic, 0); err != nil || string(magic) != fileMagic {
+		return fmt.Errorf("%w: %s is not a state file", ErrCorrupted, db.path)
+	}
+
+	db.accounts = map[types.Address]*account{}
+	db.codes = map[types.Hash]location{}
+	db.blockHashes = map[uint64]types.Hash{}
+	db.head, db.hasHead = 0, false
+
+	if db.end, err = replay(db.file, size, db.applyHead, db.applyEntry); err != nil {
+		return err
+	}
+
+	if db.end < size {
+		if err = db.file.Truncate(db.end); err != nil {
+			return err
+		}
+
+		if err = db.file.Sync(); err != nil {
+			return err
+		}
+	}
+
+	if db.hasHead {
+		db.currentBlock = db.head + 1
+	}
+
+	return nil
+}
+
+func (db *DB) applyHead(flags byte, number uint64, hash types.Hash) {
+	if flags&flagHead == 0 {
+		return
+	}
+
+	db.head, db.hasHead = number, true
+	db.blockHashes[number] = hash
+}
+
+func (db *DB) indexed(addr types.Address) *account {
+	acc := db.accounts[addr]
+	if acc == nil {
+		acc = &account{
+			balance: evmInt256.New(0),
+			slots:   map[types.Slot]location{},
+			blocks:  map[types.Slot]location{},
+		}
+		db.accounts[addr] = acc
+	}
+
+	return acc
+}
+
+func allZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (db *DB) applyEntry(kind byte, key []byte, value []byte, offset int64) {
+	var addr types.Address
+	var slot types.Slot
+	switch kind {
+	case kindDelete, kindAccount:
+		addr.SetBytes(key)
+	case kindSlot, kindDataBlock:
+		addr.SetBytes(key[:types.AddressBytesLen])
+		slot.SetBytes(key[types.AddressBytesLen:])
+	}
+
+	loc := location{offset: offset, size: uint32(len(value))}
+	switch kind {
+	case kindDelete:
+		delete(db.accounts, addr)
+	case kindAccount:
+		acc := db.indexed(addr)
+		acc.balance = evmInt256.FromBytes(value[:32])
+		acc.nonce = binary.BigEndian.Uint64(value[32:40])
+		acc.codeHash.SetBytes(value[40:accountValueLen])
+	case kindSlot:
+		if allZero(value) {
+			delete(db.indexed(addr).slots, slot)
+		} else {
+			db.indexed(addr).slots[slot] = loc
+		}
+	case kindDataBlock:
+		if len(value) == 0 {
+			delete(db.indexed(addr).blocks, slot)
+		} else {
+			db.indexed(addr).blocks[slot] = loc
+		}
+	case kindCode:
+		var hash types.Hash
+		hash.SetBytes(key)
+		db.codes[hash] = loc
+	case kindBlockHash:
+		var hash types.Hash
+		hash.SetBytes(value)
+		db.blockHashes[binary.BigEndian.Uint64(key)] = hash
+	}
+}
+
+func (db *DB) read(loc location) ([]byte, error) {
+	value := make([]byte, loc.size)
+	if _, err := db.file.ReadAt(value, loc.offset); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// Close closes the file, the changes not written by CommitBlock are lost.
+func (db *DB) Close() error {
+	return db.file.Close()
+}
+
+// Head is the number of the last block written, ok is false before the first one.
+func (db *DB) Head() (number uint64, ok bool) {
+	return db.head, db.hasHead
+}
+
+// CommitBlock writes the changes made since the previous block as the block number with
+// hash, in one batch synced to the disk. The block is either written as a whole or not at
+// all, the changes are kept when it fails. The first block may have any number, the next
+// ones follow it.
+func (db *DB) CommitBlock(number uint64, hash types.Hash) error {
+	if db.hasHead && number != db.head+1 {
+		return fmt.Errorf("%w: have %d, want %d", ErrNonSequentialBlocks, number, db.head+1)
+	}
+
+	b := newBatch(true, number, hash)
+
+	addrs := make([]types.Address, 0, len(db.pending))
+	for addr := range db.pending {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+
+	for codeHash, code := range db.pendingCodes {
+		if _, ok := db.codes[codeHash]; !ok {
+			b.code(codeHash, code)
+		}
+	}
+
+	for _, addr := range addrs {
+		p := db.pending[addr]
+		if !p.exists || p.cleared {
+			b.delete(addr)
+		}
+
+		if !p.exists {
+			continue
+		}
+
+		b.account(addr, p.balance, p.nonce, p.codeHash)
+		for slot, val := range p.slots {
+			b.slot(addr, slot, val)
+		}
+
+		for slot, data := range p.blocks {
+			b.dataBlock(addr, slot, data)
+		}
+	}
+
+	for n, h := range db.pendingHashes {
+		b.blockHash(n, h)
+	}
+
+	data := b.bytes()
+	if _, err := db.file.WriteAt(data, db.end); err != nil {
+		db.file.Truncate(db.end)
+		return err
+	}
+
+	if err := db.file.Sync(); err != nil {
+		db.file.Truncate(db.end)
+		return err
+	}
+
+	if err := decodePayload(b.payload, db.end+batchHeaderLen, db.applyHead, db.applyEntry); err != nil {
+		//the index is partly applied, it is built again from the file without the batch
+		if truncErr := db.file.Truncate(db.end); truncErr != nil {
+			return errors.Join(err, truncErr)
+		}
+
+		return errors.Join(err, db.file.Sync(), db.load())
+	}
+
+	db.end += int64(len(data))
+	db.currentBlock = number + 1
+	db.Rollback()
+	return nil
+}
+
+// Rollback drops the changes made since the previous block with the snapshots.
+func (db *DB) Rollback() {
+	db.pending = map[types.Address]*pendingAccount{}
+	db.pendingCodes = map[types.Hash][]byte{}
+	db.pendingHashes = map[uint64]types.Hash{}
+	db.ClearSnapshots()
+}
+
+// touch returns the pending account of addr for a change, which makes it exist.
+func (db *DB) touch(addr types.Address) *pendingAccount {
+	db.record(addr)
+
+	p := db.pending[addr]
+	if p == nil {
+		p = &pendingAccount{
+			balance: evmInt256.New(0),
+			slots:   map[types.Slot]*evmInt256.Int{},
+			blocks:  types.DataBlock{},
+		}
+
+		if acc := db.accounts[addr]; acc != nil {
+			p.balance = acc.balance.Clone()
+			p.nonce = acc.nonce
+			p.codeHash = acc.codeHash
+		}
+
+		db.pending[addr] = p
+	}
+
+	p.exists = true
+	return p
+}
+
+// header returns the fields of an account, pending or written.
+func (db *DB) header(addr types.Address) (exists bool, balance *evmInt256.Int, nonce uint64, codeHash types.Hash) {
+	if p := db.pending[addr]; p != nil {
+		return p.exists, p.balance, p.nonce, p.codeHash
+	}
+
+	if acc := db.accounts[addr]; acc != nil {
+		return true, acc.balance, acc.nonce, acc.codeHash
+	}
+
+	return false, evmInt256.New(0), 0, types.Hash{}
+}
+
+func (db *DB) code(codeHash types.Hash) ([]byte, error) {
+	if codeHash == (types.Hash{}) {
+		return nil, nil
+	}
+
+	if code := db.pendingCodes[codeHash]; code != nil {
+		return code, nil
+	}
+
+	if code := db.codeCache[codeHash]; code != nil {
+		return code, nil
+	}
+
+	loc, ok := db.codes[codeHash]
+	if !ok {
+		return nil, fmt.Errorf("%w: missing code %s", ErrCorrupted, codeHash)
+	}
+
+	code, err := db.read(loc)
+	if err != nil {
+		return nil, err
+	}
+
+	db.codeCache[codeHash] = code
+	return code, nil
+}
+
+func (db *DB) setCode(p *pendingAccount, code []byte) {
+	p.codeHash = types.Hash{}
+	if len(code) > 0 {
+		p.codeHash = db.HashOfCode(code)
+		if _, ok := db.codes[p.codeHash]; !ok {
+			db.pendingCodes[p.codeHash] = types.Bytes(code).Clone()
+		}
+	}
+}
+
+// clearStorage hides the slots and the data blocks of addr.
+func (db *DB) clearStorage(p *pendingAccount) {
+	p.cleared = true
+	p.slots = map[types.Slot]*evmInt256.Int{}
+	p.blocks = types.DataBlock{}
+}
+
+func (db *DB) SetCode(addr types.Address, code []byte) {
+	db.setCode(db.touch(addr), code)
+}
+
+func (db *DB) SetState(addr types.Address, slot types.Slot, val *evmInt256.Int) {
+	db.touch(addr).slots[slot] = val.Clone()
+}
+
+func (db *DB) SetDataBlock(addr types.Address, slot types.Slot, data types.Bytes) {
+	db.touch(addr).blocks[slot] = data.Clone()
+}
+
+func (db *DB) Nonce(addr types.Address) uint64 {
+	_, _, nonce, _ := db.header(addr)
+	return nonce
+}
+
+func (db *DB) SetNonce(addr types.Address, nonce uint64) {
+	db.touch(addr).nonce = nonce
+}
+
+func (db *DB) Balance(addr types.Address) *evmInt256.Int {
+	_, balance, _, _ := db.header(addr)
+	return balance.Clone()
+}
+
+func (db *DB) SetBalance(addr types.Address, balance *evmInt256.Int) {
+	db.touch(addr).balance = balance.Clone()
+}
+
+func (db *DB) AddBalance(addr types.Address, amount *evmInt256.Int) {
+	p := db.touch(addr)
+	p.balance = evmInt256.FromBigInt(p.balance.Int).Add(amount)
+}
+
+// SubBalance does not check the balance, the caller has to.
+func (db *DB) SubBalance(addr types.Address, amount *evmInt256.Int) {
+	p := db.touch(addr)
+	p.balance = evmInt256.FromBigInt(p.balance.Int).Sub(amount)
+}
+
+func (db *DB) Code(addr types.Address) []byte {
+	_, _, _, codeHash := db.header(addr)
+	code, _ := db.code(codeHash)
+	return code
+}
+
+// DeleteAccount removes an account with its nonce, its storage and its data blocks.
+func (db *DB) DeleteAccount(addr types.Address) {
+	p := db.touch(addr)
+	*p = pendingAccount{balance: evmInt256.New(0)}
+	db.clearStorage(p)
+}
+
+// SetBlockHash stores the hash BLOCKHASH returns for the block number, with the next block.
+// CommitBlock stores the hash of its block itself.
+func (db *DB) SetBlockHash(number uint64, hash types.Hash) {
+	db.pendingHashes[number] = hash
+}
+
+// SetCurrentBlock sets the number of the block in execution, BLOCKHASH only reads the 256
+// blocks before it. It is the block after the last one written by default.
+func (db *DB) SetCurrentBlock(number uint64) {
+	db.currentBlock = number
+}
+
+func (db *DB) GetBlockHash(block *evmInt256.Int) (*evmInt256.Int, error) {
+	if !block.IsUint64() {
+		return evmInt256.New(0), nil
+	}
+
+	number := block.Uint64()
+	if number >= db.currentBlock || number+blockHashWindow < db.currentBlock {
+		return evmInt256.New(0), nil
+	}
+
+	hash, ok := db.pendingHashes[number]
+	if !ok {
+		hash = db.blockHashes[number]
+	}
+
+	return evmInt256.FromBytes(hash[:]), nil
+}
+
+// GetAccount returns the account without its slots, they are read by Load. An account which
+// does not exist is returned empty.
+func (db *DB) GetAccount(address types.Address) (*environment.Account, error) {
+	exists, balance, _, codeHash := db.header(address)
+	if !exists {
+		return environment.NewAccount(address, nil, nil), nil
+	}
+
+	code, err := db.code(codeHash)
+	if err != nil {
+		return nil, err
+	}
+
+	var contract *environment.Contract
+	if len(code) > 0 {
+		contract = &environment.Contract{
+			Code:     code,
+			CodeHash: codeHash,
+			CodeSize: uint64(len(code)),
+		}
+	}
+
+	return environment.NewAccount(address, balance.Clone(), contract), nil
+}
+
+func (db *DB) AccountExist(address types.Address) bool {
+	exists, _, _, _ := db.header(address)
+	return exists
+}
+
+// AccountEmpty follows EIP-161: an account is empty when it has no nonce, no balance and no
+// code, and an account which does not exist is empty too.
+func (db *DB) AccountEmpty(address types.Address) bool {
+	exists, balance, nonce, codeHash := db.header(address)
+	return !exists || (nonce == 0 && balance.IsZero() && codeHash == types.Hash{})
+}
+
+func (db *DB) HashOfCode(code []byte) types.Hash {
+	var hash types.Hash
+	hash.SetBytes(hashes.Keccak256(code))
+	return hash
+}
+
+// created accounts start with nonce 1 (EIP-161).
+func (db *DB) created(addr types.Address) types.Address {
+	db.touch(addr).nonce = 1
+	return addr
+}
+
+func (db *DB) CreateAddress(caller types.Address, tx environment.Transaction) types.Address {
+	p := db.touch(caller)
+	nonce := p.nonce
+	p.nonce++
+	return db.created(types.Address(crypto.CreateAddress(common.Address(caller), nonce)))
+}
+
+func (db *DB) CreateFixedAddress(caller types.Address, salt types.Hash, code []byte, tx environment.Transaction) types.Address {
+	db.touch(caller).nonce++
+	return db.created(types.Address(crypto.CreateAddress2(common.Address(caller), salt, hashes.Keccak256(code))))
+}
+
+func (db *DB) Load(address types.Address, slot types.Slot) (*evmInt256.Int, error) {
+	if p := db.pending[address]; p != nil {
+		if val := p.slots[slot]; val != nil {
+			return val.Clone(), nil
+		}
+
+		if p.cleared {
+			return evmInt256.New(0), nil
+		}
+	}
+
+	acc := db.accounts[address]
+	if acc == nil {
+		return evmInt256.New(0), nil
+	}
+
+	loc, ok := acc.slots[slot]
+	if !ok {
+		return evmInt256.New(0), nil
+	}
+
+	value, err := db.read(loc)
+	if err != nil {
+		return nil, err
+	}
+
+	return evmInt256.FromBytes(value), nil
+}
+
+func (db *DB) GetDataBlock(address types.Address, slot types.Slot) (types.Bytes, error) {
+	if p := db.pending[address]; p != nil {
+		if data, ok := p.blocks[slot]; ok {
+			if len(data) == 0 {
+				return nil, nil
+			}
+			return data.Clone(), nil
+		}
+
+		if p.cleared {
+			return nil, nil
+		}
+	}
+
+	acc := db.accounts[address]
+	if acc == nil {
+		return nil, nil
+	}
+
+	loc, ok := acc.blocks[slot]
+	if !ok {
+		return nil, nil
+	}
+
+	return db.read(loc)
+}
+
+// ClearStorage hides the slots and the data blocks of addr.
+func (db *DB) ClearStorage(addr types.Address) {
+	db.clearStorage(db.touch(addr))
+}
+
+// Commit applies the result of a successful execution to the current block, see
+// statedb.Commit.
+func (db *DB) Commit(result *cache.ResultCache) {
+	statedb.Commit(db, result)
+}
+
+// Compact rewrites the file with the live state of the last block written, dropping the
+// values replaced since. The new file is written beside the old one and renamed over it,
+// a crash leaves one of them whole. The changes of the current block are kept.
+func (db *DB) Compact() error {
+	b := newBatch(db.hasHead, db.head, db.blockHashes[db.head])
+
+	addrs := make([]types.Address, 0, len(db.accounts))
+	for addr := range db.accounts {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+
+	written := map[types.Hash]bool{}
+	for _, addr := range addrs {
+		acc := db.accounts[addr]
+		if acc.codeHash != (types.Hash{}) && !written[acc.codeHash] {
+			code, err := db.code(acc.codeHash)
+			if err != nil {
+				return err
+			}
+
+			b.code(acc.codeHash, code)
+			written[acc.codeHash] = true
+		}
+
+		b.account(addr, acc.balance, acc.nonce, acc.codeHash)
+		for slot, loc := range acc.slots {
+			value, err := db.read(loc)
+			if err != nil {
+				return err
+			}
+
+			b.add(kindSlot, slotKey(addr, slot), value)
+		}
+
+		for slot, loc := range acc.blocks {
+			data, err := db.read(loc)
+			if err != nil {
+				return err
+			}
+
+			b.dataBlock(addr, slot, data)
+		}
+	}
+
+	for number, hash := range db.blockHashes {
+		b.blockHash(number, hash)
+	}
+
+	if err := writeFile(compactPath(db.path), append([]byte(fileMagic), b.bytes()...)); err != nil {
+		return err
+	}
+
+	if err := os.Rename(compactPath(db.path), db.path); err != nil {
+		return err
+	}
+
+	if err := syncDir(filepath.Dir(db.path)); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(db.path, os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+
+	db.file.Close()
+	db.file = file
+	return db.load()
+}
+
+func writeFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// syncDir makes a rename in dir durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
@@ -0,0 +1,244 @@
+package disk_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/statedb/disk"
+	"github.com/SealSC/SealEVM/storage/cache"
+	"github.com/SealSC/SealEVM/types"
+)
+
+var (
+	alice = types.Address{0xa1}
+	bob   = types.Address{0xb0}
+)
+
+// writeBlocks writes the blocks 1 to n to a new file, block i gives alice a balance of i, and
+// returns the path with the size of the file after each block.
+func writeBlocks(t *testing.T, n int) (string, []int64) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "state.db")
+	db, err := disk.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var sizes []int64
+	for i := 1; i <= n; i++ {
+		db.SetBalance(alice, evmInt256.New(uint64(i)))
+		if err = db.CommitBlock(uint64(i), types.Hash{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sizes = append(sizes, info.Size())
+	}
+
+	return path, sizes
+}
+
+func checkHead(t *testing.T, db *disk.DB, want uint64) {
+	t.Helper()
+
+	if head, ok := db.Head(); !ok || head != want {
+		t.Fatalf("head is %d (%v), want %d", head, ok, want)
+	}
+
+	if balance := db.Balance(alice); balance.Uint64() != want {
+		t.Fatalf("balance is %s, want %d", balance.Text(10), want)
+	}
+}
+
+func TestOpenDropsTornTail(t *testing.T) {
+	for name, tear := range map[string]func(data []byte, last int64) []byte{
+		//the batch of block 3 cut in the middle
+		"short": func(data []byte, last int64) []byte {
+			return data[:last+(int64(len(data))-last)/2]
+		},
+		//the batch of block 3 whole in size but not in content
+		"checksum": func(data []byte, last int64) []byte {
+			data[len(data)-1] ^= 0xff
+			return data
+		},
+		//the header of the batch of block 3 lost
+		"header": func(data []byte, last int64) []byte {
+			copy(data[last:], make([]byte, 8))
+			return data
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			path, sizes := writeBlocks(t, 3)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err = os.WriteFile(path, tear(data, sizes[1]), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			db, err := disk.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			checkHead(t, db, 2)
+			if info, _ := os.Stat(path); info.Size() != sizes[1] {
+				t.Fatalf("file is %d bytes, want %d, the end of block 2", info.Size(), sizes[1])
+			}
+
+			//the chain goes on from the last complete block
+			db.SetBalance(alice, evmInt256.New(3))
+			if err = db.CommitBlock(3, types.Hash{3}); err != nil {
+				t.Fatal(err)
+			}
+
+			db.Close()
+			if db, err = disk.Open(path); err != nil {
+				t.Fatal(err)
+			}
+			checkHead(t, db, 3)
+		})
+	}
+}
+
+func TestOpenReportsCorruptionBeforeValidBatches(t *testing.T) {
+	for name, offset := range map[string]func(sizes []int64) int64{
+		"payload": func(sizes []int64) int64 { return sizes[1] - 1 },
+		"length":  func(sizes []int64) int64 { return sizes[0] + 1 },
+	} {
+		t.Run(name, func(t *testing.T) {
+			path, sizes := writeBlocks(t, 3)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			//damage the batch of block 2, block 3 after it is intact
+			data[offset(sizes)] ^= 0xff
+			if err = os.WriteFile(path, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err = disk.Open(path); !errors.Is(err, disk.ErrCorrupted) {
+				t.Fatalf("got %v, want %v", err, disk.ErrCorrupted)
+			}
+
+			after, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(after, data) {
+				t.Fatalf("the corrupted file was modified, %d bytes before and %d after", len(data), len(after))
+			}
+		})
+	}
+}
+
+func TestCommitSkipsAccountsOnlyRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+	db, err := disk.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	//bob was read by BALANCE, alice received a value
+	result := cache.NewResultCache()
+	result.CacheAccount(environment.NewAccount(bob, nil, nil))
+	result.CacheAccount(environment.NewAccount(alice, nil, nil)).Balance = evmInt256.New(1)
+
+	db.Commit(&result)
+	if err = db.CommitBlock(1, types.Hash{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if db.AccountExist(bob) {
+		t.Fatalf("an account only read was written")
+	}
+
+	if !db.AccountExist(alice) || db.Balance(alice).Uint64() != 1 {
+		t.Fatalf("the balance of alice was not written")
+	}
+}
+
+func TestCommitBlockNonSequential(t *testing.T) {
+	path, _ := writeBlocks(t, 2)
+	db, err := disk.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	db.SetBalance(alice, evmInt256.New(4))
+	for _, number := range []uint64{2, 4} {
+		if err = db.CommitBlock(number, types.Hash{byte(number)}); !errors.Is(err, disk.ErrNonSequentialBlocks) {
+			t.Fatalf("block %d after block 2 got %v, want %v", number, err, disk.ErrNonSequentialBlocks)
+		}
+	}
+
+	//the changes are kept for the next block
+	if err = db.CommitBlock(3, types.Hash{3}); err != nil {
+		t.Fatal(err)
+	}
+
+	if head, _ := db.Head(); head != 3 || db.Balance(alice).Uint64() != 4 {
+		t.Fatalf("head is %d with a balance of %d, want 3 and 4", head, db.Balance(alice).Uint64())
+	}
+}
+
+func TestSnapshotRevertsPendingChanges(t *testing.T) {
+	path, _ := writeBlocks(t, 1)
+	db, err := disk.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	db.SetNonce(bob, 3)
+	outer := db.Snapshot()
+	db.SetBalance(alice, evmInt256.New(9))
+	created := db.CreateAddress(bob, environment.Transaction{})
+
+	inner := db.Snapshot()
+	db.SetState(alice, types.Slot{31: 1}, evmInt256.New(7))
+	db.DeleteAccount(created)
+
+	db.RevertToSnapshot(inner)
+	if val, _ := db.Load(alice, types.Slot{31: 1}); !val.IsZero() || !db.AccountExist(created) {
+		t.Fatalf("the inner snapshot kept slot 1 = %s, the created account exists: %v", val.Text(10), db.AccountExist(created))
+	}
+
+	db.RevertToSnapshot(outer)
+	if db.Balance(alice).Uint64() != 1 || db.Nonce(bob) != 3 || db.AccountExist(created) {
+		t.Fatalf("balance %s, nonce %d, created account %v after the revert, want 1, 3 and none",
+			db.Balance(alice).Text(10), db.Nonce(bob), db.AccountExist(created))
+	}
+
+	if err = db.CommitBlock(2, types.Hash{2}); err != nil {
+		t.Fatal(err)
+	}
+
+	db.Close()
+	if db, err = disk.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if db.Balance(alice).Uint64() != 1 || db.Nonce(bob) != 3 || db.AccountExist(created) {
+		t.Fatalf("the reverted changes were written")
+	}
+}
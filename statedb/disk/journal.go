@@ -0,0 +1,91 @@
+package disk
+
+import (
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/types"
+)
+
+// journalEntry is the pending account before its first change under a snapshot, nil when
+// it had none.
+type journalEntry struct {
+	addr types.Address
+	prev *pendingAccount
+}
+
+// snapshot is the length of the journal when it was taken, with the accounts journaled
+// since.
+type snapshot struct {
+	mark      int
+	journaled map[types.Address]bool
+}
+
+func (p *pendingAccount) clone() *pendingAccount {
+	c := *p
+	c.slots = make(map[types.Slot]*evmInt256.Int, len(p.slots))
+	for slot, val := range p.slots {
+		c.slots[slot] = val
+	}
+
+	c.blocks = make(types.DataBlock, len(p.blocks))
+	for slot, data := range p.blocks {
+		c.blocks[slot] = data
+	}
+
+	return &c
+}
+
+// record keeps the pending account of addr before a change, once for each snapshot. Values
+// are replaced and never changed in place, the copy only shares them.
+func (db *DB) record(addr types.Address) {
+	if len(db.snapshots) == 0 {
+		return
+	}
+
+	top := &db.snapshots[len(db.snapshots)-1]
+	if top.journaled[addr] {
+		return
+	}
+
+	var prev *pendingAccount
+	if p := db.pending[addr]; p != nil {
+		prev = p.clone()
+	}
+
+	db.journal = append(db.journal, journalEntry{addr: addr, prev: prev})
+	top.journaled[addr] = true
+}
+
+// Snapshot returns the id of the state of the current block, for RevertToSnapshot. Snapshots
+// nest, they are dropped by CommitBlock, Rollback and ClearSnapshots. The codes set since a
+// snapshot are kept by a revert, they are only reached through the accounts.
+func (db *DB) Snapshot() int {
+	db.snapshots = append(db.snapshots, snapshot{mark: len(db.journal), journaled: map[types.Address]bool{}})
+	return len(db.snapshots) - 1
+}
+
+// RevertToSnapshot undoes the changes made since the snapshot id was taken, it and the
+// snapshots taken after it are dropped. An unknown id is ignored.
+func (db *DB) RevertToSnapshot(id int) {
+	if id < 0 || id >= len(db.snapshots) {
+		return
+	}
+
+	mark := db.snapshots[id].mark
+	for i := len(db.journal) - 1; i >= mark; i-- {
+		e := db.journal[i]
+		if e.prev == nil {
+			delete(db.pending, e.addr)
+		} else {
+			db.pending[e.addr] = e.prev
+		}
+	}
+
+	db.journal = db.journal[:mark]
+	db.snapshots = db.snapshots[:id]
+}
+
+// ClearSnapshots keeps the current state and drops every snapshot with the journal.
+func (db *DB) ClearSnapshots() {
+	db.journal = nil
+	db.snapshots = nil
+}
@@ -0,0 +1,276 @@
+package disk
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/types"
+)
+
+// The log starts with fileMagic and is followed by batches:
+//
+//	[payload length u32][crc32c of payload u32][payload]
+//
+// A payload starts with a header, [flags u8][block number u64][block hash 32], and holds
+// entries, [kind u8][key][value length u32][value], applied in order. Integers are big
+// endian. A batch is valid only when complete with a matching checksum, the log ends at the
+// first batch which is not when it is the torn tail of the file.
+const (
+	fileMagic = "SEALSDB1"
+
+	batchHeaderLen   = 8
+	payloadHeaderLen = 1 + 8 + types.HashBytesLen
+
+	flagHead = 1
+)
+
+// entry kinds
+const (
+	//removes an account with its storage and data blocks, no value
+	kindDelete byte = iota + 1
+	//the balance, nonce and code hash of an account, creating it when missing
+	kindAccount
+	kindSlot
+	kindDataBlock
+	kindCode
+	kindBlockHash
+)
+
+const accountValueLen = 32 + 8 + types.HashBytesLen
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+func keyLen(kind byte) int {
+	switch kind {
+	case kindDelete, kindAccount:
+		return types.AddressBytesLen
+	case kindSlot, kindDataBlock:
+		return types.AddressBytesLen + types.HashBytesLen
+	case kindCode:
+		return types.HashBytesLen
+	case kindBlockHash:
+		return 8
+	}
+
+	return -1
+}
+
+// location is where a value is in the log.
+type location struct {
+	offset int64
+	size   uint32
+}
+
+// batch builds the payload of a batch.
+type batch struct {
+	payload []byte
+}
+
+func newBatch(head bool, number uint64, hash types.Hash) *batch {
+	var flags byte
+	if head {
+		flags |= flagHead
+	}
+
+	b := &batch{payload: []byte{flags}}
+	b.payload = binary.BigEndian.AppendUint64(b.payload, number)
+	b.payload = append(b.payload, hash[:]...)
+	return b
+}
+
+func (b *batch) add(kind byte, key []byte, value []byte) {
+	b.payload = append(b.payload, kind)
+	b.payload = append(b.payload, key...)
+	b.payload = binary.BigEndian.AppendUint32(b.payload, uint32(len(value)))
+	b.payload = append(b.payload, value...)
+}
+
+func slotKey(addr types.Address, slot types.Slot) []byte {
+	return append(addr[:len(addr):len(addr)], slot[:]...)
+}
+
+func (b *batch) delete(addr types.Address) {
+	b.add(kindDelete, addr[:], nil)
+}
+
+func (b *batch) account(addr types.Address, balance *evmInt256.Int, nonce uint64, codeHash types.Hash) {
+	word := types.Int256ToHash(balance)
+	value := make([]byte, 0, accountValueLen)
+	value = append(value, word[:]...)
+	value = binary.BigEndian.AppendUint64(value, nonce)
+	value = append(value, codeHash[:]...)
+	b.add(kindAccount, addr[:], value)
+}
+
+func (b *batch) slot(addr types.Address, slot types.Slot, val *evmInt256.Int) {
+	word := types.Int256ToHash(val)
+	b.add(kindSlot, slotKey(addr, slot), word[:])
+}
+
+func (b *batch) dataBlock(addr types.Address, slot types.Slot, data types.Bytes) {
+	b.add(kindDataBlock, slotKey(addr, slot), data)
+}
+
+func (b *batch) code(hash types.Hash, code []byte) {
+	b.add(kindCode, hash[:], code)
+}
+
+func (b *batch) blockHash(number uint64, hash types.Hash) {
+	b.add(kindBlockHash, binary.BigEndian.AppendUint64(nil, number), hash[:])
+}
+
+// bytes is the batch as written to the log.
+func (b *batch) bytes() []byte {
+	buf := make([]byte, batchHeaderLen, batchHeaderLen+len(b.payload))
+	binary.BigEndian.PutUint32(buf, uint32(len(b.payload)))
+	binary.BigEndian.PutUint32(buf[4:], crc32.Checksum(b.payload, crcTable))
+	return append(buf, b.payload...)
+}
+
+// entryVisitor is given the entries of a batch in order, offset is the one of the value in
+// the log.
+type entryVisitor func(kind byte, key []byte, value []byte, offset int64)
+
+// decodePayload checks payload and passes its header and entries to visit, base is the
+// offset of the payload in the log.
+func decodePayload(payload []byte, base int64, head func(flags byte, number uint64, hash types.Hash), visit entryVisitor) error {
+	if len(payload) < payloadHeaderLen {
+		return fmt.Errorf("%w: short batch header", ErrCorrupted)
+	}
+
+	var hash types.Hash
+	hash.SetBytes(payload[9:payloadHeaderLen])
+
+	//entries are checked before any of them is visited
+	pos := payloadHeaderLen
+	for pos < len(payload) {
+		n := keyLen(payload[pos])
+		if n < 0 {
+			return fmt.Errorf("%w: unknown entry kind %d", ErrCorrupted, payload[pos])
+		}
+
+		pos += 1 + n
+		if pos+4 > len(payload) {
+			return fmt.Errorf("%w: truncated entry", ErrCorrupted)
+		}
+
+		pos += 4 + int(binary.BigEndian.Uint32(payload[pos:]))
+		if pos > len(payload) {
+			return fmt.Errorf("%w: truncated entry", ErrCorrupted)
+		}
+	}
+
+	head(payload[0], binary.BigEndian.Uint64(payload[1:9]), hash)
+
+	pos = payloadHeaderLen
+	for pos < len(payload) {
+		kind := payload[pos]
+		n := keyLen(kind)
+		key := payload[pos+1 : pos+1+n]
+		pos += 1 + n
+
+		size := int(binary.BigEndian.Uint32(payload[pos:]))
+		pos += 4
+
+		visit(kind, key, payload[pos:pos+size], base+int64(pos))
+		pos += size
+	}
+
+	return nil
+}
+
+// readBatch returns the payload of the batch at offset, nil when the batch does not fit in
+// the file, is too short for its header or fails its checksum.
+func readBatch(f *os.File, offset int64, size int64) ([]byte, error) {
+	if offset+batchHeaderLen > size {
+		return nil, nil
+	}
+
+	header := make([]byte, batchHeaderLen)
+	if _, err := f.ReadAt(header, offset); err != nil {
+		return nil, err
+	}
+
+	length := int64(binary.BigEndian.Uint32(header))
+	if length < payloadHeaderLen || offset+batchHeaderLen+length > size {
+		return nil, nil
+	}
+
+	payload := make([]byte, length)
+	if _, err := f.ReadAt(payload, offset+batchHeaderLen); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if crc32.Checksum(payload, crcTable) != binary.BigEndian.Uint32(header[4:]) {
+		return nil, nil
+	}
+
+	return payload, nil
+}
+
+// validBatchAfter is the offset of the first valid batch which starts after from, -1 when
+// there is none. The batches are searched at every offset, the length of the invalid batch
+// before them cannot be trusted.
+func validBatchAfter(f *os.File, from int64, size int64) (int64, error) {
+	if from >= size {
+		return -1, nil
+	}
+
+	data := make([]byte, size-from)
+	if _, err := f.ReadAt(data, from); err != nil && err != io.EOF {
+		return 0, err
+	}
+
+	for pos := 0; pos+batchHeaderLen+payloadHeaderLen <= len(data); pos++ {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		if length < payloadHeaderLen || length > len(data)-pos-batchHeaderLen {
+			continue
+		}
+
+		payload := data[pos+batchHeaderLen : pos+batchHeaderLen+length]
+		if crc32.Checksum(payload, crcTable) == binary.BigEndian.Uint32(data[pos+4:]) {
+			return from + int64(pos), nil
+		}
+	}
+
+	return -1, nil
+}
+
+// replay reads the batches of f from the end of the magic, and returns the end of the last
+// valid one. An invalid batch with no valid one after it is the torn tail of a write cut by
+// a crash, it ends the log. An invalid batch followed by a valid one is a damage of the file,
+// which is reported as ErrCorrupted, as is a valid batch whose entries do not decode.
+func replay(f *os.File, size int64, head func(flags byte, number uint64, hash types.Hash), visit entryVisitor) (int64, error) {
+	offset := int64(len(fileMagic))
+	for offset < size {
+		payload, err := readBatch(f, offset, size)
+		if err != nil {
+			return 0, err
+		}
+
+		if payload == nil {
+			valid, err := validBatchAfter(f, offset+1, size)
+			if err != nil {
+				return 0, err
+			}
+
+			if valid >= 0 {
+				return 0, fmt.Errorf("%w: invalid batch at offset %d, followed by a valid one at %d", ErrCorrupted, offset, valid)
+			}
+
+			break
+		}
+
+		if err = decodePayload(payload, offset+batchHeaderLen, head, visit); err != nil {
+			return 0, fmt.Errorf("%w at offset %d", err, offset)
+		}
+
+		offset += batchHeaderLen + int64(len(payload))
+	}
+
+	return offset, nil
+}
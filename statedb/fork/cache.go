@@ -0,0 +1,242 @@
+package fork
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/types"
+)
+
+// The cache file starts with a header, [cacheMagic][chain id u64][block number u64], and is
+// followed by records:
+//
+//	[kind u8][key][value length u32][value][crc32c of the previous fields u32]
+//
+// Integers are big endian. Only what the remote node returned is cached, so a record is never
+// replaced, and the file ends at the first record which is incomplete or fails its checksum.
+const (
+	cacheMagic     = "SEALFRK1"
+	cacheHeaderLen = len(cacheMagic) + 8 + 8
+)
+
+// record kinds
+const (
+	//the balance, nonce and code hash of an account
+	kindAccount byte = iota + 1
+	kindCode
+	kindSlot
+	kindBlockHash
+)
+
+const accountValueLen = 32 + 8 + types.HashBytesLen
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+func keyLen(kind byte) int {
+	switch kind {
+	case kindAccount:
+		return types.AddressBytesLen
+	case kindCode:
+		return types.HashBytesLen
+	case kindSlot:
+		return types.AddressBytesLen + types.HashBytesLen
+	case kindBlockHash:
+		return 8
+	}
+
+	return -1
+}
+
+// cache is the file of the data fetched from a chain at a block.
+type cache struct {
+	file *os.File
+}
+
+func cacheHeader(chainID uint64, block uint64) []byte {
+	header := make([]byte, 0, cacheHeaderLen)
+	header = append(header, cacheMagic...)
+	header = binary.BigEndian.AppendUint64(header, chainID)
+	return binary.BigEndian.AppendUint64(header, block)
+}
+
+// openCache opens the cache at path for the chain and the block, creating it when missing, and
+// passes its records to visit. A torn tail, as left by a crash, is cut.
+func openCache(path string, chainID uint64, block uint64, visit func(kind byte, key []byte, value []byte)) (*cache, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &cache{file: file}
+	if err = c.load(chainID, block, visit); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *cache) load(chainID uint64, block uint64, visit func(kind byte, key []byte, value []byte)) error {
+	data, err := io.ReadAll(c.file)
+	if err != nil {
+		return err
+	}
+
+	header := cacheHeader(chainID, block)
+	if len(data) < cacheHeaderLen {
+		if !bytes.HasPrefix(header, data) {
+			return fmt.Errorf("%w: not a fork cache of chain %d at block %d", ErrCacheMismatch, chainID, block)
+		}
+
+		//new, or cut while writing the header
+		if err = c.file.Truncate(0); err != nil {
+			return err
+		}
+
+		_, err = c.file.WriteAt(header, 0)
+		if err == nil {
+			_, err = c.file.Seek(int64(cacheHeaderLen), io.SeekStart)
+		}
+		return err
+	}
+
+	if string(data[:len(cacheMagic)]) != cacheMagic {
+		return fmt.Errorf("%w: not a fork cache", ErrCacheMismatch)
+	}
+
+	if string(data[:cacheHeaderLen]) != string(header) {
+		return fmt.Errorf("%w: cache of chain %d at block %d", ErrCacheMismatch,
+			binary.BigEndian.Uint64(data[len(cacheMagic):]), binary.BigEndian.Uint64(data[len(cacheMagic)+8:]))
+	}
+
+	end := cacheHeaderLen
+	for end < len(data) {
+		n := parseRecord(data[end:], visit)
+		if n == 0 {
+			break
+		}
+		end += n
+	}
+
+	if end < len(data) {
+		if err = c.file.Truncate(int64(end)); err != nil {
+			return err
+		}
+	}
+
+	_, err = c.file.Seek(int64(end), io.SeekStart)
+	return err
+}
+
+// parseRecord visits the record at the start of data and returns its length, 0 when it is not
+// a complete record.
+func parseRecord(data []byte, visit func(kind byte, key []byte, value []byte)) int {
+	if len(data) < 1 {
+		return 0
+	}
+
+	n := keyLen(data[0])
+	if n < 0 || len(data) < 1+n+4 {
+		return 0
+	}
+
+	valueLen := int(binary.BigEndian.Uint32(data[1+n:]))
+	size := 1 + n + 4 + valueLen + 4
+	if valueLen > len(data) || len(data) < size {
+		return 0
+	}
+
+	if crc32.Checksum(data[:size-4], crcTable) != binary.BigEndian.Uint32(data[size-4:]) {
+		return 0
+	}
+
+	visit(data[0], data[1:1+n], data[1+n+4:size-4])
+	return size
+}
+
+func (c *cache) put(kind byte, key []byte, value []byte) error {
+	record := make([]byte, 0, 1+len(key)+4+len(value)+4)
+	record = append(record, kind)
+	record = append(record, key...)
+	record = binary.BigEndian.AppendUint32(record, uint32(len(value)))
+	record = append(record, value...)
+	record = binary.BigEndian.AppendUint32(record, crc32.Checksum(record, crcTable))
+
+	_, err := c.file.Write(record)
+	return err
+}
+
+func (c *cache) close() error {
+	if err := c.file.Sync(); err != nil {
+		c.file.Close()
+		return err
+	}
+
+	return c.file.Close()
+}
+
+func accountValue(acc *account) []byte {
+	balance := types.Int256ToHash(acc.balance)
+	value := make([]byte, 0, accountValueLen)
+	value = append(value, balance[:]...)
+	value = binary.BigEndian.AppendUint64(value, acc.nonce)
+	return append(value, acc.codeHash[:]...)
+}
+
+func slotKey(addr types.Address, slot types.Slot) []byte {
+	return append(addr[:len(addr):len(addr)], slot[:]...)
+}
+
+func numberKey(number uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, number)
+}
+
+// apply adds a record of the cache to the data of s, records with a value of the wrong length
+// are ignored.
+func (s *Source) apply(kind byte, key []byte, value []byte) {
+	switch kind {
+	case kindAccount:
+		if len(value) != accountValueLen {
+			return
+		}
+
+		var addr types.Address
+		addr.SetBytes(key)
+		acc := &account{
+			balance: evmInt256.FromBytes(value[:32]),
+			nonce:   binary.BigEndian.Uint64(value[32:]),
+		}
+		acc.codeHash.SetBytes(value[40:])
+		s.accounts[addr] = acc
+
+	case kindCode:
+		var hash types.Hash
+		hash.SetBytes(key)
+		s.codes[hash] = types.Bytes(value).Clone()
+
+	case kindSlot:
+		if len(value) != 32 {
+			return
+		}
+
+		var addr types.Address
+		var slot types.Slot
+		addr.SetBytes(key[:types.AddressBytesLen])
+		slot.SetBytes(key[types.AddressBytesLen:])
+		s.slots(addr)[slot] = evmInt256.FromBytes(value)
+
+	case kindBlockHash:
+		if len(value) != types.HashBytesLen {
+			return
+		}
+
+		var hash types.Hash
+		hash.SetBytes(value)
+		s.blockHashes[binary.BigEndian.Uint64(key)] = hash
+	}
+}
@@ -0,0 +1,159 @@
+package fork
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/types"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// RPCError is an error returned by the remote node.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("json-rpc error %d: %s", e.Code, e.Message)
+}
+
+type request struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type response struct {
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+// Client calls the methods of an Ethereum JSON-RPC endpoint over HTTP.
+type Client struct {
+	url    string
+	http   *http.Client
+	nextID atomic.Uint64
+}
+
+func NewClient(url string) *Client {
+	return &Client{
+		url:  url,
+		http: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Call calls method with params and decodes its result into result, a null result leaves
+// result unchanged.
+func (c *Client) Call(result interface{}, method string, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+
+	body, err := json.Marshal(&request{JSONRPC: "2.0", ID: c.nextID.Add(1), Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.http.Post(c.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: http status %s", method, resp.Status)
+	}
+
+	var r response
+	if err = json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	if r.Error != nil {
+		return fmt.Errorf("%s: %w", method, r.Error)
+	}
+
+	if len(r.Result) == 0 || string(r.Result) == "null" {
+		return nil
+	}
+
+	if err = json.Unmarshal(r.Result, result); err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	return nil
+}
+
+func blockParam(number uint64) string {
+	return hexutil.EncodeUint64(number)
+}
+
+func (c *Client) ChainID() (uint64, error) {
+	var id hexutil.Uint64
+	err := c.Call(&id, "eth_chainId")
+	return uint64(id), err
+}
+
+func (c *Client) BlockNumber() (uint64, error) {
+	var number hexutil.Uint64
+	err := c.Call(&number, "eth_blockNumber")
+	return uint64(number), err
+}
+
+func (c *Client) Balance(addr types.Address, block uint64) (*evmInt256.Int, error) {
+	var balance hexutil.Big
+	if err := c.Call(&balance, "eth_getBalance", addr, blockParam(block)); err != nil {
+		return nil, err
+	}
+
+	return evmInt256.FromBigInt(balance.ToInt()), nil
+}
+
+func (c *Client) Nonce(addr types.Address, block uint64) (uint64, error) {
+	var nonce hexutil.Uint64
+	err := c.Call(&nonce, "eth_getTransactionCount", addr, blockParam(block))
+	return uint64(nonce), err
+}
+
+func (c *Client) Code(addr types.Address, block uint64) ([]byte, error) {
+	var code hexutil.Bytes
+	err := c.Call(&code, "eth_getCode", addr, blockParam(block))
+	return code, err
+}
+
+func (c *Client) StorageAt(addr types.Address, slot types.Slot, block uint64) (*evmInt256.Int, error) {
+	var val hexutil.Bytes
+	if err := c.Call(&val, "eth_getStorageAt", addr, slot, blockParam(block)); err != nil {
+		return nil, err
+	}
+
+	if len(val) > types.HashBytesLen {
+		return nil, fmt.Errorf("eth_getStorageAt: value of %d bytes", len(val))
+	}
+
+	return evmInt256.FromBytes(val), nil
+}
+
+// BlockHash is the hash of the block number, ErrUnknownBlock when the node has no such block.
+func (c *Client) BlockHash(number uint64) (types.Hash, error) {
+	var block *struct {
+		Hash types.Hash `json:"hash"`
+	}
+
+	if err := c.Call(&block, "eth_getBlockByNumber", blockParam(number), false); err != nil {
+		return types.Hash{}, err
+	}
+
+	if block == nil {
+		return types.Hash{}, fmt.Errorf("%w: %d", ErrUnknownBlock, number)
+	}
+
+	return block.Hash, nil
+}
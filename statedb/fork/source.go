@@ -0,0 +1,362 @@
+// Package fork is a state for SealEVM forked from a remote chain, as Anvil and Hardhat fork
+// one. Accounts, code and slots are fetched from an Ethereum JSON-RPC endpoint at a pinned
+// block when first read, and kept in a cache file so that the next runs read them locally.
+// The remote state is only read, executions write to an overlay above it.
+package fork
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/SealSC/SealEVM/crypto/hashes"
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/override"
+	"github.com/SealSC/SealEVM/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	ErrUnknownBlock  = errors.New("unknown block")
+	ErrCacheMismatch = errors.New("cache mismatch")
+)
+
+// blockHashWindow is the number of previous blocks BLOCKHASH can read.
+const blockHashWindow = 256
+
+type account struct {
+	balance  *evmInt256.Int
+	nonce    uint64
+	codeHash types.Hash
+}
+
+// exists tells whether the account exists, JSON-RPC does not tell an empty account from a
+// missing one, and there are no empty accounts since EIP-161.
+func (acc *account) exists() bool {
+	return acc.nonce != 0 || !acc.balance.IsZero() || acc.codeHash != types.Hash{}
+}
+
+// Source is the state of a remote chain at a block, an IExternalStorage which is only read.
+// The addresses of created contracts are derived without changing the nonces, executions run
+// over the layer of Overlay, which reads the nonces of the source. Source is safe for
+// concurrent use.
+//
+// The methods of IExternalStorage returning no error read a failed fetch as a missing
+// account, Err returns the first of these failures. The layers of Overlay return it from
+// their Err too, the executions of package sim on them fail then, and the source has to be
+// dropped.
+type Source struct {
+	lock   sync.Mutex
+	client *Client
+	block  uint64
+	cache  *cache
+
+	accounts    map[types.Address]*account
+	codes       map[types.Hash][]byte
+	storage     map[types.Address]map[types.Slot]*evmInt256.Int
+	blockHashes map[uint64]types.Hash
+
+	//the hashes of the blocks built on top of the fork
+	localHashes  map[uint64]types.Hash
+	currentBlock uint64
+
+	err error
+}
+
+// New forks the chain of client at block. The data fetched is kept in the cache file at
+// cachePath, which only serves the same chain at the same block, an empty cachePath keeps it
+// in memory.
+func New(client *Client, block uint64, cachePath string) (*Source, error) {
+	s := &Source{
+		client:       client,
+		block:        block,
+		accounts:     map[types.Address]*account{},
+		codes:        map[types.Hash][]byte{},
+		storage:      map[types.Address]map[types.Slot]*evmInt256.Int{},
+		blockHashes:  map[uint64]types.Hash{},
+		localHashes:  map[uint64]types.Hash{},
+		currentBlock: block + 1,
+	}
+
+	if cachePath == "" {
+		return s, nil
+	}
+
+	chainID, err := client.ChainID()
+	if err != nil {
+		return nil, err
+	}
+
+	if s.cache, err = openCache(cachePath, chainID, block, s.apply); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Block is the number of the block the chain is forked at.
+func (s *Source) Block() uint64 {
+	return s.block
+}
+
+// Err is the first fetch which failed in a method returning no error.
+func (s *Source) Err() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.err
+}
+
+// Overlay is a new layer over s, holding the changes of the executions. Layers over the same
+// source share its fetches.
+func (s *Source) Overlay() *override.Storage {
+	//no overrides, nothing to validate
+	layer, _ := override.NewStorage(s, nil)
+	return layer
+}
+
+// Close closes the cache file.
+func (s *Source) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.cache == nil {
+		return nil
+	}
+
+	err := s.cache.close()
+	s.cache = nil
+	return err
+}
+
+// fail records err for Err, the lock must be held.
+func (s *Source) fail(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// put writes a record to the cache when there is one, the lock must be held.
+func (s *Source) put(kind byte, key []byte, value []byte) error {
+	if s.cache == nil {
+		return nil
+	}
+
+	if err := s.cache.put(kind, key, value); err != nil {
+		return fmt.Errorf("fork cache: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Source) slots(addr types.Address) map[types.Slot]*evmInt256.Int {
+	slots := s.storage[addr]
+	if slots == nil {
+		slots = map[types.Slot]*evmInt256.Int{}
+		s.storage[addr] = slots
+	}
+
+	return slots
+}
+
+// account returns the account at addr, fetching it when missing, the lock must be held.
+func (s *Source) account(addr types.Address) (*account, error) {
+	if acc := s.accounts[addr]; acc != nil {
+		return acc, nil
+	}
+
+	balance, err := s.client.Balance(addr, s.block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := s.client.Nonce(addr, s.block)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := s.client.Code(addr, s.block)
+	if err != nil {
+		return nil, err
+	}
+
+	acc := &account{balance: balance, nonce: nonce}
+	if len(code) > 0 {
+		acc.codeHash = s.HashOfCode(code)
+		if s.codes[acc.codeHash] == nil {
+			//the code goes first, a cached account always finds its code
+			if err = s.put(kindCode, acc.codeHash[:], code); err != nil {
+				return nil, err
+			}
+			s.codes[acc.codeHash] = code
+		}
+	}
+
+	if err = s.put(kindAccount, addr[:], accountValue(acc)); err != nil {
+		return nil, err
+	}
+
+	s.accounts[addr] = acc
+	return acc, nil
+}
+
+// Nonce is the nonce of addr at the block, zero when the fetch fails.
+func (s *Source) Nonce(addr types.Address) uint64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	acc, err := s.account(addr)
+	if err != nil {
+		s.fail(err)
+		return 0
+	}
+
+	return acc.nonce
+}
+
+// SetBlockHash sets the hash BLOCKHASH returns for a block built on top of the fork.
+func (s *Source) SetBlockHash(number uint64, hash types.Hash) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.localHashes[number] = hash
+}
+
+// SetCurrentBlock sets the number of the block in execution, BLOCKHASH only reads the 256
+// blocks before it. It is the block after the fork by default.
+func (s *Source) SetCurrentBlock(number uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.currentBlock = number
+}
+
+// GetBlockHash reads the hashes of the blocks up to the fork from the remote chain, the
+// hashes of the blocks after it are the ones given to SetBlockHash.
+func (s *Source) GetBlockHash(block *evmInt256.Int) (*evmInt256.Int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if !block.IsUint64() {
+		return evmInt256.New(0), nil
+	}
+
+	number := block.Uint64()
+	if number >= s.currentBlock || number+blockHashWindow < s.currentBlock {
+		return evmInt256.New(0), nil
+	}
+
+	if number > s.block {
+		hash := s.localHashes[number]
+		return evmInt256.FromBytes(hash[:]), nil
+	}
+
+	hash, ok := s.blockHashes[number]
+	if !ok {
+		var err error
+		if hash, err = s.client.BlockHash(number); err != nil {
+			return nil, err
+		}
+
+		if err = s.put(kindBlockHash, numberKey(number), hash[:]); err != nil {
+			return nil, err
+		}
+		s.blockHashes[number] = hash
+	}
+
+	return evmInt256.FromBytes(hash[:]), nil
+}
+
+// GetAccount fetches the account when it was not read before.
+func (s *Source) GetAccount(address types.Address) (*environment.Account, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	acc, err := s.account(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if !acc.exists() {
+		return environment.NewAccount(address, nil, nil), nil
+	}
+
+	var contract *environment.Contract
+	if code := s.codes[acc.codeHash]; len(code) > 0 {
+		contract = &environment.Contract{
+			Code:     types.Bytes(code).Clone(),
+			CodeHash: acc.codeHash,
+			CodeSize: uint64(len(code)),
+		}
+	}
+
+	return environment.NewAccount(address, acc.balance.Clone(), contract), nil
+}
+
+func (s *Source) AccountExist(address types.Address) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	acc, err := s.account(address)
+	if err != nil {
+		s.fail(err)
+		return false
+	}
+
+	return acc.exists()
+}
+
+// AccountEmpty follows EIP-161, an account which does not exist is empty.
+func (s *Source) AccountEmpty(address types.Address) bool {
+	return !s.AccountExist(address)
+}
+
+func (s *Source) HashOfCode(code []byte) types.Hash {
+	var hash types.Hash
+	hash.SetBytes(hashes.Keccak256(code))
+	return hash
+}
+
+// CreateAddress derives the address from the nonce of caller at the block, it is the zero
+// address when the nonce fails to be fetched.
+func (s *Source) CreateAddress(caller types.Address, tx environment.Transaction) types.Address {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	acc, err := s.account(caller)
+	if err != nil {
+		s.fail(err)
+		return types.Address{}
+	}
+
+	return types.Address(crypto.CreateAddress(common.Address(caller), acc.nonce))
+}
+
+func (s *Source) CreateFixedAddress(caller types.Address, salt types.Hash, code []byte, tx environment.Transaction) types.Address {
+	return types.Address(crypto.CreateAddress2(common.Address(caller), salt, hashes.Keccak256(code)))
+}
+
+func (s *Source) Load(address types.Address, slot types.Slot) (*evmInt256.Int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	slots := s.slots(address)
+	if val := slots[slot]; val != nil {
+		return val.Clone(), nil
+	}
+
+	val, err := s.client.StorageAt(address, slot, s.block)
+	if err != nil {
+		return nil, err
+	}
+
+	word := types.Int256ToHash(val)
+	if err = s.put(kindSlot, slotKey(address, slot), word[:]); err != nil {
+		return nil, err
+	}
+
+	slots[slot] = val
+	return val.Clone(), nil
+}
@@ -0,0 +1,352 @@
+package fork_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/sim"
+	"github.com/SealSC/SealEVM/statedb/fork"
+	"github.com/SealSC/SealEVM/types"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const forkBlock = 100
+
+var (
+	sender   = types.Address{0x5e}
+	contract = types.Address{0xc0}
+	broken   = types.Address{0xee}
+)
+
+// returns slot 0 when called without data, stores the first word of the data in it otherwise
+var contractCode = hexutil.MustDecode("0x36600c575f545f5260205ff35b5f355f5500")
+
+// stubNode is a JSON-RPC node serving a chain of forkBlock blocks, counting the calls of
+// each method. The fetches of broken fail.
+type stubNode struct {
+	t       *testing.T
+	chainID uint64
+
+	lock  sync.Mutex
+	calls map[string]int
+}
+
+func newStubNode(t *testing.T, chainID uint64) (*stubNode, *fork.Client) {
+	node := &stubNode{t: t, chainID: chainID, calls: map[string]int{}}
+	srv := httptest.NewServer(node)
+	t.Cleanup(srv.Close)
+
+	return node, fork.NewClient(srv.URL)
+}
+
+func blockHash(number uint64) types.Hash {
+	return types.Hash{0xbb, byte(number)}
+}
+
+func (n *stubNode) result(method string, params []json.RawMessage) (interface{}, *fork.RPCError) {
+	var addr types.Address
+	if len(params) > 1 {
+		json.Unmarshal(params[0], &addr)
+		if addr == broken {
+			return nil, &fork.RPCError{Code: -32000, Message: "header not found"}
+		}
+
+		var block string
+		json.Unmarshal(params[len(params)-1], &block)
+		if block != hexutil.EncodeUint64(forkBlock) && method != "eth_getBlockByNumber" {
+			n.t.Errorf("%s at block %s, want %d", method, block, forkBlock)
+		}
+	}
+
+	switch method {
+	case "eth_chainId":
+		return hexutil.Uint64(n.chainID), nil
+
+	case "eth_getBalance":
+		if addr == sender {
+			return (*hexutil.Big)(evmInt256.New(1e18).Int), nil
+		}
+		return "0x0", nil
+
+	case "eth_getTransactionCount":
+		switch addr {
+		case sender:
+			return "0x5", nil
+		case contract:
+			return "0x1", nil
+		}
+		return "0x0", nil
+
+	case "eth_getCode":
+		if addr == contract {
+			return hexutil.Bytes(contractCode), nil
+		}
+		return "0x", nil
+
+	case "eth_getStorageAt":
+		var slot types.Slot
+		json.Unmarshal(params[1], &slot)
+		if addr == contract && slot == (types.Slot{}) {
+			return types.Hash{31: 7}, nil
+		}
+		return types.Hash{}, nil
+
+	case "eth_getBlockByNumber":
+		var number hexutil.Uint64
+		json.Unmarshal(params[0], &number)
+		if number > forkBlock {
+			return nil, nil
+		}
+		return map[string]interface{}{"number": number, "hash": blockHash(uint64(number))}, nil
+	}
+
+	return nil, &fork.RPCError{Code: -32601, Message: "method not found"}
+}
+
+func (n *stubNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     json.RawMessage   `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	n.lock.Lock()
+	n.calls[req.Method]++
+	n.lock.Unlock()
+
+	resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+	if result, err := n.result(req.Method, req.Params); err != nil {
+		resp["error"] = err
+	} else {
+		resp["result"] = result
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+// fetches is the number of calls reading the state, eth_chainId left out.
+func (n *stubNode) fetches() int {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	total := 0
+	for method, calls := range n.calls {
+		if method != "eth_chainId" {
+			total += calls
+		}
+	}
+
+	return total
+}
+
+func blockEnv() *sim.BlockEnv {
+	return &sim.BlockEnv{
+		Number:     forkBlock + 1,
+		GasLimit:   30000000,
+		ChainID:    evmInt256.New(1),
+		BaseFee:    evmInt256.New(0),
+		Difficulty: evmInt256.New(0),
+	}
+}
+
+// readSlot calls the contract without data, which returns its slot 0.
+func readSlot(t *testing.T, state sim.StateDB) uint64 {
+	t.Helper()
+
+	result, err := sim.CallMessage(state, blockEnv(), &sim.Message{From: sender, To: &contract, GasLimit: 100000}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Err != nil {
+		t.Fatal(result.Err)
+	}
+
+	return evmInt256.FromBytes(result.ReturnData).Uint64()
+}
+
+func TestSourceFetch(t *testing.T) {
+	node, client := newStubNode(t, 1)
+	src, err := fork.New(client, forkBlock, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layer := src.Overlay()
+	if val := readSlot(t, layer); val != 7 {
+		t.Fatalf("slot 0 is %d, want 7", val)
+	}
+
+	if src.Nonce(sender) != 5 || src.Nonce(contract) != 1 {
+		t.Fatalf("nonces are %d and %d, want 5 and 1", src.Nonce(sender), src.Nonce(contract))
+	}
+
+	//the second read is served by the fetches of the first one
+	fetched := node.fetches()
+	readSlot(t, src.Overlay())
+	if node.fetches() != fetched {
+		t.Fatalf("a second read made %d fetches", node.fetches()-fetched)
+	}
+
+	word := make([]byte, 32)
+	word[31] = 9
+	msg := &sim.Message{From: sender, To: &contract, Nonce: 5, GasLimit: 100000, GasPrice: evmInt256.New(1), Data: word}
+	if result, err := sim.ApplyMessage(layer, blockEnv(), msg); err != nil || result.Err != nil {
+		t.Fatal(err, result)
+	}
+
+	if val := readSlot(t, layer); val != 9 {
+		t.Fatalf("slot 0 of the layer is %d, want 9", val)
+	}
+
+	if val, _ := src.Load(contract, types.Slot{}); val.Uint64() != 7 || src.Nonce(sender) != 5 {
+		t.Fatalf("the transaction changed the source")
+	}
+
+	if layer.Err() != nil || src.Err() != nil {
+		t.Fatal(layer.Err(), src.Err())
+	}
+}
+
+func TestSourceCacheReuse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fork.cache")
+
+	_, client := newStubNode(t, 1)
+	src, err := fork.New(client, forkBlock, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readSlot(t, src.Overlay())
+	if _, err = src.GetBlockHash(evmInt256.New(forkBlock)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = src.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	node, client := newStubNode(t, 1)
+	if src, err = fork.New(client, forkBlock, path); err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	if val := readSlot(t, src.Overlay()); val != 7 {
+		t.Fatalf("slot 0 is %d, want 7", val)
+	}
+
+	hash, err := src.GetBlockHash(evmInt256.New(forkBlock))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := blockHash(forkBlock); types.Int256ToHash(hash) != want {
+		t.Fatalf("hash of block %d is %s, want %s", forkBlock, types.Int256ToHash(hash), want)
+	}
+
+	if node.fetches() != 0 {
+		t.Fatalf("the cached source made %d fetches", node.fetches())
+	}
+}
+
+func TestSourceCacheMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fork.cache")
+
+	_, client := newStubNode(t, 1)
+	src, err := fork.New(client, forkBlock, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src.Close()
+
+	if _, err = fork.New(client, forkBlock-1, path); !errors.Is(err, fork.ErrCacheMismatch) {
+		t.Fatalf("another block got %v, want %v", err, fork.ErrCacheMismatch)
+	}
+
+	_, other := newStubNode(t, 5)
+	if _, err = fork.New(other, forkBlock, path); !errors.Is(err, fork.ErrCacheMismatch) {
+		t.Fatalf("another chain got %v, want %v", err, fork.ErrCacheMismatch)
+	}
+}
+
+func TestSourceBlockHashWindow(t *testing.T) {
+	_, client := newStubNode(t, 1)
+	src, err := fork.New(client, forkBlock, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := types.Hash{0x1c}
+	src.SetBlockHash(150, local)
+	src.SetCurrentBlock(300)
+
+	for _, c := range []struct {
+		number uint64
+		want   types.Hash
+	}{
+		{43, types.Hash{}},
+		{44, blockHash(44)},
+		{forkBlock, blockHash(forkBlock)},
+		{150, local},
+		{299, types.Hash{}},
+		{300, types.Hash{}},
+	} {
+		hash, err := src.GetBlockHash(evmInt256.New(c.number))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if types.Int256ToHash(hash) != c.want {
+			t.Errorf("hash of block %d is %s, want %s", c.number, types.Int256ToHash(hash), c.want)
+		}
+	}
+}
+
+func TestSourceFailedFetch(t *testing.T) {
+	_, client := newStubNode(t, 1)
+	src, err := fork.New(client, forkBlock, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rpcErr *fork.RPCError
+	if addr := src.CreateAddress(broken, environment.Transaction{}); addr != (types.Address{}) {
+		t.Fatalf("the address was derived from a nonce which failed to be fetched")
+	}
+
+	if !errors.As(src.Err(), &rpcErr) {
+		t.Fatalf("Err is %v, want the error of the node", src.Err())
+	}
+
+	//the failure of the source is the one of its layers
+	if src, err = fork.New(client, forkBlock, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	layer := src.Overlay()
+	if layer.AccountExist(broken) {
+		t.Fatalf("an account which failed to be fetched exists")
+	}
+
+	if !errors.As(layer.Err(), &rpcErr) {
+		t.Fatalf("Err of the layer is %v, want the error of the node", layer.Err())
+	}
+
+	_, err = sim.CallMessage(layer, blockEnv(), &sim.Message{From: sender, To: &contract, GasLimit: 100000}, nil)
+	if !errors.Is(err, sim.ErrStateRead) {
+		t.Fatalf("the call got %v, want %v", err, sim.ErrStateRead)
+	}
+}
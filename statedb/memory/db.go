@@ -0,0 +1,320 @@
+// Package memory is an in-memory state database for SealEVM, a reference implementation of
+// storage.IExternalStorage and storage.IExternalDataBlockStorage. The results of executions
+// are applied with Commit, Snapshot and RevertToSnapshot undo them.
+package memory
+
+import (
+	"github.com/SealSC/SealEVM/crypto/hashes"
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/statedb"
+	"github.com/SealSC/SealEVM/storage/cache"
+	"github.com/SealSC/SealEVM/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// blockHashWindow is the number of previous blocks BLOCKHASH can read.
+const blockHashWindow = 256
+
+// DB holds the accounts, their nonces and the data blocks of the precompiled contracts.
+// Nonces are kept beside the accounts since environment.Account has none, the addresses of
+// created contracts are derived from them by the rules of Ethereum.
+//
+// CreateAddress and CreateFixedAddress are called during the execution and change the
+// nonces at once, take a Snapshot before an execution and revert to it when the execution
+// fails. DB is not safe for concurrent use.
+type DB struct {
+	accounts   map[types.Address]*environment.Account
+	nonces     map[types.Address]uint64
+	dataBlocks map[types.Address]types.DataBlock
+
+	blockHashes  map[uint64]types.Hash
+	currentBlock uint64
+
+	journal   []journalEntry
+	snapshots []int
+}
+
+func NewDB() *DB {
+	return &DB{
+		accounts:    map[types.Address]*environment.Account{},
+		nonces:      map[types.Address]uint64{},
+		dataBlocks:  map[types.Address]types.DataBlock{},
+		blockHashes: map[uint64]types.Hash{},
+	}
+}
+
+// Copy is a deep copy of db without its snapshots.
+func (db *DB) Copy() *DB {
+	replica := NewDB()
+	for addr, acc := range db.accounts {
+		replica.accounts[addr] = acc.Clone()
+	}
+
+	for addr, nonce := range db.nonces {
+		replica.nonces[addr] = nonce
+	}
+
+	for addr, block := range db.dataBlocks {
+		replica.dataBlocks[addr] = block.Clone()
+	}
+
+	for number, hash := range db.blockHashes {
+		replica.blockHashes[number] = hash
+	}
+
+	replica.currentBlock = db.currentBlock
+	return replica
+}
+
+// ForEachAccount calls visit with each account and its nonce, in no particular order. The
+// accounts are the stored ones, visit must not modify them.
+func (db *DB) ForEachAccount(visit func(acc *environment.Account, nonce uint64)) {
+	for addr, acc := range db.accounts {
+		visit(acc, db.nonces[addr])
+	}
+}
+
+// account returns the stored account, creating an empty one when addr has none.
+func (db *DB) account(addr types.Address) *environment.Account {
+	acc := db.accounts[addr]
+	if acc == nil {
+		acc = environment.NewAccount(addr, nil, nil)
+		db.record(&createAccount{address: addr})
+		db.accounts[addr] = acc
+	}
+
+	return acc
+}
+
+func (db *DB) setNonce(addr types.Address, nonce uint64) {
+	prev, ok := db.nonces[addr]
+	db.record(&nonceChange{address: addr, prev: prev, existed: ok})
+	db.nonces[addr] = nonce
+}
+
+func (db *DB) setBalance(acc *environment.Account, balance *evmInt256.Int) {
+	db.record(&balanceChange{address: acc.Address, prev: acc.Balance})
+	acc.Balance = balance
+}
+
+func (db *DB) setSlot(acc *environment.Account, slot types.Slot, val *evmInt256.Int) {
+	db.record(&slotChange{address: acc.Address, slot: slot, prev: acc.Slots[slot]})
+	acc.Slots[slot] = val
+}
+
+func (db *DB) setDataBlock(addr types.Address, slot types.Slot, data types.Bytes) {
+	block := db.dataBlocks[addr]
+	if block == nil {
+		block = types.DataBlock{}
+		db.dataBlocks[addr] = block
+	}
+
+	prev, ok := block[slot]
+	db.record(&dataBlockChange{address: addr, slot: slot, prev: prev, existed: ok})
+	block[slot] = data
+}
+
+// clearStorage drops the slots and the data blocks of addr.
+func (db *DB) clearStorage(acc *environment.Account) {
+	db.record(&storageReset{address: acc.Address, slots: acc.Slots, dataBlock: db.dataBlocks[acc.Address]})
+	acc.Slots = map[types.Slot]*evmInt256.Int{}
+	delete(db.dataBlocks, acc.Address)
+}
+
+// SetAccount stores a copy of acc with nonce, replacing the account at its address with its
+// storage.
+func (db *DB) SetAccount(acc *environment.Account, nonce uint64) {
+	db.DeleteAccount(acc.Address)
+
+	replica := acc.Clone()
+	db.record(&createAccount{address: acc.Address})
+	db.accounts[acc.Address] = replica
+	db.setNonce(acc.Address, nonce)
+}
+
+// SetCode sets the code of addr, empty code removes it.
+func (db *DB) SetCode(addr types.Address, code []byte) {
+	acc := db.account(addr)
+	db.record(&codeChange{address: addr, prev: acc.Contract})
+
+	acc.Contract = nil
+	if len(code) > 0 {
+		code = types.Bytes(code).Clone()
+		acc.Contract = &environment.Contract{
+			Code:     code,
+			CodeHash: db.HashOfCode(code),
+			CodeSize: uint64(len(code)),
+		}
+	}
+}
+
+func (db *DB) SetState(addr types.Address, slot types.Slot, val *evmInt256.Int) {
+	db.setSlot(db.account(addr), slot, val.Clone())
+}
+
+func (db *DB) SetDataBlock(addr types.Address, slot types.Slot, data types.Bytes) {
+	db.account(addr)
+	db.setDataBlock(addr, slot, data.Clone())
+}
+
+func (db *DB) Nonce(addr types.Address) uint64 {
+	return db.nonces[addr]
+}
+
+func (db *DB) SetNonce(addr types.Address, nonce uint64) {
+	db.account(addr)
+	db.setNonce(addr, nonce)
+}
+
+func (db *DB) Balance(addr types.Address) *evmInt256.Int {
+	if acc := db.accounts[addr]; acc != nil {
+		return acc.Balance.Clone()
+	}
+
+	return evmInt256.New(0)
+}
+
+func (db *DB) SetBalance(addr types.Address, balance *evmInt256.Int) {
+	db.setBalance(db.account(addr), balance.Clone())
+}
+
+func (db *DB) AddBalance(addr types.Address, amount *evmInt256.Int) {
+	acc := db.account(addr)
+	db.setBalance(acc, evmInt256.FromBigInt(acc.Balance.Int).Add(amount))
+}
+
+// SubBalance does not check the balance, the caller has to.
+func (db *DB) SubBalance(addr types.Address, amount *evmInt256.Int) {
+	acc := db.account(addr)
+	db.setBalance(acc, evmInt256.FromBigInt(acc.Balance.Int).Sub(amount))
+}
+
+func (db *DB) Code(addr types.Address) []byte {
+	if acc := db.accounts[addr]; acc != nil && acc.Contract != nil {
+		return acc.Contract.Code
+	}
+
+	return nil
+}
+
+// DeleteAccount removes an account with its nonce, its storage and its data blocks.
+func (db *DB) DeleteAccount(addr types.Address) {
+	acc := db.accounts[addr]
+	nonce, hasNonce := db.nonces[addr]
+	block := db.dataBlocks[addr]
+	if acc == nil && !hasNonce && block == nil {
+		return
+	}
+
+	db.record(&deleteAccount{address: addr, account: acc, nonce: nonce, hasNonce: hasNonce, dataBlock: block})
+	delete(db.accounts, addr)
+	delete(db.nonces, addr)
+	delete(db.dataBlocks, addr)
+}
+
+// SetBlockHash stores the hash BLOCKHASH returns for the block number. Block hashes are not
+// part of the snapshots.
+func (db *DB) SetBlockHash(number uint64, hash types.Hash) {
+	db.blockHashes[number] = hash
+}
+
+// SetCurrentBlock sets the number of the block in execution, BLOCKHASH only reads the 256
+// blocks before it.
+func (db *DB) SetCurrentBlock(number uint64) {
+	db.currentBlock = number
+}
+
+func (db *DB) GetBlockHash(block *evmInt256.Int) (*evmInt256.Int, error) {
+	if !block.IsUint64() {
+		return evmInt256.New(0), nil
+	}
+
+	number := block.Uint64()
+	if number >= db.currentBlock || number+blockHashWindow < db.currentBlock {
+		return evmInt256.New(0), nil
+	}
+
+	hash := db.blockHashes[number]
+	return evmInt256.FromBytes(hash[:]), nil
+}
+
+// GetAccount returns a copy of the account without its slots, an empty account when it does
+// not exist.
+func (db *DB) GetAccount(address types.Address) (*environment.Account, error) {
+	if acc := db.accounts[address]; acc != nil {
+		var contract *environment.Contract
+		if acc.Contract != nil {
+			contract = acc.Contract.Clone()
+		}
+
+		return environment.NewAccount(address, acc.Balance.Clone(), contract), nil
+	}
+
+	return environment.NewAccount(address, nil, nil), nil
+}
+
+func (db *DB) AccountExist(address types.Address) bool {
+	return db.accounts[address] != nil
+}
+
+// AccountEmpty follows EIP-161: an account is empty when it has no nonce, no balance and no
+// code, and an account which does not exist is empty too.
+func (db *DB) AccountEmpty(address types.Address) bool {
+	acc := db.accounts[address]
+	if acc == nil {
+		return true
+	}
+
+	return db.nonces[address] == 0 && acc.Balance.IsZero() && (acc.Contract == nil || len(acc.Contract.Code) == 0)
+}
+
+func (db *DB) HashOfCode(code []byte) types.Hash {
+	var hash types.Hash
+	hash.SetBytes(hashes.Keccak256(code))
+	return hash
+}
+
+// created accounts start with nonce 1 (EIP-161).
+func (db *DB) created(addr types.Address) types.Address {
+	db.setNonce(addr, 1)
+	return addr
+}
+
+func (db *DB) CreateAddress(caller types.Address, tx environment.Transaction) types.Address {
+	nonce := db.nonces[caller]
+	db.setNonce(caller, nonce+1)
+	return db.created(types.Address(crypto.CreateAddress(common.Address(caller), nonce)))
+}
+
+func (db *DB) CreateFixedAddress(caller types.Address, salt types.Hash, code []byte, tx environment.Transaction) types.Address {
+	db.setNonce(caller, db.nonces[caller]+1)
+	return db.created(types.Address(crypto.CreateAddress2(common.Address(caller), salt, hashes.Keccak256(code))))
+}
+
+func (db *DB) Load(address types.Address, slot types.Slot) (*evmInt256.Int, error) {
+	if acc := db.accounts[address]; acc != nil && acc.Slots[slot] != nil {
+		return acc.Slots[slot].Clone(), nil
+	}
+
+	return evmInt256.New(0), nil
+}
+
+func (db *DB) GetDataBlock(address types.Address, slot types.Slot) (types.Bytes, error) {
+	if data := db.dataBlocks[address][slot]; data != nil {
+		return data.Clone(), nil
+	}
+
+	return nil, nil
+}
+
+// ClearStorage drops the slots and the data blocks of addr.
+func (db *DB) ClearStorage(addr types.Address) {
+	db.clearStorage(db.account(addr))
+}
+
+// Commit applies the result of a successful execution, see statedb.Commit.
+func (db *DB) Commit(result *cache.ResultCache) {
+	statedb.Commit(db, result)
+}
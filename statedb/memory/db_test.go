@@ -0,0 +1,104 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/SealSC/SealEVM"
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/statedb/memory"
+	"github.com/SealSC/SealEVM/types"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	caller   = types.Address{0xca}
+	contract = types.Address{0xc0}
+	target   = types.Address{0x77}
+)
+
+// execute runs the contract with code on db and commits the result, it returns the gas used.
+func execute(t *testing.T, db *memory.DB, code string) uint64 {
+	t.Helper()
+
+	db.SetCode(contract, common.FromHex(code))
+	db.SetBalance(contract, evmInt256.New(10))
+
+	const gasLimit = 100000
+	evm := SealEVM.New(SealEVM.EVMParam{
+		MaxStackDepth: 1024,
+		ExternalStore: db,
+		Context: &environment.Context{
+			Block: environment.Block{
+				ChainID:     evmInt256.New(1),
+				Difficulty:  evmInt256.New(0),
+				GasLimit:    evmInt256.New(gasLimit),
+				BaseFee:     evmInt256.New(0),
+				BlobBaseFee: evmInt256.New(1),
+			},
+			Transaction: environment.Transaction{
+				Origin:   caller,
+				To:       &contract,
+				GasPrice: evmInt256.New(0),
+				GasLimit: evmInt256.New(gasLimit),
+			},
+			Message: environment.Message{Caller: caller, Value: evmInt256.New(0)},
+		},
+	})
+
+	result, err := evm.Execute()
+	if err != nil {
+		t.Fatalf("%s: %v", code, err)
+	}
+
+	db.Commit(&result.StorageCache)
+	return gasLimit - result.GasLeft
+}
+
+func init() {
+	SealEVM.Load()
+}
+
+func TestCommitSkipsAccountsOnlyRead(t *testing.T) {
+	db := memory.NewDB()
+
+	//PUSH20 0x77.. BALANCE POP STOP
+	execute(t, db, "73"+common.Bytes2Hex(target[:])+"315000")
+	if db.AccountExist(target) {
+		t.Fatalf("BALANCE made %s exist", target)
+	}
+
+	//PUSH0 PUSH0 PUSH0 PUSH0 PUSH1 1 PUSH20 0x77.. GAS CALL POP STOP, sends 1 wei
+	call := "5f5f5f5f6001" + "73" + common.Bytes2Hex(target[:]) + "5af15000"
+	fresh := execute(t, memory.NewDB(), call)
+	afterRead := execute(t, db, call)
+	if afterRead != fresh {
+		t.Fatalf("CALL with value after BALANCE used %d gas, want %d as on a fresh state", afterRead, fresh)
+	}
+
+	if !db.AccountExist(target) || db.Balance(target).Uint64() != 1 {
+		t.Fatalf("the value sent did not create %s", target)
+	}
+}
+
+func TestCommitWritesChangedSlotsOnly(t *testing.T) {
+	db := memory.NewDB()
+	db.SetState(contract, types.Slot{31: 1}, evmInt256.New(5))
+
+	//PUSH1 1 SLOAD POP PUSH1 7 PUSH1 2 SSTORE STOP
+	snapshot := db.Snapshot()
+	execute(t, db, "600154506007600255"+"00")
+
+	if val, _ := db.Load(contract, types.Slot{31: 2}); val.Uint64() != 7 {
+		t.Fatalf("slot 2 is %s, want 7", val.Text(10))
+	}
+
+	if val, _ := db.Load(contract, types.Slot{31: 1}); val.Uint64() != 5 {
+		t.Fatalf("slot 1 is %s, want 5", val.Text(10))
+	}
+
+	db.RevertToSnapshot(snapshot)
+	if val, _ := db.Load(contract, types.Slot{31: 2}); !val.IsZero() {
+		t.Fatalf("slot 2 is %s after the revert, want 0", val.Text(10))
+	}
+}
@@ -0,0 +1,160 @@
+package memory
+
+import (
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/types"
+)
+
+// journalEntry is a change of the DB which can be undone. Values are replaced and never
+// changed in place, so the entries keep the previous ones without copying them.
+type journalEntry interface {
+	revert(db *DB)
+}
+
+type createAccount struct {
+	address types.Address
+}
+
+func (e *createAccount) revert(db *DB) {
+	delete(db.accounts, e.address)
+}
+
+type deleteAccount struct {
+	address   types.Address
+	account   *environment.Account
+	nonce     uint64
+	hasNonce  bool
+	dataBlock types.DataBlock
+}
+
+func (e *deleteAccount) revert(db *DB) {
+	if e.account != nil {
+		db.accounts[e.address] = e.account
+	}
+
+	if e.hasNonce {
+		db.nonces[e.address] = e.nonce
+	}
+
+	if e.dataBlock != nil {
+		db.dataBlocks[e.address] = e.dataBlock
+	}
+}
+
+type nonceChange struct {
+	address types.Address
+	prev    uint64
+	existed bool
+}
+
+func (e *nonceChange) revert(db *DB) {
+	if e.existed {
+		db.nonces[e.address] = e.prev
+	} else {
+		delete(db.nonces, e.address)
+	}
+}
+
+type balanceChange struct {
+	address types.Address
+	prev    *evmInt256.Int
+}
+
+func (e *balanceChange) revert(db *DB) {
+	db.accounts[e.address].Balance = e.prev
+}
+
+type codeChange struct {
+	address types.Address
+	prev    *environment.Contract
+}
+
+func (e *codeChange) revert(db *DB) {
+	db.accounts[e.address].Contract = e.prev
+}
+
+type slotChange struct {
+	address types.Address
+	slot    types.Slot
+	prev    *evmInt256.Int
+}
+
+func (e *slotChange) revert(db *DB) {
+	slots := db.accounts[e.address].Slots
+	if e.prev == nil {
+		delete(slots, e.slot)
+	} else {
+		slots[e.slot] = e.prev
+	}
+}
+
+type storageReset struct {
+	address   types.Address
+	slots     map[types.Slot]*evmInt256.Int
+	dataBlock types.DataBlock
+}
+
+func (e *storageReset) revert(db *DB) {
+	db.accounts[e.address].Slots = e.slots
+	if e.dataBlock != nil {
+		db.dataBlocks[e.address] = e.dataBlock
+	}
+}
+
+type dataBlockChange struct {
+	address types.Address
+	slot    types.Slot
+	prev    types.Bytes
+	existed bool
+}
+
+func (e *dataBlockChange) revert(db *DB) {
+	block := db.dataBlocks[e.address]
+	if e.existed {
+		block[e.slot] = e.prev
+		return
+	}
+
+	delete(block, e.slot)
+	if len(block) == 0 {
+		delete(db.dataBlocks, e.address)
+	}
+}
+
+// record keeps e while a snapshot is taken, changes made without one can not be undone.
+func (db *DB) record(e journalEntry) {
+	if len(db.snapshots) > 0 {
+		db.journal = append(db.journal, e)
+	}
+}
+
+// Snapshot returns the id of the current state, for RevertToSnapshot. Snapshots nest, every
+// change is journaled until the outermost one is reverted or ClearSnapshots is called.
+func (db *DB) Snapshot() int {
+	id := len(db.snapshots)
+	db.snapshots = append(db.snapshots, len(db.journal))
+	return id
+}
+
+// RevertToSnapshot undoes the changes made since the snapshot id was taken, it and the
+// snapshots taken after it are dropped. An unknown id is ignored.
+func (db *DB) RevertToSnapshot(id int) {
+	if id < 0 || id >= len(db.snapshots) {
+		return
+	}
+
+	mark := db.snapshots[id]
+	for i := len(db.journal) - 1; i >= mark; i-- {
+		db.journal[i].revert(db)
+	}
+
+	db.journal = db.journal[:mark]
+	db.snapshots = db.snapshots[:id]
+}
+
+// ClearSnapshots keeps the current state and drops every snapshot with the journal.
+func (db *DB) ClearSnapshots() {
+	db.journal = nil
+	db.snapshots = nil
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/types"
+)
+
+type SlotAccess struct {
+	Address types.Address
+	Slot    types.Slot
+	Before  *evmInt256.Int
+	Written bool
+}
+
+// FrameJournal records the logs emitted and the persistent slots accessed by one frame,
+// accesses of sub frames are recorded by their own storage.
+type FrameJournal struct {
+	Logs     []*types.Log
+	Accesses []*SlotAccess
+
+	index map[types.Address]map[types.Slot]*SlotAccess
+}
+
+func newFrameJournal() *FrameJournal {
+	return &FrameJournal{
+		index: map[types.Address]map[types.Slot]*SlotAccess{},
+	}
+}
+
+func (j *FrameJournal) access(address types.Address, slot types.Slot) *SlotAccess {
+	if j.index[address] == nil {
+		return nil
+	}
+
+	return j.index[address][slot]
+}
+
+func (j *FrameJournal) read(address types.Address, slot types.Slot, val *evmInt256.Int) {
+	if j.access(address, slot) != nil {
+		return
+	}
+
+	if j.index[address] == nil {
+		j.index[address] = map[types.Slot]*SlotAccess{}
+	}
+
+	access := &SlotAccess{
+		Address: address,
+		Slot:    slot,
+		Before:  val.Clone(),
+	}
+
+	j.index[address][slot] = access
+	j.Accesses = append(j.Accesses, access)
+}
+
+func (s *Storage) EnableJournal() {
+	s.journal = newFrameJournal()
+}
+
+func (s *Storage) Journal() *FrameJournal {
+	return s.journal
+}
@@ -25,6 +25,8 @@ import (
 type IExternalStorage interface {
 	GetBlockHash(block *evmInt256.Int) (*evmInt256.Int, error)
 
+	//GetAccount returns the account without its slots. SealEVM takes the slots of a returned
+	//account as warm, slot values are read by Load so that the first access is charged cold.
 	GetAccount(address types.Address) (*environment.Account, error)
 	AccountExist(address types.Address) bool
 	AccountEmpty(address types.Address) bool
@@ -31,6 +31,8 @@ type Storage struct {
 	readOnlyCache   cache.ReadOnlyCache
 	externalStorage IExternalStorage
 	externalDataBlockStorage IExternalDataBlockStorage
+	journal         *FrameJournal
+	refund          uint64
 }
 
 func New(extStorage IExternalStorage, extDataBlockStorage IExternalDataBlockStorage) *Storage {
@@ -52,6 +54,7 @@ func (s *Storage) Clone() *Storage {
 		readOnlyCache:   s.readOnlyCache,
 		externalStorage: s.externalStorage,
 		externalDataBlockStorage: s.externalDataBlockStorage,
+		refund:          s.refund,
 	}
 
 	return replica
@@ -83,10 +86,24 @@ func (s *Storage) XLoad(address types.Address, slot types.Slot, t cache.TypeOfSt
 		s.ResultCache.XOriginalStore(address, slot, i, t)
 	}
 
+	if s.journal != nil && t == cache.SStorage {
+		s.journal.read(address, slot, i)
+	}
+
 	return i, nil
 }
 
 func (s *Storage) XStore(address types.Address, slot types.Slot, val *evmInt256.Int, t cache.TypeOfStorage) {
+	if s.journal != nil && t == cache.SStorage {
+		if s.journal.access(address, slot) == nil {
+			_, _ = s.XLoad(address, slot, t)
+		}
+
+		if access := s.journal.access(address, slot); access != nil {
+			access.Written = true
+		}
+	}
+
 	s.ResultCache.XCachedStore(address, slot, val, t)
 }
 
@@ -126,6 +143,10 @@ func (s *Storage) Transfer(fromAddr types.Address, toAddr types.Address, val *ev
 
 func (s *Storage) Log(log *types.Log) {
 	*s.ResultCache.Logs = append(*s.ResultCache.Logs, log)
+
+	if s.journal != nil {
+		s.journal.Logs = append(s.journal.Logs, log)
+	}
 }
 
 func (s *Storage) Destruct(address types.Address) {
@@ -257,11 +278,34 @@ func (s *Storage) CachedContract(addr types.Address) bool {
 }
 
 func (s *Storage) CachedData(addr types.Address, slot types.Slot) (org *evmInt256.Int, current *evmInt256.Int) {
-	org = s.ResultCache.CachedAccounts.GetSlot(addr, slot)
+	org = s.ResultCache.OriginalAccounts.GetSlot(addr, slot)
 	current = s.ResultCache.CachedAccounts.GetSlot(addr, slot)
 	return org, current
 }
 
+// Refund is the gas refunded to the transaction at its end, the clone of a frame starts with
+// the refund of its parent and gives it back only when the frame succeeds.
+func (s *Storage) Refund() uint64 {
+	return s.refund
+}
+
+func (s *Storage) SetRefund(gas uint64) {
+	s.refund = gas
+}
+
+func (s *Storage) AddRefund(gas uint64) {
+	s.refund += gas
+}
+
+// SubRefund takes gas from the refund, which never goes below zero.
+func (s *Storage) SubRefund(gas uint64) {
+	if gas > s.refund {
+		gas = s.refund
+	}
+
+	s.refund -= gas
+}
+
 func (s *Storage) ContractExist(addr types.Address) bool {
 	return s.externalStorage.AccountExist(addr)
 }
@@ -0,0 +1,230 @@
+package timeTravel
+
+import (
+	"errors"
+
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/types"
+)
+
+type FrameState struct {
+	Depth      uint64
+	Address    types.Address
+	Stack      []*evmInt256.Int
+	Memory     types.Bytes
+	ReturnData types.Bytes
+}
+
+func (f *FrameState) clone() *FrameState {
+	return &FrameState{
+		Depth:      f.Depth,
+		Address:    f.Address,
+		Stack:      append([]*evmInt256.Int{}, f.Stack...),
+		Memory:     f.Memory.Clone(),
+		ReturnData: f.ReturnData,
+	}
+}
+
+// VMState is the rebuilt VM state at a position of the trace.
+// Frames are ordered by depth, the last one is the frame of Step.
+// Step is nil at the end of the trace.
+type VMState struct {
+	Position int
+	Step     *StepRecord
+	Frames   []*FrameState
+
+	storage map[storageKey]*evmInt256.Int
+}
+
+// Storage returns the value of a slot written before this position, nil if the slot
+// was not written yet.
+func (s *VMState) Storage(address types.Address, slot types.Slot) *evmInt256.Int {
+	return s.storage[storageKey{address: address, slot: slot}]
+}
+
+func (s *VMState) TransientStorage(address types.Address, slot types.Slot) *evmInt256.Int {
+	return s.storage[storageKey{address: address, slot: slot, transient: true}]
+}
+
+type snapshot struct {
+	frames  []*FrameState
+	storage map[storageKey]*evmInt256.Int
+}
+
+// Cursor walks a Trace forwards and backwards.
+type Cursor struct {
+	trace   *Trace
+	pos     int
+	frames  []*FrameState
+	storage map[storageKey]*evmInt256.Int
+}
+
+func (c *Cursor) snapshot() *snapshot {
+	s := &snapshot{
+		frames:  make([]*FrameState, len(c.frames)),
+		storage: make(map[storageKey]*evmInt256.Int, len(c.storage)),
+	}
+
+	for i, f := range c.frames {
+		s.frames[i] = f.clone()
+	}
+
+	for k, v := range c.storage {
+		s.storage[k] = v
+	}
+
+	return s
+}
+
+func (c *Cursor) restore(pos int, s *snapshot) {
+	c.pos = pos
+	c.frames = make([]*FrameState, len(s.frames))
+	c.storage = make(map[storageKey]*evmInt256.Int, len(s.storage))
+
+	for i, f := range s.frames {
+		c.frames[i] = f.clone()
+	}
+
+	for k, v := range s.storage {
+		c.storage[k] = v
+	}
+}
+
+func (c *Cursor) reset() {
+	c.pos = 0
+	c.frames = nil
+	c.storage = map[storageKey]*evmInt256.Int{}
+	if len(c.trace.Steps) > 0 {
+		c.enter(c.trace.Steps[0])
+	}
+}
+
+func (c *Cursor) enter(record *StepRecord) {
+	if record.EnterFrame {
+		if uint64(len(c.frames)) > record.Depth {
+			c.frames = c.frames[:record.Depth]
+		}
+
+		for uint64(len(c.frames)) <= record.Depth {
+			c.frames = append(c.frames, &FrameState{Depth: uint64(len(c.frames))})
+		}
+
+		c.frames[record.Depth].Address = record.Address
+	}
+
+	if record.ReturnDataSet && uint64(len(c.frames)) > record.Depth {
+		c.frames[record.Depth].ReturnData = record.ReturnData
+	}
+}
+
+func (c *Cursor) apply(record *StepRecord) {
+	for _, w := range record.StorageWrites {
+		c.storage[w.key()] = w.Value
+	}
+
+	if uint64(len(c.frames)) <= record.Depth {
+		return
+	}
+
+	frame := c.frames[record.Depth]
+
+	popTo := len(frame.Stack) - record.StackPop
+	if popTo < 0 {
+		popTo = 0
+	}
+	frame.Stack = append(frame.Stack[:popTo:popTo], record.StackPush...)
+
+	if uint64(len(frame.Memory)) < record.MemorySize {
+		frame.Memory = append(frame.Memory, make([]byte, record.MemorySize-uint64(len(frame.Memory)))...)
+	}
+
+	for _, w := range record.MemoryWrites {
+		copy(frame.Memory[w.Offset:], w.Data)
+	}
+}
+
+func (c *Cursor) advance() {
+	next := c.pos + 1
+	for _, idx := range c.trace.applyAt[next] {
+		c.apply(c.trace.Steps[idx])
+	}
+
+	if next < len(c.trace.Steps) {
+		c.enter(c.trace.Steps[next])
+	}
+
+	c.pos = next
+}
+
+func (c *Cursor) Position() int {
+	return c.pos
+}
+
+// Seek moves the cursor to pos, the state is rebuilt from the nearest checkpoint.
+func (c *Cursor) Seek(pos int) error {
+	if pos < 0 || pos > len(c.trace.Steps) {
+		return errors.New("position out of range")
+	}
+
+	cp := pos / checkpointInterval
+	if pos < c.pos || c.pos < cp*checkpointInterval {
+		c.restore(cp*checkpointInterval, c.trace.checkpoints[cp])
+	}
+
+	for c.pos < pos {
+		c.advance()
+	}
+
+	return nil
+}
+
+func (c *Cursor) Next() bool {
+	if c.pos >= len(c.trace.Steps) {
+		return false
+	}
+
+	c.advance()
+	return true
+}
+
+func (c *Cursor) Prev() bool {
+	if c.pos == 0 {
+		return false
+	}
+
+	_ = c.Seek(c.pos - 1)
+	return true
+}
+
+// State returns a copy of the VM state at the current position.
+func (c *Cursor) State() *VMState {
+	state := &VMState{
+		Position: c.pos,
+		storage:  make(map[storageKey]*evmInt256.Int, len(c.storage)),
+	}
+
+	active := 1
+	if c.pos < len(c.trace.Steps) {
+		state.Step = c.trace.Steps[c.pos]
+		active = int(state.Step.Depth) + 1
+	}
+
+	if active > len(c.frames) {
+		active = len(c.frames)
+	}
+
+	for _, f := range c.frames[:active] {
+		frame := f.clone()
+		for i, item := range frame.Stack {
+			frame.Stack[i] = item.Clone()
+		}
+
+		state.Frames = append(state.Frames, frame)
+	}
+
+	for k, v := range c.storage {
+		state.storage[k] = v
+	}
+
+	return state
+}
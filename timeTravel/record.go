@@ -0,0 +1,87 @@
+package timeTravel
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/opcodes"
+	"github.com/SealSC/SealEVM/types"
+)
+
+type MemoryWrite struct {
+	Offset uint64
+	Data   types.Bytes
+}
+
+type StorageWrite struct {
+	Address   types.Address
+	Slot      types.Slot
+	Value     *evmInt256.Int
+	Transient bool `json:",omitempty"`
+}
+
+// StepRecord holds the changes made by one executed instruction.
+// The changes are visible from step ApplyAt on, which is the step right after the instruction,
+// or for CALL and CREATE families, the first step after the called frame finished.
+type StepRecord struct {
+	PC      uint64
+	OpCode  opcodes.OpCode
+	Depth   uint64
+	Address types.Address
+	Gas     uint64
+	GasCost uint64
+	Error   string `json:",omitempty"`
+
+	EnterFrame    bool        `json:",omitempty"`
+	ReturnDataSet bool        `json:",omitempty"`
+	ReturnData    types.Bytes `json:",omitempty"`
+
+	StackPop      int              `json:",omitempty"`
+	StackPush     []*evmInt256.Int `json:",omitempty"`
+	MemorySize    uint64
+	MemoryWrites  []MemoryWrite  `json:",omitempty"`
+	StorageWrites []StorageWrite `json:",omitempty"`
+
+	ApplyAt int
+}
+
+type storageKey struct {
+	address   types.Address
+	slot      types.Slot
+	transient bool
+}
+
+func (w StorageWrite) key() storageKey {
+	return storageKey{
+		address:   w.Address,
+		slot:      w.Slot,
+		transient: w.Transient,
+	}
+}
+
+// validate checks the changes of r can be applied, each memory write must lie in the memory
+// of the frame after the step, which is MemorySize long.
+func (r *StepRecord) validate() error {
+	if r.StackPop < 0 {
+		return fmt.Errorf("negative stack pop %d", r.StackPop)
+	}
+
+	if r.MemorySize > maxMemorySize {
+		return fmt.Errorf("memory size %d out of range", r.MemorySize)
+	}
+
+	for _, w := range r.MemoryWrites {
+		if w.Offset > r.MemorySize || uint64(len(w.Data)) > r.MemorySize-w.Offset {
+			return fmt.Errorf("memory write of %d bytes at %d out of memory size %d", len(w.Data), w.Offset, r.MemorySize)
+		}
+	}
+
+	for _, v := range r.StackPush {
+		if v == nil {
+			return errors.New("missing stack item")
+		}
+	}
+
+	return nil
+}
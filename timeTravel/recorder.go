@@ -0,0 +1,291 @@
+package timeTravel
+
+import (
+	"bytes"
+
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/instructions"
+	"github.com/SealSC/SealEVM/opcodes"
+	"github.com/SealSC/SealEVM/storage/cache"
+	"github.com/SealSC/SealEVM/types"
+)
+
+var memoryWriters = map[opcodes.OpCode]bool{
+	opcodes.MSTORE:         true,
+	opcodes.MSTORE8:        true,
+	opcodes.MCOPY:          true,
+	opcodes.CALLDATACOPY:   true,
+	opcodes.CODECOPY:       true,
+	opcodes.EXTCODECOPY:    true,
+	opcodes.RETURNDATACOPY: true,
+	opcodes.CALL:           true,
+	opcodes.CALLCODE:       true,
+	opcodes.DELEGATECALL:   true,
+	opcodes.STATICCALL:     true,
+}
+
+var frameCreators = map[opcodes.OpCode]bool{
+	opcodes.CALL:         true,
+	opcodes.CALLCODE:     true,
+	opcodes.DELEGATECALL: true,
+	opcodes.STATICCALL:   true,
+	opcodes.CREATE:       true,
+	opcodes.CREATE2:      true,
+}
+
+type recordingFrame struct {
+	enteredAt  int
+	memory     []byte
+	returnData []byte
+	touched    map[storageKey]bool
+}
+
+type pendingStep struct {
+	step   *instructions.ExecutionStep
+	index  int
+	stack  []*evmInt256.Int
+	writes []StorageWrite
+}
+
+// Recorder is an instructions.IStepHook which records every executed instruction of an
+// execution, the recorded Trace can rebuild the VM state at any step without executing again.
+type Recorder struct {
+	steps      []*StepRecord
+	completion []int
+
+	frames    []*recordingFrame
+	pending   []*pendingStep
+	lastDepth uint64
+
+	storage  map[storageKey]*evmInt256.Int
+	original map[storageKey]*evmInt256.Int
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{
+		storage:  map[storageKey]*evmInt256.Int{},
+		original: map[storageKey]*evmInt256.Int{},
+	}
+}
+
+// Trace returns the trace recorded so far.
+func (r *Recorder) Trace() *Trace {
+	return newTrace(r.steps, r.completion)
+}
+
+func cloneStack(items []*evmInt256.Int) []*evmInt256.Int {
+	replica := make([]*evmInt256.Int, len(items))
+	for i, item := range items {
+		replica[i] = item.Clone()
+	}
+
+	return replica
+}
+
+func (r *Recorder) enterFrame(depth uint64) {
+	if uint64(len(r.frames)) > depth {
+		r.frames = r.frames[:depth]
+	}
+
+	for uint64(len(r.frames)) < depth {
+		r.frames = append(r.frames, &recordingFrame{touched: map[storageKey]bool{}})
+	}
+
+	r.frames = append(r.frames, &recordingFrame{
+		enteredAt: len(r.steps),
+		touched:   map[storageKey]bool{},
+	})
+}
+
+func (r *Recorder) newRecord(step *instructions.ExecutionStep) *StepRecord {
+	enter := len(r.steps) == 0 || step.Depth > r.lastDepth || uint64(len(r.frames)) <= step.Depth
+	r.lastDepth = step.Depth
+
+	record := &StepRecord{
+		PC:         step.PC,
+		OpCode:     step.OpCode,
+		Depth:      step.Depth,
+		Address:    step.Context.Address(),
+		Gas:        step.Gas,
+		GasCost:    step.GasCost,
+		EnterFrame: enter,
+	}
+
+	if enter {
+		r.enterFrame(step.Depth)
+	}
+
+	frame := r.frames[step.Depth]
+	if !bytes.Equal(frame.returnData, step.ReturnData) {
+		record.ReturnDataSet = true
+		record.ReturnData = types.Bytes(step.ReturnData).Clone()
+		frame.returnData = record.ReturnData
+	}
+
+	r.steps = append(r.steps, record)
+	return record
+}
+
+func (r *Recorder) storageWrite(step *instructions.ExecutionStep, t cache.TypeOfStorage) []StorageWrite {
+	slot := types.Int256ToSlot(step.Stack.PeekPos(0))
+	write := StorageWrite{
+		Address:   step.Context.Address(),
+		Slot:      slot,
+		Value:     step.Stack.PeekPos(1).Clone(),
+		Transient: t == cache.TStorage,
+	}
+
+	key := write.key()
+	if _, exists := r.original[key]; !exists {
+		org := step.Storage.ResultCache.XCachedLoad(write.Address, slot, t)
+		if org == nil {
+			org = evmInt256.New(0)
+		}
+
+		r.original[key] = org.Clone()
+		r.storage[key] = org.Clone()
+	}
+
+	return []StorageWrite{write}
+}
+
+func (r *Recorder) BeforeStep(step *instructions.ExecutionStep) error {
+	r.newRecord(step)
+
+	pending := &pendingStep{
+		step:  step,
+		index: len(r.steps) - 1,
+		stack: cloneStack(step.Stack.All()),
+	}
+
+	switch step.OpCode {
+	case opcodes.SSTORE:
+		pending.writes = r.storageWrite(step, cache.SStorage)
+	case opcodes.TSTORE:
+		pending.writes = r.storageWrite(step, cache.TStorage)
+	}
+
+	r.pending = append(r.pending, pending)
+	return nil
+}
+
+func (r *Recorder) recordStack(record *StepRecord, before []*evmInt256.Int, after []*evmInt256.Int) {
+	common := 0
+	for common < len(before) && common < len(after) {
+		if before[common].Cmp(after[common].Int) != 0 {
+			break
+		}
+		common += 1
+	}
+
+	record.StackPop = len(before) - common
+	record.StackPush = cloneStack(after[common:])
+}
+
+func (r *Recorder) recordMemory(record *StepRecord, frame *recordingFrame, live []byte) {
+	record.MemorySize = uint64(len(live))
+	if len(live) > len(frame.memory) {
+		frame.memory = append(frame.memory, make([]byte, len(live)-len(frame.memory))...)
+	}
+
+	if !memoryWriters[record.OpCode] {
+		return
+	}
+
+	first, last := -1, -1
+	for i := range live {
+		if live[i] != frame.memory[i] {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+
+	if first < 0 {
+		return
+	}
+
+	write := MemoryWrite{
+		Offset: uint64(first),
+		Data:   types.Bytes(live[first : last+1]).Clone(),
+	}
+
+	copy(frame.memory[first:], write.Data)
+	record.MemoryWrites = append(record.MemoryWrites, write)
+}
+
+func (r *Recorder) reconcileStorage(record *StepRecord, step *instructions.ExecutionStep, frame *recordingFrame) {
+	child := (*recordingFrame)(nil)
+	if uint64(len(r.frames)) > step.Depth+1 && r.frames[step.Depth+1].enteredAt > r.pending[len(r.pending)-1].index {
+		child = r.frames[step.Depth+1]
+	}
+
+	if child == nil {
+		return
+	}
+
+	for key := range child.touched {
+		t := cache.SStorage
+		if key.transient {
+			t = cache.TStorage
+		}
+
+		actual := step.Storage.ResultCache.XCachedLoad(key.address, key.slot, t)
+		if actual == nil {
+			actual = r.original[key]
+		}
+
+		if actual.Cmp(r.storage[key].Int) != 0 {
+			record.StorageWrites = append(record.StorageWrites, StorageWrite{
+				Address:   key.address,
+				Slot:      key.slot,
+				Value:     actual.Clone(),
+				Transient: key.transient,
+			})
+		}
+
+		frame.touched[key] = true
+	}
+}
+
+func (r *Recorder) AfterStep(step *instructions.ExecutionStep, err error) {
+	var pending *pendingStep
+	if len(r.pending) > 0 && r.pending[len(r.pending)-1].step == step {
+		pending = r.pending[len(r.pending)-1]
+	} else {
+		r.newRecord(step)
+		pending = &pendingStep{step: step, index: len(r.steps) - 1}
+		r.pending = append(r.pending, pending)
+	}
+
+	record := r.steps[pending.index]
+	frame := r.frames[step.Depth]
+
+	if err != nil {
+		record.Error = err.Error()
+	} else {
+		record.StorageWrites = pending.writes
+		for _, w := range pending.writes {
+			frame.touched[w.key()] = true
+		}
+	}
+
+	if pending.stack != nil {
+		r.recordStack(record, pending.stack, step.Stack.All())
+	}
+
+	r.recordMemory(record, frame, step.Memory.All())
+
+	if frameCreators[step.OpCode] {
+		r.reconcileStorage(record, step, frame)
+	}
+
+	for _, w := range record.StorageWrites {
+		r.storage[w.key()] = w.Value
+	}
+
+	record.ApplyAt = len(r.steps)
+	r.completion = append(r.completion, pending.index)
+	r.pending = r.pending[:len(r.pending)-1]
+}
@@ -0,0 +1,205 @@
+package timeTravel_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/instructions"
+	"github.com/SealSC/SealEVM/sim"
+	"github.com/SealSC/SealEVM/storage/cache"
+	"github.com/SealSC/SealEVM/timeTravel"
+	"github.com/SealSC/SealEVM/types"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	sender   = types.Address{0x5e}
+	contract = types.Address{19: 0xc0}
+	child    = types.Address{19: 0xc1}
+)
+
+// contract runs 200 times a loop writing i to memory at i and to slot i&7, then calls the
+// child and stores slot 0 at 0x40 in memory. The child writes its slots 0 and 5 and its
+// memory then reverts.
+var (
+	contractCode = "6000" + "5b8080528060071681905560010180" + "6100c8" + "1160025750" +
+		"5f5f5f5f5f73" + common.Bytes2Hex(child[:]) + "5af1" + "600054604052" + "00"
+	childCode = "602a600055" + "602a600052" + "602a600555" + "5f5ffd"
+)
+
+type watchedSlot struct {
+	address types.Address
+	slot    types.Slot
+}
+
+var watched = []watchedSlot{
+	{child, types.Slot{}},
+	{child, types.Slot{31: 5}},
+}
+
+func init() {
+	for i := byte(0); i < 8; i++ {
+		watched = append(watched, watchedSlot{contract, types.Slot{31: i}})
+	}
+}
+
+// liveState is the state of the VM right before a step.
+type liveState struct {
+	pc      uint64
+	depth   uint64
+	gas     uint64
+	stack   []*evmInt256.Int
+	memory  []byte
+	storage []uint64
+}
+
+// probe records the execution and keeps the live state of each step.
+type probe struct {
+	*timeTravel.Recorder
+	live []liveState
+}
+
+func (p *probe) BeforeStep(step *instructions.ExecutionStep) error {
+	state := liveState{
+		pc:     step.PC,
+		depth:  step.Depth,
+		gas:    step.Gas,
+		memory: append([]byte{}, step.Memory.All()...),
+	}
+
+	for _, item := range step.Stack.All() {
+		state.stack = append(state.stack, item.Clone())
+	}
+
+	for _, w := range watched {
+		var val uint64
+		if v := step.Storage.ResultCache.XCachedLoad(w.address, w.slot, cache.SStorage); v != nil {
+			val = v.Uint64()
+		}
+
+		state.storage = append(state.storage, val)
+	}
+
+	p.live = append(p.live, state)
+	return p.Recorder.BeforeStep(step)
+}
+
+func record(t *testing.T) (*timeTravel.Trace, []liveState) {
+	t.Helper()
+
+	state := sim.NewState(sim.Alloc{
+		sender:   {Balance: evmInt256.New(1e18)},
+		contract: {Code: common.FromHex(contractCode)},
+		child:    {Code: common.FromHex(childCode)},
+	})
+
+	p := &probe{Recorder: timeTravel.NewRecorder()}
+	msg := &sim.Message{From: sender, To: &contract, GasLimit: 1000000, GasPrice: evmInt256.New(0)}
+	result, err := sim.TraceMessage(state, &sim.BlockEnv{GasLimit: 30000000}, msg, p)
+	if err != nil || result.Err != nil {
+		t.Fatalf("execution failed: %v %v", err, result.Err)
+	}
+
+	trace := p.Trace()
+	if trace.Len() != len(p.live) || trace.Len() <= 1024 {
+		t.Fatalf("recorded %d steps of %d", trace.Len(), len(p.live))
+	}
+
+	return trace, p.live
+}
+
+// compare checks the state the cursor rebuilt at its position against the live one.
+func compare(t *testing.T, c *timeTravel.Cursor, live []liveState) {
+	t.Helper()
+
+	state := c.State()
+	want := live[state.Position]
+	if state.Step.PC != want.pc || state.Step.Depth != want.depth || state.Step.Gas != want.gas {
+		t.Fatalf("step %d is at pc %d, depth %d with %d gas, want %d, %d, %d", state.Position,
+			state.Step.PC, state.Step.Depth, state.Step.Gas, want.pc, want.depth, want.gas)
+	}
+
+	frame := state.Frames[len(state.Frames)-1]
+	if len(frame.Stack) != len(want.stack) {
+		t.Fatalf("step %d has a stack of %d, want %d", state.Position, len(frame.Stack), len(want.stack))
+	}
+
+	for i := range want.stack {
+		if frame.Stack[i].Cmp(want.stack[i].Int) != 0 {
+			t.Fatalf("step %d has %s at %d of the stack, want %s", state.Position, frame.Stack[i], i, want.stack[i])
+		}
+	}
+
+	//the memory expanded by the gas of the step is zero past the rebuilt one
+	if len(frame.Memory) > len(want.memory) || !bytes.Equal(frame.Memory, want.memory[:len(frame.Memory)]) ||
+		len(bytes.Trim(want.memory[len(frame.Memory):], "\x00")) != 0 {
+		t.Fatalf("step %d has memory %x, want %x", state.Position, frame.Memory, want.memory)
+	}
+
+	for i, w := range watched {
+		var val uint64
+		if v := state.Storage(w.address, w.slot); v != nil {
+			val = v.Uint64()
+		}
+
+		if val != want.storage[i] {
+			t.Fatalf("step %d has %d in slot %s of %s, want %d", state.Position, val, w.slot, w.address, want.storage[i])
+		}
+	}
+}
+
+func TestCursorRebuildsLiveState(t *testing.T) {
+	trace, live := record(t)
+	c := trace.NewCursor()
+
+	//forwards, with jumps across the checkpoints
+	for pos := 0; pos < trace.Len(); pos += 7 {
+		if err := c.Seek(pos); err != nil {
+			t.Fatal(err)
+		}
+
+		compare(t, c, live)
+	}
+
+	//backwards, step by step
+	if err := c.Seek(trace.Len() - 1); err != nil {
+		t.Fatal(err)
+	}
+
+	for {
+		compare(t, c, live)
+		if !c.Prev() {
+			break
+		}
+	}
+}
+
+func TestCursorDropsRevertedWrites(t *testing.T) {
+	trace, live := record(t)
+	c := trace.NewCursor()
+
+	reverted := -1
+	for pos, state := range live {
+		if state.depth == 1 && state.storage[0] == 42 {
+			reverted = pos
+		}
+	}
+
+	if reverted < 0 {
+		t.Fatalf("the child never wrote its slot")
+	}
+
+	//seen in the child, gone once it returned
+	for _, pos := range []int{reverted, reverted + 1, reverted + 2, trace.Len() - 1} {
+		if err := c.Seek(pos); err != nil {
+			t.Fatal(err)
+		}
+
+		compare(t, c, live)
+	}
+
+	if v := c.State().Storage(child, types.Slot{}); v != nil && !v.IsZero() {
+		t.Fatalf("slot 0 of the child is %s after the revert, want 0", v)
+	}
+}
@@ -0,0 +1,129 @@
+package timeTravel
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+const (
+	TraceVersion       = 1
+	checkpointInterval = 1024
+
+	//far above the memory any gas limit pays for
+	maxMemorySize = 1 << 32
+)
+
+var ErrCorruptedTrace = errors.New("corrupted trace")
+
+// Trace is the recorded execution. Position N of a trace is the VM state right before
+// step N executes, position Len() is the state after the last step.
+type Trace struct {
+	Version    int
+	Steps      []*StepRecord
+	Completion []int
+
+	applyAt [][]int
+
+	checkpointOnce sync.Once
+	checkpoints    []*snapshot
+}
+
+func newTrace(steps []*StepRecord, completion []int) *Trace {
+	t := &Trace{
+		Version:    TraceVersion,
+		Steps:      steps,
+		Completion: completion,
+	}
+
+	t.index()
+	return t
+}
+
+func (t *Trace) index() {
+	t.applyAt = make([][]int, len(t.Steps)+1)
+	for _, idx := range t.Completion {
+		at := t.Steps[idx].ApplyAt
+		t.applyAt[at] = append(t.applyAt[at], idx)
+	}
+}
+
+func (t *Trace) Len() int {
+	return len(t.Steps)
+}
+
+func (t *Trace) Encode(w io.Writer) error {
+	return json.NewEncoder(w).Encode(t)
+}
+
+func Decode(r io.Reader) (*Trace, error) {
+	t := &Trace{}
+	err := json.NewDecoder(r).Decode(t)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Version > TraceVersion {
+		return nil, errors.New("unsupported trace version")
+	}
+
+	for i, record := range t.Steps {
+		if record == nil {
+			return nil, fmt.Errorf("%w: step %d is missing", ErrCorruptedTrace, i)
+		}
+
+		if err = record.validate(); err != nil {
+			return nil, fmt.Errorf("%w: step %d: %v", ErrCorruptedTrace, i, err)
+		}
+	}
+
+	for _, idx := range t.Completion {
+		if idx < 0 || idx >= len(t.Steps) || t.Steps[idx].ApplyAt > len(t.Steps) || t.Steps[idx].ApplyAt <= idx {
+			return nil, ErrCorruptedTrace
+		}
+	}
+
+	t.index()
+	return t, nil
+}
+
+func (t *Trace) buildCheckpoints() {
+	t.checkpointOnce.Do(func() {
+		c := &Cursor{trace: t}
+		c.reset()
+
+		for {
+			if c.pos%checkpointInterval == 0 {
+				t.checkpoints = append(t.checkpoints, c.snapshot())
+			}
+
+			if c.pos >= len(t.Steps) {
+				break
+			}
+
+			c.advance()
+		}
+	})
+}
+
+// NewCursor returns a cursor at position 0.
+func (t *Trace) NewCursor() *Cursor {
+	t.buildCheckpoints()
+
+	c := &Cursor{trace: t}
+	c.reset()
+	return c
+}
+
+// Search returns the smallest position in [0, Len()] at which f returns true, f must be
+// false before and true from that position on, as in sort.Search.
+func (t *Trace) Search(f func(state *VMState) bool) int {
+	c := t.NewCursor()
+	return sort.Search(len(t.Steps)+1, func(pos int) bool {
+		_ = c.Seek(pos)
+		return f(c.State())
+	})
+}
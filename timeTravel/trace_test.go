@@ -0,0 +1,40 @@
+package timeTravel
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func trace(writes string) string {
+	return `{"Version":1,"Steps":[` +
+		`{"PC":0,"OpCode":82,"Depth":0,"EnterFrame":true,"StackPop":2,"MemorySize":32,"MemoryWrites":[` + writes + `],"ApplyAt":1},` +
+		`{"PC":1,"OpCode":0,"Depth":0,"MemorySize":32,"ApplyAt":2}` +
+		`],"Completion":[0,1]}`
+}
+
+func TestDecodeAppliesMemoryWrites(t *testing.T) {
+	tr, err := Decode(strings.NewReader(trace(`{"Offset":31,"Data":"0x2a"}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := tr.NewCursor()
+	c.Next()
+	memory := c.State().Frames[0].Memory
+	if len(memory) != 32 || memory[31] != 0x2a {
+		t.Fatalf("memory %x", memory)
+	}
+}
+
+func TestDecodeRejectsMemoryWritesOutOfFrame(t *testing.T) {
+	for _, writes := range []string{
+		`{"Offset":31,"Data":"0x2a2a"}`,
+		`{"Offset":64,"Data":"0x"}`,
+		`{"Offset":18446744073709551615,"Data":"0x2a"}`,
+	} {
+		if _, err := Decode(strings.NewReader(trace(writes))); !errors.Is(err, ErrCorruptedTrace) {
+			t.Fatalf("%s: got %v, want %v", writes, err, ErrCorruptedTrace)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package tracer
+
+import (
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/instructions"
+	"github.com/SealSC/SealEVM/opcodes"
+	"github.com/SealSC/SealEVM/precompiledContracts"
+	"github.com/SealSC/SealEVM/types"
+)
+
+// AccessListTracer is an instructions.IStepHook which collects the accounts and slots an
+// execution accesses, as an EIP-2930 access list in the order of the first accesses. The
+// excluded addresses and the precompiled contracts are left out, as go-ethereum does an
+// excluded address is still listed with the slots accessed in its storage.
+type AccessListTracer struct {
+	excluded  map[types.Address]bool
+	addresses []types.Address
+	slots     map[types.Address][]types.Slot
+	seen      map[types.Address]map[types.Slot]bool
+}
+
+// NewAccessListTracer starts from the entries of list, an access list to extend.
+func NewAccessListTracer(list []environment.AccessTuple, excluded ...types.Address) *AccessListTracer {
+	t := &AccessListTracer{
+		excluded: map[types.Address]bool{},
+		slots:    map[types.Address][]types.Slot{},
+		seen:     map[types.Address]map[types.Slot]bool{},
+	}
+
+	for _, addr := range excluded {
+		t.excluded[addr] = true
+	}
+
+	for _, tuple := range list {
+		t.addAddress(tuple.Address)
+		for _, slot := range tuple.StorageKeys {
+			t.addSlot(tuple.Address, slot)
+		}
+	}
+
+	return t
+}
+
+func (t *AccessListTracer) addAddress(addr types.Address) {
+	if t.excluded[addr] || precompiledContracts.IsPrecompiledContract(addr) || precompiledContracts.IsWithStoragePrecompiled(addr) {
+		return
+	}
+
+	t.listAddress(addr)
+}
+
+func (t *AccessListTracer) listAddress(addr types.Address) {
+	if t.seen[addr] == nil {
+		t.seen[addr] = map[types.Slot]bool{}
+		t.addresses = append(t.addresses, addr)
+	}
+}
+
+func (t *AccessListTracer) addSlot(addr types.Address, slot types.Slot) {
+	t.listAddress(addr)
+	if t.seen[addr][slot] {
+		return
+	}
+
+	t.seen[addr][slot] = true
+	t.slots[addr] = append(t.slots[addr], slot)
+}
+
+func (t *AccessListTracer) BeforeStep(step *instructions.ExecutionStep) error {
+	switch step.OpCode {
+	case opcodes.SLOAD, opcodes.SSTORE:
+		t.addSlot(step.Context.Address(), types.Int256ToSlot(step.Stack.PeekPos(0)))
+	case opcodes.BALANCE, opcodes.EXTCODESIZE, opcodes.EXTCODECOPY, opcodes.EXTCODEHASH, opcodes.SELFDESTRUCT:
+		t.addAddress(types.Int256ToAddress(step.Stack.PeekPos(0)))
+	case opcodes.CALL, opcodes.CALLCODE, opcodes.DELEGATECALL, opcodes.STATICCALL:
+		t.addAddress(types.Int256ToAddress(step.Stack.PeekPos(1)))
+	}
+
+	return nil
+}
+
+func (t *AccessListTracer) AfterStep(step *instructions.ExecutionStep, err error) {}
+
+// AccessList is the list collected so far.
+func (t *AccessListTracer) AccessList() []environment.AccessTuple {
+	list := make([]environment.AccessTuple, 0, len(t.addresses))
+	for _, addr := range t.addresses {
+		list = append(list, environment.AccessTuple{
+			Address:     addr,
+			StorageKeys: append([]types.Slot{}, t.slots[addr]...),
+		})
+	}
+
+	return list
+}
+
+// Equal tells whether t and other collected the same accounts and slots, in any order.
+func (t *AccessListTracer) Equal(other *AccessListTracer) bool {
+	if len(t.seen) != len(other.seen) {
+		return false
+	}
+
+	for addr, slots := range t.seen {
+		otherSlots, ok := other.seen[addr]
+		if !ok || len(slots) != len(otherSlots) {
+			return false
+		}
+
+		for slot := range slots {
+			if !otherSlots[slot] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,150 @@
+package tracer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/instructions"
+	"github.com/SealSC/SealEVM/opcodes"
+	"github.com/SealSC/SealEVM/types"
+)
+
+type hexUint64 uint64
+
+func (h hexUint64) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fmt.Sprintf("0x%x", uint64(h)))
+}
+
+// eip3155Step is one line of an EIP-3155 trace, Depth starts from 1.
+type eip3155Step struct {
+	PC         uint64           `json:"pc"`
+	Op         opcodes.OpCode   `json:"op"`
+	Gas        hexUint64        `json:"gas"`
+	GasCost    hexUint64        `json:"gasCost"`
+	Memory     *types.Bytes     `json:"memory,omitempty"`
+	MemSize    uint64           `json:"memSize"`
+	Stack      []*evmInt256.Int `json:"stack"`
+	ReturnData *types.Bytes     `json:"returnData,omitempty"`
+	Depth      uint64           `json:"depth"`
+	Refund     uint64           `json:"refund"`
+	OpName     string           `json:"opName"`
+	Error      string           `json:"error,omitempty"`
+}
+
+type eip3155Summary struct {
+	Output  types.Bytes `json:"output"`
+	GasUsed hexUint64   `json:"gasUsed"`
+	Pass    bool        `json:"pass"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// JSONLogger is an instructions.IStepHook which writes an EIP-3155 trace, one JSON object
+// per line. A step which fails after it started is written again with its error, as geth does.
+type JSONLogger struct {
+	cfg     Config
+	encoder *json.Encoder
+	frames  frameTracker
+	started []*instructions.ExecutionStep
+	count   int
+	err     error
+}
+
+func NewJSONLogger(cfg *Config, w io.Writer) *JSONLogger {
+	l := &JSONLogger{
+		encoder: json.NewEncoder(w),
+	}
+
+	if cfg != nil {
+		l.cfg = *cfg
+	}
+
+	return l
+}
+
+// Err returns the first error of the underlying writer.
+func (l *JSONLogger) Err() error {
+	return l.err
+}
+
+func (l *JSONLogger) write(v interface{}) {
+	if l.err == nil {
+		l.err = l.encoder.Encode(v)
+	}
+}
+
+func (l *JSONLogger) writeStep(step *instructions.ExecutionStep, err error) {
+	l.frames.enter(step)
+	if l.cfg.Limit > 0 && l.count >= l.cfg.Limit {
+		return
+	}
+
+	mem := l.frames.memory(step)
+	line := &eip3155Step{
+		PC:      step.PC,
+		Op:      step.OpCode,
+		Gas:     hexUint64(step.Gas),
+		GasCost: hexUint64(step.GasCost),
+		MemSize: uint64(len(mem)),
+		Stack:   []*evmInt256.Int{},
+		Depth:   step.Depth + 1,
+		OpName:  step.OpCode.String(),
+	}
+
+	if !l.cfg.DisableStack {
+		line.Stack = append(line.Stack, step.Stack.All()...)
+	}
+
+	if l.cfg.EnableMemory {
+		memory := types.Bytes(mem)
+		line.Memory = &memory
+	}
+
+	if l.cfg.EnableReturnData {
+		returnData := types.Bytes(step.ReturnData)
+		line.ReturnData = &returnData
+	}
+
+	if err != nil {
+		line.Error = err.Error()
+	}
+
+	l.count += 1
+	l.write(line)
+}
+
+func (l *JSONLogger) BeforeStep(step *instructions.ExecutionStep) error {
+	l.writeStep(step, nil)
+	l.started = append(l.started, step)
+	return nil
+}
+
+func (l *JSONLogger) AfterStep(step *instructions.ExecutionStep, err error) {
+	started := len(l.started) > 0 && l.started[len(l.started)-1] == step
+	if started {
+		l.started = l.started[:len(l.started)-1]
+	}
+
+	if err != nil || !started {
+		l.writeStep(step, err)
+	}
+
+	l.frames.leave(step)
+}
+
+// WriteSummary writes the closing line of the trace.
+func (l *JSONLogger) WriteSummary(output []byte, gasUsed uint64, err error) error {
+	summary := &eip3155Summary{
+		Output:  output,
+		GasUsed: hexUint64(gasUsed),
+		Pass:    err == nil,
+	}
+
+	if err != nil {
+		summary.Error = err.Error()
+	}
+
+	l.write(summary)
+	return l.err
+}
@@ -0,0 +1,178 @@
+package tracer
+
+import (
+	"encoding/hex"
+
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/instructions"
+	"github.com/SealSC/SealEVM/opcodes"
+	"github.com/SealSC/SealEVM/types"
+)
+
+// StructLog is one step in the struct-log format of geth's debug_trace* methods.
+// Depth starts from 1, memory words and storage entries are hex without 0x prefix.
+type StructLog struct {
+	PC         uint64            `json:"pc"`
+	Op         string            `json:"op"`
+	Gas        uint64            `json:"gas"`
+	GasCost    uint64            `json:"gasCost"`
+	Depth      uint64            `json:"depth"`
+	Error      string            `json:"error,omitempty"`
+	Stack      []*evmInt256.Int  `json:"stack"`
+	ReturnData types.Bytes       `json:"returnData,omitempty"`
+	Memory     []string          `json:"memory,omitempty"`
+	Storage    map[string]string `json:"storage,omitempty"`
+}
+
+// ExecutionResult is the result of a struct-log trace, ReturnValue is hex without 0x prefix.
+type ExecutionResult struct {
+	Gas         uint64       `json:"gas"`
+	Failed      bool         `json:"failed"`
+	ReturnValue string       `json:"returnValue"`
+	StructLogs  []*StructLog `json:"structLogs"`
+}
+
+type pendingLog struct {
+	step *instructions.ExecutionStep
+	log  *StructLog
+	slot types.Slot
+}
+
+// StructLogger is an instructions.IStepHook which collects the steps in struct-log format.
+type StructLogger struct {
+	cfg    Config
+	logs   []*StructLog
+	frames frameTracker
+
+	pending []*pendingLog
+	storage map[types.Address]map[types.Slot]*evmInt256.Int
+}
+
+func NewStructLogger(cfg *Config) *StructLogger {
+	l := &StructLogger{
+		storage: map[types.Address]map[types.Slot]*evmInt256.Int{},
+	}
+
+	if cfg != nil {
+		l.cfg = *cfg
+	}
+
+	return l
+}
+
+func (l *StructLogger) StructLogs() []*StructLog {
+	return l.logs
+}
+
+// Result wraps the collected logs with the outcome of the execution.
+func (l *StructLogger) Result(gasUsed uint64, returnData []byte, err error) *ExecutionResult {
+	logs := l.logs
+	if logs == nil {
+		logs = []*StructLog{}
+	}
+
+	return &ExecutionResult{
+		Gas:         gasUsed,
+		Failed:      err != nil,
+		ReturnValue: hex.EncodeToString(returnData),
+		StructLogs:  logs,
+	}
+}
+
+func (l *StructLogger) contractStorage(address types.Address) map[types.Slot]*evmInt256.Int {
+	if l.storage[address] == nil {
+		l.storage[address] = map[types.Slot]*evmInt256.Int{}
+	}
+
+	return l.storage[address]
+}
+
+func (l *StructLogger) snapshotStorage(address types.Address) map[string]string {
+	snapshot := map[string]string{}
+	for slot, val := range l.storage[address] {
+		word := types.Int256ToHash(val)
+		snapshot[hex.EncodeToString(slot[:])] = hex.EncodeToString(word[:])
+	}
+
+	return snapshot
+}
+
+func (l *StructLogger) newLog(step *instructions.ExecutionStep) *StructLog {
+	l.frames.enter(step)
+
+	log := &StructLog{
+		PC:      step.PC,
+		Op:      step.OpCode.String(),
+		Gas:     step.Gas,
+		GasCost: step.GasCost,
+		Depth:   step.Depth + 1,
+	}
+
+	if !l.cfg.DisableStack {
+		log.Stack = cloneStack(step.Stack.All())
+	}
+
+	if l.cfg.EnableReturnData {
+		log.ReturnData = types.Bytes(step.ReturnData).Clone()
+	}
+
+	if l.cfg.EnableMemory {
+		mem := l.frames.memory(step)
+		log.Memory = make([]string, 0, (len(mem)+31)/32)
+		for i := 0; i < len(mem); i += 32 {
+			end := i + 32
+			if end > len(mem) {
+				end = len(mem)
+			}
+			log.Memory = append(log.Memory, hex.EncodeToString(mem[i:end]))
+		}
+	}
+
+	if l.cfg.Limit == 0 || len(l.logs) < l.cfg.Limit {
+		l.logs = append(l.logs, log)
+	}
+
+	return log
+}
+
+func (l *StructLogger) BeforeStep(step *instructions.ExecutionStep) error {
+	pending := &pendingLog{
+		step: step,
+		log:  l.newLog(step),
+	}
+
+	switch step.OpCode {
+	case opcodes.SLOAD:
+		pending.slot = types.Int256ToSlot(step.Stack.Peek())
+	case opcodes.SSTORE:
+		if !l.cfg.DisableStorage {
+			slot := types.Int256ToSlot(step.Stack.PeekPos(0))
+			l.contractStorage(step.Context.Address())[slot] = step.Stack.PeekPos(1).Clone()
+			pending.log.Storage = l.snapshotStorage(step.Context.Address())
+		}
+	}
+
+	l.pending = append(l.pending, pending)
+	return nil
+}
+
+func (l *StructLogger) AfterStep(step *instructions.ExecutionStep, err error) {
+	var pending *pendingLog
+	if len(l.pending) > 0 && l.pending[len(l.pending)-1].step == step {
+		pending = l.pending[len(l.pending)-1]
+		l.pending = l.pending[:len(l.pending)-1]
+	} else {
+		pending = &pendingLog{step: step, log: l.newLog(step)}
+	}
+
+	log := pending.log
+	if err != nil {
+		log.Error = err.Error()
+	} else if !l.cfg.DisableStorage && step.OpCode == opcodes.SLOAD {
+		//the loaded value is only known after the step
+		l.contractStorage(step.Context.Address())[pending.slot] = step.Stack.Peek().Clone()
+		log.Storage = l.snapshotStorage(step.Context.Address())
+	}
+
+	l.frames.leave(step)
+}
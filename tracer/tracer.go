@@ -0,0 +1,60 @@
+package tracer
+
+import (
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/instructions"
+)
+
+// Config selects the optional parts of a traced step, as the geth logger config does.
+type Config struct {
+	EnableMemory     bool
+	DisableStack     bool
+	DisableStorage   bool
+	EnableReturnData bool
+
+	// Limit is the maximum number of recorded steps, 0 means no limit
+	Limit int
+}
+
+// frameTracker follows the frames of an execution. The memory of a step is expanded
+// before the hook is called, tracers report the size it had before the step as geth does.
+type frameTracker struct {
+	memorySize []uint64
+	lastDepth  uint64
+	started    bool
+}
+
+func (f *frameTracker) enter(step *instructions.ExecutionStep) {
+	if !f.started || step.Depth > f.lastDepth || uint64(len(f.memorySize)) <= step.Depth {
+		for uint64(len(f.memorySize)) <= step.Depth {
+			f.memorySize = append(f.memorySize, 0)
+		}
+		f.memorySize[step.Depth] = 0
+	}
+
+	f.started = true
+	f.lastDepth = step.Depth
+}
+
+func (f *frameTracker) memory(step *instructions.ExecutionStep) []byte {
+	mem := step.Memory.All()
+	if size := f.memorySize[step.Depth]; size < uint64(len(mem)) {
+		mem = mem[:size]
+	}
+
+	return mem
+}
+
+func (f *frameTracker) leave(step *instructions.ExecutionStep) {
+	f.enter(step)
+	f.memorySize[step.Depth] = step.Memory.Size()
+}
+
+func cloneStack(items []*evmInt256.Int) []*evmInt256.Int {
+	replica := make([]*evmInt256.Int, len(items))
+	for i, item := range items {
+		replica[i] = item.Clone()
+	}
+
+	return replica
+}
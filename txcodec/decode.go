@@ -0,0 +1,251 @@
+package txcodec
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/SealSC/SealEVM/crypto/hashes"
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// fieldCount is the number of fields of each type, with the signature.
+var fieldCount = map[byte]int{
+	LegacyTxType:     9,
+	AccessListTxType: 11,
+	DynamicFeeTxType: 12,
+	BlobTxType:       14,
+	SetCodeTxType:    13,
+}
+
+type rlpAccessTuple struct {
+	Address     types.Address
+	StorageKeys []types.Hash
+}
+
+// fieldReader decodes the fields of a list in order, the first error is kept.
+type fieldReader struct {
+	fields []rlp.RawValue
+	next   int
+	err    error
+}
+
+func newFieldReader(list []byte) (*fieldReader, error) {
+	r := &fieldReader{}
+	if err := rlp.DecodeBytes(list, &r.fields); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *fieldReader) read(v interface{}) {
+	if r.err != nil {
+		return
+	}
+
+	if r.next >= len(r.fields) {
+		r.err = fmt.Errorf("missing field %d", r.next)
+		return
+	}
+
+	if r.err = rlp.DecodeBytes(r.fields[r.next], v); r.err != nil {
+		r.err = fmt.Errorf("field %d: %w", r.next, r.err)
+	}
+	r.next++
+}
+
+func (r *fieldReader) uint() uint64 {
+	var v uint64
+	r.read(&v)
+	return v
+}
+
+func (r *fieldReader) big() *evmInt256.Int {
+	v := new(big.Int)
+	r.read(v)
+	return evmInt256.FromBigInt(v)
+}
+
+func (r *fieldReader) to() *types.Address {
+	var to []byte
+	r.read(&to)
+	if r.err != nil || len(to) == 0 {
+		return nil
+	}
+
+	if len(to) != types.AddressBytesLen {
+		r.err = fmt.Errorf("invalid recipient length %d", len(to))
+		return nil
+	}
+
+	var addr types.Address
+	addr.SetBytes(to)
+	return &addr
+}
+
+func (r *fieldReader) accessList() []environment.AccessTuple {
+	var list []rlpAccessTuple
+	r.read(&list)
+
+	out := make([]environment.AccessTuple, len(list))
+	for i, t := range list {
+		out[i] = environment.AccessTuple{Address: t.Address, StorageKeys: t.StorageKeys}
+	}
+
+	return out
+}
+
+func (r *fieldReader) authorizationList() []Authorization {
+	var items []rlp.RawValue
+	r.read(&items)
+
+	list := make([]Authorization, len(items))
+	for i, item := range items {
+		if r.err != nil {
+			return nil
+		}
+
+		auth, err := newFieldReader(item)
+		if err != nil {
+			r.err = fmt.Errorf("authorization %d: %w", i, err)
+			return nil
+		}
+
+		list[i].ChainID = auth.big()
+		auth.read(&list[i].Address)
+		list[i].Nonce = auth.uint()
+		auth.read(&list[i].YParity)
+		list[i].R, list[i].S = auth.big(), auth.big()
+		if auth.err == nil && auth.next != len(auth.fields) {
+			auth.err = fmt.Errorf("%d fields, want %d", len(auth.fields), auth.next)
+		}
+
+		if auth.err != nil {
+			r.err = fmt.Errorf("authorization %d: %w", i, auth.err)
+		}
+	}
+
+	return list
+}
+
+// Decode decodes a raw legacy, EIP-2930, EIP-1559, EIP-4844 or EIP-7702 transaction, as
+// given to eth_sendRawTransaction, and recovers its sender. Blob transactions may come in
+// their network form, with the blobs, which are not checked. The authorizations of an
+// EIP-7702 transaction are not checked either, see Authorization.Authority.
+func Decode(raw []byte) (*Transaction, error) {
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("%w: empty", ErrInvalidTransaction)
+	}
+
+	tx := &Transaction{}
+	payload := raw
+	if raw[0] <= 0x7f {
+		tx.Type = raw[0]
+		payload = raw[1:]
+	}
+
+	count, ok := fieldCount[tx.Type]
+	if !ok {
+		return nil, fmt.Errorf("%w %d", ErrUnsupportedType, tx.Type)
+	}
+
+	r, err := newFieldReader(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTransaction, err)
+	}
+
+	if tx.Type == BlobTxType && len(r.fields) == 4 {
+		//network form: [tx, blobs, commitments, proofs]
+		kind, _, _, err := rlp.Split(r.fields[0])
+		if err == nil && kind == rlp.List {
+			if r, err = newFieldReader(r.fields[0]); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidTransaction, err)
+			}
+		}
+	}
+
+	if len(r.fields) != count {
+		return nil, fmt.Errorf("%w: type %d has %d fields, want %d", ErrInvalidTransaction, tx.Type, len(r.fields), count)
+	}
+
+	if tx.Type != LegacyTxType {
+		tx.ChainID = r.big()
+	}
+
+	tx.Nonce = r.uint()
+	switch tx.Type {
+	case LegacyTxType, AccessListTxType:
+		tx.GasPrice = r.big()
+	default:
+		tx.GasTipCap = r.big()
+		tx.GasFeeCap = r.big()
+	}
+
+	tx.GasLimit = r.uint()
+	tx.To = r.to()
+	tx.Value = r.big()
+	r.read(&tx.Data)
+	if tx.Type != LegacyTxType {
+		tx.AccessList = r.accessList()
+	}
+
+	switch tx.Type {
+	case BlobTxType:
+		tx.BlobGasFeeCap = r.big()
+		r.read(&tx.BlobHashes)
+		if tx.BlobHashes == nil {
+			tx.BlobHashes = []types.Hash{}
+		}
+	case SetCodeTxType:
+		tx.AuthorizationList = r.authorizationList()
+	}
+
+	tx.V, tx.R, tx.S = r.big(), r.big(), r.big()
+	if r.err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTransaction, r.err)
+	}
+
+	if err = tx.validate(); err != nil {
+		return nil, err
+	}
+
+	if tx.Type == LegacyTxType {
+		tx.ChainID = legacyChainID(tx.V)
+	}
+
+	if tx.From, err = tx.Sender(); err != nil {
+		return nil, err
+	}
+
+	if tx.Raw, err = tx.Encode(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTransaction, err)
+	}
+
+	tx.Hash.SetBytes(hashes.Keccak256(tx.Raw))
+	return tx, nil
+}
+
+func (tx *Transaction) validate() error {
+	switch {
+	case (tx.Type == BlobTxType || tx.Type == SetCodeTxType) && tx.To == nil:
+		return fmt.Errorf("%w: type %d transaction without recipient", ErrInvalidTransaction, tx.Type)
+	case tx.Type == SetCodeTxType && len(tx.AuthorizationList) == 0:
+		return fmt.Errorf("%w: empty authorization list", ErrInvalidTransaction)
+	}
+
+	return nil
+}
+
+// legacyChainID is the chain id of a legacy transaction signed with EIP-155, where
+// v = chainID * 2 + 35 + y parity, nil for one signed without it.
+func legacyChainID(v *evmInt256.Int) *evmInt256.Int {
+	if v.Cmp(big.NewInt(35)) < 0 {
+		return nil
+	}
+
+	chainID := new(big.Int).Sub(v.Int, big.NewInt(35))
+	return evmInt256.FromBigInt(chainID.Rsh(chainID, 1))
+}
@@ -0,0 +1,250 @@
+package txcodec
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+
+	"github.com/SealSC/SealEVM/crypto/hashes"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// authorizationMagic prefixes the payload signed by an EIP-7702 authority.
+const authorizationMagic = 0x05
+
+func bigOf(i *evmInt256.Int) *big.Int {
+	if i == nil {
+		return new(big.Int)
+	}
+
+	return i.Int
+}
+
+func (tx *Transaction) rlpTo() []byte {
+	if tx.To == nil {
+		return []byte{}
+	}
+
+	return tx.To[:]
+}
+
+func (tx *Transaction) rlpAccessList() []rlpAccessTuple {
+	list := make([]rlpAccessTuple, len(tx.AccessList))
+	for i, t := range tx.AccessList {
+		list[i] = rlpAccessTuple{Address: t.Address, StorageKeys: t.StorageKeys}
+	}
+
+	return list
+}
+
+func (a *Authorization) unsigned() []interface{} {
+	return []interface{}{bigOf(a.ChainID), a.Address, a.Nonce}
+}
+
+func (tx *Transaction) rlpAuthorizationList() []interface{} {
+	list := make([]interface{}, len(tx.AuthorizationList))
+	for i := range tx.AuthorizationList {
+		a := &tx.AuthorizationList[i]
+		list[i] = append(a.unsigned(), a.YParity, bigOf(a.R), bigOf(a.S))
+	}
+
+	return list
+}
+
+// unsigned are the fields of tx without the signature.
+func (tx *Transaction) unsigned() ([]interface{}, error) {
+	switch tx.Type {
+	case LegacyTxType:
+		return []interface{}{tx.Nonce, bigOf(tx.GasPrice), tx.GasLimit, tx.rlpTo(), bigOf(tx.Value), []byte(tx.Data)}, nil
+	case AccessListTxType:
+		return []interface{}{bigOf(tx.ChainID), tx.Nonce, bigOf(tx.GasPrice), tx.GasLimit, tx.rlpTo(), bigOf(tx.Value), []byte(tx.Data),
+			tx.rlpAccessList()}, nil
+	}
+
+	fields := []interface{}{bigOf(tx.ChainID), tx.Nonce, bigOf(tx.GasTipCap), bigOf(tx.GasFeeCap), tx.GasLimit, tx.rlpTo(), bigOf(tx.Value),
+		[]byte(tx.Data), tx.rlpAccessList()}
+
+	switch tx.Type {
+	case DynamicFeeTxType:
+		return fields, nil
+	case BlobTxType:
+		blobHashes := tx.BlobHashes
+		if blobHashes == nil {
+			blobHashes = []types.Hash{}
+		}
+		return append(fields, bigOf(tx.BlobGasFeeCap), blobHashes), nil
+	case SetCodeTxType:
+		return append(fields, tx.rlpAuthorizationList()), nil
+	}
+
+	return nil, fmt.Errorf("%w %d", ErrUnsupportedType, tx.Type)
+}
+
+func (tx *Transaction) envelope(fields []interface{}) ([]byte, error) {
+	payload, err := rlp.EncodeToBytes(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	if tx.Type == LegacyTxType {
+		return payload, nil
+	}
+
+	return append([]byte{tx.Type}, payload...), nil
+}
+
+// Encode is the canonical encoding of the signed tx, a blob transaction is encoded without
+// its blobs.
+func (tx *Transaction) Encode() ([]byte, error) {
+	fields, err := tx.unsigned()
+	if err != nil {
+		return nil, err
+	}
+
+	return tx.envelope(append(fields, bigOf(tx.V), bigOf(tx.R), bigOf(tx.S)))
+}
+
+// SigningHash is the hash the sender signs, a legacy transaction with a ChainID is signed
+// with EIP-155.
+func (tx *Transaction) SigningHash() (types.Hash, error) {
+	var hash types.Hash
+
+	fields, err := tx.unsigned()
+	if err != nil {
+		return hash, err
+	}
+
+	if tx.Type == LegacyTxType && tx.ChainID != nil {
+		fields = append(fields, bigOf(tx.ChainID), uint(0), uint(0))
+	}
+
+	payload, err := tx.envelope(fields)
+	if err != nil {
+		return hash, err
+	}
+
+	hash.SetBytes(hashes.Keccak256(payload))
+	return hash, nil
+}
+
+// yParity is the recovery id of the signature of tx.
+func (tx *Transaction) yParity() *big.Int {
+	v := bigOf(tx.V)
+	if tx.Type != LegacyTxType {
+		return v
+	}
+
+	if tx.ChainID == nil {
+		return new(big.Int).Sub(v, big.NewInt(27))
+	}
+
+	//v = chainID * 2 + 35 + y parity
+	parity := new(big.Int).Sub(v, big.NewInt(35))
+	return parity.Sub(parity, new(big.Int).Lsh(bigOf(tx.ChainID), 1))
+}
+
+func recoverAddress(hash types.Hash, parity *big.Int, r, s *evmInt256.Int) (types.Address, error) {
+	var addr types.Address
+
+	if !parity.IsUint64() || parity.Uint64() > 1 || !crypto.ValidateSignatureValues(byte(parity.Uint64()), bigOf(r), bigOf(s), true) {
+		return addr, ErrInvalidSignature
+	}
+
+	sig := make([]byte, 65)
+	bigOf(r).FillBytes(sig[:32])
+	bigOf(s).FillBytes(sig[32:64])
+	sig[64] = byte(parity.Uint64())
+
+	pub, err := crypto.SigToPub(hash[:], sig)
+	if err != nil {
+		return addr, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	return types.Address(crypto.PubkeyToAddress(*pub)), nil
+}
+
+// Sender recovers the signer of tx.
+func (tx *Transaction) Sender() (types.Address, error) {
+	hash, err := tx.SigningHash()
+	if err != nil {
+		return types.Address{}, err
+	}
+
+	return recoverAddress(hash, tx.yParity(), tx.R, tx.S)
+}
+
+// Sign signs tx with key and sets its signature, From, Raw and Hash. Typed transactions
+// need a ChainID, a legacy one is signed with EIP-155 when it has one.
+func Sign(tx *Transaction, key *ecdsa.PrivateKey) error {
+	if tx.Type != LegacyTxType && tx.ChainID == nil {
+		return fmt.Errorf("%w: type %d transaction without chain id", ErrInvalidTransaction, tx.Type)
+	}
+
+	if err := tx.validate(); err != nil {
+		return err
+	}
+
+	hash, err := tx.SigningHash()
+	if err != nil {
+		return err
+	}
+
+	sig, err := crypto.Sign(hash[:], key)
+	if err != nil {
+		return err
+	}
+
+	v := new(big.Int).SetUint64(uint64(sig[64]))
+	if tx.Type == LegacyTxType {
+		if tx.ChainID != nil {
+			v.Add(v, new(big.Int).Lsh(bigOf(tx.ChainID), 1))
+			v.Add(v, big.NewInt(35))
+		} else {
+			v.Add(v, big.NewInt(27))
+		}
+	}
+
+	tx.V = evmInt256.FromBigInt(v)
+	tx.R = evmInt256.FromBytes(sig[:32])
+	tx.S = evmInt256.FromBytes(sig[32:64])
+	tx.From = types.Address(crypto.PubkeyToAddress(key.PublicKey))
+
+	if tx.Raw, err = tx.Encode(); err != nil {
+		return err
+	}
+
+	tx.Hash.SetBytes(hashes.Keccak256(tx.Raw))
+	return nil
+}
+
+// SigningHash is the hash the authority signs.
+func (a *Authorization) SigningHash() types.Hash {
+	var hash types.Hash
+
+	payload, _ := rlp.EncodeToBytes(a.unsigned())
+	hash.SetBytes(hashes.Keccak256(append([]byte{authorizationMagic}, payload...)))
+	return hash
+}
+
+// Authority recovers the signer of a. An invalid signature does not make the transaction
+// invalid, the authorization is skipped.
+func (a *Authorization) Authority() (types.Address, error) {
+	return recoverAddress(a.SigningHash(), new(big.Int).SetUint64(uint64(a.YParity)), a.R, a.S)
+}
+
+// SignAuthorization signs a with key.
+func SignAuthorization(a *Authorization, key *ecdsa.PrivateKey) error {
+	hash := a.SigningHash()
+	sig, err := crypto.Sign(hash[:], key)
+	if err != nil {
+		return err
+	}
+
+	a.YParity = sig[64]
+	a.R = evmInt256.FromBytes(sig[:32])
+	a.S = evmInt256.FromBytes(sig[32:64])
+	return nil
+}
@@ -0,0 +1,141 @@
+// Package txcodec decodes signed Ethereum transactions from their raw form, recovers their
+// senders and maps them to the execution context of SealEVM. The encoding and signing
+// side is there to build transactions in tests.
+package txcodec
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/types"
+)
+
+// EIP-2718 transaction types
+const (
+	LegacyTxType     = 0x00
+	AccessListTxType = 0x01
+	DynamicFeeTxType = 0x02
+	BlobTxType       = 0x03
+	SetCodeTxType    = 0x04
+)
+
+// intrinsic gas SealEVM does not charge itself
+const (
+	accessListAddressGas    = 2400
+	accessListStorageKeyGas = 1900
+	initCodeWordGas         = 2
+	authorizationGas        = 25000
+)
+
+var (
+	ErrInvalidTransaction = errors.New("invalid transaction")
+	ErrUnsupportedType    = errors.New("unsupported transaction type")
+	ErrInvalidSignature   = errors.New("invalid signature")
+	ErrIntrinsicGas       = errors.New("intrinsic gas too low")
+)
+
+// Authorization is an entry of the authorization list of an EIP-7702 transaction.
+type Authorization struct {
+	ChainID *evmInt256.Int
+	Address types.Address
+	Nonce   uint64
+	YParity uint8
+	R, S    *evmInt256.Int
+}
+
+// Transaction is a signed transaction of any type, the fields a type does not have are
+// nil. GasPrice is set for legacy and access list transactions, GasFeeCap and GasTipCap
+// for the later types. ChainID is nil for a legacy transaction signed without EIP-155,
+// V is the y parity of the typed transactions.
+//
+// From, Hash and Raw are set by Decode and Sign, Raw is the canonical encoding, without
+// the blobs of a blob transaction in its network form.
+type Transaction struct {
+	Type              byte
+	ChainID           *evmInt256.Int
+	Nonce             uint64
+	GasPrice          *evmInt256.Int
+	GasTipCap         *evmInt256.Int
+	GasFeeCap         *evmInt256.Int
+	GasLimit          uint64
+	To                *types.Address
+	Value             *evmInt256.Int
+	Data              types.Bytes
+	AccessList        []environment.AccessTuple
+	BlobGasFeeCap     *evmInt256.Int
+	BlobHashes        []types.Hash
+	AuthorizationList []Authorization
+
+	V, R, S *evmInt256.Int
+
+	From types.Address
+	Hash types.Hash
+	Raw  []byte
+}
+
+// EffectiveGasPrice is the price paid per gas in a block of baseFee.
+func (tx *Transaction) EffectiveGasPrice(baseFee *evmInt256.Int) *evmInt256.Int {
+	if tx.GasFeeCap == nil {
+		return valueOf(tx.GasPrice)
+	}
+
+	price := valueOf(baseFee)
+	price.Add(valueOf(tx.GasTipCap))
+	if price.GT(tx.GasFeeCap) {
+		return tx.GasFeeCap.Clone()
+	}
+
+	return price
+}
+
+// extraIntrinsicGas is the part of the intrinsic gas SealEVM does not charge itself.
+func (tx *Transaction) extraIntrinsicGas() uint64 {
+	var gas uint64
+	for _, tuple := range tx.AccessList {
+		gas += accessListAddressGas + uint64(len(tuple.StorageKeys))*accessListStorageKeyGas
+	}
+
+	if tx.To == nil {
+		gas += (uint64(len(tx.Data)) + 31) / 32 * initCodeWordGas
+	}
+
+	return gas + uint64(len(tx.AuthorizationList))*authorizationGas
+}
+
+// Context is the execution context of tx in block. The gas limit of the transaction is
+// lowered by the intrinsic gas SealEVM does not charge: the access list, the words of the
+// init code and the authorizations. Applying the authorizations is left to the caller.
+func (tx *Transaction) Context(block environment.Block) (*environment.Context, error) {
+	extra := tx.extraIntrinsicGas()
+	if tx.GasLimit < extra {
+		return nil, fmt.Errorf("%w: have %d, want more than %d", ErrIntrinsicGas, tx.GasLimit, extra)
+	}
+
+	return &environment.Context{
+		Block: block,
+		Transaction: environment.Transaction{
+			TxHash:     tx.Hash,
+			Origin:     tx.From,
+			To:         tx.To,
+			GasPrice:   tx.EffectiveGasPrice(block.BaseFee),
+			GasLimit:   evmInt256.New(tx.GasLimit - extra),
+			BlobHashes: tx.BlobHashes,
+			AccessList: tx.AccessList,
+		},
+		Message: environment.Message{
+			Caller: tx.From,
+			Value:  valueOf(tx.Value),
+			Data:   tx.Data,
+		},
+	}, nil
+}
+
+func valueOf(i *evmInt256.Int) *evmInt256.Int {
+	if i == nil {
+		return evmInt256.New(0)
+	}
+
+	return i.Clone()
+}
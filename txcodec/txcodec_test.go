@@ -0,0 +1,170 @@
+package txcodec_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/SealSC/SealEVM/environment"
+	"github.com/SealSC/SealEVM/evmInt256"
+	"github.com/SealSC/SealEVM/txcodec"
+	"github.com/SealSC/SealEVM/types"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	from    = types.Address(crypto.PubkeyToAddress(key.PublicKey))
+	to      = types.Address{19: 0xaa}
+	chainID = evmInt256.New(1337)
+)
+
+// transactions signed by go-ethereum 1.13 with key for the chain 1337
+var gethVectors = []struct {
+	txType byte
+	raw    string
+	hash   string
+}{
+	{
+		txcodec.LegacyTxType,
+		"0xf8670184773594008252089400000000000000000000000000000000000000aa05820102820a96a015a43103f96808b211bc38c883d839bfe189cb993fd55634e97bf95113a517a2a00de60903fa1f5823030b85fe44d8eda800e01199646e4135caa1a7341b648347",
+		"0x11a68f3770f2926dc12d3fdf1ca1fb84d08349daa74427b4b5ef32c28f82d706",
+	},
+	{
+		txcodec.AccessListTxType,
+		"0x01f8a082053902847735940082c3509400000000000000000000000000000000000000aa0503f838f79400000000000000000000000000000000000000aae1a0000000000000000000000000000000000000000000000000000000000000000101a0de45a7766f59a26ddd32cfbe51f1418e92a01fecfcc3daa8f9ce6468d550cb6aa0602ab90528eb6292e73e43574528152e5a396f4a8a348bae88ea6236bc17b018",
+		"0xd6a3a2d70cee891079dfdb2dc1583c84f06b1e890d28271e4863aaa3c5d753ab",
+	},
+	{
+		txcodec.DynamicFeeTxType,
+		"0x02f89382053903843b9aca0084b2d05e0082ea608080826000f838f79400000000000000000000000000000000000000aae1a0000000000000000000000000000000000000000000000000000000000000000180a04949d7826a30a6651dcda3a7ca6251b322c701464cca876315a1a9c4c66ee2d7a068e373a5e1938e7b742f1c0b8dcce5ce4c9d55ea636d209e849c911dd05c5d92",
+		"0x2e5e9389635000492bb4188c2e85cf95c8da1593e918fe6b833a7dcd39583ed8",
+	},
+	{
+		txcodec.BlobTxType,
+		"0x03f8c982053904843b9aca0084b2d05e00830111709400000000000000000000000000000000000000aa8080f838f79400000000000000000000000000000000000000aae1a0000000000000000000000000000000000000000000000000000000000000000107e1a0010000000000000000000000000000000000000000000000000000000000000201a0322cdc3f189f8fc56e36c4e5d32f8c99cba659fca684fe4c2927ff6082ebec7ba028dfd52180822f90ae5771520db70d4f9ef99d8d5e1c6c2a82c88c71ca2ed200",
+		"0xa7a69a945bb781bb16de64259ef174fe59e95e56d4c3d1ed30f51365b630d819",
+	},
+}
+
+func TestDecodeGethVectors(t *testing.T) {
+	for _, v := range gethVectors {
+		raw := hexutil.MustDecode(v.raw)
+		tx, err := txcodec.Decode(raw)
+		if err != nil {
+			t.Fatalf("type %d: %v", v.txType, err)
+		}
+
+		if tx.Type != v.txType || tx.From != from || tx.Hash != types.Hash(hexutil.MustDecode(v.hash)) {
+			t.Errorf("type %d decoded as type %d from %s with hash %s", v.txType, tx.Type, tx.From, tx.Hash)
+		}
+
+		//the dynamic fee transaction creates a contract
+		if !bytes.Equal(tx.Raw, raw) || tx.ChainID == nil || tx.ChainID.Uint64() != 1337 || (tx.To == nil) != (v.txType == txcodec.DynamicFeeTxType) {
+			t.Errorf("type %d decoded as %+v", v.txType, tx)
+		}
+
+		encoded, err := tx.Encode()
+		if err != nil || !bytes.Equal(encoded, raw) {
+			t.Errorf("type %d encoded back as %x, %v", v.txType, encoded, err)
+		}
+	}
+}
+
+// the example of EIP-155
+func TestDecodeEIP155Example(t *testing.T) {
+	raw := hexutil.MustDecode("0xf86c098504a817c800825208943535353535353535353535353535353535353535880de0b6b3a76400008025a028ef61340bd939bc2195fe537567866003e1a15d3c71ff63e1590620aa636276a067cbe9d8997f761aecb703304b3800ccf555c9f3dc64214b297fb1966a3b6d83")
+	tx, err := txcodec.Decode(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := types.Address(hexutil.MustDecode("0x9d8a62f656a8d1615c1294fd71e9cfb3e4855a4f")); tx.From != want {
+		t.Fatalf("sender is %s, want %s", tx.From, want)
+	}
+
+	if tx.ChainID == nil || tx.ChainID.Uint64() != 1 || tx.Nonce != 9 || tx.GasLimit != 21000 {
+		t.Fatalf("decoded as %+v", tx)
+	}
+}
+
+func signed(t *testing.T, tx *txcodec.Transaction) *txcodec.Transaction {
+	t.Helper()
+
+	if err := txcodec.Sign(tx, key); err != nil {
+		t.Fatal(err)
+	}
+
+	return tx
+}
+
+func TestSignDecodeRoundTrip(t *testing.T) {
+	accessList := []environment.AccessTuple{{Address: to, StorageKeys: []types.Hash{{31: 1}}}}
+
+	auth := txcodec.Authorization{ChainID: chainID, Address: to, Nonce: 4}
+	if err := txcodec.SignAuthorization(&auth, key); err != nil {
+		t.Fatal(err)
+	}
+
+	txs := []*txcodec.Transaction{
+		{Type: txcodec.LegacyTxType, Nonce: 1, GasPrice: evmInt256.New(7), GasLimit: 21000, To: &to, Value: evmInt256.New(5)},
+		{Type: txcodec.LegacyTxType, ChainID: chainID, Nonce: 1, GasPrice: evmInt256.New(7), GasLimit: 21000, To: &to, Data: types.Bytes{1, 2}},
+		{Type: txcodec.AccessListTxType, ChainID: chainID, Nonce: 2, GasPrice: evmInt256.New(7), GasLimit: 50000, To: &to, AccessList: accessList},
+		{Type: txcodec.DynamicFeeTxType, ChainID: chainID, Nonce: 3, GasTipCap: evmInt256.New(1), GasFeeCap: evmInt256.New(9), GasLimit: 60000, Data: types.Bytes{0x60, 0x00}},
+		{Type: txcodec.BlobTxType, ChainID: chainID, Nonce: 4, GasTipCap: evmInt256.New(1), GasFeeCap: evmInt256.New(9), GasLimit: 70000, To: &to,
+			BlobGasFeeCap: evmInt256.New(3), BlobHashes: []types.Hash{{0: 1}}},
+		{Type: txcodec.SetCodeTxType, ChainID: chainID, Nonce: 5, GasTipCap: evmInt256.New(1), GasFeeCap: evmInt256.New(9), GasLimit: 80000, To: &to,
+			AuthorizationList: []txcodec.Authorization{auth}},
+	}
+
+	for _, tx := range txs {
+		signed(t, tx)
+		if tx.From != from {
+			t.Fatalf("type %d signed by %s, want %s", tx.Type, tx.From, from)
+		}
+
+		raw, err := tx.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(raw, tx.Raw) {
+			t.Fatalf("type %d encoded as %x, signed as %x", tx.Type, raw, tx.Raw)
+		}
+
+		decoded, err := txcodec.Decode(raw)
+		if err != nil {
+			t.Fatalf("type %d: %v", tx.Type, err)
+		}
+
+		sender, err := decoded.Sender()
+		if err != nil || sender != from || decoded.From != from {
+			t.Fatalf("type %d recovered %s and %s, %v, want %s", tx.Type, sender, decoded.From, err, from)
+		}
+
+		if decoded.Hash != tx.Hash || decoded.Nonce != tx.Nonce || decoded.GasLimit != tx.GasLimit || !reflect.DeepEqual(decoded.To, tx.To) {
+			t.Fatalf("type %d decoded as %+v, want %+v", tx.Type, decoded, tx)
+		}
+	}
+
+	authority, err := txs[len(txs)-1].AuthorizationList[0].Authority()
+	if err != nil || authority != from {
+		t.Fatalf("authority is %s, %v, want %s", authority, err, from)
+	}
+}
+
+func TestDecodeTypeRange(t *testing.T) {
+	//0x7f is the last type of EIP-2718, it is not read as RLP
+	if _, err := txcodec.Decode([]byte{0x7f, 0xc0}); !errors.Is(err, txcodec.ErrUnsupportedType) {
+		t.Fatalf("type 0x7f got %v, want %v", err, txcodec.ErrUnsupportedType)
+	}
+
+	tx := signed(t, &txcodec.Transaction{Type: txcodec.DynamicFeeTxType, ChainID: chainID, GasLimit: 21000, To: &to})
+	raw := append([]byte{}, tx.Raw...)
+	raw[len(raw)-1] ^= 1
+	if decoded, err := txcodec.Decode(raw); err == nil && decoded.From == from {
+		t.Fatalf("a changed signature recovered the sender")
+	}
+}